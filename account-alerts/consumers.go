@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// maxTopConsumers is the number of consumers listed in a subnet alert.
+const maxTopConsumers = 5
+
+// ipv4PrefixSize is the number of addresses in a delegated /28 IPv4 prefix.
+const ipv4PrefixSize = 16
+
+// networkInterfaceDescriber is the subset of the EC2 API used to break down
+// the IP consumption of a subnet.
+type networkInterfaceDescriber interface {
+	DescribeNetworkInterfacesPages(input *ec2.DescribeNetworkInterfacesInput, fn func(*ec2.DescribeNetworkInterfacesOutput, bool) bool) error
+}
+
+type ipConsumer struct {
+	name string
+	ips  int
+}
+
+// subnetIPConsumers returns the network interface types and attachments that
+// consume the most IPs in a subnet, largest first.
+func subnetIPConsumers(svc networkInterfaceDescriber, subnetID string) ([]ipConsumer, error) {
+	counts := map[string]int{}
+	err := svc.DescribeNetworkInterfacesPages(&ec2.DescribeNetworkInterfacesInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("subnet-id"),
+				Values: []*string{aws.String(subnetID)},
+			},
+		},
+	}, func(page *ec2.DescribeNetworkInterfacesOutput, _ bool) bool {
+		for _, networkInterface := range page.NetworkInterfaces {
+			counts[consumerName(networkInterface)] += len(networkInterface.PrivateIpAddresses) + ipv4PrefixSize*len(networkInterface.Ipv4Prefixes)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	consumers := make([]ipConsumer, 0, len(counts))
+	for name, ips := range counts {
+		consumers = append(consumers, ipConsumer{name: name, ips: ips})
+	}
+	sort.Slice(consumers, func(i, j int) bool {
+		if consumers[i].ips != consumers[j].ips {
+			return consumers[i].ips > consumers[j].ips
+		}
+		return consumers[i].name < consumers[j].name
+	})
+	if len(consumers) > maxTopConsumers {
+		consumers = consumers[:maxTopConsumers]
+	}
+
+	return consumers, nil
+}
+
+// consumerName groups a network interface by its type and what it is
+// attached to, e.g. "interface / i-0123" or "nat_gateway / unattached".
+func consumerName(networkInterface *ec2.NetworkInterface) string {
+	interfaceType := aws.StringValue(networkInterface.InterfaceType)
+	if interfaceType == "" {
+		interfaceType = "interface"
+	}
+
+	attachment := "unattached"
+	if networkInterface.Attachment != nil {
+		if instanceID := aws.StringValue(networkInterface.Attachment.InstanceId); instanceID != "" {
+			attachment = instanceID
+		} else if owner := aws.StringValue(networkInterface.Attachment.InstanceOwnerId); owner != "" {
+			attachment = owner
+		}
+	}
+
+	return fmt.Sprintf("%s / %s", interfaceType, attachment)
+}
+
+func formatConsumers(consumers []ipConsumer) string {
+	lines := make([]string, 0, len(consumers))
+	for _, consumer := range consumers {
+		lines = append(lines, fmt.Sprintf("%s: %d IPs", consumer.name, consumer.ips))
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEC2 serves each element of pages as one DescribeNetworkInterfaces page
+// and the subnets of each VPC ID from subnets.
+type fakeEC2 struct {
+	pages [][]*ec2.NetworkInterface
+	err   error
+	input *ec2.DescribeNetworkInterfacesInput
+
+	subnets           map[string][]*ec2.Subnet
+	describedVPCs     []string
+	onDescribeSubnets func()
+}
+
+func (f *fakeEC2) DescribeSubnets(input *ec2.DescribeSubnetsInput) (*ec2.DescribeSubnetsOutput, error) {
+	vpcID := aws.StringValue(input.Filters[0].Values[0])
+	f.describedVPCs = append(f.describedVPCs, vpcID)
+	if f.onDescribeSubnets != nil {
+		f.onDescribeSubnets()
+	}
+
+	return &ec2.DescribeSubnetsOutput{Subnets: f.subnets[vpcID]}, nil
+}
+
+func (f *fakeEC2) DescribeNetworkInterfacesPages(input *ec2.DescribeNetworkInterfacesInput, fn func(*ec2.DescribeNetworkInterfacesOutput, bool) bool) error {
+	f.input = input
+	if f.err != nil {
+		return f.err
+	}
+	for i, page := range f.pages {
+		if !fn(&ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: page}, i == len(f.pages)-1) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func networkInterface(interfaceType, instanceID, owner string, ips, prefixes int) *ec2.NetworkInterface {
+	networkInterface := &ec2.NetworkInterface{InterfaceType: aws.String(interfaceType)}
+	if instanceID != "" || owner != "" {
+		networkInterface.Attachment = &ec2.NetworkInterfaceAttachment{}
+		if instanceID != "" {
+			networkInterface.Attachment.InstanceId = aws.String(instanceID)
+		}
+		if owner != "" {
+			networkInterface.Attachment.InstanceOwnerId = aws.String(owner)
+		}
+	}
+	for i := 0; i < ips; i++ {
+		networkInterface.PrivateIpAddresses = append(networkInterface.PrivateIpAddresses, &ec2.NetworkInterfacePrivateIpAddress{})
+	}
+	for i := 0; i < prefixes; i++ {
+		networkInterface.Ipv4Prefixes = append(networkInterface.Ipv4Prefixes, &ec2.Ipv4PrefixSpecification{})
+	}
+
+	return networkInterface
+}
+
+func TestSubnetIPConsumers(t *testing.T) {
+	svc := &fakeEC2{pages: [][]*ec2.NetworkInterface{
+		{
+			networkInterface("interface", "i-node1", "", 10, 0),
+			networkInterface("interface", "i-node1", "", 5, 0),
+			networkInterface("interface", "i-node2", "", 1, 1),
+		},
+		{
+			networkInterface("nat_gateway", "", "", 1, 0),
+			networkInterface("interface", "", "amazon-elb", 3, 0),
+			networkInterface("lambda", "", "amazon-aws", 2, 0),
+			networkInterface("vpc_endpoint", "", "", 1, 0),
+		},
+	}}
+
+	consumers, err := subnetIPConsumers(svc, "subnet-1")
+	require.NoError(t, err)
+
+	assert.Equal(t, "subnet-1", *svc.input.Filters[0].Values[0])
+	assert.Equal(t, []ipConsumer{
+		{name: "interface / i-node2", ips: 17},
+		{name: "interface / i-node1", ips: 15},
+		{name: "interface / amazon-elb", ips: 3},
+		{name: "lambda / amazon-aws", ips: 2},
+		{name: "nat_gateway / unattached", ips: 1},
+	}, consumers)
+	assert.Equal(t, "interface / i-node2: 17 IPs\ninterface / i-node1: 15 IPs\ninterface / amazon-elb: 3 IPs\nlambda / amazon-aws: 2 IPs\nnat_gateway / unattached: 1 IPs", formatConsumers(consumers))
+}
+
+func TestAlertSubnetIncludesTopConsumers(t *testing.T) {
+	t.Setenv("PAGERDUTY_INTEGRATION_KEY", "")
+	mattermost := newMattermostServer(t)
+	svc := &fakeEC2{pages: [][]*ec2.NetworkInterface{{networkInterface("interface", "i-node1", "", 10, 0)}}}
+
+	alertSubnet(svc, &ec2.Subnet{
+		SubnetId:                aws.String("subnet-1"),
+		AvailableIpAddressCount: aws.Int64(5),
+	}, environmentVariables{MinSubnetFreeIPs: 100})
+
+	received := payloads(t, mattermost)
+	require.Len(t, received, 1)
+	fields := received[0].Attachments[0].Fields
+	require.Len(t, fields, 3)
+	assert.Equal(t, "Top IP Consumers", fields[2].Title)
+	assert.Equal(t, "interface / i-node1: 10 IPs", fields[2].Value)
+}
+
+func TestAlertSubnetWithoutConsumers(t *testing.T) {
+	t.Setenv("PAGERDUTY_INTEGRATION_KEY", "")
+	mattermost := newMattermostServer(t)
+
+	alertSubnet(&fakeEC2{err: errors.New("access denied")}, &ec2.Subnet{
+		SubnetId:                aws.String("subnet-1"),
+		AvailableIpAddressCount: aws.Int64(5),
+	}, environmentVariables{MinSubnetFreeIPs: 100})
+
+	received := payloads(t, mattermost)
+	require.Len(t, received, 1)
+	assert.Len(t, received[0].Attachments[0].Fields, 2)
+}
@@ -3,20 +3,24 @@ module github.com/mattermost/mattermost-cloud-lambdas/account-mattermost-alerts
 go 1.23
 
 require (
+	github.com/PagerDuty/go-pagerduty v1.8.0
 	github.com/aws/aws-lambda-go v1.47.0
 	github.com/aws/aws-sdk-go v1.55.5
 	github.com/mattermost/mattermost/server/public v0.1.9
 	github.com/pkg/errors v0.9.1
 	github.com/sirupsen/logrus v1.9.3
+	github.com/stretchr/testify v1.10.0
 )
 
 require (
 	github.com/blang/semver/v4 v4.0.0 // indirect
+	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
 	github.com/dyatlov/go-opengraph/opengraph v0.0.0-20220524092352-606d7b1e5f8a // indirect
 	github.com/fatih/color v1.18.0 // indirect
 	github.com/francoispqt/gojay v1.2.13 // indirect
 	github.com/go-asn1-ber/asn1-ber v1.5.7 // indirect
 	github.com/golang/protobuf v1.5.4 // indirect
+	github.com/google/go-querystring v1.1.0 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/gorilla/websocket v1.5.3 // indirect
 	github.com/hashicorp/errwrap v1.1.0 // indirect
@@ -28,12 +32,18 @@ require (
 	github.com/mattermost/go-i18n v1.11.1-0.20211013152124-5c415071e404 // indirect
 	github.com/mattermost/ldap v0.0.0-20231116144001-0f480c025956 // indirect
 	github.com/mattermost/logr/v2 v2.0.21 // indirect
+	github.com/mattermost/mattermost-cloud-lambdas/internal/colors v0.0.0
+	github.com/mattermost/mattermost-cloud-lambdas/internal/metrics v0.0.0
+	github.com/mattermost/mattermost-cloud-lambdas/internal/pagerduty v0.0.0
+	github.com/mattermost/mattermost-cloud-lambdas/internal/retry v0.0.0
+	github.com/mattermost/mattermost-cloud-lambdas/internal/testutil v0.0.0
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/oklog/run v1.1.0 // indirect
 	github.com/pborman/uuid v1.2.1 // indirect
 	github.com/pelletier/go-toml v1.9.5 // indirect
 	github.com/philhofer/fwd v1.1.3-0.20240916144458-20a13a1f6b7c // indirect
+	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
 	github.com/rogpeppe/go-internal v1.12.0 // indirect
 	github.com/tinylib/msgp v1.2.5 // indirect
 	github.com/vmihailenco/msgpack/v5 v5.4.1 // indirect
@@ -49,4 +59,15 @@ require (
 	google.golang.org/protobuf v1.36.1 // indirect
 	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/testutil => ../internal/testutil
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/metrics => ../internal/metrics
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/retry => ../internal/retry
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/colors => ../internal/colors
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/pagerduty => ../internal/pagerduty
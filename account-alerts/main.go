@@ -7,6 +7,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws"
@@ -15,19 +16,34 @@ import (
 	"github.com/pkg/errors"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
 type environmentVariables struct {
 	MinSubnetFreeIPs int64
+	// CriticalSubnetFreeIPs is optional; zero disables the warning tier.
+	CriticalSubnetFreeIPs int64
+	// IgnoredSubnetIDs are never evaluated, e.g. intentionally small subnets.
+	IgnoredSubnetIDs []string
+	// VPCTagKey and VPCTagValue select the provisioning VPCs to check. With
+	// VPCTagNegate the VPCs whose tag is set to any other value are checked.
+	VPCTagKey    string
+	VPCTagValue  string
+	VPCTagNegate bool
 }
 
 func main() {
 	lambda.Start(handler)
 }
 
-func handler() {
+// deadlineMargin is the time left before the Lambda deadline under which no
+// further VPC is evaluated, so the summary can still be reported.
+const deadlineMargin = 10 * time.Second
+
+func handler(ctx context.Context) {
 
 	envVars, err := validateAndGetEnvVars()
 	if err != nil {
@@ -40,7 +56,7 @@ func handler() {
 	}
 
 	log.Info("Getting existing Provisioning Subnet IP limits")
-	err = checkProvisioningSubnetIPLimits(*envVars)
+	err = checkProvisioningSubnetIPLimits(ctx, *envVars)
 	if err != nil {
 		log.WithError(err).Error("Unable to get the number of available VPCs")
 	}
@@ -60,11 +76,63 @@ func validateAndGetEnvVars() (*environmentVariables, error) {
 	}
 	envVars.MinSubnetFreeIPs = int64(number)
 
+	criticalSubnetFreeIPs := os.Getenv("CRITICAL_SUBNET_FREE_IPs")
+	if len(criticalSubnetFreeIPs) > 0 {
+		number, err = strconv.Atoi(criticalSubnetFreeIPs)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid CRITICAL_SUBNET_FREE_IPs")
+		}
+		if int64(number) > envVars.MinSubnetFreeIPs {
+			return nil, errors.Errorf("CRITICAL_SUBNET_FREE_IPs (%d) must not be greater than MIN_SUBNET_FREE_IPs (%d)", number, envVars.MinSubnetFreeIPs)
+		}
+		envVars.CriticalSubnetFreeIPs = int64(number)
+	}
+
+	envVars.IgnoredSubnetIDs = parseList(os.Getenv("IGNORED_SUBNET_IDS"))
+
+	envVars.VPCTagKey = defaultVPCTagKey
+	if vpcTagKey := os.Getenv("VPC_TAG_KEY"); vpcTagKey != "" {
+		envVars.VPCTagKey = vpcTagKey
+	}
+	envVars.VPCTagValue = defaultVPCTagValue
+	if vpcTagValue := os.Getenv("VPC_TAG_VALUE"); vpcTagValue != "" {
+		envVars.VPCTagValue = vpcTagValue
+	}
+	if vpcTagNegate := os.Getenv("VPC_TAG_NEGATE"); vpcTagNegate != "" {
+		envVars.VPCTagNegate, err = strconv.ParseBool(vpcTagNegate)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid VPC_TAG_NEGATE")
+		}
+	}
+
 	return envVars, nil
 }
 
+// parseList splits a comma-separated environment variable value.
+func parseList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+func (e environmentVariables) isIgnoredSubnet(subnetID string) bool {
+	for _, ignored := range e.IgnoredSubnetIDs {
+		if ignored == subnetID {
+			return true
+		}
+	}
+
+	return false
+}
+
 // getSetProvisioningSubnetIPLimits is used to get the Provisioning VPCs Subnet IP limits and set the CW metric data.
-func checkProvisioningSubnetIPLimits(envVars environmentVariables) error {
+func checkProvisioningSubnetIPLimits(ctx context.Context, envVars environmentVariables) error {
 	sess, err := session.NewSession(&aws.Config{})
 	if err != nil {
 		return err
@@ -73,20 +141,39 @@ func checkProvisioningSubnetIPLimits(envVars environmentVariables) error {
 	svc := ec2.New(sess)
 
 	vpcs, err := svc.DescribeVpcs(&ec2.DescribeVpcsInput{
-		Filters: []*ec2.Filter{
-			{
-				Name: aws.String("tag:Available"),
-				Values: []*string{
-					aws.String("false"),
-				},
-			},
-		},
+		Filters: vpcFilters(envVars),
 	})
 	if err != nil {
 		return err
 	}
 
+	var selected []*ec2.Vpc
 	for _, vpc := range vpcs.Vpcs {
+		if vpcSelected(vpc, envVars) {
+			selected = append(selected, vpc)
+		}
+	}
+
+	return checkVPCs(ctx, svc, selected, envVars)
+}
+
+// subnetDescriber is the subset of the EC2 API used to check the subnets of
+// a VPC.
+type subnetDescriber interface {
+	networkInterfaceDescriber
+	DescribeSubnets(input *ec2.DescribeSubnetsInput) (*ec2.DescribeSubnetsOutput, error)
+}
+
+// checkVPCs alerts on the subnets of each VPC. It stops early when the
+// context is done or its deadline is too close, and reports how many VPCs
+// were evaluated either way.
+func checkVPCs(ctx context.Context, svc subnetDescriber, vpcs []*ec2.Vpc, envVars environmentVariables) error {
+	for i, vpc := range vpcs {
+		if err := deadlineReached(ctx); err != nil {
+			log.WithError(err).Warnf("Stopping early, evaluated %d of %d VPCs", i, len(vpcs))
+			return errors.Wrapf(err, "evaluated %d of %d VPCs", i, len(vpcs))
+		}
+
 		log.Infof("Exploring VPC %s", *vpc.VpcId)
 		subnets, err := svc.DescribeSubnets(&ec2.DescribeSubnetsInput{
 			Filters: []*ec2.Filter{
@@ -102,12 +189,61 @@ func checkProvisioningSubnetIPLimits(envVars environmentVariables) error {
 			return err
 		}
 		for _, subnet := range subnets.Subnets {
-			if *subnet.AvailableIpAddressCount < envVars.MinSubnetFreeIPs {
-				log.Infof("Subnet %s has low number of available IPs (%d)", *subnet.SubnetId, *subnet.AvailableIpAddressCount)
-				sendMattermostAlertNotification(fmt.Sprintf("Subnet %s has low number of available IPs (%d)", *subnet.SubnetId, *subnet.AvailableIpAddressCount), "VPC Subnets")
-			}
+			alertSubnet(svc, subnet, envVars)
 		}
 	}
 
+	log.Infof("Evaluated %d of %d VPCs", len(vpcs), len(vpcs))
+	return nil
+}
+
+// deadlineReached returns an error when the context is done or less than
+// deadlineMargin is left before its deadline.
+func deadlineReached(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < deadlineMargin {
+		return context.DeadlineExceeded
+	}
+
 	return nil
 }
+
+// alertSubnet notifies Mattermost when a subnet is low on free IPs and pages
+// PagerDuty when it is critically low.
+//
+// Only IPv4 capacity is checked. EC2 does not report a free IPv6 address
+// count, and subnet IPv6 CIDRs are always /64, so dual-stack subnets cannot
+// realistically run out of IPv6 addresses.
+func alertSubnet(svc networkInterfaceDescriber, subnet *ec2.Subnet, envVars environmentVariables) {
+	if envVars.isIgnoredSubnet(*subnet.SubnetId) {
+		log.WithField("subnet", *subnet.SubnetId).Debug("Skipping ignored subnet")
+		return
+	}
+
+	severity := subnetSeverity(*subnet.AvailableIpAddressCount, envVars)
+	if severity == severityNone {
+		return
+	}
+
+	message := fmt.Sprintf("Subnet %s has low number of available IPs (%d)", *subnet.SubnetId, *subnet.AvailableIpAddressCount)
+	log.WithField("severity", severity).Info(message)
+
+	var topConsumers string
+	consumers, err := subnetIPConsumers(svc, *subnet.SubnetId)
+	if err != nil {
+		log.WithError(err).WithField("subnet", *subnet.SubnetId).Warn("Failed to describe subnet network interfaces")
+	} else {
+		topConsumers = formatConsumers(consumers)
+	}
+
+	err = sendMattermostAlertNotification(message, "VPC Subnets", severityColor(severity), topConsumers)
+	if err != nil {
+		log.WithError(err).Error("Failed to send Mattermost alert notification")
+	}
+
+	if severity == severityCritical {
+		sendPagerDutyNotification(message, "VPC Subnets")
+	}
+}
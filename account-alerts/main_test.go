@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/colors"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMattermostServer starts a fake webhook that account-alerts posts to.
+func newMattermostServer(t *testing.T) *testutil.MattermostServer {
+	t.Helper()
+
+	server := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_ALERTS_HOOK", server.URL)
+
+	return server
+}
+
+// newPagerDutyServer starts a fake PagerDuty Events API that account-alerts
+// pages.
+func newPagerDutyServer(t *testing.T) *testutil.PagerDutyServer {
+	t.Helper()
+
+	server := testutil.NewPagerDutyServer(t)
+	t.Setenv("PAGERDUTY_INTEGRATION_KEY", "routing-key")
+
+	previousClient := pagerDutyClient
+	pagerDutyClient = server.Client()
+	t.Cleanup(func() { pagerDutyClient = previousClient })
+
+	return server
+}
+
+func payloads(t *testing.T, server *testutil.MattermostServer) []model.CommandResponse {
+	return testutil.Payloads[model.CommandResponse](t, server)
+}
+
+func TestValidateAndGetEnvVars(t *testing.T) {
+	t.Run("min only", func(t *testing.T) {
+		t.Setenv("MIN_SUBNET_FREE_IPs", "100")
+		t.Setenv("CRITICAL_SUBNET_FREE_IPs", "")
+
+		envVars, err := validateAndGetEnvVars()
+		require.NoError(t, err)
+		assert.Equal(t, environmentVariables{
+			MinSubnetFreeIPs: 100,
+			VPCTagKey:        defaultVPCTagKey,
+			VPCTagValue:      defaultVPCTagValue,
+		}, *envVars)
+	})
+
+	t.Run("ignored subnets", func(t *testing.T) {
+		t.Setenv("MIN_SUBNET_FREE_IPs", "100")
+		t.Setenv("IGNORED_SUBNET_IDS", "subnet-1, subnet-2,")
+
+		envVars, err := validateAndGetEnvVars()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"subnet-1", "subnet-2"}, envVars.IgnoredSubnetIDs)
+	})
+
+	t.Run("critical", func(t *testing.T) {
+		t.Setenv("MIN_SUBNET_FREE_IPs", "100")
+		t.Setenv("CRITICAL_SUBNET_FREE_IPs", "20")
+
+		envVars, err := validateAndGetEnvVars()
+		require.NoError(t, err)
+		assert.Equal(t, int64(20), envVars.CriticalSubnetFreeIPs)
+	})
+
+	t.Run("critical above minimum", func(t *testing.T) {
+		t.Setenv("MIN_SUBNET_FREE_IPs", "100")
+		t.Setenv("CRITICAL_SUBNET_FREE_IPs", "200")
+
+		_, err := validateAndGetEnvVars()
+		require.Error(t, err)
+	})
+
+	t.Run("vpc tag", func(t *testing.T) {
+		t.Setenv("MIN_SUBNET_FREE_IPs", "100")
+		t.Setenv("VPC_TAG_KEY", "InUse")
+		t.Setenv("VPC_TAG_VALUE", "true")
+		t.Setenv("VPC_TAG_NEGATE", "true")
+
+		envVars, err := validateAndGetEnvVars()
+		require.NoError(t, err)
+		assert.Equal(t, "InUse", envVars.VPCTagKey)
+		assert.Equal(t, "true", envVars.VPCTagValue)
+		assert.True(t, envVars.VPCTagNegate)
+	})
+
+	t.Run("invalid vpc tag negate", func(t *testing.T) {
+		t.Setenv("MIN_SUBNET_FREE_IPs", "100")
+		t.Setenv("VPC_TAG_NEGATE", "sometimes")
+
+		_, err := validateAndGetEnvVars()
+		require.Error(t, err)
+	})
+
+	t.Run("invalid critical", func(t *testing.T) {
+		t.Setenv("MIN_SUBNET_FREE_IPs", "100")
+		t.Setenv("CRITICAL_SUBNET_FREE_IPs", "low")
+
+		_, err := validateAndGetEnvVars()
+		require.Error(t, err)
+	})
+}
+
+func TestAlertSubnetTiers(t *testing.T) {
+	envVars := environmentVariables{MinSubnetFreeIPs: 100, CriticalSubnetFreeIPs: 20}
+
+	testCases := []struct {
+		name          string
+		available     int64
+		expectedColor string
+		expectedPages int
+	}{
+		{"warning", 50, colors.DefaultWarning, 0},
+		{"critical", 5, colors.DefaultFailure, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mattermost := newMattermostServer(t)
+			pagerDuty := newPagerDutyServer(t)
+
+			alertSubnet(&fakeEC2{}, &ec2.Subnet{
+				SubnetId:                aws.String("subnet-1"),
+				AvailableIpAddressCount: aws.Int64(tc.available),
+			}, envVars)
+
+			received := payloads(t, mattermost)
+			require.Len(t, received, 1)
+			attachment := received[0].Attachments[0]
+			assert.Equal(t, tc.expectedColor, attachment.Color)
+			assert.Contains(t, attachment.Fields[0].Title, "subnet-1")
+			assert.Len(t, pagerDuty.Events(), tc.expectedPages)
+		})
+	}
+
+	t.Run("healthy", func(t *testing.T) {
+		mattermost := newMattermostServer(t)
+		pagerDuty := newPagerDutyServer(t)
+
+		alertSubnet(&fakeEC2{}, &ec2.Subnet{
+			SubnetId:                aws.String("subnet-1"),
+			AvailableIpAddressCount: aws.Int64(500),
+		}, envVars)
+
+		assert.Empty(t, payloads(t, mattermost))
+		assert.Empty(t, pagerDuty.Events())
+	})
+}
+
+func TestAlertSubnetIgnored(t *testing.T) {
+	t.Setenv("PAGERDUTY_INTEGRATION_KEY", "")
+	mattermost := newMattermostServer(t)
+	envVars := environmentVariables{MinSubnetFreeIPs: 100, IgnoredSubnetIDs: []string{"subnet-mgmt"}}
+
+	alertSubnet(&fakeEC2{}, &ec2.Subnet{
+		SubnetId:                aws.String("subnet-mgmt"),
+		AvailableIpAddressCount: aws.Int64(1),
+	}, envVars)
+	assert.Empty(t, payloads(t, mattermost))
+
+	alertSubnet(&fakeEC2{}, &ec2.Subnet{
+		SubnetId:                aws.String("subnet-app"),
+		AvailableIpAddressCount: aws.Int64(1),
+	}, envVars)
+	assert.Len(t, payloads(t, mattermost), 1)
+}
+
+func testVPCs(n int) []*ec2.Vpc {
+	var vpcs []*ec2.Vpc
+	for i := 0; i < n; i++ {
+		vpcs = append(vpcs, &ec2.Vpc{VpcId: aws.String(fmt.Sprintf("vpc-%d", i))})
+	}
+
+	return vpcs
+}
+
+func TestCheckVPCs(t *testing.T) {
+	envVars := environmentVariables{MinSubnetFreeIPs: 100}
+
+	t.Run("all evaluated", func(t *testing.T) {
+		mattermost := newMattermostServer(t)
+		svc := &fakeEC2{subnets: map[string][]*ec2.Subnet{
+			"vpc-2": {{SubnetId: aws.String("subnet-1"), AvailableIpAddressCount: aws.Int64(50)}},
+		}}
+
+		require.NoError(t, checkVPCs(context.Background(), svc, testVPCs(3), envVars))
+		assert.Equal(t, []string{"vpc-0", "vpc-1", "vpc-2"}, svc.describedVPCs)
+		assert.Len(t, payloads(t, mattermost), 1)
+	})
+
+	t.Run("cancelled partway", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		svc := &fakeEC2{}
+		svc.onDescribeSubnets = func() {
+			if len(svc.describedVPCs) == 2 {
+				cancel()
+			}
+		}
+
+		err := checkVPCs(ctx, svc, testVPCs(5), envVars)
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Contains(t, err.Error(), "evaluated 2 of 5 VPCs")
+		assert.Equal(t, []string{"vpc-0", "vpc-1"}, svc.describedVPCs)
+	})
+
+	t.Run("deadline too close", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), deadlineMargin/2)
+		defer cancel()
+
+		svc := &fakeEC2{}
+		err := checkVPCs(ctx, svc, testVPCs(3), envVars)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Contains(t, err.Error(), "evaluated 0 of 3 VPCs")
+		assert.Empty(t, svc.describedVPCs)
+	})
+}
+
+func TestDeadlineReached(t *testing.T) {
+	assert.NoError(t, deadlineReached(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	assert.NoError(t, deadlineReached(ctx))
+}
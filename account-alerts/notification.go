@@ -5,33 +5,55 @@ import (
 	"encoding/json"
 	"net/http"
 	"os"
+	"time"
 
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/colors"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/metrics"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/retry"
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/pkg/errors"
 )
 
+const sendAttempts = 3
+
+// sendRetryDelay is the initial delay between attempts to send a payload.
+var sendRetryDelay = time.Second
+
+// send posts the payload to the Mattermost webhook, retrying network errors
+// and 5xx responses with backoff.
 func send(webhookURL string, payload model.CommandResponse) error {
 	marshalContent, err := json.Marshal(payload)
 	if err != nil {
 		return errors.Wrap(err, "failed to marshal payload")
 	}
 
-	req, err := http.NewRequest("POST", webhookURL, bytes.NewBuffer(marshalContent))
+	return retry.Do(sendAttempts, sendRetryDelay, func() error {
+		return post(webhookURL, marshalContent)
+	})
+}
+
+func post(webhookURL string, body []byte) error {
+	req, err := http.NewRequest("POST", webhookURL, bytes.NewBuffer(body))
 	if err != nil {
-		return errors.Wrap(err, "failed to create HTTP request")
+		return retry.Permanent(errors.Wrap(err, "failed to create HTTP request"))
 	}
 	req.Header.Set("X-Custom-Header", "aws-sns")
 	req.Header.Set("Content-Type", "application/json")
 
 	client := &http.Client{}
+	start := time.Now()
 	resp, err := client.Do(req)
+	metrics.RecordNotificationLatency(metrics.TargetMattermost, start)
 	if err != nil {
 		return errors.Wrap(err, "failed to send HTTP request")
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return errors.Errorf("unexpected response status: %s", resp.Status)
+	}
 	if resp.StatusCode != http.StatusOK {
-		return errors.Wrapf(err, "unexpected response status: %s", resp.Status)
+		return retry.Permanent(errors.Errorf("unexpected response status: %s", resp.Status))
 	}
 
 	return nil
@@ -39,7 +61,7 @@ func send(webhookURL string, payload model.CommandResponse) error {
 
 func sendMattermostErrorNotification(errorMessage error, message string) error {
 	attachment := &model.SlackAttachment{
-		Color: "#FF0000",
+		Color: colors.Failure(),
 		Fields: []*model.SlackAttachmentField{
 			{Title: message, Short: false},
 			{Title: "Error Message", Value: errorMessage.Error(), Short: false},
@@ -59,14 +81,17 @@ func sendMattermostErrorNotification(errorMessage error, message string) error {
 	return nil
 }
 
-func sendMattermostAlertNotification(message, resource string) error {
+func sendMattermostAlertNotification(message, resource, color, topConsumers string) error {
 	attachment := &model.SlackAttachment{
-		Color: "#FF0000",
+		Color: color,
 		Fields: []*model.SlackAttachmentField{
 			{Title: message, Short: false},
 			{Title: "Resource", Value: resource, Short: true},
 		},
 	}
+	if topConsumers != "" {
+		attachment.Fields = append(attachment.Fields, &model.SlackAttachmentField{Title: "Top IP Consumers", Value: topConsumers, Short: false})
+	}
 
 	payload := model.CommandResponse{
 		Username:    "Account Alerts",
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/colors"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStatusServer answers each request with the next of statuses, repeating
+// the last one, and counts the requests.
+func newStatusServer(t *testing.T, statuses ...int) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		i := int(atomic.AddInt32(&requests, 1)) - 1
+		if i >= len(statuses) {
+			i = len(statuses) - 1
+		}
+		w.WriteHeader(statuses[i])
+	}))
+	t.Cleanup(server.Close)
+
+	previousDelay := sendRetryDelay
+	sendRetryDelay = time.Millisecond
+	t.Cleanup(func() { sendRetryDelay = previousDelay })
+
+	return server, &requests
+}
+
+func TestSend(t *testing.T) {
+	t.Run("retries server errors", func(t *testing.T) {
+		server, requests := newStatusServer(t, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK)
+
+		require.NoError(t, send(server.URL, model.CommandResponse{Text: "alert"}))
+		assert.EqualValues(t, 3, atomic.LoadInt32(requests))
+	})
+
+	t.Run("gives up after the last attempt", func(t *testing.T) {
+		server, requests := newStatusServer(t, http.StatusInternalServerError)
+
+		err := send(server.URL, model.CommandResponse{Text: "alert"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "500 Internal Server Error")
+		assert.EqualValues(t, sendAttempts, atomic.LoadInt32(requests))
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		server, requests := newStatusServer(t, http.StatusBadRequest)
+
+		err := send(server.URL, model.CommandResponse{Text: "alert"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "400 Bad Request")
+		assert.EqualValues(t, 1, atomic.LoadInt32(requests))
+	})
+
+	t.Run("retries network errors", func(t *testing.T) {
+		server, _ := newStatusServer(t, http.StatusOK)
+		server.Close()
+
+		assert.Error(t, send(server.URL, model.CommandResponse{Text: "alert"}))
+	})
+}
+
+func TestSendMattermostAlertNotificationStatusError(t *testing.T) {
+	mattermost := newMattermostServer(t)
+	mattermost.SetStatusCode(http.StatusInternalServerError)
+
+	previousDelay := sendRetryDelay
+	sendRetryDelay = time.Millisecond
+	t.Cleanup(func() { sendRetryDelay = previousDelay })
+
+	assert.Error(t, sendMattermostAlertNotification("Subnet subnet-1 is low", "VPC Subnets", colors.DefaultWarning, ""))
+	assert.Error(t, sendMattermostErrorNotification(assert.AnError, "Environment variable validation failed"))
+}
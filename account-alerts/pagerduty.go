@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	pagerduty "github.com/PagerDuty/go-pagerduty"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/metrics"
+	cloudpagerduty "github.com/mattermost/mattermost-cloud-lambdas/internal/pagerduty"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultPagerDutySource is the source of the PagerDuty events when
+// PAGERDUTY_SOURCE is unset.
+const defaultPagerDutySource = "account-alerts"
+
+// pagerDutyClient sends the PagerDuty events.
+var pagerDutyClient = pagerduty.NewClient("")
+
+// sendPagerDutyNotification triggers a PagerDuty incident when
+// PAGERDUTY_INTEGRATION_KEY is configured.
+func sendPagerDutyNotification(summary, resource string) {
+	integrationKey := os.Getenv("PAGERDUTY_INTEGRATION_KEY")
+	if integrationKey == "" {
+		log.Debug("No PagerDuty Integration Key setup")
+		return
+	}
+
+	event := pagerduty.V2Event{
+		RoutingKey: integrationKey,
+		Action:     "trigger",
+		Payload: &pagerduty.V2Payload{
+			Summary:  summary,
+			Severity: severityCritical,
+			Details: map[string]interface{}{
+				"Resource": resource,
+			},
+		},
+	}
+	cloudpagerduty.SetOrigin(event.Payload, defaultPagerDutySource)
+
+	start := time.Now()
+	_, err := pagerDutyClient.ManageEventWithContext(context.TODO(), &event)
+	metrics.RecordNotificationLatency(metrics.TargetPagerDuty, start)
+	if err != nil {
+		log.WithError(err).Error("Failed to send PagerDuty notification")
+		return
+	}
+
+	log.Info("PagerDuty event sent successfully")
+}
@@ -0,0 +1,32 @@
+package main
+
+import "github.com/mattermost/mattermost-cloud-lambdas/internal/colors"
+
+const (
+	severityNone     = ""
+	severityWarning  = "warning"
+	severityCritical = "critical"
+)
+
+// subnetSeverity returns the alert severity for a subnet with the given number
+// of free IPs. Subnets below CRITICAL_SUBNET_FREE_IPs are critical and subnets
+// below MIN_SUBNET_FREE_IPs are a warning. Without a critical threshold every
+// subnet below the minimum is critical, as before.
+func subnetSeverity(availableIPs int64, envVars environmentVariables) string {
+	if availableIPs >= envVars.MinSubnetFreeIPs {
+		return severityNone
+	}
+	if envVars.CriticalSubnetFreeIPs == 0 || availableIPs < envVars.CriticalSubnetFreeIPs {
+		return severityCritical
+	}
+
+	return severityWarning
+}
+
+func severityColor(severity string) string {
+	if severity == severityWarning {
+		return colors.Warning()
+	}
+
+	return colors.Failure()
+}
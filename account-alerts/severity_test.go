@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/colors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubnetSeverity(t *testing.T) {
+	tiered := environmentVariables{MinSubnetFreeIPs: 100, CriticalSubnetFreeIPs: 20}
+	minOnly := environmentVariables{MinSubnetFreeIPs: 100}
+
+	testCases := []struct {
+		name      string
+		available int64
+		envVars   environmentVariables
+		expected  string
+	}{
+		{"healthy", 150, tiered, severityNone},
+		{"at minimum", 100, tiered, severityNone},
+		{"warning", 50, tiered, severityWarning},
+		{"at critical threshold", 20, tiered, severityWarning},
+		{"critical", 5, tiered, severityCritical},
+		{"minimum only", 50, minOnly, severityCritical},
+		{"minimum only healthy", 150, minOnly, severityNone},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, subnetSeverity(tc.available, tc.envVars))
+		})
+	}
+}
+
+func TestSeverityColor(t *testing.T) {
+	assert.Equal(t, colors.DefaultWarning, severityColor(severityWarning))
+	assert.Equal(t, colors.DefaultFailure, severityColor(severityCritical))
+
+	t.Setenv("COLOR_WARNING", "#FFCC00")
+	t.Setenv("COLOR_FAILURE", "#CC0000")
+	assert.Equal(t, "#FFCC00", severityColor(severityWarning))
+	assert.Equal(t, "#CC0000", severityColor(severityCritical))
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+const (
+	defaultVPCTagKey   = "Available"
+	defaultVPCTagValue = "false"
+)
+
+// vpcFilters returns the DescribeVpcs filters selecting the VPCs to check.
+// EC2 filters cannot express "not equal", so with VPC_TAG_NEGATE only the
+// VPCs carrying the tag are listed and vpcSelected drops the ones that match.
+func vpcFilters(envVars environmentVariables) []*ec2.Filter {
+	if envVars.VPCTagNegate {
+		return []*ec2.Filter{{
+			Name:   aws.String("tag-key"),
+			Values: []*string{aws.String(envVars.VPCTagKey)},
+		}}
+	}
+
+	return []*ec2.Filter{{
+		Name:   aws.String(fmt.Sprintf("tag:%s", envVars.VPCTagKey)),
+		Values: []*string{aws.String(envVars.VPCTagValue)},
+	}}
+}
+
+// vpcSelected reports whether the VPC should be checked: its VPC_TAG_KEY tag
+// equals VPC_TAG_VALUE or, with VPC_TAG_NEGATE, is set to anything else.
+func vpcSelected(vpc *ec2.Vpc, envVars environmentVariables) bool {
+	for _, tag := range vpc.Tags {
+		if aws.StringValue(tag.Key) != envVars.VPCTagKey {
+			continue
+		}
+
+		return (aws.StringValue(tag.Value) == envVars.VPCTagValue) != envVars.VPCTagNegate
+	}
+
+	return false
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func vpcWithTag(key, value string) *ec2.Vpc {
+	return &ec2.Vpc{
+		VpcId: aws.String("vpc-1"),
+		Tags:  []*ec2.Tag{{Key: aws.String(key), Value: aws.String(value)}},
+	}
+}
+
+func TestVPCFilters(t *testing.T) {
+	filters := vpcFilters(environmentVariables{VPCTagKey: "Available", VPCTagValue: "false"})
+	require.Len(t, filters, 1)
+	assert.Equal(t, "tag:Available", *filters[0].Name)
+	assert.Equal(t, []*string{aws.String("false")}, filters[0].Values)
+
+	filters = vpcFilters(environmentVariables{VPCTagKey: "Available", VPCTagValue: "true", VPCTagNegate: true})
+	require.Len(t, filters, 1)
+	assert.Equal(t, "tag-key", *filters[0].Name)
+	assert.Equal(t, []*string{aws.String("Available")}, filters[0].Values)
+}
+
+func TestVPCSelected(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		negate   bool
+		vpc      *ec2.Vpc
+		expected bool
+	}{
+		{"equals false, tagged false", "false", false, vpcWithTag("Available", "false"), true},
+		{"equals false, tagged true", "false", false, vpcWithTag("Available", "true"), false},
+		{"equals true, tagged true", "true", false, vpcWithTag("Available", "true"), true},
+		{"not true, tagged false", "true", true, vpcWithTag("Available", "false"), true},
+		{"not true, tagged true", "true", true, vpcWithTag("Available", "true"), false},
+		{"not false, tagged true", "false", true, vpcWithTag("Available", "true"), true},
+		{"not true, other tag", "true", true, vpcWithTag("Name", "vpc"), false},
+		{"untagged", "false", false, &ec2.Vpc{}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			envVars := environmentVariables{VPCTagKey: "Available", VPCTagValue: tc.value, VPCTagNegate: tc.negate}
+			assert.Equal(t, tc.expected, vpcSelected(tc.vpc, envVars))
+		})
+	}
+}
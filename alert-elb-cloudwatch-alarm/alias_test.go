@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/accountalias"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIAM embeds the client interface, so any call it does not implement
+// panics and fails the test.
+type fakeIAM struct {
+	iamiface.IAMAPI
+	aliases []string
+}
+
+func (f *fakeIAM) ListAccountAliases(*iam.ListAccountAliasesInput) (*iam.ListAccountAliasesOutput, error) {
+	return &iam.ListAccountAliasesOutput{AccountAliases: aws.StringSlice(f.aliases)}, nil
+}
+
+func useFakeIAM(t *testing.T, fake *fakeIAM) {
+	t.Helper()
+
+	previous := accountAliases
+	accountAliases = &accountalias.Cache{NewClient: func() (iamiface.IAMAPI, error) { return fake, nil }}
+	t.Cleanup(func() { accountAliases = previous })
+}
+
+func TestHandlerAccountAlias(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	pagerDuty := testutil.NewPagerDutyServer(t)
+	previousClient := pagerDutyClient
+	pagerDutyClient = pagerDuty.Client()
+	defer func() { pagerDutyClient = previousClient }()
+	t.Setenv("MATTERMOST_HOOK", mattermost.URL)
+	t.Setenv("ENVIRONMENT", "prod")
+	t.Setenv("PAGERDUTY_INTEGRATION_KEY", "routing-key")
+	t.Setenv("INCLUDE_ACCOUNT_ALIAS", "true")
+	useFakeIAM(t, &fakeIAM{aliases: []string{"mattermost-prod"}})
+
+	handler(context.Background(), testutil.SNSEvent(alarmMessage(t, "Alarm-my-elb")))
+
+	payloads := testutil.Payloads[MMSlashResponse](t, mattermost)
+	require.Len(t, payloads, 1)
+	fields := payloads[0].Attachments[0].Fields
+	assert.Equal(t, "AWS Account", fields[2].Title)
+	assert.Equal(t, &MMField{Title: "Account Alias", Value: "mattermost-prod", Short: true}, fields[3])
+
+	events := pagerDuty.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, "mattermost-prod", events[0].Payload.Details.(map[string]interface{})["Account Alias"])
+}
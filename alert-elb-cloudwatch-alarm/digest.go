@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	log "github.com/sirupsen/logrus"
+)
+
+// digestMode reports whether DIGEST_MODE is set to true, in which case all
+// the alarms of an SNS batch are posted to Mattermost as a single message.
+func digestMode() bool {
+	return strings.EqualFold(os.Getenv("DIGEST_MODE"), "true")
+}
+
+// processDigest posts the alarms in the batch as one Mattermost message per
+// webhook with an attachment per alarm, then notifies PagerDuty about each
+// alarm on its own. Records that fail to decode or come from a denied
+// namespace are logged and left out of the digest.
+func processDigest(snsEvent events.SNSEvent) {
+	var source string
+	var hooks []string
+	var messageNotifications []SNSMessageNotification
+	digests := map[string][]SNSMessageNotification{}
+	for _, record := range snsEvent.Records {
+		messageNotification, err := decodeAlarm(record.SNS.Message)
+		if err != nil {
+			log.WithError(err).Error("Decode Error on message notification")
+			continue
+		}
+		if namespaceDenied(messageNotification.Trigger.Namespace) {
+			logDeniedAlarm(messageNotification)
+			continue
+		}
+		source = record.EventSource
+		messageNotifications = append(messageNotifications, messageNotification)
+
+		hook := mattermostHook(record.SNS.MessageAttributes)
+		if _, ok := digests[hook]; !ok {
+			hooks = append(hooks, hook)
+		}
+		digests[hook] = append(digests[hook], messageNotification)
+	}
+
+	if len(messageNotifications) == 0 {
+		return
+	}
+
+	for _, hook := range hooks {
+		if err := sendMattermostNotification(source, hook, digests[hook]...); err != nil {
+			log.WithError(err).WithField("alarms", len(digests[hook])).Error("Failed to deliver alarm digest")
+		}
+	}
+	for _, messageNotification := range messageNotifications {
+		notifyPagerDuty(messageNotification)
+	}
+}
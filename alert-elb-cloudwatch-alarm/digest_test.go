@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func alarmMessage(t *testing.T, name string) string {
+	t.Helper()
+
+	message, err := json.Marshal(testutil.CloudWatchAlarm{AlarmName: name, NewStateValue: alarmStateAlarm})
+	require.NoError(t, err)
+
+	return string(message)
+}
+
+func TestHandlerDigestMode(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	pagerDuty := testutil.NewPagerDutyServer(t)
+	previousClient := pagerDutyClient
+	pagerDutyClient = pagerDuty.Client()
+	defer func() { pagerDutyClient = previousClient }()
+	t.Setenv("MATTERMOST_HOOK", mattermost.URL)
+	t.Setenv("ENVIRONMENT", "prod")
+	t.Setenv("PAGERDUTY_INTEGRATION_KEY", "routing-key")
+	t.Setenv("ALARM_MENTION_MAP", `{"Alarm-": "@sre"}`)
+	t.Setenv("DIGEST_MODE", "true")
+
+	handler(context.Background(), testutil.SNSEvent(
+		alarmMessage(t, "Alarm-first"),
+		"not json",
+		alarmMessage(t, "Alarm-second"),
+		alarmMessage(t, "Alarm-third"),
+	))
+
+	payloads := testutil.Payloads[MMSlashResponse](t, mattermost)
+	require.Len(t, payloads, 1)
+	require.Len(t, payloads[0].Attachments, 3)
+	assert.Equal(t, "Alarm-first", payloads[0].Attachments[0].Fields[0].Value)
+	assert.Equal(t, "Alarm-second", payloads[0].Attachments[1].Fields[0].Value)
+	assert.Equal(t, "Alarm-third", payloads[0].Attachments[2].Fields[0].Value)
+	assert.Equal(t, "@sre", payloads[0].Text)
+
+	pagerDutyEvents := pagerDuty.Events()
+	require.Len(t, pagerDutyEvents, 3)
+	assert.Equal(t, "Alarm-first - ", pagerDutyEvents[0].Payload.Summary)
+	assert.Equal(t, "Alarm-third - ", pagerDutyEvents[2].Payload.Summary)
+}
+
+func TestHandlerWithoutDigestMode(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_HOOK", mattermost.URL)
+	t.Setenv("ENVIRONMENT", "test")
+
+	handler(context.Background(), testutil.SNSEvent(
+		alarmMessage(t, "Alarm-first"),
+		alarmMessage(t, "Alarm-second"),
+	))
+
+	assert.Len(t, testutil.Payloads[MMSlashResponse](t, mattermost), 2)
+}
@@ -5,11 +5,38 @@ go 1.23
 require (
 	github.com/PagerDuty/go-pagerduty v1.8.0
 	github.com/aws/aws-lambda-go v1.47.0
+	github.com/aws/aws-sdk-go v1.55.5
 	github.com/pkg/errors v0.9.1
 	github.com/sirupsen/logrus v1.9.3
+	github.com/stretchr/testify v1.10.0
 )
 
 require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/google/go-querystring v1.1.0 // indirect
+	github.com/jmespath/go-jmespath v0.4.0 // indirect
+	github.com/mattermost/mattermost-cloud-lambdas/internal/accountalias v0.0.0
+	github.com/mattermost/mattermost-cloud-lambdas/internal/awsconfig v0.0.0 // indirect
+	github.com/mattermost/mattermost-cloud-lambdas/internal/colors v0.0.0
+	github.com/mattermost/mattermost-cloud-lambdas/internal/metrics v0.0.0
+	github.com/mattermost/mattermost-cloud-lambdas/internal/pagerduty v0.0.0
+	github.com/mattermost/mattermost-cloud-lambdas/internal/testutil v0.0.0
+	github.com/mattermost/mattermost-cloud-lambdas/internal/webhook v0.0.0
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/sys v0.28.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/testutil => ../internal/testutil
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/metrics => ../internal/metrics
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/awsconfig => ../internal/awsconfig
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/colors => ../internal/colors
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/accountalias => ../internal/accountalias
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/webhook => ../internal/webhook
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/pagerduty => ../internal/pagerduty
@@ -6,13 +6,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/colors"
+	cloudpagerduty "github.com/mattermost/mattermost-cloud-lambdas/internal/pagerduty"
 	log "github.com/sirupsen/logrus"
 
 	pagerduty "github.com/PagerDuty/go-pagerduty"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/metrics"
+	"github.com/pkg/errors"
 )
 
 // SNSMessageNotification represents the details of an SNS message related to AWS alarms.
@@ -44,47 +50,141 @@ type SNSMessageNotification struct {
 	} `json:"Trigger"`
 }
 
+const (
+	alarmStateOK               = "OK"
+	alarmStateAlarm            = "ALARM"
+	alarmStateInsufficientData = "INSUFFICIENT_DATA"
+)
+
 func main() {
+	if replayEnabled() {
+		lambda.Start(replayHandler)
+		return
+	}
 	lambda.Start(handler)
 }
 
 func handler(_ context.Context, snsEvent events.SNSEvent) {
-	for _, record := range snsEvent.Records {
-		snsRecord := record.SNS
+	if digestMode() {
+		processDigest(snsEvent)
+		return
+	}
 
-		var messageNotification SNSMessageNotification
-		if err := json.Unmarshal([]byte(snsRecord.Message), &messageNotification); err != nil {
+	for _, record := range snsEvent.Records {
+		if err := processMessage(record.EventSource, record.SNS.Message, record.SNS.MessageAttributes); err != nil {
+			var delivery *deliveryError
+			if errors.As(err, &delivery) {
+				log.WithError(err).Error("Failed to deliver message notification")
+				continue
+			}
 			log.WithError(err).Error("Decode Error on message notification")
 			return
 		}
+	}
+}
 
-		sendMattermostNotification(record.EventSource, messageNotification)
+// processMessage notifies Mattermost and PagerDuty about a CloudWatch alarm
+// SNS message. The message attributes select the Mattermost webhook.
+// PagerDuty is notified even when Mattermost does not accept the
+// notification, which is then reported as a deliveryError.
+func processMessage(source, message string, attributes map[string]interface{}) error {
+	messageNotification, err := decodeAlarm(message)
+	if err != nil {
+		return err
+	}
+	if namespaceDenied(messageNotification.Trigger.Namespace) {
+		logDeniedAlarm(messageNotification)
+		return nil
+	}
 
-		// Trigger PagerDuty
-		if os.Getenv("ENVIRONMENT") != "" && os.Getenv("ENVIRONMENT") != "test" {
-			if messageNotification.NewStateValue != "OK" {
-				sendPagerDutyNotification(messageNotification)
-			} else {
-				closePagerDutyIncidents(messageNotification)
-			}
-		}
+	err = sendMattermostNotification(source, mattermostHook(attributes), messageNotification)
+	notifyPagerDuty(messageNotification)
+
+	if err != nil {
+		return &deliveryError{err}
 	}
 
+	return nil
 }
 
-func sendMattermostNotification(source string, messageNotification SNSMessageNotification) {
-	attachment := []MMAttachment{}
-	attach := MMAttachment{
-		Color: "#FF0000",
+// decodeAlarm decodes the CloudWatch alarm carried in an SNS message.
+func decodeAlarm(message string) (SNSMessageNotification, error) {
+	var messageNotification SNSMessageNotification
+	if err := json.Unmarshal([]byte(message), &messageNotification); err != nil {
+		return messageNotification, errors.Wrap(err, "failed to decode alarm notification")
+	}
+
+	return messageNotification, nil
+}
+
+// logDeniedAlarm logs that an alarm is skipped because of its namespace.
+func logDeniedAlarm(messageNotification SNSMessageNotification) {
+	log.WithFields(log.Fields{
+		"alarm":     messageNotification.AlarmName,
+		"namespace": messageNotification.Trigger.Namespace,
+	}).Info("Skipping alarm from a denied namespace")
+}
+
+// notifyPagerDuty triggers or resolves the PagerDuty incident for an alarm
+// depending on its new state.
+func notifyPagerDuty(messageNotification SNSMessageNotification) {
+	if os.Getenv("ENVIRONMENT") == "" || os.Getenv("ENVIRONMENT") == "test" {
+		return
+	}
+
+	switch {
+	case messageNotification.NewStateValue == alarmStateOK:
+		closePagerDutyIncidents(messageNotification)
+	case alarmSuppressed(messageNotification.AlarmName):
+		log.WithField("alarm", messageNotification.AlarmName).Info("Skipping PagerDuty for suppressed alarm")
+	case shouldPage(messageNotification.NewStateValue):
+		sendPagerDutyNotification(messageNotification)
+	default:
+		log.WithField("alarm", messageNotification.AlarmName).Info("Skipping PagerDuty for suppressed alarm state")
+	}
+}
+
+// sendMattermostNotification posts the alarms to the Mattermost hook as a
+// single message with one attachment per alarm.
+func sendMattermostNotification(source, hook string, messageNotifications ...SNSMessageNotification) error {
+	attachments := []MMAttachment{}
+	var mentions []string
+	for _, messageNotification := range messageNotifications {
+		attachments = append(attachments, alarmAttachment(messageNotification))
+
+		mention := alarmMention(messageNotification.AlarmName, messageNotification.NewStateValue)
+		if mention != "" && !slices.Contains(mentions, mention) {
+			mentions = append(mentions, mention)
+		}
 	}
 
-	if messageNotification.NewStateValue == "OK" {
-		attach.Color = "#006400"
+	payload := MMSlashResponse{
+		Username:    source,
+		IconURL:     "https://cdn2.iconfinder.com/data/icons/amazon-aws-stencils/100/Non-Service_Specific_copy__AWS_Cloud-128.png",
+		Attachments: attachments,
+	}
+	if len(mentions) > 0 {
+		payload.Text = strings.TrimSpace(strings.Join(mentions, " ") + " " + payload.Text)
+	}
+	if hook == "" {
+		return nil
+	}
+
+	return errors.Wrap(send(hook, payload), "failed to send the Mattermost notification")
+}
+
+// alarmAttachment builds the Mattermost attachment describing an alarm.
+func alarmAttachment(messageNotification SNSMessageNotification) MMAttachment {
+	attach := MMAttachment{
+		Color: stateColor(messageNotification.NewStateValue),
 	}
 
 	attach = *attach.AddField(MMField{Title: "AlarmName", Value: messageNotification.AlarmName, Short: true})
 	attach = *attach.AddField(MMField{Title: "AlarmDescription", Value: messageNotification.AlarmDescription, Short: true})
 	attach = *attach.AddField(MMField{Title: "AWS Account", Value: messageNotification.AWSAccountID, Short: true})
+	if alias := accountAliases.Alias(); alias != "" {
+		attach = *attach.AddField(MMField{Title: "Account Alias", Value: alias, Short: true})
+	}
 	attach = *attach.AddField(MMField{Title: "Region", Value: messageNotification.Region, Short: true})
 	attach = *attach.AddField(MMField{Title: "New State", Value: messageNotification.NewStateValue, Short: true})
 	attach = *attach.AddField(MMField{Title: "Old State", Value: messageNotification.OldStateValue, Short: true})
@@ -98,18 +198,45 @@ func sendMattermostNotification(source string, messageNotification SNSMessageNot
 	}
 	attach = *attach.AddField(MMField{Title: "Dimensions", Value: strings.Join(dimensions, "\n"), Short: false})
 
-	attachment = append(attachment, attach)
+	if runbook := runbookURL(messageNotification.AlarmName); runbook != "" {
+		attach = *attach.AddField(MMField{Title: "Runbook", Value: runbook, Short: false})
+	}
 
-	payload := MMSlashResponse{
-		Username:    source,
-		IconURL:     "https://cdn2.iconfinder.com/data/icons/amazon-aws-stencils/100/Non-Service_Specific_copy__AWS_Cloud-128.png",
-		Attachments: attachment,
+	if alarmSuppressed(messageNotification.AlarmName) {
+		attach = *attach.AddField(MMField{Title: "Suppressed", Value: "PagerDuty paging is suppressed for this alarm", Short: false})
 	}
-	if os.Getenv("MATTERMOST_HOOK") != "" {
-		send(os.Getenv("MATTERMOST_HOOK"), payload)
+
+	return attach
+}
+
+// stateColor returns the attachment color for a CloudWatch alarm state.
+func stateColor(state string) string {
+	switch state {
+	case alarmStateOK:
+		return colors.Success()
+	case alarmStateInsufficientData:
+		return colors.Warning()
+	default:
+		return colors.Failure()
 	}
 }
 
+// shouldPage reports whether a non-OK alarm state should trigger PagerDuty.
+// INSUFFICIENT_DATA is skipped when SUPPRESS_INSUFFICIENT_DATA is set to true.
+func shouldPage(state string) bool {
+	if state == alarmStateOK {
+		return false
+	}
+	if state == alarmStateInsufficientData && os.Getenv("SUPPRESS_INSUFFICIENT_DATA") == "true" {
+		return false
+	}
+
+	return true
+}
+
+// pagerDutyClient sends the PagerDuty events.
+var pagerDutyClient = pagerduty.NewClient("")
+
 func sendPagerDutyNotification(messageNotification SNSMessageNotification) {
 	integrationKey := os.Getenv("PAGERDUTY_INTEGRATION_KEY")
 	if integrationKey == "" {
@@ -131,21 +258,31 @@ func sendPagerDutyNotification(messageNotification SNSMessageNotification) {
 		strings.Join(dimensions, "\n"),
 	)
 
+	details := map[string]interface{}{
+		"Message": detailString,
+	}
+	if alias := accountAliases.Alias(); alias != "" {
+		details["Account Alias"] = alias
+	}
+	if runbook := runbookURL(messageNotification.AlarmName); runbook != "" {
+		details["Runbook"] = runbook
+	}
+
 	event := pagerduty.V2Event{
 		RoutingKey: integrationKey,
 		Action:     "trigger",
 		Payload: &pagerduty.V2Payload{
 			Summary:  messageNotification.AlarmName + " - " + messageNotification.AlarmDescription,
-			Source:   "Alarm System",
 			Severity: "critical",
-			Details: map[string]interface{}{
-				"Message": detailString,
-			},
+			Details:  details,
 		},
 	}
+	cloudpagerduty.SetOrigin(event.Payload, defaultPagerDutySource)
 
 	// Send the event to PagerDuty
-	_, err := pagerduty.ManageEventWithContext(context.TODO(), event)
+	start := time.Now()
+	_, err := pagerDutyClient.ManageEventWithContext(context.TODO(), &event)
+	metrics.RecordNotificationLatency(metrics.TargetPagerDuty, start)
 	if err != nil {
 		log.WithError(err).Error("Failed to send PagerDuty notification")
 		return
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateColor(t *testing.T) {
+	assert.Equal(t, "#006400", stateColor(alarmStateOK))
+	assert.Equal(t, "#FF0000", stateColor(alarmStateAlarm))
+	assert.Equal(t, "#FFA500", stateColor(alarmStateInsufficientData))
+
+	t.Setenv("COLOR_SUCCESS", "#00AA00")
+	t.Setenv("COLOR_FAILURE", "#AA0000")
+	t.Setenv("COLOR_WARNING", "#AAAA00")
+	assert.Equal(t, "#00AA00", stateColor(alarmStateOK))
+	assert.Equal(t, "#AA0000", stateColor(alarmStateAlarm))
+	assert.Equal(t, "#AAAA00", stateColor(alarmStateInsufficientData))
+
+	t.Setenv("COLOR_FAILURE", "not-a-color")
+	assert.Equal(t, "#FF0000", stateColor(alarmStateAlarm))
+}
+
+func TestShouldPage(t *testing.T) {
+	testCases := []struct {
+		state    string
+		suppress string
+		expected bool
+	}{
+		{alarmStateOK, "", false},
+		{alarmStateOK, "true", false},
+		{alarmStateAlarm, "", true},
+		{alarmStateAlarm, "true", true},
+		{alarmStateInsufficientData, "", true},
+		{alarmStateInsufficientData, "true", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.state+"/"+tc.suppress, func(t *testing.T) {
+			t.Setenv("SUPPRESS_INSUFFICIENT_DATA", tc.suppress)
+			assert.Equal(t, tc.expected, shouldPage(tc.state))
+		})
+	}
+}
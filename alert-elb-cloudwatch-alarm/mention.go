@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// parseAlarmMentions parses the ALARM_MENTION_MAP JSON object which maps an
+// alarm name prefix to the Mattermost @mention to notify.
+func parseAlarmMentions(value string) (map[string]string, error) {
+	mentions := map[string]string{}
+	if value == "" {
+		return mentions, nil
+	}
+
+	if err := json.Unmarshal([]byte(value), &mentions); err != nil {
+		return nil, err
+	}
+
+	return mentions, nil
+}
+
+// alarmMention returns the @mention for the longest alarm name prefix
+// configured in ALARM_MENTION_MAP. Only alarms in the ALARM state mention
+// anyone.
+func alarmMention(alarmName, state string) string {
+	if state != alarmStateAlarm {
+		return ""
+	}
+
+	mentions, err := parseAlarmMentions(os.Getenv("ALARM_MENTION_MAP"))
+	if err != nil {
+		log.WithError(err).Error("Failed to parse ALARM_MENTION_MAP")
+	}
+
+	var matched, mention string
+	for prefix, value := range mentions {
+		if strings.HasPrefix(alarmName, prefix) && len(prefix) >= len(matched) {
+			matched, mention = prefix, value
+		}
+	}
+
+	return mention
+}
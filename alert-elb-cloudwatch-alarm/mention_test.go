@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlarmMention(t *testing.T) {
+	t.Setenv("ALARM_MENTION_MAP", `{"Alarm-": "@sre", "Alarm-RDS-": "@dba-oncall"}`)
+
+	testCases := []struct {
+		name      string
+		alarmName string
+		state     string
+		expected  string
+	}{
+		{"matching", "Alarm-my-elb", alarmStateAlarm, "@sre"},
+		{"longest prefix", "Alarm-RDS-cluster", alarmStateAlarm, "@dba-oncall"},
+		{"non-matching", "Other-alarm", alarmStateAlarm, ""},
+		{"ok state", "Alarm-my-elb", alarmStateOK, ""},
+		{"insufficient data", "Alarm-my-elb", alarmStateInsufficientData, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, alarmMention(tc.alarmName, tc.state))
+		})
+	}
+}
+
+func TestAlarmMentionInvalidMapping(t *testing.T) {
+	t.Setenv("ALARM_MENTION_MAP", "not json")
+
+	assert.Empty(t, alarmMention("Alarm-my-elb", alarmStateAlarm))
+}
+
+func TestSendMattermostNotificationMention(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	t.Setenv("ALARM_MENTION_MAP", `{"Alarm-": "@sre"}`)
+
+	sendMattermostNotification("aws:sns", mattermost.URL, SNSMessageNotification{AlarmName: "Alarm-my-elb", NewStateValue: alarmStateAlarm})
+	sendMattermostNotification("aws:sns", mattermost.URL, SNSMessageNotification{AlarmName: "Alarm-my-elb", NewStateValue: alarmStateOK})
+	sendMattermostNotification("aws:sns", mattermost.URL, SNSMessageNotification{AlarmName: "Other-alarm", NewStateValue: alarmStateAlarm})
+
+	payloads := testutil.Payloads[MMSlashResponse](t, mattermost)
+	require.Len(t, payloads, 3)
+	assert.Equal(t, "@sre", payloads[0].Text)
+	assert.Empty(t, payloads[1].Text)
+	assert.Empty(t, payloads[2].Text)
+}
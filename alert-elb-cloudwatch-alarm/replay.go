@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/webhook"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// replaySource is the event source reported for replayed messages.
+const replaySource = "aws:sns"
+
+// replayEnabled reports whether REPLAY_ENABLED is set to true, in which case
+// the lambda is invoked over HTTP to reprocess a stored SNS message instead of
+// by SNS itself.
+func replayEnabled() bool {
+	return strings.EqualFold(os.Getenv("REPLAY_ENABLED"), "true")
+}
+
+// replayHandler runs a stored SNS message posted as the request body through
+// the same processing as an SNS delivery. The body is either the full SNS
+// message, as delivered to HTTP subscriptions, or just its Message content.
+// Messages Mattermost did not accept are answered with 502 Bad Gateway.
+func replayHandler(_ context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	body, err := webhook.RequestBody(request)
+	if err != nil {
+		return replayResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	var entity events.SNSEntity
+	var attributes map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &entity); err == nil && entity.Message != "" {
+		body = entity.Message
+		attributes = entity.MessageAttributes
+	}
+
+	if err := processMessage(replaySource, body, attributes); err != nil {
+		log.WithError(err).Error("Failed to replay SNS message")
+		statusCode := http.StatusBadRequest
+		var delivery *deliveryError
+		if errors.As(err, &delivery) {
+			statusCode = http.StatusBadGateway
+		}
+		return replayResponse(statusCode, err.Error()), nil
+	}
+
+	log.Info("Replayed SNS message")
+	return replayResponse(http.StatusOK, "processed"), nil
+}
+
+func replayResponse(statusCode int, status string) events.APIGatewayProxyResponse {
+	body, _ := json.Marshal(map[string]string{"status": status})
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(body),
+	}
+}
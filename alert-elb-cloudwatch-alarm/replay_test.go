@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayHandler(t *testing.T) {
+	alarm, err := json.Marshal(testutil.CloudWatchAlarm{
+		AlarmName:     "Alarm-my-elb",
+		NewStateValue: alarmStateAlarm,
+		Region:        "us-east-1",
+	})
+	require.NoError(t, err)
+	envelope, err := json.Marshal(map[string]string{"Type": "Notification", "Message": string(alarm)})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		request events.APIGatewayProxyRequest
+	}{
+		{"message", testutil.APIGatewayRequest(http.MethodPost, "/replay", string(alarm), nil)},
+		{"sns envelope", testutil.APIGatewayRequest(http.MethodPost, "/replay", string(envelope), nil)},
+		{"base64 body", testutil.Base64APIGatewayRequest(http.MethodPost, "/replay", string(alarm), nil)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mattermost := testutil.NewMattermostServer(t)
+			t.Setenv("MATTERMOST_HOOK", mattermost.URL)
+
+			response, err := replayHandler(context.Background(), tc.request)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+
+			payloads := testutil.Payloads[MMSlashResponse](t, mattermost)
+			require.Len(t, payloads, 1)
+			assert.Equal(t, "Alarm-my-elb", payloads[0].Attachments[0].Fields[0].Value)
+		})
+	}
+}
+
+func TestReplayHandlerInvalidMessage(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_HOOK", mattermost.URL)
+
+	response, err := replayHandler(context.Background(), testutil.APIGatewayRequest(http.MethodPost, "/replay", "not json", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	assert.Empty(t, mattermost.Bodies())
+}
+
+func TestReplayHandlerMattermostFailure(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	mattermost.SetStatusCode(http.StatusInternalServerError)
+	t.Setenv("MATTERMOST_HOOK", mattermost.URL)
+
+	alarm, err := json.Marshal(testutil.CloudWatchAlarm{AlarmName: "Alarm-my-elb", NewStateValue: alarmStateAlarm})
+	require.NoError(t, err)
+
+	response, err := replayHandler(context.Background(), testutil.APIGatewayRequest(http.MethodPost, "/replay", string(alarm), nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, response.StatusCode)
+	assert.Contains(t, response.Body, "500")
+	assert.Len(t, mattermost.Bodies(), 1)
+}
+
+func TestReplayHandlerMattermostUnreachable(t *testing.T) {
+	mattermost := httptest.NewServer(http.NotFoundHandler())
+	mattermost.Close()
+	t.Setenv("MATTERMOST_HOOK", mattermost.URL)
+
+	alarm, err := json.Marshal(testutil.CloudWatchAlarm{AlarmName: "Alarm-my-elb", NewStateValue: alarmStateAlarm})
+	require.NoError(t, err)
+
+	response, err := replayHandler(context.Background(), testutil.APIGatewayRequest(http.MethodPost, "/replay", string(alarm), nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, response.StatusCode)
+}
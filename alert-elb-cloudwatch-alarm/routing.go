@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// routingAttributes are the SNS message attributes that select the Mattermost
+// webhook of an alarm, in order of precedence.
+var routingAttributes = []string{"channel", "team"}
+
+// parseRouteHooks parses the ROUTE_HOOKS JSON object which maps the value of a
+// routing message attribute to a Mattermost webhook URL.
+func parseRouteHooks(value string) (map[string]string, error) {
+	hooks := map[string]string{}
+	if value == "" {
+		return hooks, nil
+	}
+
+	if err := json.Unmarshal([]byte(value), &hooks); err != nil {
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+// messageAttribute returns the value of a string SNS message attribute, which
+// Lambda delivers as a {"Type": ..., "Value": ...} object.
+func messageAttribute(attributes map[string]interface{}, name string) string {
+	attribute, ok := attributes[name].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	value, _ := attribute["Value"].(string)
+
+	return value
+}
+
+// mattermostHook returns the webhook the alarms of an SNS message are posted
+// to. A channel or team message attribute with a hook in ROUTE_HOOKS
+// overrides MATTERMOST_HOOK.
+func mattermostHook(attributes map[string]interface{}) string {
+	defaultHook := os.Getenv("MATTERMOST_HOOK")
+	if len(attributes) == 0 {
+		return defaultHook
+	}
+
+	hooks, err := parseRouteHooks(os.Getenv("ROUTE_HOOKS"))
+	if err != nil {
+		log.WithError(err).Error("Failed to parse ROUTE_HOOKS")
+		return defaultHook
+	}
+
+	for _, name := range routingAttributes {
+		value := messageAttribute(attributes, name)
+		if value == "" {
+			continue
+		}
+		if hook := hooks[value]; hook != "" {
+			return hook
+		}
+		log.WithField(name, value).Warn("No webhook in ROUTE_HOOKS for message attribute")
+	}
+
+	return defaultHook
+}
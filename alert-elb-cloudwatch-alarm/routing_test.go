@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stringAttribute(value string) map[string]interface{} {
+	return map[string]interface{}{"Type": "String", "Value": value}
+}
+
+func TestMattermostHook(t *testing.T) {
+	t.Setenv("MATTERMOST_HOOK", "https://default")
+	t.Setenv("ROUTE_HOOKS", `{"sre": "https://sre", "cloud": "https://cloud"}`)
+
+	testCases := map[string]struct {
+		attributes map[string]interface{}
+		expected   string
+	}{
+		"no attributes":        {nil, "https://default"},
+		"channel":              {map[string]interface{}{"channel": stringAttribute("sre")}, "https://sre"},
+		"team":                 {map[string]interface{}{"team": stringAttribute("cloud")}, "https://cloud"},
+		"channel before team":  {map[string]interface{}{"channel": stringAttribute("sre"), "team": stringAttribute("cloud")}, "https://sre"},
+		"unknown channel":      {map[string]interface{}{"channel": stringAttribute("other"), "team": stringAttribute("cloud")}, "https://cloud"},
+		"unrelated attributes": {map[string]interface{}{"severity": stringAttribute("sre")}, "https://default"},
+		"not a string":         {map[string]interface{}{"channel": "sre"}, "https://default"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, mattermostHook(tc.attributes))
+		})
+	}
+
+	t.Run("invalid ROUTE_HOOKS", func(t *testing.T) {
+		t.Setenv("ROUTE_HOOKS", "not json")
+		assert.Equal(t, "https://default", mattermostHook(map[string]interface{}{"channel": stringAttribute("sre")}))
+	})
+}
+
+func TestHandlerRoutesOnMessageAttributes(t *testing.T) {
+	defaultServer := testutil.NewMattermostServer(t)
+	sreServer := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_HOOK", defaultServer.URL)
+	t.Setenv("ROUTE_HOOKS", `{"sre": "`+sreServer.URL+`"}`)
+	t.Setenv("ENVIRONMENT", "test")
+
+	event := testutil.SNSEvent(alarmMessage(t, "Alarm-routed"), alarmMessage(t, "Alarm-default"))
+	event.Records[0].SNS.MessageAttributes = map[string]interface{}{"team": stringAttribute("sre")}
+
+	handler(context.Background(), event)
+
+	routed := testutil.Payloads[MMSlashResponse](t, sreServer)
+	require.Len(t, routed, 1)
+	assert.Equal(t, "Alarm-routed", routed[0].Attachments[0].Fields[0].Value)
+	unrouted := testutil.Payloads[MMSlashResponse](t, defaultServer)
+	require.Len(t, unrouted, 1)
+	assert.Equal(t, "Alarm-default", unrouted[0].Attachments[0].Fields[0].Value)
+}
+
+func TestHandlerDigestModeRoutesOnMessageAttributes(t *testing.T) {
+	defaultServer := testutil.NewMattermostServer(t)
+	sreServer := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_HOOK", defaultServer.URL)
+	t.Setenv("ROUTE_HOOKS", `{"sre": "`+sreServer.URL+`"}`)
+	t.Setenv("ENVIRONMENT", "test")
+	t.Setenv("DIGEST_MODE", "true")
+
+	event := testutil.SNSEvent(
+		alarmMessage(t, "Alarm-first"),
+		alarmMessage(t, "Alarm-second"),
+		alarmMessage(t, "Alarm-third"),
+	)
+	routed := map[string]interface{}{"channel": stringAttribute("sre")}
+	event.Records[0].SNS.MessageAttributes = routed
+	event.Records[2].SNS.MessageAttributes = routed
+
+	handler(context.Background(), event)
+
+	sre := testutil.Payloads[MMSlashResponse](t, sreServer)
+	require.Len(t, sre, 1)
+	require.Len(t, sre[0].Attachments, 2)
+	assert.Equal(t, "Alarm-first", sre[0].Attachments[0].Fields[0].Value)
+	assert.Equal(t, "Alarm-third", sre[0].Attachments[1].Fields[0].Value)
+	unrouted := testutil.Payloads[MMSlashResponse](t, defaultServer)
+	require.Len(t, unrouted, 1)
+	require.Len(t, unrouted[0].Attachments, 1)
+	assert.Equal(t, "Alarm-second", unrouted[0].Attachments[0].Fields[0].Value)
+}
+
+func TestReplayHandlerRoutesOnMessageAttributes(t *testing.T) {
+	defaultServer := testutil.NewMattermostServer(t)
+	sreServer := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_HOOK", defaultServer.URL)
+	t.Setenv("ROUTE_HOOKS", `{"sre": "`+sreServer.URL+`"}`)
+	t.Setenv("ENVIRONMENT", "test")
+
+	envelope, err := json.Marshal(map[string]interface{}{
+		"Type":              "Notification",
+		"Message":           alarmMessage(t, "Alarm-replayed"),
+		"MessageAttributes": map[string]interface{}{"channel": stringAttribute("sre")},
+	})
+	require.NoError(t, err)
+
+	response, err := replayHandler(context.Background(), events.APIGatewayProxyRequest{Body: string(envelope)})
+	require.NoError(t, err)
+	assert.Equal(t, 200, response.StatusCode)
+
+	assert.Len(t, testutil.Payloads[MMSlashResponse](t, sreServer), 1)
+	assert.Empty(t, testutil.Payloads[MMSlashResponse](t, defaultServer))
+}
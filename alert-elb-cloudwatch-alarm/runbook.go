@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// parseAlarmRunbooks parses the ALARM_RUNBOOKS JSON object which maps an alarm
+// name prefix to a runbook URL.
+func parseAlarmRunbooks(value string) (map[string]string, error) {
+	runbooks := map[string]string{}
+	if value == "" {
+		return runbooks, nil
+	}
+
+	if err := json.Unmarshal([]byte(value), &runbooks); err != nil {
+		return nil, err
+	}
+
+	return runbooks, nil
+}
+
+// runbookURL returns the runbook for the longest alarm name prefix configured
+// in ALARM_RUNBOOKS, falling back to DEFAULT_RUNBOOK_URL.
+func runbookURL(alarmName string) string {
+	runbooks, err := parseAlarmRunbooks(os.Getenv("ALARM_RUNBOOKS"))
+	if err != nil {
+		log.WithError(err).Error("Failed to parse ALARM_RUNBOOKS")
+	}
+
+	var matched, url string
+	for prefix, link := range runbooks {
+		if strings.HasPrefix(alarmName, prefix) && len(prefix) >= len(matched) {
+			matched, url = prefix, link
+		}
+	}
+	if url != "" {
+		return url
+	}
+
+	return os.Getenv("DEFAULT_RUNBOOK_URL")
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunbookURL(t *testing.T) {
+	t.Setenv("ALARM_RUNBOOKS", `{"Alarm-": "https://runbooks/elb", "Alarm-RDS-": "https://runbooks/rds"}`)
+	t.Setenv("DEFAULT_RUNBOOK_URL", "https://runbooks/default")
+
+	testCases := []struct {
+		alarmName string
+		expected  string
+	}{
+		{"Alarm-my-elb", "https://runbooks/elb"},
+		{"Alarm-RDS-cluster", "https://runbooks/rds"},
+		{"Other-alarm", "https://runbooks/default"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.alarmName, func(t *testing.T) {
+			assert.Equal(t, tc.expected, runbookURL(tc.alarmName))
+		})
+	}
+}
+
+func TestRunbookURLNoDefault(t *testing.T) {
+	t.Setenv("ALARM_RUNBOOKS", "")
+	t.Setenv("DEFAULT_RUNBOOK_URL", "")
+
+	assert.Empty(t, runbookURL("Alarm-my-elb"))
+}
+
+func TestRunbookURLInvalidMapping(t *testing.T) {
+	t.Setenv("ALARM_RUNBOOKS", "{invalid")
+	t.Setenv("DEFAULT_RUNBOOK_URL", "https://runbooks/default")
+
+	assert.Equal(t, "https://runbooks/default", runbookURL("Alarm-my-elb"))
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// alarmSuppressed reports whether the alarm matches one of the names or
+// prefixes in SUPPRESSED_ALARMS. Suppressed alarms are still posted to
+// Mattermost but never trigger PagerDuty, e.g. during planned maintenance.
+func alarmSuppressed(alarmName string) bool {
+	for _, prefix := range parseList(os.Getenv("SUPPRESSED_ALARMS")) {
+		if strings.HasPrefix(alarmName, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// namespaceDenied reports whether the alarm's metric namespace is listed in
+// NAMESPACE_DENYLIST. Denied alarms are dropped entirely: neither Mattermost
+// nor PagerDuty is notified.
+func namespaceDenied(namespace string) bool {
+	for _, denied := range parseList(os.Getenv("NAMESPACE_DENYLIST")) {
+		if strings.EqualFold(namespace, denied) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseList splits a comma-separated list, dropping empty entries.
+func parseList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
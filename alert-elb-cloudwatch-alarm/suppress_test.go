@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlarmSuppressed(t *testing.T) {
+	t.Setenv("SUPPRESSED_ALARMS", "Alarm-maintenance-elb, Alarm-db-")
+
+	assert.True(t, alarmSuppressed("Alarm-maintenance-elb"))
+	assert.True(t, alarmSuppressed("Alarm-db-primary"))
+	assert.False(t, alarmSuppressed("Alarm-web-elb"))
+	assert.False(t, alarmSuppressed("Alarm-db"))
+
+	t.Setenv("SUPPRESSED_ALARMS", "")
+	assert.False(t, alarmSuppressed("Alarm-maintenance-elb"))
+}
+
+func TestHandlerSuppressedAlarms(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	pagerDuty := testutil.NewPagerDutyServer(t)
+	previousClient := pagerDutyClient
+	pagerDutyClient = pagerDuty.Client()
+	defer func() { pagerDutyClient = previousClient }()
+	t.Setenv("MATTERMOST_HOOK", mattermost.URL)
+	t.Setenv("ENVIRONMENT", "prod")
+	t.Setenv("PAGERDUTY_INTEGRATION_KEY", "routing-key")
+	t.Setenv("SUPPRESSED_ALARMS", "Alarm-maintenance-")
+
+	handler(context.Background(), testutil.SNSEvent(
+		alarmMessage(t, "Alarm-maintenance-elb"),
+		alarmMessage(t, "Alarm-web-elb"),
+	))
+
+	payloads := testutil.Payloads[MMSlashResponse](t, mattermost)
+	require.Len(t, payloads, 2)
+	suppressed := payloads[0].Attachments[0].Fields
+	assert.Equal(t, "Suppressed", suppressed[len(suppressed)-1].Title)
+	for _, field := range payloads[1].Attachments[0].Fields {
+		assert.NotEqual(t, "Suppressed", field.Title)
+	}
+
+	pagerDutyEvents := pagerDuty.Events()
+	require.Len(t, pagerDutyEvents, 1)
+	assert.Equal(t, "Alarm-web-elb - ", pagerDutyEvents[0].Payload.Summary)
+}
+
+func TestNamespaceDenied(t *testing.T) {
+	t.Setenv("NAMESPACE_DENYLIST", "AWS/Usage, AWS/Billing")
+
+	assert.True(t, namespaceDenied("AWS/Usage"))
+	assert.True(t, namespaceDenied("aws/billing"))
+	assert.False(t, namespaceDenied("AWS/ApplicationELB"))
+	assert.False(t, namespaceDenied(""))
+
+	t.Setenv("NAMESPACE_DENYLIST", "")
+	assert.False(t, namespaceDenied("AWS/Usage"))
+}
+
+func TestHandlerDeniedNamespace(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	pagerDuty := testutil.NewPagerDutyServer(t)
+	previousClient := pagerDutyClient
+	pagerDutyClient = pagerDuty.Client()
+	defer func() { pagerDutyClient = previousClient }()
+	t.Setenv("MATTERMOST_HOOK", mattermost.URL)
+	t.Setenv("ENVIRONMENT", "prod")
+	t.Setenv("PAGERDUTY_INTEGRATION_KEY", "routing-key")
+	t.Setenv("NAMESPACE_DENYLIST", "AWS/Usage")
+
+	message, err := json.Marshal(testutil.CloudWatchAlarm{
+		AlarmName:     "Alarm-usage",
+		NewStateValue: alarmStateAlarm,
+		Trigger:       testutil.CloudWatchMetric{Namespace: "AWS/Usage"},
+	})
+	require.NoError(t, err)
+
+	for _, digest := range []string{"false", "true"} {
+		t.Setenv("DIGEST_MODE", digest)
+		handler(context.Background(), testutil.SNSEvent(string(message)))
+	}
+
+	assert.Empty(t, testutil.Payloads[MMSlashResponse](t, mattermost))
+	assert.Empty(t, pagerDuty.Events())
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	deviceIndexEnv    = "DEVICE_INDEX"
+	eniSubnetIDsEnv   = "ENI_SUBNET_IDS"
+	mattermostHookEnv = "MATTERMOST_HOOK"
+	eniAgeTagEnv      = "ENI_AGE_TAG"
+	preferOldestEnv   = "PREFER_OLDEST_ENI"
+	eniMaxAgeEnv      = "ENI_MAX_AGE_HOURS"
+
+	defaultDeviceIndex = 1
+)
+
+// Config holds the settings of the network attachment, read from the
+// environment at startup.
+type Config struct {
+	// DeviceIndex is the device index the network interface is attached at.
+	DeviceIndex int64
+	// SubnetIDs restricts the attachment to instances launched in one of
+	// these subnets; empty allows every subnet.
+	SubnetIDs []string
+	// MattermostHook is notified when a lifecycle action is abandoned; empty
+	// disables notifications.
+	MattermostHook string
+	AgeTag         string
+	PreferOldest   bool
+	// MaxAge is the age above which the selected network interface is
+	// reported as stale; zero disables the guard.
+	MaxAge time.Duration
+}
+
+// loadConfig reads and validates the configuration. Every invalid value is
+// reported in the returned error rather than only the first one.
+func loadConfig() (*Config, error) {
+	config := &Config{
+		DeviceIndex:    defaultDeviceIndex,
+		MattermostHook: os.Getenv(mattermostHookEnv),
+		AgeTag:         defaultAgeTag,
+	}
+	var errs []error
+
+	if value := os.Getenv(deviceIndexEnv); value != "" {
+		deviceIndex, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || deviceIndex < 1 {
+			errs = append(errs, fmt.Errorf("environment variable %s must be a positive number", deviceIndexEnv))
+		} else {
+			config.DeviceIndex = deviceIndex
+		}
+	}
+
+	for _, subnetID := range strings.Split(os.Getenv(eniSubnetIDsEnv), ",") {
+		subnetID = strings.TrimSpace(subnetID)
+		if subnetID == "" {
+			continue
+		}
+		if !strings.HasPrefix(subnetID, "subnet-") {
+			errs = append(errs, fmt.Errorf("environment variable %s contains an invalid subnet ID %q", eniSubnetIDsEnv, subnetID))
+			continue
+		}
+		config.SubnetIDs = append(config.SubnetIDs, subnetID)
+	}
+
+	if config.MattermostHook != "" {
+		hookURL, err := url.Parse(config.MattermostHook)
+		if err != nil || (hookURL.Scheme != "http" && hookURL.Scheme != "https") || hookURL.Host == "" {
+			errs = append(errs, fmt.Errorf("environment variable %s must be an http(s) URL", mattermostHookEnv))
+		}
+	}
+
+	if value := os.Getenv(eniAgeTagEnv); value != "" {
+		config.AgeTag = value
+	}
+
+	if value := os.Getenv(preferOldestEnv); value != "" {
+		preferOldest, err := strconv.ParseBool(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("environment variable %s must be a boolean", preferOldestEnv))
+		}
+		config.PreferOldest = preferOldest
+	}
+
+	if value := os.Getenv(eniMaxAgeEnv); value != "" {
+		hours, err := strconv.ParseFloat(value, 64)
+		if err != nil || hours <= 0 {
+			errs = append(errs, fmt.Errorf("environment variable %s must be a positive number of hours", eniMaxAgeEnv))
+		} else {
+			config.MaxAge = time.Duration(hours * float64(time.Hour))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// subnetAllowed reports whether instances launched in subnetID get a network
+// interface attached.
+func (c *Config) subnetAllowed(subnetID string) bool {
+	return len(c.SubnetIDs) == 0 || slices.Contains(c.SubnetIDs, subnetID)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setConfigEnv(t *testing.T, env map[string]string) {
+	for _, name := range []string{deviceIndexEnv, eniSubnetIDsEnv, mattermostHookEnv, eniAgeTagEnv, preferOldestEnv, eniMaxAgeEnv} {
+		t.Setenv(name, env[name])
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		setConfigEnv(t, nil)
+
+		config, err := loadConfig()
+		require.NoError(t, err)
+		assert.Equal(t, &Config{DeviceIndex: defaultDeviceIndex, AgeTag: defaultAgeTag}, config)
+		assert.True(t, config.subnetAllowed("subnet-any"))
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		setConfigEnv(t, map[string]string{
+			deviceIndexEnv:    "2",
+			eniSubnetIDsEnv:   "subnet-a, subnet-b,",
+			mattermostHookEnv: "https://mattermost/hooks/abc",
+			eniAgeTagEnv:      "Created",
+			preferOldestEnv:   "true",
+			eniMaxAgeEnv:      "1.5",
+		})
+
+		config, err := loadConfig()
+		require.NoError(t, err)
+		assert.Equal(t, &Config{
+			DeviceIndex:    2,
+			SubnetIDs:      []string{"subnet-a", "subnet-b"},
+			MattermostHook: "https://mattermost/hooks/abc",
+			AgeTag:         "Created",
+			PreferOldest:   true,
+			MaxAge:         90 * time.Minute,
+		}, config)
+		assert.True(t, config.subnetAllowed("subnet-b"))
+		assert.False(t, config.subnetAllowed("subnet-c"))
+	})
+
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{"device index zero", map[string]string{deviceIndexEnv: "0"}, "environment variable DEVICE_INDEX must be a positive number"},
+		{"device index not a number", map[string]string{deviceIndexEnv: "eth1"}, "environment variable DEVICE_INDEX must be a positive number"},
+		{"invalid subnet", map[string]string{eniSubnetIDsEnv: "subnet-a,vpc-b"}, `environment variable ENI_SUBNET_IDS contains an invalid subnet ID "vpc-b"`},
+		{"hook without scheme", map[string]string{mattermostHookEnv: "mattermost/hooks/abc"}, "environment variable MATTERMOST_HOOK must be an http(s) URL"},
+		{"prefer oldest not a boolean", map[string]string{preferOldestEnv: "sometimes"}, "environment variable PREFER_OLDEST_ENI must be a boolean"},
+		{"negative max age", map[string]string{eniMaxAgeEnv: "-1"}, "environment variable ENI_MAX_AGE_HOURS must be a positive number of hours"},
+		{"every invalid value", map[string]string{
+			deviceIndexEnv:    "-1",
+			eniSubnetIDsEnv:   "bad",
+			mattermostHookEnv: "ftp://mattermost",
+		}, "environment variable DEVICE_INDEX must be a positive number\n" +
+			`environment variable ENI_SUBNET_IDS contains an invalid subnet ID "bad"` + "\n" +
+			"environment variable MATTERMOST_HOOK must be an http(s) URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setConfigEnv(t, tt.env)
+
+			config, err := loadConfig()
+			assert.EqualError(t, err, tt.expected)
+			assert.Nil(t, config)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/metrics"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultAgeTag is the tag holding the RFC3339 creation time of a
+	// network interface when ENI_AGE_TAG is not set.
+	defaultAgeTag = "CreatedAt"
+
+	// networkInterfaceAgeMetric is the metric name of the selected network
+	// interface age.
+	networkInterfaceAgeMetric = "NetworkInterfaceAge"
+)
+
+// networkInterfaceAge returns how long ago the network interface was created
+// according to its age tag. It returns false when the tag is missing or
+// cannot be parsed.
+func networkInterfaceAge(networkInterface *ec2.NetworkInterface, tag string, now time.Time) (time.Duration, bool) {
+	for _, t := range networkInterface.TagSet {
+		if t.Key == nil || t.Value == nil || *t.Key != tag {
+			continue
+		}
+		createdAt, err := time.Parse(time.RFC3339, *t.Value)
+		if err != nil {
+			return 0, false
+		}
+		return now.Sub(createdAt), true
+	}
+	return 0, false
+}
+
+// selectNetworkInterface returns the network interface to attach among the
+// available ones. When oldestFirst is set the oldest tagged interface wins and
+// untagged interfaces come last in listing order, otherwise the first
+// available interface is returned. It returns nil if none is available.
+func selectNetworkInterface(networkInterfaces []*ec2.NetworkInterface, tag string, now time.Time, oldestFirst bool) *ec2.NetworkInterface {
+	var available []*ec2.NetworkInterface
+	for _, networkInterface := range networkInterfaces {
+		if networkInterface.Status != nil && *networkInterface.Status == ec2.NetworkInterfaceStatusAvailable {
+			available = append(available, networkInterface)
+		}
+	}
+	if len(available) == 0 {
+		return nil
+	}
+	if !oldestFirst {
+		return available[0]
+	}
+
+	sort.SliceStable(available, func(i, j int) bool {
+		ageI, okI := networkInterfaceAge(available[i], tag, now)
+		ageJ, okJ := networkInterfaceAge(available[j], tag, now)
+		if okI != okJ {
+			return okI
+		}
+		return ageI > ageJ
+	})
+	return available[0]
+}
+
+// recordNetworkInterfaceAge logs and emits the age of the selected network
+// interface, warning when it is older than the configured maximum age.
+func recordNetworkInterfaceAge(config *Config, networkInterface *ec2.NetworkInterface, subNetID string, now time.Time) {
+	age, ok := networkInterfaceAge(networkInterface, config.AgeTag, now)
+	logger := log.WithField("networkInterfaceID", *networkInterface.NetworkInterfaceId)
+	if !ok {
+		logger.Infof("Network interface has no valid %s tag, age unknown", config.AgeTag)
+		return
+	}
+
+	logger = logger.WithField("age", age.Round(time.Second).String())
+	if config.MaxAge > 0 && age > config.MaxAge {
+		logger.Warnf("Network interface is older than the maximum age of %s", config.MaxAge)
+	} else {
+		logger.Info("Selected network interface")
+	}
+
+	metrics.Record(networkInterfaceAgeMetric, "Seconds", age.Seconds(), map[string]string{"SubnetId": subNetID})
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func networkInterface(id, status, createdAt string) *ec2.NetworkInterface {
+	networkInterface := &ec2.NetworkInterface{
+		NetworkInterfaceId: aws.String(id),
+		Status:             aws.String(status),
+	}
+	if createdAt != "" {
+		networkInterface.TagSet = []*ec2.Tag{
+			{Key: aws.String("BindServer"), Value: aws.String("true")},
+			{Key: aws.String(defaultAgeTag), Value: aws.String(createdAt)},
+		}
+	}
+	return networkInterface
+}
+
+func TestSelectNetworkInterface(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	interfaces := []*ec2.NetworkInterface{
+		networkInterface("eni-untagged", "available", ""),
+		networkInterface("eni-new", "available", "2024-05-31T00:00:00Z"),
+		networkInterface("eni-oldest-in-use", "in-use", "2024-01-01T00:00:00Z"),
+		networkInterface("eni-old", "available", "2024-05-01T00:00:00Z"),
+		networkInterface("eni-invalid", "available", "yesterday"),
+	}
+
+	tests := []struct {
+		name        string
+		interfaces  []*ec2.NetworkInterface
+		oldestFirst bool
+		expected    string
+	}{
+		{"first available", interfaces, false, "eni-untagged"},
+		{"oldest available", interfaces, true, "eni-old"},
+		{"untagged fall back to listing order", []*ec2.NetworkInterface{
+			networkInterface("eni-invalid", "available", "yesterday"),
+			networkInterface("eni-untagged", "available", ""),
+		}, true, "eni-invalid"},
+		{"none available", []*ec2.NetworkInterface{
+			networkInterface("eni-in-use", "in-use", "2024-05-01T00:00:00Z"),
+		}, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected := selectNetworkInterface(tt.interfaces, defaultAgeTag, now, tt.oldestFirst)
+			if tt.expected == "" {
+				assert.Nil(t, selected)
+				return
+			}
+			require.NotNil(t, selected)
+			assert.Equal(t, tt.expected, *selected.NetworkInterfaceId)
+		})
+	}
+}
+
+func TestNetworkInterfaceAge(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	age, ok := networkInterfaceAge(networkInterface("eni-1", "available", "2024-05-31T12:00:00Z"), defaultAgeTag, now)
+	assert.True(t, ok)
+	assert.Equal(t, 12*time.Hour, age)
+
+	_, ok = networkInterfaceAge(networkInterface("eni-2", "available", ""), defaultAgeTag, now)
+	assert.False(t, ok)
+
+	_, ok = networkInterfaceAge(networkInterface("eni-3", "available", "not-a-time"), defaultAgeTag, now)
+	assert.False(t, ok)
+}
@@ -8,7 +8,6 @@ package main
 import (
 	"context"
 	"fmt"
-	"math/rand"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -17,15 +16,23 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/retry"
 
 	log "github.com/sirupsen/logrus"
 )
 
 func main() {
-	lambda.Start(handler)
+	config, err := loadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	lambda.Start(func(ctx context.Context, autoScalingEvent events.AutoScalingEvent) {
+		handler(ctx, config, autoScalingEvent)
+	})
 }
 
-func handler(_ context.Context, autoScalingEvent events.AutoScalingEvent) {
+func handler(_ context.Context, config *Config, autoScalingEvent events.AutoScalingEvent) {
 	if autoScalingEvent.DetailType == "EC2 Instance-launch Lifecycle Action" {
 		instanceID := autoScalingEvent.Detail["EC2InstanceId"].(string)
 		lifecycleHookName := autoScalingEvent.Detail["LifecycleHookName"].(string)
@@ -34,23 +41,27 @@ func handler(_ context.Context, autoScalingEvent events.AutoScalingEvent) {
 		vpcID, subNetID, err := getVpcSubNetID(instanceID)
 		if err != nil {
 			log.WithError(err).Errorf("Error getting the subnet from instanceID=%s", instanceID)
-			err = completeLifecycleActionFailure(lifecycleHookName, autoScalingGroupName, instanceID)
-			if err != nil {
-				log.WithError(err).Error("Failed to complete lifecycle action failure")
-			}
+			abandonLifecycleAction(config, lifecycleHookName, autoScalingGroupName, instanceID, err)
 			return
 		}
 		log.Infof("vpcID=%s Subnet=%s\n", vpcID, subNetID)
 
-		err = retry(5, 2*time.Second, func() error {
-			networkInterfaceID, innerErr := getNetWorkInterface(vpcID, subNetID)
+		if !config.subnetAllowed(subNetID) {
+			err = fmt.Errorf("subnet %s is not listed in %s", subNetID, eniSubnetIDsEnv)
+			log.WithError(err).Errorf("Refusing to attach a network interface to instanceID=%s", instanceID)
+			abandonLifecycleAction(config, lifecycleHookName, autoScalingGroupName, instanceID, err)
+			return
+		}
+
+		err = retry.Do(5, 2*time.Second, func() error {
+			networkInterfaceID, innerErr := getNetWorkInterface(config, vpcID, subNetID)
 			if innerErr != nil {
 				log.WithError(innerErr).Errorf("Error getting the network interface for instanceID=%s", instanceID)
 				return innerErr
 			}
 			log.Infof("networkInterfaceID=%s\n", networkInterfaceID)
 
-			attachID, innerErr := attachInterface(networkInterfaceID, instanceID)
+			attachID, innerErr := attachInterface(config, networkInterfaceID, instanceID)
 			if innerErr != nil {
 				log.WithError(innerErr).Errorf("Error attaching the network interface to instanceID=%s", instanceID)
 				return innerErr
@@ -60,10 +71,7 @@ func handler(_ context.Context, autoScalingEvent events.AutoScalingEvent) {
 		})
 
 		if err != nil {
-			err = completeLifecycleActionFailure(lifecycleHookName, autoScalingGroupName, instanceID)
-			if err != nil {
-				log.WithError(err).Error("Failed to complete lifecycle action failure")
-			}
+			abandonLifecycleAction(config, lifecycleHookName, autoScalingGroupName, instanceID, err)
 		} else {
 			err = completeLifecycleActionSuccess(lifecycleHookName, autoScalingGroupName, instanceID)
 			if err != nil {
@@ -73,17 +81,21 @@ func handler(_ context.Context, autoScalingEvent events.AutoScalingEvent) {
 	}
 }
 
-func retry(attempts int, sleep time.Duration, fn func() error) error {
-	var err error
-	for i := 0; i < attempts; i++ {
-		if err = fn(); err == nil {
-			return nil
-		}
-		jitter := time.Duration(rand.Int63n(int64(sleep)))
-		time.Sleep(sleep + jitter)
-		sleep = sleep * 2
+// abandonLifecycleAction abandons the lifecycle action of the instance and
+// notifies the Mattermost hook, if configured, of the cause.
+func abandonLifecycleAction(config *Config, hookName, groupName, instanceID string, cause error) {
+	err := completeLifecycleActionFailure(hookName, groupName, instanceID)
+	if err != nil {
+		log.WithError(err).Error("Failed to complete lifecycle action failure")
+	}
+
+	if config.MattermostHook == "" {
+		return
+	}
+	err = sendMattermostNotification(config.MattermostHook, groupName, instanceID, cause)
+	if err != nil {
+		log.WithError(err).Error("Failed to send Mattermost notification")
 	}
-	return fmt.Errorf("after %d attempts, last error: %s", attempts, err)
 }
 
 func completeLifecycleActionFailure(hookName, groupName, instanceID string) error {
@@ -136,7 +148,7 @@ func completeLifecycleActionSuccess(hookName, groupName, instanceID string) erro
 	return nil
 }
 
-func attachInterface(networkInterfaceID, instanceID string) (string, error) {
+func attachInterface(config *Config, networkInterfaceID, instanceID string) (string, error) {
 	sess, err := session.NewSession(&aws.Config{})
 	if err != nil {
 		return "", err
@@ -144,7 +156,7 @@ func attachInterface(networkInterfaceID, instanceID string) (string, error) {
 	svc := ec2.New(sess)
 
 	input := &ec2.AttachNetworkInterfaceInput{
-		DeviceIndex:        aws.Int64(1),
+		DeviceIndex:        aws.Int64(config.DeviceIndex),
 		InstanceId:         aws.String(instanceID),
 		NetworkInterfaceId: aws.String(networkInterfaceID),
 	}
@@ -157,7 +169,7 @@ func attachInterface(networkInterfaceID, instanceID string) (string, error) {
 	return *result.AttachmentId, nil
 }
 
-func getNetWorkInterface(vpcID, subNetID string) (string, error) {
+func getNetWorkInterface(config *Config, vpcID, subNetID string) (string, error) {
 	sess, err := session.NewSession(&aws.Config{})
 	if err != nil {
 		return "", err
@@ -194,12 +206,14 @@ func getNetWorkInterface(vpcID, subNetID string) (string, error) {
 		return "", err
 	}
 
-	for _, networkInterface := range result.NetworkInterfaces {
-		if *networkInterface.Status == "available" {
-			return *networkInterface.NetworkInterfaceId, nil
-		}
+	now := time.Now()
+	networkInterface := selectNetworkInterface(result.NetworkInterfaces, config.AgeTag, now, config.PreferOldest)
+	if networkInterface == nil {
+		return "", fmt.Errorf("no Network Interface available")
 	}
-	return "", fmt.Errorf("no Network Interface available")
+	recordNetworkInterfaceAge(config, networkInterface, subNetID, now)
+
+	return *networkInterface.NetworkInterfaceId, nil
 }
 
 func getVpcSubNetID(instanceID string) (string, string, error) {
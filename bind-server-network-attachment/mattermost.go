@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type webhookRequest struct {
+	Username string `json:"username"`
+	Text     string `json:"text"`
+}
+
+// sendMattermostNotification posts the reason an instance's lifecycle action
+// was abandoned to the Mattermost hook.
+func sendMattermostNotification(hookURL, groupName, instanceID string, cause error) error {
+	payload := &webhookRequest{
+		Username: "Bind Server Network Attachment",
+		Text: fmt.Sprintf("Bind server lifecycle action ABANDONED\n---\nAuto Scaling Group: %s\nInstance: %s\nError: %s\n",
+			groupName,
+			instanceID,
+			cause,
+		),
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, hookURL, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	client := http.Client{Timeout: 5 * time.Second}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("received status code %d", response.StatusCode)
+	}
+
+	return nil
+}
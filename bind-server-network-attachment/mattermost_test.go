@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendMattermostNotification(t *testing.T) {
+	var received webhookRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer server.Close()
+
+	err := sendMattermostNotification(server.URL, "bind-asg", "i-123", errors.New("no Network Interface available"))
+	require.NoError(t, err)
+	assert.Contains(t, received.Text, "Auto Scaling Group: bind-asg")
+	assert.Contains(t, received.Text, "Instance: i-123")
+	assert.Contains(t, received.Text, "Error: no Network Interface available")
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	err = sendMattermostNotification(failing.URL, "bind-asg", "i-123", errors.New("boom"))
+	assert.EqualError(t, err, "received status code 500")
+}
@@ -0,0 +1,14 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/apigateway"
+)
+
+// validateCloudRequestV2 is the HTTP API entrypoint. It reuses
+// validateCloudRequest for the actual work.
+func validateCloudRequestV2(config *Config, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	response, err := validateCloudRequest(config, apigateway.ProxyRequestFromV2(request))
+
+	return apigateway.V2ResponseFromProxy(response), err
+}
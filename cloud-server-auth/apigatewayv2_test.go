@@ -0,0 +1,38 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCloudRequestV2(t *testing.T) {
+	var receivedPath, receivedBody string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		receivedPath, receivedBody = r.URL.Path, string(b)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"installation"}`))
+	}))
+	defer upstream.Close()
+
+	config := &Config{CloudServerURL: upstream.URL, MattermostWebhookURL: upstream.URL, UpstreamTimeout: defaultUpstreamTimeout}
+	response, err := validateCloudRequestV2(config, events.APIGatewayV2HTTPRequest{
+		RawPath: "/api/installation",
+		Headers: map[string]string{"content-type": "application/json"},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodPost, Path: "/api/installation"},
+		},
+		Body: `{"dns":"test"}`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "/api/installation", receivedPath)
+	assert.Equal(t, `{"dns":"test"}`, receivedBody)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, `{"id":"installation"}`, response.Body)
+}
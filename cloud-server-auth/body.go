@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pkg/errors"
+)
+
+// requestBody returns the raw request body, decoding it when API Gateway
+// delivered it base64-encoded.
+func requestBody(request events.APIGatewayProxyRequest) ([]byte, error) {
+	if !request.IsBase64Encoded {
+		return []byte(request.Body), nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(request.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode base64 request body")
+	}
+
+	return decoded, nil
+}
+
+// gzipBody compresses a request body for an upstream that accepts gzip
+// encoded requests.
+func gzipBody(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(body); err != nil {
+		return nil, errors.Wrap(err, "failed to gzip request body")
+	}
+	if err := writer.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to gzip request body")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// readResponseBody reads an upstream response body. Requests ask for the
+// identity encoding, but some upstreams gzip their responses regardless, so a
+// gzip Content-Encoding is decompressed here.
+func readResponseBody(resp *http.Response) ([]byte, error) {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read cloud server response body")
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(resp.Header.Get("Content-Encoding")), "gzip") {
+		return body, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decompress cloud server response body")
+	}
+	defer reader.Close()
+
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decompress cloud server response body")
+	}
+
+	return decompressed, nil
+}
+
+// isBinaryResponse reports whether an upstream response body must be
+// base64-encoded to be returned through API Gateway.
+func isBinaryResponse(contentType string, body []byte) bool {
+	if contentType == "" {
+		return !utf8.Valid(body)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return !utf8.Valid(body)
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/json",
+		mediaType == "application/xml",
+		mediaType == "application/javascript",
+		mediaType == "application/x-www-form-urlencoded",
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return false
+	}
+
+	return true
+}
+
+// proxyResponse builds the API Gateway response for an upstream response.
+func proxyResponse(statusCode int, contentType string, body []byte) events.APIGatewayProxyResponse {
+	if isBinaryResponse(contentType, body) {
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode:      statusCode,
+			Headers:         map[string]string{"Content-Type": contentType},
+			Body:            base64.StdEncoding.EncodeToString(body),
+			IsBase64Encoded: true,
+		}
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(body),
+	}
+}
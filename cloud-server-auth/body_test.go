@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsBinaryResponse(t *testing.T) {
+	assert.False(t, isBinaryResponse("application/json", []byte(`{}`)))
+	assert.False(t, isBinaryResponse("application/json; charset=utf-8", []byte(`{}`)))
+	assert.False(t, isBinaryResponse("text/plain", []byte("ok")))
+	assert.False(t, isBinaryResponse("", []byte("ok")))
+	assert.True(t, isBinaryResponse("application/octet-stream", []byte("ok")))
+	assert.True(t, isBinaryResponse("", []byte{0xff, 0xfe, 0x00}))
+}
+
+func TestValidateCloudRequestBase64Body(t *testing.T) {
+	binaryBody := []byte{0x1f, 0x8b, 0x00, 0xff, 0x10}
+
+	var received []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(received)
+	}))
+	defer upstream.Close()
+
+	config := &Config{CloudServerURL: upstream.URL, MattermostWebhookURL: upstream.URL, UpstreamTimeout: defaultUpstreamTimeout}
+	response, err := validateCloudRequest(config, events.APIGatewayProxyRequest{
+		HTTPMethod:      http.MethodPost,
+		Path:            "/api/installation",
+		Body:            base64.StdEncoding.EncodeToString(binaryBody),
+		IsBase64Encoded: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, binaryBody, received)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.True(t, response.IsBase64Encoded)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(binaryBody), response.Body)
+	assert.Equal(t, "application/octet-stream", response.Headers["Content-Type"])
+}
+
+func TestValidateCloudRequestPlainBody(t *testing.T) {
+	var received string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		received = string(b)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"installation"}`))
+	}))
+	defer upstream.Close()
+
+	config := &Config{CloudServerURL: upstream.URL, MattermostWebhookURL: upstream.URL, UpstreamTimeout: defaultUpstreamTimeout}
+	response, err := validateCloudRequest(config, events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodPost,
+		Path:       "/api/installation",
+		Body:       `{"dns":"test"}`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `{"dns":"test"}`, received)
+	assert.False(t, response.IsBase64Encoded)
+	assert.Equal(t, `{"id":"installation"}`, response.Body)
+}
+
+func TestValidateCloudRequestCompressUpstream(t *testing.T) {
+	var contentEncoding string
+	var received []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentEncoding = r.Header.Get("Content-Encoding")
+		received, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	payload := `{"dns": "test.cloud.mattermost.com"}`
+	request := events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodPost,
+		Path:       "/api/installation",
+		Body:       payload,
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		config := &Config{CloudServerURL: upstream.URL, UpstreamTimeout: defaultUpstreamTimeout}
+
+		_, err := validateCloudRequest(config, request)
+		require.NoError(t, err)
+		assert.Empty(t, contentEncoding)
+		assert.Equal(t, payload, string(received))
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		config := &Config{CloudServerURL: upstream.URL, UpstreamTimeout: defaultUpstreamTimeout, CompressUpstream: true}
+
+		_, err := validateCloudRequest(config, request)
+		require.NoError(t, err)
+		assert.Equal(t, "gzip", contentEncoding)
+
+		reader, err := gzip.NewReader(bytes.NewReader(received))
+		require.NoError(t, err)
+		decompressed, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, payload, string(decompressed))
+	})
+
+	t.Run("empty body is sent as is", func(t *testing.T) {
+		config := &Config{CloudServerURL: upstream.URL, UpstreamTimeout: defaultUpstreamTimeout, CompressUpstream: true}
+
+		_, err := validateCloudRequest(config, events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/api/installations"})
+		require.NoError(t, err)
+		assert.Empty(t, contentEncoding)
+		assert.Empty(t, received)
+	})
+}
+
+func TestValidateCloudRequestGzipResponse(t *testing.T) {
+	payload := `{"id":"installation","dns":"test.example.com"}`
+	compressed, err := gzipBody([]byte(payload))
+	require.NoError(t, err)
+
+	var acceptEncoding string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compressed)
+	}))
+	defer upstream.Close()
+
+	config := &Config{CloudServerURL: upstream.URL, UpstreamTimeout: defaultUpstreamTimeout}
+	response, err := validateCloudRequest(config, events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/api/installations"})
+	require.NoError(t, err)
+	assert.Equal(t, "identity", acceptEncoding)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.False(t, response.IsBase64Encoded)
+	assert.Equal(t, payload, response.Body)
+}
+
+func TestReadResponseBody(t *testing.T) {
+	compressed, err := gzipBody([]byte("hello"))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name            string
+		contentEncoding string
+		body            []byte
+		expected        string
+		expectError     bool
+	}{
+		{"identity", "", []byte("hello"), "hello", false},
+		{"gzip", "gzip", compressed, "hello", false},
+		{"gzip mixed case", " GZip ", compressed, "hello", false},
+		{"invalid gzip", "gzip", []byte("hello"), "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := &http.Response{
+				Header: http.Header{"Content-Encoding": []string{tc.contentEncoding}},
+				Body:   io.NopCloser(bytes.NewReader(tc.body)),
+			}
+
+			body, err := readResponseBody(resp)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, string(body))
+		})
+	}
+}
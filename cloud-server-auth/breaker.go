@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const (
+	defaultBreakerFailureThreshold = 5
+	defaultBreakerCooldown         = 30 * time.Second
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// upstreamBreaker guards the cloud server calls for the lifetime of a warm
+// container. It is nil when the breaker is disabled.
+var upstreamBreaker *circuitBreaker
+
+// circuitBreaker stops calling the upstream after threshold consecutive
+// failures. Once the cooldown has passed a single trial call is let through:
+// its success closes the breaker again and its failure reopens it.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+	state     breakerState
+	failures  int
+	openedAt  time.Time
+}
+
+// newCircuitBreaker returns a breaker with the given threshold and cooldown,
+// or nil when the threshold disables it.
+func newCircuitBreaker(threshold int, cooldown time.Duration, now func() time.Time) *circuitBreaker {
+	if threshold <= 0 {
+		return nil
+	}
+
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       now,
+	}
+}
+
+// allow reports whether the upstream may be called.
+func (b *circuitBreaker) allow() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		// The trial call is still in flight.
+		return false
+	default:
+		return true
+	}
+}
+
+func (b *circuitBreaker) success() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = breakerClosed
+	b.failures = 0
+}
+
+func (b *circuitBreaker) failure() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+func (b *circuitBreaker) currentState() breakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// breakerOpenResponse fast-fails a request while the breaker is open,
+// without calling the upstream or the failure webhook.
+func breakerOpenResponse() events.APIGatewayProxyResponse {
+	body, _ := json.Marshal(errorResponse{Error: "cloud server is unavailable, try again later"})
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusServiceUnavailable,
+		Body:       string(body),
+	}
+}
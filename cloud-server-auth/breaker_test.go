@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCircuitBreakerDisabled(t *testing.T) {
+	breaker := newCircuitBreaker(0, time.Minute, time.Now)
+	assert.Nil(t, breaker)
+	assert.True(t, breaker.allow())
+	breaker.failure()
+	breaker.success()
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	clock := &fakeClock{current: time.Now()}
+	breaker := newCircuitBreaker(3, time.Minute, clock.now)
+
+	// Failures below the threshold, or interrupted by a success, keep it closed.
+	breaker.failure()
+	breaker.failure()
+	breaker.success()
+	breaker.failure()
+	breaker.failure()
+	assert.True(t, breaker.allow())
+	assert.Equal(t, breakerClosed, breaker.currentState())
+
+	breaker.failure()
+	assert.Equal(t, breakerOpen, breaker.currentState())
+	assert.False(t, breaker.allow())
+
+	clock.current = clock.current.Add(59 * time.Second)
+	assert.False(t, breaker.allow())
+
+	// After the cooldown a single trial call goes through.
+	clock.current = clock.current.Add(time.Second)
+	assert.True(t, breaker.allow())
+	assert.Equal(t, breakerHalfOpen, breaker.currentState())
+	assert.False(t, breaker.allow())
+
+	// A failed trial reopens it for another cooldown.
+	breaker.failure()
+	assert.Equal(t, breakerOpen, breaker.currentState())
+	assert.False(t, breaker.allow())
+
+	clock.current = clock.current.Add(time.Minute)
+	assert.True(t, breaker.allow())
+
+	// A successful trial closes it.
+	breaker.success()
+	assert.Equal(t, breakerClosed, breaker.currentState())
+	assert.True(t, breaker.allow())
+	breaker.failure()
+	assert.Equal(t, breakerClosed, breaker.currentState())
+}
+
+func TestValidateCloudRequestCircuitBreaker(t *testing.T) {
+	var upstreamStatus, upstreamCalls, webhookCalls int32
+	atomic.StoreInt32(&upstreamStatus, http.StatusInternalServerError)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&upstreamCalls, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&upstreamStatus)))
+	}))
+	defer upstream.Close()
+
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&webhookCalls, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer webhook.Close()
+
+	clock := &fakeClock{current: time.Now()}
+	upstreamBreaker = newCircuitBreaker(2, time.Minute, clock.now)
+	defer func() { upstreamBreaker = nil }()
+
+	config := &Config{CloudServerURL: upstream.URL, MattermostWebhookURL: webhook.URL, UpstreamTimeout: defaultUpstreamTimeout}
+	request := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/api/installations"}
+
+	for i := 0; i < 2; i++ {
+		response, err := validateCloudRequest(config, request)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+	}
+	assert.Equal(t, breakerOpen, upstreamBreaker.currentState())
+
+	response, err := validateCloudRequest(config, request)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&upstreamCalls))
+	assert.Zero(t, atomic.LoadInt32(&webhookCalls))
+
+	clock.current = clock.current.Add(time.Minute)
+	atomic.StoreInt32(&upstreamStatus, http.StatusOK)
+
+	response, err = validateCloudRequest(config, request)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.EqualValues(t, 3, atomic.LoadInt32(&upstreamCalls))
+	assert.Equal(t, breakerClosed, upstreamBreaker.currentState())
+}
+
+func TestLoadConfigBreaker(t *testing.T) {
+	t.Setenv(cloudServerEnv, "https://provisioner.internal")
+	t.Setenv(mattermostWebhookEnv, "https://mattermost/hooks/abc")
+
+	config, err := loadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, defaultBreakerFailureThreshold, config.BreakerThreshold)
+	assert.Equal(t, defaultBreakerCooldown, config.BreakerCooldown)
+
+	t.Setenv(breakerThresholdEnv, "0")
+	t.Setenv(breakerCooldownEnv, "5")
+	config, err = loadConfig()
+	require.NoError(t, err)
+	assert.Zero(t, config.BreakerThreshold)
+	assert.Equal(t, 5*time.Second, config.BreakerCooldown)
+
+	t.Setenv(breakerThresholdEnv, "-1")
+	_, err = loadConfig()
+	assert.Error(t, err)
+
+	t.Setenv(breakerThresholdEnv, "")
+	t.Setenv(breakerCooldownEnv, "0")
+	_, err = loadConfig()
+	assert.Error(t, err)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const cacheHeader = "X-Cache"
+
+// upstreamCache holds authorized GET responses for the lifetime of a warm
+// container. It is nil when caching is disabled.
+var upstreamCache *responseCache
+
+type cachedResponse struct {
+	response  events.APIGatewayProxyResponse
+	expiresAt time.Time
+}
+
+// responseCache is an in-memory TTL cache of upstream responses keyed by
+// method, path and query.
+type responseCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]cachedResponse
+}
+
+// newResponseCache returns a cache with the given TTL, or nil when the TTL
+// disables caching.
+func newResponseCache(ttl time.Duration, now func() time.Time) *responseCache {
+	if ttl <= 0 {
+		return nil
+	}
+
+	return &responseCache{
+		ttl:     ttl,
+		now:     now,
+		entries: make(map[string]cachedResponse),
+	}
+}
+
+func (c *responseCache) get(key string) (events.APIGatewayProxyResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return events.APIGatewayProxyResponse{}, false
+	}
+	if !c.now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return events.APIGatewayProxyResponse{}, false
+	}
+
+	return copyResponse(entry.response), true
+}
+
+// set caches the response for key. Expired entries of the other keys are
+// dropped at the same time, as get only drops the ones requested again and
+// paging through varying queries would otherwise grow the cache for as long
+// as the container stays warm.
+func (c *responseCache) set(key string, response events.APIGatewayProxyResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for cachedKey, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, cachedKey)
+		}
+	}
+
+	c.entries[key] = cachedResponse{
+		response:  copyResponse(response),
+		expiresAt: now.Add(c.ttl),
+	}
+}
+
+// withCacheHeader returns a copy of the response carrying the X-Cache header.
+func withCacheHeader(response events.APIGatewayProxyResponse, value string) events.APIGatewayProxyResponse {
+	response = copyResponse(response)
+	response.Headers[cacheHeader] = value
+	return response
+}
+
+func copyResponse(response events.APIGatewayProxyResponse) events.APIGatewayProxyResponse {
+	headers := make(map[string]string, len(response.Headers))
+	for key, value := range response.Headers {
+		headers[key] = value
+	}
+	response.Headers = headers
+
+	return response
+}
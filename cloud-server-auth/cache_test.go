@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	current time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.current
+}
+
+func TestNewResponseCacheDisabled(t *testing.T) {
+	assert.Nil(t, newResponseCache(0, time.Now))
+}
+
+func TestValidateCloudRequestCache(t *testing.T) {
+	var calls int
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	defer upstream.Close()
+
+	clock := &fakeClock{current: time.Now()}
+	upstreamCache = newResponseCache(time.Minute, clock.now)
+	defer func() { upstreamCache = nil }()
+
+	config := &Config{CloudServerURL: upstream.URL, MattermostWebhookURL: upstream.URL, UpstreamTimeout: defaultUpstreamTimeout}
+	request := events.APIGatewayProxyRequest{
+		HTTPMethod:            http.MethodGet,
+		Path:                  "/api/installations",
+		QueryStringParameters: map[string]string{"page": "0"},
+	}
+
+	response, err := validateCloudRequest(config, request)
+	require.NoError(t, err)
+	assert.Equal(t, "MISS", response.Headers[cacheHeader])
+	assert.Equal(t, 1, calls)
+
+	response, err = validateCloudRequest(config, request)
+	require.NoError(t, err)
+	assert.Equal(t, "HIT", response.Headers[cacheHeader])
+	assert.Equal(t, `[]`, response.Body)
+	assert.Equal(t, 1, calls)
+
+	t.Run("different query misses", func(t *testing.T) {
+		other := request
+		other.QueryStringParameters = map[string]string{"page": "1"}
+		response, err := validateCloudRequest(config, other)
+		require.NoError(t, err)
+		assert.Equal(t, "MISS", response.Headers[cacheHeader])
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("non GET requests are not cached", func(t *testing.T) {
+		post := request
+		post.HTTPMethod = http.MethodPost
+		response, err := validateCloudRequest(config, post)
+		require.NoError(t, err)
+		assert.NotContains(t, response.Headers, cacheHeader)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("expired entries miss", func(t *testing.T) {
+		clock.current = clock.current.Add(time.Minute)
+		response, err := validateCloudRequest(config, request)
+		require.NoError(t, err)
+		assert.Equal(t, "MISS", response.Headers[cacheHeader])
+		assert.Equal(t, 4, calls)
+	})
+}
+
+func TestResponseCacheSetDropsExpiredEntries(t *testing.T) {
+	clock := &fakeClock{current: time.Now()}
+	cache := newResponseCache(time.Minute, clock.now)
+
+	cache.set("GET /api/installations?page=0", events.APIGatewayProxyResponse{Body: "0"})
+	cache.set("GET /api/installations?page=1", events.APIGatewayProxyResponse{Body: "1"})
+	clock.current = clock.current.Add(30 * time.Second)
+	cache.set("GET /api/installations?page=2", events.APIGatewayProxyResponse{Body: "2"})
+	require.Len(t, cache.entries, 3)
+
+	clock.current = clock.current.Add(30 * time.Second)
+	cache.set("GET /api/installations?page=3", events.APIGatewayProxyResponse{Body: "3"})
+
+	assert.Len(t, cache.entries, 2)
+	assert.Contains(t, cache.entries, "GET /api/installations?page=2")
+	assert.Contains(t, cache.entries, "GET /api/installations?page=3")
+}
@@ -4,8 +4,17 @@ go 1.23
 
 require (
 	github.com/aws/aws-lambda-go v1.47.0
+	github.com/mattermost/mattermost-cloud-lambdas/internal/apigateway v0.0.0
 	github.com/pkg/errors v0.9.1
 	github.com/sirupsen/logrus v1.9.3
+	github.com/stretchr/testify v1.10.0
 )
 
-require golang.org/x/sys v0.28.0 // indirect
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.28.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/apigateway => ../internal/apigateway
@@ -10,16 +10,17 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/apigateway"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
@@ -27,6 +28,16 @@ import (
 const (
 	cloudServerEnv           = "CLOUD_SERVER"
 	mattermostWebhookEnv     = "MATTERMOST_WEBHOOK"
+	upstreamTimeoutEnv       = "UPSTREAM_TIMEOUT_SECONDS"
+	cacheTTLEnv              = "CACHE_TTL_SECONDS"
+	sensitiveQueryKeysEnv    = "SENSITIVE_QUERY_KEYS"
+	cloudServerRoutesEnv     = "CLOUD_SERVER_ROUTES"
+	breakerThresholdEnv      = "BREAKER_FAILURE_THRESHOLD"
+	breakerCooldownEnv       = "BREAKER_COOLDOWN_SECONDS"
+	compressUpstreamEnv      = "COMPRESS_UPSTREAM"
+	requestIDHeader          = "X-Request-ID"
+	forwardedForHeader       = "X-Forwarded-For"
+	defaultUpstreamTimeout   = 10 * time.Second
 	mattermostWebhookIconURL = "https://images2.minutemediacdn.com/image/upload/c_fill,g_auto,h_1248,w_2220/f_auto,q_auto,w_1100/v1555925520/shape/mentalfloss/800px-princesslineup.jpg"
 )
 
@@ -34,6 +45,16 @@ const (
 type Config struct {
 	CloudServerURL       string
 	MattermostWebhookURL string
+	UpstreamTimeout      time.Duration
+	CacheTTL             time.Duration
+	SensitiveQueryKeys   []string
+	Routes               map[string]string
+	// BreakerThreshold is the number of consecutive upstream failures that
+	// open the circuit breaker; zero disables it.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// CompressUpstream gzips request bodies sent to the cloud server.
+	CompressUpstream bool
 }
 
 type errorResponse struct {
@@ -46,6 +67,42 @@ type webhookRequest struct {
 	IconURL  string `json:"icon_url"`
 }
 
+// requestLogger returns a logger tagging every line with the API Gateway
+// request ID of the invocation, so it can be correlated with the upstream
+// call and the webhook.
+func requestLogger(request events.APIGatewayProxyRequest) *log.Entry {
+	return log.WithField("request_id", request.RequestContext.RequestID)
+}
+
+// forwardedFor returns the X-Forwarded-For value for the upstream request:
+// the incoming value, if any, followed by the caller's source IP, so the cloud
+// server sees the real client rather than the Lambda.
+func forwardedFor(request events.APIGatewayProxyRequest) string {
+	var existing string
+	for name, value := range request.Headers {
+		if strings.EqualFold(name, forwardedForHeader) {
+			existing = strings.TrimSpace(value)
+			break
+		}
+	}
+
+	sourceIP := request.RequestContext.Identity.SourceIP
+	if sourceIP == "" {
+		return existing
+	}
+	if existing == "" {
+		return sourceIP
+	}
+
+	// API Gateway usually already appended the caller to the header.
+	hops := strings.Split(existing, ",")
+	if strings.TrimSpace(hops[len(hops)-1]) == sourceIP {
+		return existing
+	}
+
+	return existing + ", " + sourceIP
+}
+
 func initLogging() {
 	log.SetFormatter(&log.JSONFormatter{})
 	log.SetOutput(os.Stdout)
@@ -67,20 +124,88 @@ func loadConfig() (*Config, error) {
 		return nil, fmt.Errorf("environment variable %s is not set", mattermostWebhookEnv)
 	}
 
+	upstreamTimeout := defaultUpstreamTimeout
+	if value := os.Getenv(upstreamTimeoutEnv); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("environment variable %s must be a positive number of seconds", upstreamTimeoutEnv)
+		}
+		upstreamTimeout = time.Duration(seconds) * time.Second
+	}
+
+	var cacheTTL time.Duration
+	if value := os.Getenv(cacheTTLEnv); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			return nil, fmt.Errorf("environment variable %s must be a non-negative number of seconds", cacheTTLEnv)
+		}
+		cacheTTL = time.Duration(seconds) * time.Second
+	}
+
+	sensitiveQueryKeys := defaultSensitiveQueryKeys
+	if value := os.Getenv(sensitiveQueryKeysEnv); value != "" {
+		sensitiveQueryKeys = nil
+		for _, key := range strings.Split(value, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				sensitiveQueryKeys = append(sensitiveQueryKeys, key)
+			}
+		}
+	}
+
+	routes, err := parseRoutes(os.Getenv(cloudServerRoutesEnv))
+	if err != nil {
+		return nil, err
+	}
+
+	breakerThreshold := defaultBreakerFailureThreshold
+	if value := os.Getenv(breakerThresholdEnv); value != "" {
+		breakerThreshold, err = strconv.Atoi(value)
+		if err != nil || breakerThreshold < 0 {
+			return nil, fmt.Errorf("environment variable %s must be a non-negative number", breakerThresholdEnv)
+		}
+	}
+
+	breakerCooldown := defaultBreakerCooldown
+	if value := os.Getenv(breakerCooldownEnv); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("environment variable %s must be a positive number of seconds", breakerCooldownEnv)
+		}
+		breakerCooldown = time.Duration(seconds) * time.Second
+	}
+
+	var compressUpstream bool
+	if value := os.Getenv(compressUpstreamEnv); value != "" {
+		compressUpstream, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("environment variable %s must be a boolean", compressUpstreamEnv)
+		}
+	}
+
 	return &Config{
 		CloudServerURL:       cloudServerURL,
 		MattermostWebhookURL: mattermostWebhookURL,
+		UpstreamTimeout:      upstreamTimeout,
+		CacheTTL:             cacheTTL,
+		SensitiveQueryKeys:   sensitiveQueryKeys,
+		Routes:               routes,
+		BreakerThreshold:     breakerThreshold,
+		BreakerCooldown:      breakerCooldown,
+		CompressUpstream:     compressUpstream,
 	}, nil
 }
 
 func validateCloudRequest(config *Config, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	parsedCloudURL, err := url.Parse(config.CloudServerURL)
+	logger := requestLogger(request)
+
+	cloudServerURL := backendURL(config, request.Path)
+	parsedCloudURL, err := url.Parse(cloudServerURL)
 	if err != nil {
-		return processFailedAuth(config, request, http.StatusInternalServerError, errors.Wrapf(err, "cloud server URL %s is invalid", config.CloudServerURL))
+		return processFailedAuth(config, request, http.StatusInternalServerError, errors.Wrapf(err, "cloud server URL %s is invalid", cloudServerURL))
 	}
 
-	log.Infof("Initial path: %s", request.Path)
-	log.Infof("Initial query parameters: %s", request.QueryStringParameters)
+	logger.Infof("Initial path: %s", request.Path)
+	logger.Infof("Initial query parameters: %s", redactQueryParameters(request.QueryStringParameters, config.SensitiveQueryKeys))
 
 	parsedPath, err := url.Parse(request.Path)
 	if err != nil {
@@ -98,33 +223,83 @@ func validateCloudRequest(config *Config, request events.APIGatewayProxyRequest)
 		return processFailedAuth(config, request, http.StatusUnauthorized, fmt.Errorf("%s is not an authorized path", final.EscapedPath()))
 	}
 
-	log.Infof("Final API call: Method %s | %s", request.HTTPMethod, final.String())
+	logger.WithFields(log.Fields{
+		"method": request.HTTPMethod,
+		"url":    redactURL(final, config.SensitiveQueryKeys),
+	}).Info("Final API call")
+
+	cacheKey := request.HTTPMethod + " " + final.String()
+	cacheable := upstreamCache != nil && request.HTTPMethod == http.MethodGet
+	if cacheable {
+		if cached, ok := upstreamCache.get(cacheKey); ok {
+			logger.Info("Serving cached response")
+			return withCacheHeader(cached, "HIT"), nil
+		}
+	}
 
-	cloudServerRequest, err := http.NewRequest(request.HTTPMethod, final.String(), bytes.NewReader([]byte(request.Body)))
+	inboundBody, err := requestBody(request)
+	if err != nil {
+		return processFailedAuth(config, request, http.StatusBadRequest, err)
+	}
+
+	compressed := config.CompressUpstream && len(inboundBody) > 0
+	if compressed {
+		inboundBody, err = gzipBody(inboundBody)
+		if err != nil {
+			return processFailedAuth(config, request, http.StatusInternalServerError, err)
+		}
+	}
+
+	cloudServerRequest, err := http.NewRequest(request.HTTPMethod, final.String(), bytes.NewReader(inboundBody))
 	if err != nil {
 		return processFailedAuth(config, request, http.StatusInternalServerError, err)
 	}
-	cloudServerRequest.Header.Set("Accept-Encoding", "")
+	cloudServerRequest.Header.Set("Accept-Encoding", "identity")
+	if compressed {
+		cloudServerRequest.Header.Set("Content-Encoding", "gzip")
+	}
+	if requestID := request.RequestContext.RequestID; requestID != "" {
+		cloudServerRequest.Header.Set(requestIDHeader, requestID)
+	}
+	if forwarded := forwardedFor(request); forwarded != "" {
+		cloudServerRequest.Header.Set(forwardedForHeader, forwarded)
+	}
+
+	if !upstreamBreaker.allow() {
+		logger.Warn("Circuit breaker is open, not calling the cloud server")
+		return breakerOpenResponse(), nil
+	}
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := &http.Client{Timeout: config.UpstreamTimeout}
 	resp, err := client.Do(cloudServerRequest)
 	if err != nil {
+		upstreamBreaker.failure()
 		return processFailedAuth(config, request, http.StatusInternalServerError, errors.Wrap(err, "failed when making request to cloud server"))
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		upstreamBreaker.failure()
+	} else {
+		upstreamBreaker.success()
+	}
+
+	body, err := readResponseBody(resp)
 	if err != nil {
-		return processFailedAuth(config, request, http.StatusInternalServerError, errors.Wrap(err, "failed to read cloud server response body"))
+		return processFailedAuth(config, request, http.StatusInternalServerError, err)
 	}
 
-	log.Info("Success!")
+	logger.Info("Success!")
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: resp.StatusCode,
-		Headers:    map[string]string{"Content-Type": "application/json"},
-		Body:       string(body),
-	}, nil
+	response := proxyResponse(resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	if cacheable {
+		if resp.StatusCode == http.StatusOK {
+			upstreamCache.set(cacheKey, response)
+		}
+		response = withCacheHeader(response, "MISS")
+	}
+
+	return response, nil
 }
 
 func isAuthorized(url *url.URL) bool {
@@ -156,10 +331,11 @@ func isAuthorized(url *url.URL) bool {
 }
 
 func processFailedAuth(config *Config, request events.APIGatewayProxyRequest, statusCode int, err error) (events.APIGatewayProxyResponse, error) {
-	log.WithError(err).Error("Auth Failure")
+	logger := requestLogger(request)
+	logger.WithError(err).Error("Auth Failure")
 
 	if webhookErr := sendToWebhook(config, request, err); webhookErr != nil {
-		log.WithError(webhookErr).Error("Mattermost Webhook Error")
+		logger.WithError(webhookErr).Error("Mattermost Webhook Error")
 	}
 
 	jsonResponse, _ := json.Marshal(errorResponse{Error: err.Error()})
@@ -216,7 +392,15 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
-
+	upstreamCache = newResponseCache(config.CacheTTL, time.Now)
+	upstreamBreaker = newCircuitBreaker(config.BreakerThreshold, config.BreakerCooldown, time.Now)
+
+	if apigateway.UseHTTPAPIPayload() {
+		lambda.Start(func(request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+			return validateCloudRequestV2(config, request)
+		})
+		return
+	}
 	lambda.Start(func(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 		return validateCloudRequest(config, request)
 	})
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv(cloudServerEnv, "https://provisioner.internal")
+	t.Setenv(mattermostWebhookEnv, "https://mattermost/hooks/abc")
+
+	t.Run("default upstream timeout", func(t *testing.T) {
+		t.Setenv(upstreamTimeoutEnv, "")
+		config, err := loadConfig()
+		require.NoError(t, err)
+		assert.Equal(t, defaultUpstreamTimeout, config.UpstreamTimeout)
+	})
+
+	t.Run("configured upstream timeout", func(t *testing.T) {
+		t.Setenv(upstreamTimeoutEnv, "30")
+		config, err := loadConfig()
+		require.NoError(t, err)
+		assert.Equal(t, 30*time.Second, config.UpstreamTimeout)
+	})
+
+	t.Run("compress upstream", func(t *testing.T) {
+		t.Setenv(compressUpstreamEnv, "")
+		config, err := loadConfig()
+		require.NoError(t, err)
+		assert.False(t, config.CompressUpstream)
+
+		t.Setenv(compressUpstreamEnv, "true")
+		config, err = loadConfig()
+		require.NoError(t, err)
+		assert.True(t, config.CompressUpstream)
+
+		t.Setenv(compressUpstreamEnv, "maybe")
+		_, err = loadConfig()
+		assert.Error(t, err)
+	})
+
+	for _, value := range []string{"0", "-5", "ten"} {
+		t.Run("invalid upstream timeout "+value, func(t *testing.T) {
+			t.Setenv(upstreamTimeoutEnv, value)
+			_, err := loadConfig()
+			assert.EqualError(t, err, "environment variable UPSTREAM_TIMEOUT_SECONDS must be a positive number of seconds")
+		})
+	}
+}
+
+func TestValidateCloudRequestUpstreamTimeout(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+	defer close(release)
+
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer webhook.Close()
+
+	config := &Config{
+		CloudServerURL:       upstream.URL,
+		MattermostWebhookURL: webhook.URL,
+		UpstreamTimeout:      50 * time.Millisecond,
+	}
+	response, err := validateCloudRequest(config, events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodGet,
+		Path:       "/api/installations",
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Client.Timeout exceeded")
+	assert.Equal(t, http.StatusInternalServerError, response.StatusCode)
+}
+
+func TestValidateCloudRequestRequestID(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	var upstreamRequestID string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamRequestID = r.Header.Get(requestIDHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	config := &Config{
+		CloudServerURL:  upstream.URL,
+		UpstreamTimeout: time.Second,
+	}
+	request := events.APIGatewayProxyRequest{
+		HTTPMethod:     http.MethodGet,
+		Path:           "/api/installations",
+		RequestContext: events.APIGatewayProxyRequestContext{RequestID: "request-123"},
+	}
+
+	response, err := validateCloudRequest(config, request)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "request-123", upstreamRequestID)
+
+	require.NotEmpty(t, hook.AllEntries())
+	for _, entry := range hook.AllEntries() {
+		assert.Equal(t, "request-123", entry.Data["request_id"], entry.Message)
+	}
+}
+
+func TestProcessFailedAuthRequestID(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer webhook.Close()
+
+	config := &Config{CloudServerURL: "https://provisioner.internal", MattermostWebhookURL: webhook.URL}
+	request := events.APIGatewayProxyRequest{
+		HTTPMethod:     http.MethodGet,
+		Path:           "/api/secrets",
+		RequestContext: events.APIGatewayProxyRequestContext{RequestID: "request-456"},
+	}
+
+	response, err := validateCloudRequest(config, request)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, "Auth Failure", entry.Message)
+	assert.Equal(t, "request-456", entry.Data["request_id"])
+}
+
+func TestForwardedFor(t *testing.T) {
+	testCases := []struct {
+		name     string
+		headers  map[string]string
+		sourceIP string
+		expected string
+	}{
+		{"source ip only", nil, "203.0.113.7", "203.0.113.7"},
+		{"appends to existing", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "203.0.113.7", "198.51.100.1, 203.0.113.7"},
+		{"lower case header", map[string]string{"x-forwarded-for": "198.51.100.1"}, "203.0.113.7", "198.51.100.1, 203.0.113.7"},
+		{"already last hop", map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.7"}, "203.0.113.7", "198.51.100.1, 203.0.113.7"},
+		{"no source ip", map[string]string{"X-Forwarded-For": "198.51.100.1"}, "", "198.51.100.1"},
+		{"nothing", nil, "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := events.APIGatewayProxyRequest{Headers: tc.headers}
+			request.RequestContext.Identity.SourceIP = tc.sourceIP
+			assert.Equal(t, tc.expected, forwardedFor(request))
+		})
+	}
+}
+
+func TestValidateCloudRequestForwardedFor(t *testing.T) {
+	var upstreamForwardedFor string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamForwardedFor = r.Header.Get(forwardedForHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	config := &Config{CloudServerURL: upstream.URL, UpstreamTimeout: time.Second}
+	request := events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodGet,
+		Path:       "/api/installations",
+		Headers:    map[string]string{"X-Forwarded-For": "198.51.100.1"},
+	}
+	request.RequestContext.Identity.SourceIP = "203.0.113.7"
+
+	response, err := validateCloudRequest(config, request)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "198.51.100.1, 203.0.113.7", upstreamForwardedFor)
+}
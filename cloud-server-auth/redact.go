@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+const redactedValue = "REDACTED"
+
+// defaultSensitiveQueryKeys are redacted from logged URLs unless
+// SENSITIVE_QUERY_KEYS is set.
+var defaultSensitiveQueryKeys = []string{"token", "secret", "password"}
+
+func isSensitiveKey(key string, sensitiveKeys []string) bool {
+	for _, sensitive := range sensitiveKeys {
+		if strings.EqualFold(key, sensitive) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redactQueryParameters returns a copy of the query parameters with the
+// values of sensitive keys replaced.
+func redactQueryParameters(params map[string]string, sensitiveKeys []string) map[string]string {
+	redacted := make(map[string]string, len(params))
+	for key, value := range params {
+		if isSensitiveKey(key, sensitiveKeys) {
+			value = redactedValue
+		}
+		redacted[key] = value
+	}
+
+	return redacted
+}
+
+// redactURL returns the URL as a string with the values of sensitive query
+// parameters replaced. The URL itself is left untouched.
+func redactURL(u *url.URL, sensitiveKeys []string) string {
+	query := u.Query()
+	for key := range query {
+		if isSensitiveKey(key, sensitiveKeys) {
+			for i := range query[key] {
+				query[key][i] = redactedValue
+			}
+		}
+	}
+
+	redacted := *u
+	redacted.RawQuery = query.Encode()
+
+	return redacted.String()
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactURL(t *testing.T) {
+	u, err := url.Parse("https://provisioner/api/installations?token=abc&Password=def&page=1")
+	require.NoError(t, err)
+
+	redacted := redactURL(u, defaultSensitiveQueryKeys)
+	assert.Equal(t, "https://provisioner/api/installations?Password=REDACTED&page=1&token=REDACTED", redacted)
+	assert.Equal(t, "token=abc&Password=def&page=1", u.RawQuery)
+}
+
+func TestLoadConfigSensitiveQueryKeys(t *testing.T) {
+	t.Setenv(cloudServerEnv, "https://provisioner.internal")
+	t.Setenv(mattermostWebhookEnv, "https://mattermost/hooks/abc")
+
+	config, err := loadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, defaultSensitiveQueryKeys, config.SensitiveQueryKeys)
+
+	t.Setenv(sensitiveQueryKeysEnv, "api_key, session")
+	config, err = loadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api_key", "session"}, config.SensitiveQueryKeys)
+}
+
+func TestValidateCloudRequestRedactsLoggedURL(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	var forwardedQuery url.Values
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwardedQuery = r.URL.Query()
+		w.Write([]byte(`{}`))
+	}))
+	defer upstream.Close()
+
+	config := &Config{
+		CloudServerURL:       upstream.URL,
+		MattermostWebhookURL: upstream.URL,
+		UpstreamTimeout:      defaultUpstreamTimeout,
+		SensitiveQueryKeys:   []string{"api_key"},
+	}
+	_, err := validateCloudRequest(config, events.APIGatewayProxyRequest{
+		HTTPMethod:            http.MethodGet,
+		Path:                  "/api/installations",
+		QueryStringParameters: map[string]string{"api_key": "super-secret", "page": "1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "super-secret", forwardedQuery.Get("api_key"))
+
+	var loggedURL string
+	for _, entry := range hook.AllEntries() {
+		assert.NotContains(t, entry.Message, "super-secret")
+		if entry.Message == "Final API call" {
+			loggedURL = entry.Data["url"].(string)
+		}
+	}
+	assert.Contains(t, loggedURL, "api_key=REDACTED")
+	assert.NotContains(t, loggedURL, "super-secret")
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// parseRoutes parses the CLOUD_SERVER_ROUTES JSON object mapping a request
+// path prefix to the backend URL that should serve it.
+func parseRoutes(value string) (map[string]string, error) {
+	routes := map[string]string{}
+	if value == "" {
+		return routes, nil
+	}
+
+	if err := json.Unmarshal([]byte(value), &routes); err != nil {
+		return nil, fmt.Errorf("environment variable %s is not a valid JSON object: %w", cloudServerRoutesEnv, err)
+	}
+
+	for prefix, backend := range routes {
+		parsed, err := url.Parse(backend)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return nil, fmt.Errorf("environment variable %s has an invalid backend URL for prefix %s", cloudServerRoutesEnv, prefix)
+		}
+	}
+
+	return routes, nil
+}
+
+// backendURL returns the backend for the longest route prefix matching the
+// request path, falling back to the default cloud server.
+func backendURL(config *Config, path string) string {
+	var matched string
+	backend := config.CloudServerURL
+	for prefix, routeBackend := range config.Routes {
+		if strings.HasPrefix(path, prefix) && len(prefix) > len(matched) {
+			matched, backend = prefix, routeBackend
+		}
+	}
+
+	return backend
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := parseRoutes("")
+	require.NoError(t, err)
+	assert.Empty(t, routes)
+
+	routes, err = parseRoutes(`{"/api/webhooks": "https://webhooks.internal"}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"/api/webhooks": "https://webhooks.internal"}, routes)
+
+	_, err = parseRoutes(`{invalid`)
+	assert.Error(t, err)
+
+	_, err = parseRoutes(`{"/api/webhooks": "not a url"}`)
+	assert.Error(t, err)
+}
+
+func TestBackendURL(t *testing.T) {
+	config := &Config{
+		CloudServerURL: "https://provisioner.internal",
+		Routes: map[string]string{
+			"/api/installation":       "https://installations.internal",
+			"/api/installation/group": "https://groups.internal",
+			"/api/webhooks":           "https://webhooks.internal",
+		},
+	}
+
+	testCases := []struct {
+		path     string
+		expected string
+	}{
+		{"/api/installations", "https://installations.internal"},
+		{"/api/installation/group/abc", "https://groups.internal"},
+		{"/api/webhooks", "https://webhooks.internal"},
+		{"/api/cluster_installations", "https://provisioner.internal"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			assert.Equal(t, tc.expected, backendURL(config, tc.path))
+		})
+	}
+}
+
+func TestValidateCloudRequestRoutes(t *testing.T) {
+	newBackend := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Write([]byte(`"` + name + `"`))
+		}))
+	}
+	defaultBackend := newBackend("default")
+	defer defaultBackend.Close()
+	webhooksBackend := newBackend("webhooks")
+	defer webhooksBackend.Close()
+
+	config := &Config{
+		CloudServerURL:       defaultBackend.URL,
+		MattermostWebhookURL: defaultBackend.URL,
+		UpstreamTimeout:      defaultUpstreamTimeout,
+		Routes:               map[string]string{"/api/webhooks": webhooksBackend.URL},
+	}
+
+	response, err := validateCloudRequest(config, events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/api/webhooks"})
+	require.NoError(t, err)
+	assert.Equal(t, `"webhooks"`, response.Body)
+
+	response, err = validateCloudRequest(config, events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/api/installations"})
+	require.NoError(t, err)
+	assert.Equal(t, `"default"`, response.Body)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/accountalias"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIAM embeds the client interface, so any call it does not implement
+// panics and fails the test.
+type fakeIAM struct {
+	iamiface.IAMAPI
+	aliases []string
+}
+
+func (f *fakeIAM) ListAccountAliases(*iam.ListAccountAliasesInput) (*iam.ListAccountAliasesOutput, error) {
+	return &iam.ListAccountAliasesOutput{AccountAliases: aws.StringSlice(f.aliases)}, nil
+}
+
+func useFakeIAM(t *testing.T, fake *fakeIAM) {
+	t.Helper()
+
+	previous := accountAliases
+	accountAliases = &accountalias.Cache{NewClient: func() (iamiface.IAMAPI, error) { return fake, nil }}
+	t.Cleanup(func() { accountAliases = previous })
+}
+
+func TestHandlerAccountAlias(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_HOOK", mattermost.URL)
+	t.Setenv("ENVIRONMENT", "test")
+	t.Setenv("ALLOWED_DETAIL_TYPES", "")
+	t.Setenv("INCLUDE_ACCOUNT_ALIAS", "true")
+	useFakeIAM(t, &fakeIAM{aliases: []string{"mattermost-prod"}})
+
+	handler(context.Background(), testutil.SNSEvent(`{"detail-type": "EBS Snapshot Notification", "account": "123456789012", "detail": {}}`))
+
+	payloads := testutil.Payloads[MMSlashResponse](t, mattermost)
+	require.Len(t, payloads, 1)
+	fields := payloads[0].Attachments[0].Fields
+	require.Len(t, fields, 6)
+	assert.Equal(t, "Account Alias", fields[3].Title)
+	assert.Equal(t, "mattermost-prod", fields[3].Value)
+
+	details := pagerDutyDetails(SNSMessage{Account: "123456789012"})
+	assert.Equal(t, "mattermost-prod", details["Account Alias"])
+}
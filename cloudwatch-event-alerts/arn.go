@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// consoleDetailPages maps EC2 ARN resource types to the console page and the
+// query parameter that identifies the resource on it.
+var consoleDetailPages = map[string][2]string{
+	"instance": {"InstanceDetails", "instanceId"},
+	"volume":   {"VolumeDetails", "volumeId"},
+	"snapshot": {"SnapshotDetails", "snapshotId"},
+}
+
+// consoleLinksEnabled reports whether RESOURCE_CONSOLE_LINKS is set to true.
+func consoleLinksEnabled() bool {
+	return strings.EqualFold(os.Getenv("RESOURCE_CONSOLE_LINKS"), "true")
+}
+
+// consoleURL returns the AWS console deep link for a recognized EC2 ARN of the
+// form arn:partition:ec2:region:account:type/id.
+func consoleURL(arn string) (string, bool) {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "ec2" || parts[3] == "" {
+		return "", false
+	}
+
+	resourceType, id, found := strings.Cut(parts[5], "/")
+	if !found || id == "" {
+		return "", false
+	}
+	page, ok := consoleDetailPages[resourceType]
+	if !ok {
+		return "", false
+	}
+
+	region := parts[3]
+	return fmt.Sprintf("https://%s.console.aws.amazon.com/ec2/home?region=%s#%s:%s=%s", region, region, page[0], page[1], id), true
+}
+
+// formatResources renders the event resources for the Mattermost attachment,
+// as console links when enabled and the raw ARN otherwise.
+func formatResources(resources []string) string {
+	if !consoleLinksEnabled() {
+		return strings.Join(resources, ",")
+	}
+
+	formatted := make([]string, 0, len(resources))
+	for _, arn := range resources {
+		if url, ok := consoleURL(arn); ok {
+			formatted = append(formatted, fmt.Sprintf("[%s](%s)", arn, url))
+			continue
+		}
+		formatted = append(formatted, arn)
+	}
+
+	return strings.Join(formatted, ",")
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsoleURL(t *testing.T) {
+	testCases := []struct {
+		name     string
+		arn      string
+		expected string
+	}{
+		{
+			"instance",
+			"arn:aws:ec2:us-east-1:123456789012:instance/i-0abc",
+			"https://us-east-1.console.aws.amazon.com/ec2/home?region=us-east-1#InstanceDetails:instanceId=i-0abc",
+		},
+		{
+			"volume",
+			"arn:aws:ec2:eu-west-1:123456789012:volume/vol-0abc",
+			"https://eu-west-1.console.aws.amazon.com/ec2/home?region=eu-west-1#VolumeDetails:volumeId=vol-0abc",
+		},
+		{
+			"snapshot",
+			"arn:aws:ec2:us-east-1::snapshot/snap-0abc",
+			"https://us-east-1.console.aws.amazon.com/ec2/home?region=us-east-1#SnapshotDetails:snapshotId=snap-0abc",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			url, ok := consoleURL(tc.arn)
+			assert.True(t, ok)
+			assert.Equal(t, tc.expected, url)
+		})
+	}
+
+	for _, arn := range []string{
+		"arn:aws:rds:us-east-1:123456789012:db:mydb",
+		"arn:aws:ec2:us-east-1:123456789012:image/ami-0abc",
+		"arn:aws:ec2::123456789012:instance/i-0abc",
+		"not-an-arn",
+	} {
+		_, ok := consoleURL(arn)
+		assert.False(t, ok, arn)
+	}
+}
+
+func TestFormatResources(t *testing.T) {
+	resources := []string{
+		"arn:aws:ec2:us-east-1:123456789012:volume/vol-0abc",
+		"arn:aws:rds:us-east-1:123456789012:db:mydb",
+	}
+
+	t.Setenv("RESOURCE_CONSOLE_LINKS", "")
+	assert.Equal(t, "arn:aws:ec2:us-east-1:123456789012:volume/vol-0abc,arn:aws:rds:us-east-1:123456789012:db:mydb", formatResources(resources))
+
+	t.Setenv("RESOURCE_CONSOLE_LINKS", "true")
+	assert.Equal(t,
+		"[arn:aws:ec2:us-east-1:123456789012:volume/vol-0abc](https://us-east-1.console.aws.amazon.com/ec2/home?region=us-east-1#VolumeDetails:volumeId=vol-0abc),"+
+			"arn:aws:rds:us-east-1:123456789012:db:mydb",
+		formatResources(resources))
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// detailTypeAllowed reports whether events of the given detail-type are
+// processed. ALLOWED_DETAIL_TYPES is a comma-separated list of detail-types;
+// when unset every event is processed.
+func detailTypeAllowed(detailType string) bool {
+	allowed := os.Getenv("ALLOWED_DETAIL_TYPES")
+	if strings.TrimSpace(allowed) == "" {
+		return true
+	}
+
+	for _, item := range strings.Split(allowed, ",") {
+		if strings.EqualFold(strings.TrimSpace(item), detailType) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetailTypeAllowed(t *testing.T) {
+	t.Setenv("ALLOWED_DETAIL_TYPES", "")
+	assert.True(t, detailTypeAllowed("EBS Snapshot Notification"))
+
+	t.Setenv("ALLOWED_DETAIL_TYPES", "EBS Snapshot Notification, EC2 Instance State-change Notification")
+	assert.True(t, detailTypeAllowed("EBS Snapshot Notification"))
+	assert.True(t, detailTypeAllowed("ec2 instance state-change notification"))
+	assert.False(t, detailTypeAllowed("AWS Health Event"))
+	assert.False(t, detailTypeAllowed(""))
+}
+
+func TestHandlerAllowedDetailTypes(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_HOOK", mattermost.URL)
+	t.Setenv("ENVIRONMENT", "test")
+	t.Setenv("ENRICH_SNAPSHOTS", "")
+	t.Setenv("ALLOWED_DETAIL_TYPES", "EBS Snapshot Notification")
+
+	var messages []string
+	for _, detailType := range []string{"AWS Health Event", "EBS Snapshot Notification"} {
+		message, err := json.Marshal(SNSMessage{Type: detailType, Detail: DetailStr{Event: "createSnapshot"}})
+		require.NoError(t, err)
+		messages = append(messages, string(message))
+	}
+
+	handler(context.Background(), testutil.SNSEvent(messages...))
+
+	payloads := testutil.Payloads[MMSlashResponse](t, mattermost)
+	require.Len(t, payloads, 1)
+	assert.Equal(t, "EBS Snapshot Notification", payloads[0].Attachments[0].Fields[1].Value)
+}
@@ -0,0 +1,29 @@
+package main
+
+// UserIdentity is the CloudTrail identity that triggered the event, present
+// in the detail of API call events.
+type UserIdentity struct {
+	Type      string `json:"type,omitempty"`
+	Arn       string `json:"arn,omitempty"`
+	AccountID string `json:"accountId,omitempty"`
+	InvokedBy string `json:"invokedBy,omitempty"`
+}
+
+// identityFields returns the attachment fields describing who triggered the
+// event, or nothing if the detail carries no identity.
+func identityFields(snsMessage SNSMessage) []MMField {
+	identity := snsMessage.Detail.UserIdentity
+	if identity == nil || (identity.Arn == "" && identity.AccountID == "") {
+		return nil
+	}
+
+	fields := []MMField{
+		{Title: "Identity ARN", Value: identity.Arn, Short: true},
+		{Title: "Identity Account", Value: identity.AccountID, Short: true},
+	}
+	if identity.InvokedBy != "" {
+		fields = append(fields, MMField{Title: "Invoked By", Value: identity.InvokedBy, Short: true})
+	}
+
+	return fields
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const identityEvent = `{
+	"detail-type": "AWS API Call via CloudTrail",
+	"account": "123456789012",
+	"detail": {
+		"event": "DeleteSnapshot",
+		"userIdentity": {
+			"type": "IAMUser",
+			"arn": "arn:aws:iam::123456789012:user/jane",
+			"accountId": "123456789012",
+			"invokedBy": "cloudformation.amazonaws.com"
+		}
+	}
+}`
+
+func TestIdentityFields(t *testing.T) {
+	var withIdentity SNSMessage
+	require.NoError(t, json.Unmarshal([]byte(identityEvent), &withIdentity))
+	assert.Equal(t, []MMField{
+		{Title: "Identity ARN", Value: "arn:aws:iam::123456789012:user/jane", Short: true},
+		{Title: "Identity Account", Value: "123456789012", Short: true},
+		{Title: "Invoked By", Value: "cloudformation.amazonaws.com", Short: true},
+	}, identityFields(withIdentity))
+
+	details := pagerDutyDetails(withIdentity)
+	assert.Equal(t, "arn:aws:iam::123456789012:user/jane", details["Identity ARN"])
+	assert.Equal(t, "123456789012", details["Identity Account"])
+
+	var withoutIdentity SNSMessage
+	require.NoError(t, json.Unmarshal([]byte(`{"detail-type": "EBS Snapshot Notification", "detail": {"event": "createSnapshot"}}`), &withoutIdentity))
+	assert.Nil(t, identityFields(withoutIdentity))
+
+	details = pagerDutyDetails(withoutIdentity)
+	assert.Len(t, details, 1)
+	assert.NotContains(t, details["Message"], "userIdentity")
+}
+
+func TestHandlerIdentity(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_HOOK", mattermost.URL)
+	t.Setenv("ENVIRONMENT", "test")
+	t.Setenv("ENRICH_SNAPSHOTS", "")
+	t.Setenv("ALLOWED_DETAIL_TYPES", "")
+
+	handler(context.Background(), testutil.SNSEvent(identityEvent, `{"detail-type": "EBS Snapshot Notification", "detail": {}}`))
+
+	payloads := testutil.Payloads[MMSlashResponse](t, mattermost)
+	require.Len(t, payloads, 2)
+	fields := payloads[0].Attachments[0].Fields
+	require.Len(t, fields, 8)
+	assert.Equal(t, "Identity ARN", fields[5].Title)
+	assert.Equal(t, "arn:aws:iam::123456789012:user/jane", fields[5].Value)
+	assert.Len(t, payloads[1].Attachments[0].Fields, 5)
+}
@@ -8,12 +8,17 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/colors"
+	cloudpagerduty "github.com/mattermost/mattermost-cloud-lambdas/internal/pagerduty"
 	log "github.com/sirupsen/logrus"
 
 	pagerduty "github.com/PagerDuty/go-pagerduty"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/metrics"
+	"github.com/pkg/errors"
 )
 
 // SNSMessage represents the structure of a message received from AWS SNS.
@@ -30,32 +35,62 @@ type DetailStr struct {
 	Result     string `json:"result"`
 	Cause      string `json:"cause"`
 	SnapshotID string `json:"snapshot_id"`
+	// UserIdentity is only set for CloudTrail API call events.
+	UserIdentity *UserIdentity `json:"userIdentity,omitempty"`
 }
 
 func main() {
+	if replayEnabled() {
+		lambda.Start(replayHandler)
+		return
+	}
 	lambda.Start(handler)
 }
 
 func handler(_ context.Context, snsEvent events.SNSEvent) {
 	log.Info(snsEvent)
 	for _, record := range snsEvent.Records {
-		snsRecord := record.SNS
-		var snsMessage SNSMessage
-		if err := json.Unmarshal([]byte(snsRecord.Message), &snsMessage); err != nil {
+		if err := processMessage(record.EventSource, record.SNS.Message); err != nil {
+			var delivery *deliveryError
+			if errors.As(err, &delivery) {
+				log.WithError(err).Error("Failed to deliver message notification")
+				continue
+			}
 			log.WithError(err).Error("Decode Error on message notification")
 			return
 		}
+	}
+}
 
-		sendMattermostNotification(record.EventSource, "#FF0000", snsMessage)
+// processMessage notifies Mattermost and PagerDuty about a CloudWatch event
+// SNS message. PagerDuty is notified even when Mattermost does not accept the
+// notification, which is then reported as a deliveryError.
+func processMessage(source, message string) error {
+	var snsMessage SNSMessage
+	if err := json.Unmarshal([]byte(message), &snsMessage); err != nil {
+		return errors.Wrap(err, "failed to decode event notification")
+	}
 
-		// Trigger PagerDuty
-		if os.Getenv("ENVIRONMENT") != "" && os.Getenv("ENVIRONMENT") != "test" {
-			sendPagerDutyNotification(snsMessage)
-		}
+	if !detailTypeAllowed(snsMessage.Type) {
+		log.WithField("detail-type", snsMessage.Type).Info("Skipping event with a detail-type not in ALLOWED_DETAIL_TYPES")
+		return nil
+	}
+
+	err := sendMattermostNotification(source, colors.Failure(), snsMessage, append(identityFields(snsMessage), enrichmentFields(snsMessage)...))
+
+	// Trigger PagerDuty
+	if os.Getenv("ENVIRONMENT") != "" && os.Getenv("ENVIRONMENT") != "test" {
+		sendPagerDutyNotification(snsMessage)
 	}
+
+	if err != nil {
+		return &deliveryError{err}
+	}
+
+	return nil
 }
 
-func sendMattermostNotification(source, color string, snsMessage SNSMessage) {
+func sendMattermostNotification(source, color string, snsMessage SNSMessage, extraFields []MMField) error {
 	detail, _ := json.Marshal(snsMessage.Detail)
 
 	attachment := []MMAttachment{}
@@ -65,8 +100,14 @@ func sendMattermostNotification(source, color string, snsMessage SNSMessage) {
 	attach = *attach.AddField(MMField{Title: "Cloudwatch Event Alert", Short: false})
 	attach = *attach.AddField(MMField{Title: "Type", Value: snsMessage.Type, Short: true})
 	attach = *attach.AddField(MMField{Title: "Account", Value: snsMessage.Account, Short: true})
-	attach = *attach.AddField(MMField{Title: "Resources", Value: strings.Join(snsMessage.Resources, ","), Short: true})
+	if alias := accountAliases.Alias(); alias != "" {
+		attach = *attach.AddField(MMField{Title: "Account Alias", Value: alias, Short: true})
+	}
+	attach = *attach.AddField(MMField{Title: "Resources", Value: formatResources(snsMessage.Resources), Short: true})
 	attach = *attach.AddField(MMField{Title: "Detail", Value: string(detail), Short: true})
+	for _, field := range extraFields {
+		attach = *attach.AddField(field)
+	}
 
 	attachment = append(attachment, attach)
 
@@ -75,9 +116,34 @@ func sendMattermostNotification(source, color string, snsMessage SNSMessage) {
 		IconURL:     "https://cdn2.iconfinder.com/data/icons/amazon-aws-stencils/100/Non-Service_Specific_copy__AWS_Cloud-128.png",
 		Attachments: attachment,
 	}
-	if os.Getenv("MATTERMOST_HOOK") != "" {
-		send(os.Getenv("MATTERMOST_HOOK"), payload)
+	if os.Getenv("MATTERMOST_HOOK") == "" {
+		return nil
 	}
+
+	return errors.Wrap(send(os.Getenv("MATTERMOST_HOOK"), payload), "failed to send the Mattermost notification")
+}
+
+// pagerDutyDetails returns the custom details of the PagerDuty event, with the
+// account alias when enabled and the identity that triggered the event when
+// the detail carries one.
+func pagerDutyDetails(snsMessage SNSMessage) map[string]interface{} {
+	detail, _ := json.Marshal(snsMessage.Detail)
+
+	details := map[string]interface{}{
+		"Message": fmt.Sprintf("AWS Account: %s\nResources: %s\nDetail:\n%s",
+			snsMessage.Account,
+			strings.Join(snsMessage.Resources, ","),
+			string(detail),
+		),
+	}
+	if alias := accountAliases.Alias(); alias != "" {
+		details["Account Alias"] = alias
+	}
+	for _, field := range identityFields(snsMessage) {
+		details[field.Title] = field.Value
+	}
+
+	return details
 }
 
 func sendPagerDutyNotification(snsMessage SNSMessage) {
@@ -87,30 +153,22 @@ func sendPagerDutyNotification(snsMessage SNSMessage) {
 		return
 	}
 
-	detail, _ := json.Marshal(snsMessage.Detail)
-
-	detailString := fmt.Sprintf("AWS Account: %s\nResources: %s\nDetail:\n%s",
-		snsMessage.Account,
-		strings.Join(snsMessage.Resources, ","),
-		string(detail),
-	)
-
 	event := pagerduty.V2Event{
 		RoutingKey: integrationKey,
 		Action:     "trigger",
 		Payload: &pagerduty.V2Payload{
 			Summary:  "New Cloudwatch Event alert was generated",
-			Source:   "Alarm System",
 			Severity: "critical",
-			Details: map[string]interface{}{
-				"Message": detailString,
-			},
+			Details:  pagerDutyDetails(snsMessage),
 		},
 	}
+	cloudpagerduty.SetOrigin(event.Payload, defaultPagerDutySource)
 
 	// Send the event to PagerDuty with context
 	ctx := context.Background()
+	start := time.Now()
 	_, err := pagerduty.ManageEventWithContext(ctx, event)
+	metrics.RecordNotificationLatency(metrics.TargetPagerDuty, start)
 	if err != nil {
 		log.WithError(err).Error("Failed to send PagerDuty notification")
 		return
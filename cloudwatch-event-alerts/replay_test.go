@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplayHandler(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_HOOK", mattermost.URL)
+	t.Setenv("ENRICH_SNAPSHOTS", "")
+
+	message, err := json.Marshal(SNSMessage{
+		Type:      "EBS Snapshot Notification",
+		Account:   "123456789012",
+		Resources: []string{"arn:aws:ec2:us-east-1::snapshot/snap-1"},
+		Detail:    DetailStr{Event: "createSnapshot", Result: "failed"},
+	})
+	require.NoError(t, err)
+
+	response, err := replayHandler(context.Background(), testutil.APIGatewayRequest(http.MethodPost, "/replay", string(message), nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.JSONEq(t, `{"status": "processed"}`, response.Body)
+
+	payloads := testutil.Payloads[MMSlashResponse](t, mattermost)
+	require.Len(t, payloads, 1)
+	assert.Equal(t, replaySource, payloads[0].Username)
+	assert.Equal(t, "EBS Snapshot Notification", payloads[0].Attachments[0].Fields[1].Value)
+}
+
+func TestReplayHandlerInvalidMessage(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_HOOK", mattermost.URL)
+
+	response, err := replayHandler(context.Background(), testutil.APIGatewayRequest(http.MethodPost, "/replay", "not json", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	assert.Empty(t, mattermost.Bodies())
+}
+
+func TestReplayHandlerMattermostFailure(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	mattermost.SetStatusCode(http.StatusInternalServerError)
+	t.Setenv("MATTERMOST_HOOK", mattermost.URL)
+	t.Setenv("ENRICH_SNAPSHOTS", "")
+
+	message, err := json.Marshal(SNSMessage{Type: "EBS Snapshot Notification", Account: "123456789012"})
+	require.NoError(t, err)
+
+	response, err := replayHandler(context.Background(), testutil.APIGatewayRequest(http.MethodPost, "/replay", string(message), nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, response.StatusCode)
+	assert.Contains(t, response.Body, "500")
+	assert.Len(t, mattermost.Bodies(), 1)
+}
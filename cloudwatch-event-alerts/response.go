@@ -3,8 +3,12 @@ package main
 import (
 	"bytes"
 	"encoding/json"
-	"github.com/pkg/errors"
 	"net/http"
+	"time"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/metrics"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/webhook"
+	"github.com/pkg/errors"
 )
 
 // MMField represents a single field in a Mattermost message attachment.
@@ -42,6 +46,7 @@ type MMSlashResponse struct {
 
 // AddField adds a field to the MMAttachment.
 func (attachment *MMAttachment) AddField(field MMField) *MMAttachment {
+	field.Value = webhook.Truncate(field.Value, webhook.MaxFieldLength())
 	attachment.Fields = append(attachment.Fields, &field)
 	return attachment
 }
@@ -52,21 +57,38 @@ func (o *MMSlashResponse) ToJSON() string {
 	return string(b)
 }
 
-func send(webhookURL string, payload MMSlashResponse) {
-	marshalContent, _ := json.Marshal(payload)
-	jsonStr := marshalContent
+// deliveryError is returned when Mattermost did not accept a notification, as
+// opposed to a message that could not be processed at all.
+type deliveryError struct {
+	error
+}
+
+func send(webhookURL string, payload MMSlashResponse) error {
+	payload = truncatedPayload(payload, webhook.MaxPayloadLength())
+	marshalContent, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal payload")
+	}
 
-	req, err := http.NewRequest("POST", webhookURL, bytes.NewBuffer(jsonStr))
+	req, err := http.NewRequest("POST", webhookURL, bytes.NewBuffer(marshalContent))
 	if err != nil {
-		panic(errors.Wrap(err, "failed to create HTTP request"))
+		return errors.Wrap(err, "failed to create HTTP request")
 	}
 	req.Header.Set("X-Custom-Header", "aws-sns")
 	req.Header.Set("Content-Type", "application/json")
 
 	client := &http.Client{}
+	start := time.Now()
 	resp, err := client.Do(req)
+	metrics.RecordNotificationLatency(metrics.TargetMattermost, start)
 	if err != nil {
-		panic(errors.Wrap(err, "failed to send HTTP request"))
+		return errors.Wrap(err, "failed to send HTTP request")
 	}
 	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return errors.Errorf("unexpected response status: %s", resp.Status)
+	}
+
+	return nil
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	log "github.com/sirupsen/logrus"
+)
+
+const snapshotNotFoundCode = "InvalidSnapshot.NotFound"
+
+// snapshotDescriber is the subset of the EC2 API used to enrich snapshot
+// alerts, so it can be replaced in tests.
+type snapshotDescriber interface {
+	DescribeSnapshots(input *ec2.DescribeSnapshotsInput) (*ec2.DescribeSnapshotsOutput, error)
+}
+
+// newSnapshotDescriber creates the EC2 client used to enrich snapshot alerts.
+var newSnapshotDescriber = func() (snapshotDescriber, error) {
+	sess, err := session.NewSession(&aws.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	return ec2.New(sess), nil
+}
+
+// enrichSnapshotsEnabled reports whether ENRICH_SNAPSHOTS is set to true.
+func enrichSnapshotsEnabled() bool {
+	return strings.EqualFold(os.Getenv("ENRICH_SNAPSHOTS"), "true")
+}
+
+// snapshotFields returns the attachment fields describing the snapshot of the
+// event: its volume, size and description. A snapshot that no longer exists
+// is reported as such, and other lookup failures only skip the enrichment.
+func snapshotFields(svc snapshotDescriber, snapshotID string) []MMField {
+	output, err := svc.DescribeSnapshots(&ec2.DescribeSnapshotsInput{
+		SnapshotIds: []*string{aws.String(snapshotID)},
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == snapshotNotFoundCode {
+			return []MMField{{Title: "Snapshot", Value: fmt.Sprintf("%s not found", snapshotID), Short: true}}
+		}
+		log.WithError(err).WithField("snapshot", snapshotID).Warn("Failed to describe snapshot")
+		return nil
+	}
+	if len(output.Snapshots) == 0 {
+		return []MMField{{Title: "Snapshot", Value: fmt.Sprintf("%s not found", snapshotID), Short: true}}
+	}
+
+	snapshot := output.Snapshots[0]
+	return []MMField{
+		{Title: "Snapshot Volume", Value: aws.StringValue(snapshot.VolumeId), Short: true},
+		{Title: "Snapshot Size", Value: fmt.Sprintf("%d GiB", aws.Int64Value(snapshot.VolumeSize)), Short: true},
+		{Title: "Snapshot Description", Value: aws.StringValue(snapshot.Description), Short: false},
+	}
+}
+
+// enrichmentFields returns the extra attachment fields for the message, if
+// enrichment is enabled and applies to it.
+func enrichmentFields(snsMessage SNSMessage) []MMField {
+	if !enrichSnapshotsEnabled() || snsMessage.Detail.SnapshotID == "" {
+		return nil
+	}
+
+	svc, err := newSnapshotDescriber()
+	if err != nil {
+		log.WithError(err).Warn("Failed to create EC2 client for snapshot enrichment")
+		return nil
+	}
+
+	return snapshotFields(svc, snsMessage.Detail.SnapshotID)
+}
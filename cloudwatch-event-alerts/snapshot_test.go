@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeEC2 struct {
+	snapshots []*ec2.Snapshot
+	err       error
+	calls     int
+}
+
+func (f *fakeEC2) DescribeSnapshots(input *ec2.DescribeSnapshotsInput) (*ec2.DescribeSnapshotsOutput, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return &ec2.DescribeSnapshotsOutput{Snapshots: f.snapshots}, nil
+}
+
+func useFakeEC2(t *testing.T, svc *fakeEC2) {
+	t.Helper()
+
+	previous := newSnapshotDescriber
+	newSnapshotDescriber = func() (snapshotDescriber, error) { return svc, nil }
+	t.Cleanup(func() { newSnapshotDescriber = previous })
+}
+
+func TestSnapshotFields(t *testing.T) {
+	t.Run("found", func(t *testing.T) {
+		svc := &fakeEC2{snapshots: []*ec2.Snapshot{{
+			SnapshotId:  aws.String("snap-1"),
+			VolumeId:    aws.String("vol-1"),
+			VolumeSize:  aws.Int64(100),
+			Description: aws.String("Created by CreateImage for ami-1"),
+		}}}
+
+		fields := snapshotFields(svc, "snap-1")
+		require.Len(t, fields, 3)
+		assert.Equal(t, MMField{Title: "Snapshot Volume", Value: "vol-1", Short: true}, fields[0])
+		assert.Equal(t, MMField{Title: "Snapshot Size", Value: "100 GiB", Short: true}, fields[1])
+		assert.Equal(t, "Created by CreateImage for ami-1", fields[2].Value)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		svc := &fakeEC2{err: awserr.New(snapshotNotFoundCode, "The snapshot 'snap-1' does not exist.", nil)}
+
+		fields := snapshotFields(svc, "snap-1")
+		require.Len(t, fields, 1)
+		assert.Equal(t, "snap-1 not found", fields[0].Value)
+	})
+
+	t.Run("other error", func(t *testing.T) {
+		svc := &fakeEC2{err: errors.New("throttled")}
+
+		assert.Empty(t, snapshotFields(svc, "snap-1"))
+	})
+}
+
+func TestEnrichmentFields(t *testing.T) {
+	svc := &fakeEC2{snapshots: []*ec2.Snapshot{{VolumeId: aws.String("vol-1"), VolumeSize: aws.Int64(8)}}}
+	useFakeEC2(t, svc)
+
+	message := SNSMessage{Detail: DetailStr{SnapshotID: "snap-1"}}
+
+	t.Setenv("ENRICH_SNAPSHOTS", "")
+	assert.Empty(t, enrichmentFields(message))
+
+	t.Setenv("ENRICH_SNAPSHOTS", "true")
+	assert.Empty(t, enrichmentFields(SNSMessage{}))
+	assert.Len(t, enrichmentFields(message), 3)
+	assert.Equal(t, 1, svc.calls)
+}
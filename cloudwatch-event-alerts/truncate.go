@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/webhook"
+)
+
+// truncatedPayload returns a copy of payload whose largest field values are
+// shortened until it fits within limit bytes. The fields of the caller's
+// payload are left untouched.
+func truncatedPayload(payload MMSlashResponse, limit int) MMSlashResponse {
+	var values []*string
+	attachments := make([]MMAttachment, len(payload.Attachments))
+	for i, attachment := range payload.Attachments {
+		fields := make([]*MMField, len(attachment.Fields))
+		for j, field := range attachment.Fields {
+			copied := *field
+			fields[j] = &copied
+			values = append(values, &copied.Value)
+		}
+		attachment.Fields = fields
+		attachments[i] = attachment
+	}
+	payload.Attachments = attachments
+
+	webhook.TruncatePayload(&payload, values, limit)
+
+	return payload
+}
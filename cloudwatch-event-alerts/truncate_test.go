@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddFieldTruncatesValue(t *testing.T) {
+	t.Setenv("MAX_FIELD_LENGTH", "100")
+
+	attach := MMAttachment{}
+	attach.AddField(MMField{Title: "Extra Data", Value: strings.Repeat("x", 500)})
+
+	require.Len(t, attach.Fields, 1)
+	assert.Len(t, attach.Fields[0].Value, 100)
+	assert.True(t, strings.HasSuffix(attach.Fields[0].Value, webhook.TruncatedMarker))
+}
+
+func TestTruncatedPayload(t *testing.T) {
+	attach := MMAttachment{}
+	attach.AddField(MMField{Title: "ID", Value: "id"})
+	attach.AddField(MMField{Title: "Detail", Value: strings.Repeat("y", 3000)})
+	attach.AddField(MMField{Title: "Extra Data", Value: strings.Repeat("z", 3000)})
+	payload := MMSlashResponse{Attachments: []MMAttachment{attach}}
+
+	truncated := truncatedPayload(payload, 2000)
+
+	content, err := json.Marshal(truncated)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(content), 2000)
+	assert.Equal(t, "id", truncated.Attachments[0].Fields[0].Value)
+	assert.True(t, strings.HasSuffix(truncated.Attachments[0].Fields[1].Value, webhook.TruncatedMarker))
+	assert.True(t, strings.HasSuffix(truncated.Attachments[0].Fields[2].Value, webhook.TruncatedMarker))
+
+	assert.Len(t, payload.Attachments[0].Fields[1].Value, 3000, "the caller's fields are not modified")
+	assert.Len(t, payload.Attachments[0].Fields[2].Value, 3000)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elb/elbiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/awsconfig"
+	log "github.com/sirupsen/logrus"
+)
+
+// backfillDryRun reports whether BACKFILL_DRY_RUN is set to true, in which
+// case the backfill scan only logs the alarms it would create.
+func backfillDryRun() bool {
+	return strings.EqualFold(os.Getenv("BACKFILL_DRY_RUN"), "true")
+}
+
+// backfillSummary is the outcome of a backfill scan.
+type backfillSummary struct {
+	DryRun bool `json:"dryRun"`
+	// LoadBalancers counts the scanned load balancers per type.
+	LoadBalancers map[string]int `json:"loadBalancers"`
+	// Alarms are the names of the alarms to create, only listed in dry run.
+	Alarms []string `json:"alarms,omitempty"`
+	// Created and Failed count the alarms created, and the load balancers
+	// whose alarms could not all be created.
+	Created int `json:"created"`
+	Failed  int `json:"failed"`
+}
+
+// runBackfill creates the AWS clients, runs the backfill scan and logs its
+// summary.
+func runBackfill() error {
+	sess, err := awsconfig.NewSession()
+	if err != nil {
+		log.WithError(err).Errorln("Error creating aws session")
+		return err
+	}
+
+	summary, err := backfillAlarms(cloudwatch.New(sess), elbv2.New(sess), elb.New(sess), backfillDryRun())
+	if err != nil {
+		return err
+	}
+
+	summaryJSON, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	logger := log.WithField("summary", string(summaryJSON))
+	if summary.DryRun {
+		logger.Infof("Backfill dry run would create %d alarm(s)", len(summary.Alarms))
+	} else {
+		logger.Infof("Backfill created %d alarm(s), failed %d load balancer(s)", summary.Created, summary.Failed)
+	}
+
+	return nil
+}
+
+// backfillAlarms goes over all load balancers and creates their CloudWatch
+// Alarms, which PutMetricAlarm leaves unchanged when they already exist.
+// With dryRun the alarms are only listed in the summary.
+func backfillAlarms(svcCloudWatch cloudwatchiface.CloudWatchAPI, svcELBV2 elbv2iface.ELBV2API, svcELB elbiface.ELBAPI, dryRun bool) (*backfillSummary, error) {
+	v2LBS, classicLBs, err := describeAllLBs(svcELBV2, svcELB)
+	if err != nil {
+		log.WithError(err).Errorln("Failed to get the v2 LBs")
+		return nil, err
+	}
+
+	summary := &backfillSummary{DryRun: dryRun, LoadBalancers: map[string]int{}}
+	backfill := func(logger *log.Entry, elbName string, targetGroupNames []string, lbType string, zones []string) {
+		summary.LoadBalancers[lbType]++
+
+		inputs, err := newMetricAlarmInputs(elbName, targetGroupNames, lbType, "")
+		if err != nil {
+			logger.WithError(err).Error("Error building the cloudwatch alarm")
+			summary.Failed++
+			return
+		}
+		inputs = withZoneAlarms(inputs, zones)
+
+		if dryRun {
+			for _, input := range inputs {
+				summary.Alarms = append(summary.Alarms, aws.StringValue(input.AlarmName))
+			}
+			return
+		}
+
+		logger.Info("Creating CloudWatch Alarm")
+		created, err := putMetricAlarms(svcCloudWatch, elbName, inputs)
+		summary.Created += created
+		if err != nil {
+			logger.WithError(err).Error("Error creating the CloudWatch Alarm")
+			summary.Failed++
+		}
+	}
+
+	for _, loadBalancer := range v2LBS {
+		elbArnName := aws.StringValue(loadBalancer.LoadBalancerArn)
+		elbName := elbArnName[strings.IndexByte(elbArnName, '/')+1:]
+		logger := log.WithFields(log.Fields{"elbName": elbName, "dnsName": aws.StringValue(loadBalancer.DNSName)})
+
+		targetGroupNames, err := describeTargetGroupNames(svcELBV2, elbArnName)
+		if err != nil {
+			logger.WithError(err).Error("Error getting the target groups")
+			summary.LoadBalancers[aws.StringValue(loadBalancer.Type)]++
+			summary.Failed++
+			continue
+		}
+
+		backfill(logger, elbName, targetGroupNames, aws.StringValue(loadBalancer.Type), alarmZones(loadBalancer))
+	}
+
+	for _, loadBalancer := range classicLBs {
+		elbName := aws.StringValue(loadBalancer.LoadBalancerName)
+		logger := log.WithFields(log.Fields{"elbName": elbName, "dnsName": aws.StringValue(loadBalancer.DNSName)})
+		backfill(logger, elbName, nil, "classic", nil)
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (f *fakeELBV2) DescribeTargetGroups(input *elbv2.DescribeTargetGroupsInput) (*elbv2.DescribeTargetGroupsOutput, error) {
+	f.calls = append(f.calls, "DescribeTargetGroups")
+	output := &elbv2.DescribeTargetGroupsOutput{}
+	for _, arn := range f.targetGroups[aws.StringValue(input.LoadBalancerArn)] {
+		output.TargetGroups = append(output.TargetGroups, &elbv2.TargetGroup{TargetGroupArn: aws.String(arn)})
+	}
+	return output, nil
+}
+
+func backfillFakes() (*fakeCloudWatch, *fakeELBV2, *fakeELB) {
+	svcCloudWatch := &fakeCloudWatch{}
+	svcELBV2 := &fakeELBV2{
+		loadBalancers: []*elbv2.LoadBalancer{
+			{LoadBalancerArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:123:loadbalancer/app/web/1"), Type: aws.String("application")},
+			{LoadBalancerArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:123:loadbalancer/net/db/2"), Type: aws.String("network")},
+			{LoadBalancerArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:123:loadbalancer/app/empty/3"), Type: aws.String("application")},
+		},
+		targetGroups: map[string][]string{
+			"arn:aws:elasticloadbalancing:us-east-1:123:loadbalancer/app/web/1": {
+				"arn:aws:elasticloadbalancing:us-east-1:123:targetgroup/web/a",
+				"arn:aws:elasticloadbalancing:us-east-1:123:targetgroup/api/b",
+			},
+			"arn:aws:elasticloadbalancing:us-east-1:123:loadbalancer/net/db/2": {
+				"arn:aws:elasticloadbalancing:us-east-1:123:targetgroup/db/c",
+			},
+		},
+	}
+	svcELB := &fakeELB{loadBalancers: []*elb.LoadBalancerDescription{
+		{LoadBalancerName: aws.String("classic-1")},
+		{LoadBalancerName: aws.String("classic-2")},
+	}}
+
+	return svcCloudWatch, svcELBV2, svcELB
+}
+
+func TestBackfillAlarmsDryRun(t *testing.T) {
+	svcCloudWatch, svcELBV2, svcELB := backfillFakes()
+
+	summary, err := backfillAlarms(svcCloudWatch, svcELBV2, svcELB, true)
+	require.NoError(t, err)
+
+	assert.True(t, summary.DryRun)
+	assert.Equal(t, map[string]int{"application": 2, "network": 1, "classic": 2}, summary.LoadBalancers)
+	assert.Equal(t, []string{
+		"Alarm-app/web/1",
+		"Alarm-app/web/1-api",
+		"Alarm-net/db/2",
+		"Alarm-classic-1",
+		"Alarm-classic-2",
+	}, summary.Alarms)
+	assert.Equal(t, 0, summary.Created)
+	assert.Equal(t, 1, summary.Failed, "the load balancer without target groups")
+	assert.NotContains(t, svcCloudWatch.calls, "PutMetricAlarm")
+}
+
+func TestBackfillAlarms(t *testing.T) {
+	svcCloudWatch, svcELBV2, svcELB := backfillFakes()
+	svcCloudWatch.putErrs = map[string]error{"Alarm-classic-2": errors.New("LimitExceeded")}
+
+	summary, err := backfillAlarms(svcCloudWatch, svcELBV2, svcELB, false)
+	require.NoError(t, err)
+
+	assert.False(t, summary.DryRun)
+	assert.Empty(t, summary.Alarms)
+	assert.Equal(t, 4, summary.Created)
+	assert.Equal(t, 2, summary.Failed)
+	assert.Equal(t, []string{
+		"Alarm-app/web/1",
+		"Alarm-app/web/1-api",
+		"Alarm-net/db/2",
+		"Alarm-classic-1",
+	}, svcCloudWatch.created)
+}
+
+func TestBackfillDryRun(t *testing.T) {
+	t.Setenv("BACKFILL_DRY_RUN", "")
+	assert.False(t, backfillDryRun())
+
+	t.Setenv("BACKFILL_DRY_RUN", "true")
+	assert.True(t, backfillDryRun())
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// decodeDetails decodes the detail of a CloudTrail event, which is usually a
+// single object but may be an array when several API calls are batched. Each
+// detail is validated so a half-populated one is rejected rather than
+// processed.
+func decodeDetails(raw json.RawMessage) ([]Detail, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, errors.New("event detail is empty")
+	}
+
+	var details []Detail
+	if raw[0] == '[' {
+		if err := json.Unmarshal(raw, &details); err != nil {
+			return nil, err
+		}
+		if len(details) == 0 {
+			return nil, errors.New("event detail is empty")
+		}
+	} else {
+		var detail Detail
+		if err := json.Unmarshal(raw, &detail); err != nil {
+			return nil, err
+		}
+		details = []Detail{detail}
+	}
+
+	for i, detail := range details {
+		if err := detail.validate(); err != nil {
+			if len(details) > 1 {
+				return nil, fmt.Errorf("event detail %d: %w", i, err)
+			}
+			return nil, err
+		}
+	}
+
+	return details, nil
+}
+
+// validate checks that the detail carries the fields its event needs.
+func (d Detail) validate() error {
+	switch d.EventName {
+	case "":
+		return errors.New("event detail is missing eventName")
+	case "CreateLoadBalancer":
+		if d.ResponseElements.DNSName == "" && len(d.ResponseElements.LoadBalancers) == 0 {
+			return errors.New("CreateLoadBalancer event detail is missing responseElements")
+		}
+		if d.ResponseElements.DNSName != "" && d.RequestParameters.LoadBalancerName == "" {
+			return errors.New("CreateLoadBalancer event detail is missing requestParameters.loadBalancerName")
+		}
+		for _, loadBalancer := range d.ResponseElements.LoadBalancers {
+			if loadBalancer.LoadBalancerArn == "" {
+				return errors.New("CreateLoadBalancer event detail is missing responseElements.loadBalancers.loadBalancerArn")
+			}
+		}
+	case "DeleteLoadBalancer":
+		if d.RequestParameters.LoadBalancerName == "" && d.RequestParameters.LoadBalancerArn == "" {
+			return errors.New("DeleteLoadBalancer event detail is missing requestParameters")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const createV2Detail = `{
+	"eventName": "CreateLoadBalancer",
+	"requestParameters": {"name": "my-lb", "type": "application"},
+	"responseElements": {"loadBalancers": [{"loadBalancerArn": "arn:aws:elasticloadbalancing:us-east-1:123:loadbalancer/app/my-lb/abc"}]}
+}`
+
+const deleteClassicDetail = `{
+	"eventName": "DeleteLoadBalancer",
+	"requestParameters": {"loadBalancerName": "my-classic-lb"},
+	"responseElements": null
+}`
+
+func TestDecodeDetails(t *testing.T) {
+	t.Run("single object", func(t *testing.T) {
+		details, err := decodeDetails(json.RawMessage(createV2Detail))
+		require.NoError(t, err)
+		require.Len(t, details, 1)
+		assert.Equal(t, "CreateLoadBalancer", details[0].EventName)
+	})
+
+	t.Run("array", func(t *testing.T) {
+		details, err := decodeDetails(json.RawMessage("[" + createV2Detail + "," + deleteClassicDetail + "]"))
+		require.NoError(t, err)
+		require.Len(t, details, 2)
+		assert.Equal(t, "my-classic-lb", details[1].RequestParameters.LoadBalancerName)
+	})
+
+	tests := []struct {
+		name     string
+		detail   string
+		expected string
+	}{
+		{"empty", ``, "event detail is empty"},
+		{"empty array", `[]`, "event detail is empty"},
+		{"missing eventName", `{"requestParameters": {"loadBalancerName": "my-lb"}}`, "event detail is missing eventName"},
+		{"missing responseElements", `{"eventName": "CreateLoadBalancer", "requestParameters": {"loadBalancerName": "my-lb"}}`, "CreateLoadBalancer event detail is missing responseElements"},
+		{"missing requestParameters", `{"eventName": "DeleteLoadBalancer"}`, "DeleteLoadBalancer event detail is missing requestParameters"},
+		{"invalid entry in array", "[" + createV2Detail + `,{"eventName": ""}]`, "event detail 1: event detail is missing eventName"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details, err := decodeDetails(json.RawMessage(tt.detail))
+			assert.EqualError(t, err, tt.expected)
+			assert.Nil(t, details)
+		})
+	}
+}
+
+func TestProcessDetailLogFields(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	processDetail(Detail{
+		EventName:    "ModifyLoadBalancerAttributes",
+		EventSource:  "elasticloadbalancing.amazonaws.com",
+		UserIdentity: UserIdentity{Arn: "arn:aws:iam::123:user/jane"},
+	})
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, "Event did not match", entry.Message)
+	assert.Equal(t, "ModifyLoadBalancerAttributes", entry.Data["eventName"])
+	assert.Equal(t, "elasticloadbalancing.amazonaws.com", entry.Data["eventSource"])
+	assert.Equal(t, "arn:aws:iam::123:user/jane", entry.Data["requester"])
+}
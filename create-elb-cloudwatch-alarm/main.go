@@ -5,7 +5,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -13,10 +12,14 @@ import (
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
 	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elb/elbiface"
 	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/awsconfig"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/statistic"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -64,142 +67,198 @@ func main() {
 }
 
 func handler(_ context.Context, event events.CloudWatchEvent) {
-	log.Infof("Detail = %s\n", event.Detail)
+	if validateMode() {
+		if err := runValidation(); err != nil {
+			log.WithError(err).Errorln("Permission validation failed")
+		}
+		return
+	}
 
-	if event.Source == "aws.elasticloadbalancing" {
-		var eventDetail Detail
-		err := json.Unmarshal(event.Detail, &eventDetail)
+	log.WithFields(log.Fields{"source": event.Source, "detail-type": event.DetailType}).Infof("Detail = %s", event.Detail)
+
+	trigger := classifyTrigger(event)
+	if trigger == triggerService {
+		eventDetails, err := decodeDetails(event.Detail)
 		if err != nil {
 			log.WithError(err).Errorln("Error decoding the Event detail")
 			return
 		}
-		log.Infof("eventDetail = %+v\n", eventDetail)
-
-		switch eventDetail.EventName {
-		case "CreateLoadBalancer":
-			var elbName, targetGroupName string
-			elbType := "classic"
-
-			if eventDetail.ResponseElements.DNSName == "" {
-				if len(eventDetail.ResponseElements.LoadBalancers) > 0 {
-					elbArnName := eventDetail.ResponseElements.LoadBalancers[0].LoadBalancerArn
-					elbName = elbArnName[strings.IndexByte(elbArnName, '/')+1:]
-
-					var err error
-					targetGroupName, err = getTargetGroup(elbArnName)
-					if err != nil {
-						log.WithError(err).Errorf("Error getting the target group for lb %s", elbName)
-						return
-					}
-
-					lb, err := getV2LB(elbArnName)
-					if err != nil {
-						log.WithError(err).Errorf("Failed to get %s information", elbName)
-						return
-					}
-
-					if len(lb) == 0 {
-						log.Errorf("Expected LB information for %s", elbName)
-						return
-					}
-
-					if len(lb) > 1 {
-						log.Errorf("Expected only one LB for %s", elbName)
-						return
-					}
-
-					elbType = *lb[0].Type
-				} else {
-					log.Error("No LoadBalancers found in the event detail")
-					return
-				}
-			} else {
-				elbName = eventDetail.RequestParameters.LoadBalancerName
+
+		for _, eventDetail := range eventDetails {
+			processDetail(eventDetail)
+		}
+
+		return
+	}
+
+	if trigger == triggerUnknown {
+		log.WithFields(log.Fields{"source": event.Source, "detail-type": event.DetailType}).Warn("Ignoring event from an unrecognized source")
+		return
+	}
+
+	if trigger == triggerManual && reconcileMode() {
+		log.WithField("dryRun", reconcileDryRun()).Info("Reconciling the CloudWatch Alarms")
+		if err := runReconcile(); err != nil {
+			log.WithError(err).Errorln("Failed to reconcile the CloudWatch Alarms")
+		}
+		return
+	}
+
+	// Scheduled and manual triggers go over all load balancers and create the missing CloudWatch Alarms
+	log.WithFields(log.Fields{"trigger": trigger.String(), "dryRun": backfillDryRun()}).Info("Running the backfill scan")
+	if err := runBackfill(); err != nil {
+		log.WithError(err).Errorln("Failed to run the backfill scan")
+	}
+}
+
+// processDetail creates or deletes the CloudWatch Alarms of the load balancer
+// the event detail is about.
+func processDetail(eventDetail Detail) {
+	logger := detailLogger(eventDetail)
+	logger.Infof("eventDetail = %+v", eventDetail)
+
+	switch eventDetail.EventName {
+	case "CreateLoadBalancer":
+		var elbName string
+		var targetGroupNames, zones []string
+		elbType := "classic"
+
+		if eventDetail.ResponseElements.DNSName == "" {
+			elbArnName := eventDetail.ResponseElements.LoadBalancers[0].LoadBalancerArn
+			elbName = elbArnName[strings.IndexByte(elbArnName, '/')+1:]
+			logger = logger.WithField("elbName", elbName)
+
+			var err error
+			targetGroupNames, err = getTargetGroups(elbArnName)
+			if err != nil {
+				logger.WithError(err).Error("Error getting the target groups")
+				return
 			}
 
-			err := createCloudWatchAlarm(elbName, targetGroupName, elbType)
+			lb, err := getV2LB(elbArnName)
 			if err != nil {
-				log.WithError(err).Errorln("Error creating the CloudWatch Alarm")
+				logger.WithError(err).Error("Failed to get the load balancer information")
 				return
 			}
-		case "DeleteLoadBalancer":
-			var elbName string
-			if eventDetail.RequestParameters.LoadBalancerName == "" {
-				elbArnName := eventDetail.RequestParameters.LoadBalancerArn
-				elbName = elbArnName[strings.IndexByte(elbArnName, '/')+1:]
-			} else {
-				elbName = eventDetail.RequestParameters.LoadBalancerName
+
+			if len(lb) == 0 {
+				logger.Error("Expected load balancer information")
+				return
 			}
-			err := deleteCloudWatchAlarm(elbName)
-			if err != nil {
-				log.WithError(err).Errorln("Error deleting the CloudWatch Alarm")
+
+			if len(lb) > 1 {
+				logger.Error("Expected only one load balancer")
 				return
 			}
-		default:
-			log.Infof("Event did not match. Event = %s", eventDetail.EventName)
+
+			elbType = *lb[0].Type
+			zones = alarmZones(lb[0])
+		} else {
+			elbName = eventDetail.RequestParameters.LoadBalancerName
+			logger = logger.WithField("elbName", elbName)
 		}
 
-		return
+		err := createCloudWatchAlarms(elbName, targetGroupNames, elbType, zones, eventDetail.UserIdentity.Arn)
+		if err != nil {
+			logger.WithError(err).Error("Error creating the CloudWatch Alarms")
+			return
+		}
+	case "DeleteLoadBalancer":
+		var elbName string
+		if eventDetail.RequestParameters.LoadBalancerName == "" {
+			elbArnName := eventDetail.RequestParameters.LoadBalancerArn
+			elbName = elbArnName[strings.IndexByte(elbArnName, '/')+1:]
+		} else {
+			elbName = eventDetail.RequestParameters.LoadBalancerName
+		}
+		logger = logger.WithField("elbName", elbName)
+
+		err := deleteCloudWatchAlarm(elbName)
+		if err != nil {
+			logger.WithError(err).Error("Error deleting the CloudWatch Alarm")
+			return
+		}
+	default:
+		logger.Info("Event did not match")
 	}
+}
 
-	listELBs()
+// detailLogger returns a logger carrying the fields identifying the event
+// detail, so the logs can be filtered on them.
+func detailLogger(eventDetail Detail) *log.Entry {
+	return log.WithFields(log.Fields{
+		"eventName":   eventDetail.EventName,
+		"eventSource": eventDetail.EventSource,
+		"requester":   eventDetail.UserIdentity.Arn,
+	})
 }
 
-func listELBs() error {
-	v2LBS, classicLBs, err := listAllLBs()
+// createCloudWatchAlarms creates a HealthyHostCount alarm for each target
+// group of the load balancer, or a single alarm for classic load balancers,
+// plus one per target group and Availability Zone in zones. requesterArn is
+// the identity that created the load balancer, if known.
+func createCloudWatchAlarms(elbName string, targetGroupNames []string, lbType string, zones []string, requesterArn string) error {
+	sess, err := awsconfig.NewSession()
 	if err != nil {
-		log.WithError(err).Errorln("Failed to get the v2 LBs")
+		log.WithError(err).Errorln("Error creating aws session")
 		return err
 	}
 
-	var targetGroupName string
+	inputs, err := newMetricAlarmInputs(elbName, targetGroupNames, lbType, requesterArn)
+	if err != nil {
+		log.WithError(err).Errorln("Error building the cloudwatch alarm")
+		return err
+	}
+	inputs = withZoneAlarms(inputs, zones)
 
-	for _, loadBalancer := range v2LBS {
-		elbArnName := *loadBalancer.LoadBalancerArn
-		elbName := elbArnName[strings.IndexByte(elbArnName, '/')+1:]
-		log.Infof("Creating CloudWatch Alarm for %+v/%+v\n", *loadBalancer.LoadBalancerName, *loadBalancer.DNSName)
+	_, err = putMetricAlarms(cloudwatch.New(sess), elbName, inputs)
+	return err
+}
 
-		targetGroupName, err = getTargetGroup(elbArnName) // Assign to already-declared variables
+// putMetricAlarms creates the alarms, stopping at the first failure, and
+// returns how many were created.
+func putMetricAlarms(svc cloudwatchiface.CloudWatchAPI, elbName string, inputs []*cloudwatch.PutMetricAlarmInput) (int, error) {
+	for i, newMetricAlarm := range inputs {
+		logger := log.WithFields(log.Fields{"elbName": elbName, "alarmName": *newMetricAlarm.AlarmName})
+		_, err := svc.PutMetricAlarm(newMetricAlarm)
 		if err != nil {
-			log.WithError(err).Errorf("Error getting the target group for lb %s", elbName)
-			continue
+			logger.WithError(err).Error("Error creating aws cloudwatch alarm")
+			return i, err
 		}
+		logger.Info("Created aws cloudwatch alarm")
+	}
 
-		err = createCloudWatchAlarm(elbName, targetGroupName, *loadBalancer.Type)
-		if err != nil {
-			log.WithError(err).Errorf("Error creating the CloudWatch Alarm for ELB %s", *loadBalancer.LoadBalancerName)
-			continue
-		}
+	return len(inputs), nil
+}
+
+func newMetricAlarmInputs(elbName string, targetGroupNames []string, lbType, requesterArn string) ([]*cloudwatch.PutMetricAlarmInput, error) {
+	if len(targetGroupNames) == 0 {
+		targetGroupNames = []string{""}
 	}
 
-	for _, loadBalancer := range classicLBs {
-		log.Infof("Creating CloudWatch Alarm for %+v/%+v\n", *loadBalancer.LoadBalancerName, *loadBalancer.DNSName)
-		err = createCloudWatchAlarm(*loadBalancer.LoadBalancerName, "", "classic")
+	var inputs []*cloudwatch.PutMetricAlarmInput
+	for i, targetGroupName := range targetGroupNames {
+		input, err := newMetricAlarmInput(elbName, targetGroupName, lbType)
 		if err != nil {
-			log.WithError(err).Errorf("Error creating the CloudWatch Alarm for ELB %s", *loadBalancer.LoadBalancerName)
-			continue
+			return nil, err
 		}
+		input.AlarmName = aws.String(alarmName(elbName, targetGroupName, i == 0))
+		tagCreatedBy(input, requesterArn)
+		inputs = append(inputs, input)
 	}
 
-	return nil
+	return inputs, nil
 }
 
-func createCloudWatchAlarm(elbName, targetGroupName, lbType string) error {
-	sess, err := session.NewSession(&aws.Config{})
-	if err != nil {
-		log.WithError(err).Errorln("Error creating aws session")
-		return err
-	}
-
+func newMetricAlarmInput(elbName, targetGroupName, lbType string) (*cloudwatch.PutMetricAlarmInput, error) {
 	newMetricAlarm := &cloudwatch.PutMetricAlarmInput{
 		ActionsEnabled:     aws.Bool(true),
-		MetricName:         aws.String("HealthyHostCount"),
-		AlarmName:          aws.String(fmt.Sprintf("Alarm-%s", elbName)),
+		MetricName:         aws.String(healthyHostCountMetric),
+		AlarmName:          aws.String(alarmName(elbName, targetGroupName, true)),
 		ComparisonOperator: aws.String(cloudwatch.ComparisonOperatorLessThanOrEqualToThreshold),
 		EvaluationPeriods:  aws.Int64(1),
 		Period:             aws.Int64(300),
-		Statistic:          aws.String(cloudwatch.StatisticAverage),
-		Threshold:          aws.Float64(0.0),
+		Threshold:          aws.Float64(targetGroupThreshold(targetGroupName)),
 		AlarmDescription:   aws.String("Alarm when having at least one unhealthy host"),
 		AlarmActions:       []*string{aws.String(os.Getenv("SNS_TOPIC"))},
 		OKActions:          []*string{aws.String(os.Getenv("SNS_TOPIC"))},
@@ -222,97 +281,129 @@ func createCloudWatchAlarm(elbName, targetGroupName, lbType string) error {
 		}
 	}
 
-	svc := cloudwatch.New(sess)
-	_, err = svc.PutMetricAlarm(newMetricAlarm)
-	if err != nil {
-		log.WithError(err).Errorln("Error creating aws cloudwatch alarm")
-		return err
+	if err := statistic.Apply(newMetricAlarm, defaultStatistics); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return newMetricAlarm, nil
 }
 
 func deleteCloudWatchAlarm(elbName string) error {
-	sess, err := session.NewSession(&aws.Config{})
+	sess, err := awsconfig.NewSession()
 	if err != nil {
 		log.WithError(err).Errorln("Error creating aws session")
 		return err
 	}
 
-	svc := cloudwatch.New(sess)
-	_, err = svc.DeleteAlarms(&cloudwatch.DeleteAlarmsInput{
-		AlarmNames: []*string{aws.String(fmt.Sprintf("Alarm-%s", elbName))},
-	})
-	if err != nil {
-		log.WithError(err).Errorln("Error deleting aws cloudwatch alarm")
-		return err
+	return deleteLoadBalancerAlarms(cloudwatch.New(sess), elbName)
+}
+
+// deleteLoadBalancerAlarms deletes the alarms of a load balancer, including
+// the per target group and per Availability Zone alarms of v2 ones.
+func deleteLoadBalancerAlarms(svc cloudwatchiface.CloudWatchAPI, elbName string) error {
+	alarmNames := []*string{aws.String(alarmName(elbName, "", true))}
+
+	// Only v2 load balancer names ("app/<name>/<id>") are unique enough to
+	// look up their per target group alarms by prefix.
+	if strings.Contains(elbName, "/") {
+		err := svc.DescribeAlarmsPages(&cloudwatch.DescribeAlarmsInput{
+			AlarmNamePrefix: aws.String(fmt.Sprintf("Alarm-%s-", elbName)),
+		}, func(page *cloudwatch.DescribeAlarmsOutput, _ bool) bool {
+			for _, alarm := range page.MetricAlarms {
+				alarmNames = append(alarmNames, alarm.AlarmName)
+			}
+			return true
+		})
+		if err != nil {
+			log.WithError(err).Errorln("Error listing aws cloudwatch alarms")
+			return err
+		}
+	}
+
+	for start := 0; start < len(alarmNames); start += deleteAlarmsBatchSize {
+		end := min(start+deleteAlarmsBatchSize, len(alarmNames))
+		_, err := svc.DeleteAlarms(&cloudwatch.DeleteAlarmsInput{
+			AlarmNames: alarmNames[start:end],
+		})
+		if err != nil {
+			log.WithError(err).WithFields(log.Fields{"elbName": elbName, "alarmNames": aws.StringValueSlice(alarmNames[start:end])}).Error("Error deleting aws cloudwatch alarm")
+			return err
+		}
 	}
 
 	return nil
 }
 
-func getTargetGroup(loadBalancerArn string) (string, error) {
-	sess, err := session.NewSession(&aws.Config{})
+// getTargetGroups returns the names of the target groups of a load balancer,
+// in the "targetgroup/<name>/<id>" form used by CloudWatch dimensions.
+func getTargetGroups(loadBalancerArn string) ([]string, error) {
+	sess, err := awsconfig.NewSession()
 	if err != nil {
 		log.WithError(err).Errorln("Error creating aws session")
-		return "", err
+		return nil, err
 	}
 
-	svcELBV2 := elbv2.New(sess)
+	return describeTargetGroupNames(elbv2.New(sess), loadBalancerArn)
+}
+
+// describeTargetGroupNames returns the names of the target groups of a load
+// balancer, as getTargetGroups does.
+func describeTargetGroupNames(svcELBV2 elbv2iface.ELBV2API, loadBalancerArn string) ([]string, error) {
 	input := &elbv2.DescribeTargetGroupsInput{LoadBalancerArn: aws.String(loadBalancerArn)}
 	targetGroups, err := svcELBV2.DescribeTargetGroups(input)
 	if err != nil {
-		log.WithError(err).Errorf("Error describing the target groups for lb %s", loadBalancerArn)
-		return "", err
+		log.WithError(err).WithField("loadBalancerArn", loadBalancerArn).Error("Error describing the target groups")
+		return nil, err
 	}
 	if len(targetGroups.TargetGroups) == 0 {
-		return "", fmt.Errorf("No target groups found for lb %s", loadBalancerArn)
+		return nil, fmt.Errorf("No target groups found for lb %s", loadBalancerArn)
 	}
 
-	targetGroupArn := *targetGroups.TargetGroups[0].TargetGroupArn
-	targetGroupName := targetGroupArn[strings.LastIndexByte(targetGroupArn, ':')+1:]
+	var targetGroupNames []string
+	for _, targetGroup := range targetGroups.TargetGroups {
+		targetGroupArn := *targetGroup.TargetGroupArn
+		targetGroupNames = append(targetGroupNames, targetGroupArn[strings.LastIndexByte(targetGroupArn, ':')+1:])
+	}
 
-	return targetGroupName, nil
+	return targetGroupNames, nil
 }
 
 func listAllLBs() ([]*elbv2.LoadBalancer, []*elb.LoadBalancerDescription, error) {
-	sess, err := session.NewSession(&aws.Config{})
+	sess, err := awsconfig.NewSession()
 	if err != nil {
 		log.WithError(err).Errorln("Error creating aws session")
 		return nil, nil, err
 	}
 
-	svcELBV2 := elbv2.New(sess)
+	return describeAllLBs(elbv2.New(sess), elb.New(sess))
+}
+
+// describeAllLBs lists every v2 and classic load balancer, following the
+// pagination.
+func describeAllLBs(svcELBV2 elbv2iface.ELBV2API, svcELB elbiface.ELBAPI) ([]*elbv2.LoadBalancer, []*elb.LoadBalancerDescription, error) {
 	var lbs []*elbv2.LoadBalancer
-	for {
-		resp, err := svcELBV2.DescribeLoadBalancers(&elbv2.DescribeLoadBalancersInput{})
-		if err != nil {
-			return nil, nil, err
-		}
-		lbs = append(lbs, resp.LoadBalancers...)
-		if resp.NextMarker == nil {
-			break
-		}
+	err := svcELBV2.DescribeLoadBalancersPages(&elbv2.DescribeLoadBalancersInput{}, func(page *elbv2.DescribeLoadBalancersOutput, _ bool) bool {
+		lbs = append(lbs, page.LoadBalancers...)
+		return true
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
-	svcELB := elb.New(sess)
 	var classicELBs []*elb.LoadBalancerDescription
-	for {
-		resp, err := svcELB.DescribeLoadBalancers(&elb.DescribeLoadBalancersInput{})
-		if err != nil {
-			return nil, nil, err
-		}
-		classicELBs = append(classicELBs, resp.LoadBalancerDescriptions...)
-		if resp.NextMarker == nil {
-			break
-		}
+	err = svcELB.DescribeLoadBalancersPages(&elb.DescribeLoadBalancersInput{}, func(page *elb.DescribeLoadBalancersOutput, _ bool) bool {
+		classicELBs = append(classicELBs, page.LoadBalancerDescriptions...)
+		return true
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
 	return lbs, classicELBs, nil
 }
 
 func getV2LB(lbARN string) ([]*elbv2.LoadBalancer, error) {
-	sess, err := session.NewSession(&aws.Config{})
+	sess, err := awsconfig.NewSession()
 	if err != nil {
 		log.WithError(err).Errorln("Error creating aws session")
 		return nil, err
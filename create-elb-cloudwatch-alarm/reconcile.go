@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elb/elbiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/awsconfig"
+	log "github.com/sirupsen/logrus"
+)
+
+// deleteAlarmsBatchSize is the maximum number of alarms DeleteAlarms accepts
+// in a single call.
+const deleteAlarmsBatchSize = 100
+
+// reconcileMode reports whether RECONCILE is set to true, in which case a
+// manual run deletes the alarms of load balancers that no longer exist
+// instead of running the backfill scan.
+func reconcileMode() bool {
+	return strings.EqualFold(os.Getenv("RECONCILE"), "true")
+}
+
+// reconcileDryRun reports whether the reconciliation only logs the orphaned
+// alarms. Alarms are only deleted when RECONCILE_DRY_RUN is set to false.
+func reconcileDryRun() bool {
+	return !strings.EqualFold(os.Getenv("RECONCILE_DRY_RUN"), "false")
+}
+
+// runReconcile creates the AWS clients and reconciles the alarms.
+func runReconcile() error {
+	sess, err := awsconfig.NewSession()
+	if err != nil {
+		log.WithError(err).Errorln("Error creating aws session")
+		return err
+	}
+
+	_, err = reconcileAlarms(cloudwatch.New(sess), elbv2.New(sess), elb.New(sess), reconcileDryRun())
+	return err
+}
+
+// reconcileAlarms finds the load balancer alarms whose load balancer no
+// longer exists, e.g. because its deletion event was missed, and deletes them
+// unless dryRun is set. It returns the names of the orphaned alarms.
+func reconcileAlarms(svcCloudWatch cloudwatchiface.CloudWatchAPI, svcELBV2 elbv2iface.ELBV2API, svcELB elbiface.ELBAPI, dryRun bool) ([]string, error) {
+	v2LBs, classicLBs, err := describeAllLBs(svcELBV2, svcELB)
+	if err != nil {
+		log.WithError(err).Errorln("Failed to list the load balancers")
+		return nil, err
+	}
+
+	existing := map[string]bool{}
+	for _, loadBalancer := range v2LBs {
+		elbArnName := aws.StringValue(loadBalancer.LoadBalancerArn)
+		existing[elbArnName[strings.IndexByte(elbArnName, '/')+1:]] = true
+	}
+	for _, loadBalancer := range classicLBs {
+		existing[aws.StringValue(loadBalancer.LoadBalancerName)] = true
+	}
+
+	var orphans []string
+	err = svcCloudWatch.DescribeAlarmsPages(&cloudwatch.DescribeAlarmsInput{
+		AlarmNamePrefix: aws.String("Alarm-"),
+	}, func(page *cloudwatch.DescribeAlarmsOutput, _ bool) bool {
+		for _, alarm := range page.MetricAlarms {
+			elbName, ok := alarmLoadBalancer(alarm)
+			if !ok || existing[elbName] {
+				continue
+			}
+			log.WithFields(log.Fields{
+				"alarmName": aws.StringValue(alarm.AlarmName),
+				"elbName":   elbName,
+				"dryRun":    dryRun,
+			}).Info("Found CloudWatch Alarm of a deleted load balancer")
+			orphans = append(orphans, aws.StringValue(alarm.AlarmName))
+		}
+		return true
+	})
+	if err != nil {
+		log.WithError(err).Errorln("Error listing aws cloudwatch alarms")
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{"orphans": len(orphans), "dryRun": dryRun}).Info("Reconciled CloudWatch Alarms")
+	if dryRun {
+		return orphans, nil
+	}
+
+	for start := 0; start < len(orphans); start += deleteAlarmsBatchSize {
+		end := min(start+deleteAlarmsBatchSize, len(orphans))
+		_, err = svcCloudWatch.DeleteAlarms(&cloudwatch.DeleteAlarmsInput{
+			AlarmNames: aws.StringSlice(orphans[start:end]),
+		})
+		if err != nil {
+			log.WithError(err).Errorln("Error deleting aws cloudwatch alarms")
+			return orphans, err
+		}
+	}
+
+	return orphans, nil
+}
+
+// alarmLoadBalancer returns the name of the load balancer an alarm watches,
+// as used in the alarm names, from its metric dimensions. Alarms on other
+// namespaces, such as the RDS ones, are not load balancer alarms.
+func alarmLoadBalancer(alarm *cloudwatch.MetricAlarm) (string, bool) {
+	var dimension string
+	switch aws.StringValue(alarm.Namespace) {
+	case "AWS/ELB":
+		dimension = "LoadBalancerName"
+	case "AWS/ApplicationELB", "AWS/NetworkELB":
+		dimension = "LoadBalancer"
+	default:
+		return "", false
+	}
+
+	for _, d := range alarm.Dimensions {
+		if aws.StringValue(d.Name) == dimension && aws.StringValue(d.Value) != "" {
+			return aws.StringValue(d.Value), true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The pages are split in two to exercise the pagination.
+
+func (f *fakeCloudWatch) DescribeAlarmsPages(input *cloudwatch.DescribeAlarmsInput, fn func(*cloudwatch.DescribeAlarmsOutput, bool) bool) error {
+	f.calls = append(f.calls, "DescribeAlarmsPages")
+	half := len(f.alarms) / 2
+	if fn(&cloudwatch.DescribeAlarmsOutput{MetricAlarms: f.alarms[:half]}, false) {
+		fn(&cloudwatch.DescribeAlarmsOutput{MetricAlarms: f.alarms[half:]}, true)
+	}
+	return nil
+}
+
+func (f *fakeELBV2) DescribeLoadBalancersPages(_ *elbv2.DescribeLoadBalancersInput, fn func(*elbv2.DescribeLoadBalancersOutput, bool) bool) error {
+	f.calls = append(f.calls, "DescribeLoadBalancersPages")
+	half := len(f.loadBalancers) / 2
+	if fn(&elbv2.DescribeLoadBalancersOutput{LoadBalancers: f.loadBalancers[:half]}, false) {
+		fn(&elbv2.DescribeLoadBalancersOutput{LoadBalancers: f.loadBalancers[half:]}, true)
+	}
+	return nil
+}
+
+func (f *fakeELB) DescribeLoadBalancersPages(_ *elb.DescribeLoadBalancersInput, fn func(*elb.DescribeLoadBalancersOutput, bool) bool) error {
+	f.calls = append(f.calls, "DescribeLoadBalancersPages")
+	fn(&elb.DescribeLoadBalancersOutput{LoadBalancerDescriptions: f.loadBalancers}, true)
+	return nil
+}
+
+func metricAlarm(name, namespace, dimension, value string) *cloudwatch.MetricAlarm {
+	return &cloudwatch.MetricAlarm{
+		AlarmName:  aws.String(name),
+		Namespace:  aws.String(namespace),
+		Dimensions: []*cloudwatch.Dimension{{Name: aws.String(dimension), Value: aws.String(value)}},
+	}
+}
+
+func reconcileFakes() (*fakeCloudWatch, *fakeELBV2, *fakeELB) {
+	svcCloudWatch := &fakeCloudWatch{alarms: []*cloudwatch.MetricAlarm{
+		metricAlarm("Alarm-app/live/1", "AWS/ApplicationELB", "LoadBalancer", "app/live/1"),
+		metricAlarm("Alarm-app/live/1-web", "AWS/ApplicationELB", "LoadBalancer", "app/live/1"),
+		metricAlarm("Alarm-app/gone/2", "AWS/ApplicationELB", "LoadBalancer", "app/gone/2"),
+		metricAlarm("Alarm-net/gone/3", "AWS/NetworkELB", "LoadBalancer", "net/gone/3"),
+		metricAlarm("Alarm-classic-live", "AWS/ELB", "LoadBalancerName", "classic-live"),
+		metricAlarm("Alarm-classic-gone", "AWS/ELB", "LoadBalancerName", "classic-gone"),
+		metricAlarm("Alarm-RDS-cluster", "AWS/RDS", "DBClusterIdentifier", "cluster"),
+	}}
+	svcELBV2 := &fakeELBV2{loadBalancers: []*elbv2.LoadBalancer{
+		{LoadBalancerArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:123:loadbalancer/app/other/9")},
+		{LoadBalancerArn: aws.String("arn:aws:elasticloadbalancing:us-east-1:123:loadbalancer/app/live/1")},
+	}}
+	svcELB := &fakeELB{loadBalancers: []*elb.LoadBalancerDescription{
+		{LoadBalancerName: aws.String("classic-live")},
+	}}
+
+	return svcCloudWatch, svcELBV2, svcELB
+}
+
+func TestReconcileAlarms(t *testing.T) {
+	svcCloudWatch, svcELBV2, svcELB := reconcileFakes()
+
+	orphans, err := reconcileAlarms(svcCloudWatch, svcELBV2, svcELB, false)
+	require.NoError(t, err)
+
+	expected := []string{"Alarm-app/gone/2", "Alarm-net/gone/3", "Alarm-classic-gone"}
+	assert.Equal(t, expected, orphans)
+	assert.Equal(t, expected, svcCloudWatch.deleted)
+}
+
+func TestReconcileAlarmsDryRun(t *testing.T) {
+	svcCloudWatch, svcELBV2, svcELB := reconcileFakes()
+
+	orphans, err := reconcileAlarms(svcCloudWatch, svcELBV2, svcELB, true)
+	require.NoError(t, err)
+
+	assert.Len(t, orphans, 3)
+	assert.NotContains(t, svcCloudWatch.calls, "DeleteAlarms")
+}
+
+func TestReconcileDryRun(t *testing.T) {
+	t.Setenv("RECONCILE_DRY_RUN", "")
+	assert.True(t, reconcileDryRun())
+
+	t.Setenv("RECONCILE_DRY_RUN", "false")
+	assert.False(t, reconcileDryRun())
+}
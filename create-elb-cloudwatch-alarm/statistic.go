@@ -0,0 +1,12 @@
+package main
+
+import "github.com/aws/aws-sdk-go/service/cloudwatch"
+
+const healthyHostCountMetric = "HealthyHostCount"
+
+// defaultStatistics holds the statistic used for each metric unless it is
+// overridden through METRIC_STATISTICS. Any unhealthy sample matters for the
+// HealthyHostCount alarm, so the minimum is used instead of the average.
+var defaultStatistics = map[string]string{
+	healthyHostCountMetric: cloudwatch.StatisticMinimum,
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMetricAlarmInputStatistic(t *testing.T) {
+	t.Setenv("METRIC_STATISTICS", "")
+
+	input, err := newMetricAlarmInput("app/my-lb/123", "targetgroup/my-tg/456", "application")
+	require.NoError(t, err)
+	assert.Equal(t, cloudwatch.StatisticMinimum, *input.Statistic)
+	assert.Equal(t, healthyHostCountMetric, *input.MetricName)
+}
+
+func TestNewMetricAlarmInputExtendedStatistic(t *testing.T) {
+	t.Setenv("METRIC_STATISTICS", "")
+	t.Setenv("METRIC_EXTENDED_STATISTICS", `{"HealthyHostCount": "p99"}`)
+
+	input, err := newMetricAlarmInput("app/my-lb/123", "targetgroup/my-tg/456", "application")
+	require.NoError(t, err)
+	assert.Nil(t, input.Statistic)
+	assert.Equal(t, "p99", *input.ExtendedStatistic)
+}
+
+func TestNewMetricAlarmInputExtendedStatisticErrors(t *testing.T) {
+	testCases := []struct {
+		description string
+		statistics  string
+		extended    string
+	}{
+		{"both statistic and extended statistic", `{"HealthyHostCount": "Maximum"}`, `{"HealthyHostCount": "p99"}`},
+		{"invalid percentile", "", `{"HealthyHostCount": "p999"}`},
+		{"invalid json", "", `{invalid`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			t.Setenv("METRIC_STATISTICS", tc.statistics)
+			t.Setenv("METRIC_EXTENDED_STATISTICS", tc.extended)
+
+			_, err := newMetricAlarmInput("app/my-lb/123", "targetgroup/my-tg/456", "application")
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatedByTag(t *testing.T) {
+	t.Setenv("METRIC_STATISTICS", "")
+	t.Setenv("METRIC_EXTENDED_STATISTICS", "")
+	t.Setenv("TARGET_GROUP_THRESHOLDS", "")
+
+	var detail Detail
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"userIdentity": {"arn": "arn:aws:sts::123456789012:assumed-role/provisioner/session"},
+		"eventName": "CreateLoadBalancer"
+	}`), &detail))
+
+	inputs, err := newMetricAlarmInputs("app/my-lb/123", []string{"targetgroup/web/456", "targetgroup/api/789"}, "application", detail.UserIdentity.Arn)
+	require.NoError(t, err)
+	require.Len(t, inputs, 2)
+	for _, input := range inputs {
+		require.Len(t, input.Tags, 1)
+		assert.Equal(t, createdByTagKey, *input.Tags[0].Key)
+		assert.Equal(t, "arn:aws:sts::123456789012:assumed-role/provisioner/session", *input.Tags[0].Value)
+	}
+
+	inputs, err = newMetricAlarmInputs("my-classic-lb", nil, "classic", "")
+	require.NoError(t, err)
+	assert.Empty(t, inputs[0].Tags)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultHealthyHostThreshold alarms when a target group has no healthy host.
+const defaultHealthyHostThreshold = 0.0
+
+// targetGroupThreshold returns the HealthyHostCount threshold for a target
+// group. The TARGET_GROUP_THRESHOLDS environment variable holds a JSON object
+// mapping target group name prefixes to thresholds, e.g. {"batch-": -1}; the
+// longest matching prefix wins. A negative threshold never alarms, for target
+// groups that are normally empty.
+func targetGroupThreshold(targetGroupName string) float64 {
+	value := os.Getenv("TARGET_GROUP_THRESHOLDS")
+	if value == "" || targetGroupName == "" {
+		return defaultHealthyHostThreshold
+	}
+
+	var thresholds map[string]float64
+	if err := json.Unmarshal([]byte(value), &thresholds); err != nil {
+		log.WithError(err).Error("Failed to parse TARGET_GROUP_THRESHOLDS, using the default threshold")
+		return defaultHealthyHostThreshold
+	}
+
+	name := targetGroupShortName(targetGroupName)
+	threshold := defaultHealthyHostThreshold
+	matched := ""
+	for prefix, value := range thresholds {
+		if strings.HasPrefix(name, prefix) && len(prefix) > len(matched) {
+			matched, threshold = prefix, value
+		}
+	}
+
+	return threshold
+}
+
+// targetGroupShortName returns the name of a target group from its
+// "targetgroup/<name>/<id>" ARN suffix.
+func targetGroupShortName(targetGroupName string) string {
+	parts := strings.Split(targetGroupName, "/")
+	if len(parts) == 3 && parts[0] == "targetgroup" {
+		return parts[1]
+	}
+
+	return targetGroupName
+}
+
+// alarmName returns the name of the alarm for a load balancer. The first
+// target group keeps the historical per-load-balancer name and any further
+// target group gets its own alarm named after it.
+func alarmName(elbName, targetGroupName string, first bool) string {
+	if first {
+		return fmt.Sprintf("Alarm-%s", elbName)
+	}
+
+	return fmt.Sprintf("Alarm-%s-%s", elbName, targetGroupShortName(targetGroupName))
+}
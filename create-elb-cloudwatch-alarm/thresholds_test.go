@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetGroupThreshold(t *testing.T) {
+	testCases := []struct {
+		description string
+		thresholds  string
+		targetGroup string
+		expected    float64
+	}{
+		{"no overrides", "", "targetgroup/batch-workers/123", 0},
+		{"no target group", `{"batch-": -1}`, "", 0},
+		{"prefix match", `{"batch-": -1}`, "targetgroup/batch-workers/123", -1},
+		{"no match", `{"batch-": -1}`, "targetgroup/web/123", 0},
+		{"longest prefix wins", `{"batch-": -1, "batch-critical": 2}`, "targetgroup/batch-critical-jobs/123", 2},
+		{"plain name", `{"web": 1}`, "web-tg", 1},
+		{"invalid json", `{invalid`, "targetgroup/batch-workers/123", 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			t.Setenv("TARGET_GROUP_THRESHOLDS", tc.thresholds)
+			assert.Equal(t, tc.expected, targetGroupThreshold(tc.targetGroup))
+		})
+	}
+}
+
+func TestNewMetricAlarmInputs(t *testing.T) {
+	t.Setenv("METRIC_STATISTICS", "")
+	t.Setenv("METRIC_EXTENDED_STATISTICS", "")
+	t.Setenv("TARGET_GROUP_THRESHOLDS", `{"batch-": -1}`)
+
+	inputs, err := newMetricAlarmInputs("app/my-lb/123", []string{"targetgroup/web/456", "targetgroup/batch-workers/789"}, "application", "")
+	require.NoError(t, err)
+	require.Len(t, inputs, 2)
+
+	assert.Equal(t, "Alarm-app/my-lb/123", *inputs[0].AlarmName)
+	assert.Equal(t, 0.0, *inputs[0].Threshold)
+	assert.Equal(t, "targetgroup/web/456", *inputs[0].Dimensions[1].Value)
+
+	assert.Equal(t, "Alarm-app/my-lb/123-batch-workers", *inputs[1].AlarmName)
+	assert.Equal(t, -1.0, *inputs[1].Threshold)
+	assert.Equal(t, "targetgroup/batch-workers/789", *inputs[1].Dimensions[1].Value)
+}
+
+func TestNewMetricAlarmInputsClassic(t *testing.T) {
+	t.Setenv("METRIC_STATISTICS", "")
+	t.Setenv("METRIC_EXTENDED_STATISTICS", "")
+	t.Setenv("TARGET_GROUP_THRESHOLDS", `{"": 5}`)
+
+	inputs, err := newMetricAlarmInputs("my-classic-lb", nil, "classic", "")
+	require.NoError(t, err)
+	require.Len(t, inputs, 1)
+	assert.Equal(t, "Alarm-my-classic-lb", *inputs[0].AlarmName)
+	assert.Equal(t, 0.0, *inputs[0].Threshold)
+}
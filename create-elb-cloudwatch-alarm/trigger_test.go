@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyTrigger(t *testing.T) {
+	testCases := []struct {
+		name     string
+		event    events.CloudWatchEvent
+		expected triggerKind
+	}{
+		{"matched service", events.CloudWatchEvent{Source: serviceEventSource, DetailType: "AWS API Call via CloudTrail"}, triggerService},
+		{"scheduled", events.CloudWatchEvent{Source: "aws.events", DetailType: scheduledEventDetailType, Detail: json.RawMessage(`{}`)}, triggerScheduled},
+		{"manual", events.CloudWatchEvent{}, triggerManual},
+		{"other service", events.CloudWatchEvent{Source: "aws.ec2", DetailType: "AWS API Call via CloudTrail"}, triggerUnknown},
+		{"other events detail type", events.CloudWatchEvent{Source: "aws.events", DetailType: "Custom"}, triggerUnknown},
+		{"detail type only", events.CloudWatchEvent{DetailType: scheduledEventDetailType}, triggerUnknown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, classifyTrigger(tc.event))
+		})
+	}
+}
+
+func TestHandlerIgnoresUnknownSource(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	handler(context.Background(), events.CloudWatchEvent{Source: "aws.ec2", DetailType: "EC2 Instance State-change Notification"})
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, log.WarnLevel, entry.Level)
+	assert.Equal(t, "Ignoring event from an unrecognized source", entry.Message)
+	assert.Equal(t, "aws.ec2", entry.Data["source"])
+}
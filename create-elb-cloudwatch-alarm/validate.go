@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elb/elbiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/awsconfig"
+	log "github.com/sirupsen/logrus"
+)
+
+// validateMode reports whether VALIDATE is set to true, in which case the
+// lambda only checks its IAM permissions with read-only calls instead of
+// managing alarms.
+func validateMode() bool {
+	return strings.EqualFold(os.Getenv("VALIDATE"), "true")
+}
+
+// permissionCheck is a read-only AWS call exercising one permission the
+// lambda needs.
+type permissionCheck struct {
+	name string
+	run  func() error
+}
+
+// runValidation creates the AWS clients and runs the permission checks.
+func runValidation() error {
+	sess, err := awsconfig.NewSession()
+	if err != nil {
+		log.WithError(err).Errorln("Error creating aws session")
+		return err
+	}
+
+	return validatePermissions(cloudwatch.New(sess), elbv2.New(sess), elb.New(sess))
+}
+
+// validatePermissions runs every permission check and logs a pass/fail
+// summary. CloudWatch has no dry run for PutMetricAlarm or DeleteAlarms, so
+// those permissions are not exercised.
+func validatePermissions(svcCloudWatch cloudwatchiface.CloudWatchAPI, svcELBV2 elbv2iface.ELBV2API, svcELB elbiface.ELBAPI) error {
+	checks := []permissionCheck{
+		{"cloudwatch:DescribeAlarms", func() error {
+			_, err := svcCloudWatch.DescribeAlarms(&cloudwatch.DescribeAlarmsInput{MaxRecords: aws.Int64(1)})
+			return err
+		}},
+		{"elasticloadbalancing:DescribeLoadBalancers (v2)", func() error {
+			_, err := svcELBV2.DescribeLoadBalancers(&elbv2.DescribeLoadBalancersInput{PageSize: aws.Int64(1)})
+			return err
+		}},
+		{"elasticloadbalancing:DescribeLoadBalancers (classic)", func() error {
+			_, err := svcELB.DescribeLoadBalancers(&elb.DescribeLoadBalancersInput{PageSize: aws.Int64(1)})
+			return err
+		}},
+	}
+
+	return runPermissionChecks(checks)
+}
+
+func runPermissionChecks(checks []permissionCheck) error {
+	var failed []string
+	for _, check := range checks {
+		if err := check.run(); err != nil {
+			log.WithError(err).WithField("check", check.name).Error("Permission check failed")
+			failed = append(failed, check.name)
+			continue
+		}
+		log.WithField("check", check.name).Info("Permission check passed")
+	}
+
+	log.WithFields(log.Fields{
+		"passed": len(checks) - len(failed),
+		"failed": len(failed),
+	}).Info("Permission validation summary")
+	log.Info("PutMetricAlarm and DeleteAlarms have no dry run and were not checked")
+
+	if len(failed) > 0 {
+		return fmt.Errorf("permission checks failed: %s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elb/elbiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The fakes embed the client interfaces, so any call they do not implement,
+// mutating ones included, panics and fails the test.
+
+type fakeCloudWatch struct {
+	cloudwatchiface.CloudWatchAPI
+	calls   []string
+	err     error
+	alarms  []*cloudwatch.MetricAlarm
+	deleted []string
+	created []string
+	putErrs map[string]error
+	onPut   func(*cloudwatch.PutMetricAlarmInput)
+}
+
+func (f *fakeCloudWatch) DescribeAlarms(*cloudwatch.DescribeAlarmsInput) (*cloudwatch.DescribeAlarmsOutput, error) {
+	f.calls = append(f.calls, "DescribeAlarms")
+	return &cloudwatch.DescribeAlarmsOutput{}, f.err
+}
+
+func (f *fakeCloudWatch) PutMetricAlarm(input *cloudwatch.PutMetricAlarmInput) (*cloudwatch.PutMetricAlarmOutput, error) {
+	f.calls = append(f.calls, "PutMetricAlarm")
+	if f.onPut != nil {
+		f.onPut(input)
+	}
+	if err := f.putErrs[aws.StringValue(input.AlarmName)]; err != nil {
+		return nil, err
+	}
+	f.created = append(f.created, aws.StringValue(input.AlarmName))
+	return &cloudwatch.PutMetricAlarmOutput{}, nil
+}
+
+func (f *fakeCloudWatch) DeleteAlarms(input *cloudwatch.DeleteAlarmsInput) (*cloudwatch.DeleteAlarmsOutput, error) {
+	f.calls = append(f.calls, "DeleteAlarms")
+	f.deleted = append(f.deleted, aws.StringValueSlice(input.AlarmNames)...)
+	return &cloudwatch.DeleteAlarmsOutput{}, nil
+}
+
+type fakeELBV2 struct {
+	elbv2iface.ELBV2API
+	calls         []string
+	loadBalancers []*elbv2.LoadBalancer
+	// targetGroups are the target group ARNs per load balancer ARN.
+	targetGroups map[string][]string
+}
+
+func (f *fakeELBV2) DescribeLoadBalancers(*elbv2.DescribeLoadBalancersInput) (*elbv2.DescribeLoadBalancersOutput, error) {
+	f.calls = append(f.calls, "DescribeLoadBalancers")
+	return &elbv2.DescribeLoadBalancersOutput{}, nil
+}
+
+type fakeELB struct {
+	elbiface.ELBAPI
+	calls         []string
+	loadBalancers []*elb.LoadBalancerDescription
+}
+
+func (f *fakeELB) DescribeLoadBalancers(*elb.DescribeLoadBalancersInput) (*elb.DescribeLoadBalancersOutput, error) {
+	f.calls = append(f.calls, "DescribeLoadBalancers")
+	return &elb.DescribeLoadBalancersOutput{}, nil
+}
+
+func TestValidatePermissions(t *testing.T) {
+	svcCloudWatch := &fakeCloudWatch{}
+	svcELBV2 := &fakeELBV2{}
+	svcELB := &fakeELB{}
+
+	require.NoError(t, validatePermissions(svcCloudWatch, svcELBV2, svcELB))
+	assert.Equal(t, []string{"DescribeAlarms"}, svcCloudWatch.calls)
+	assert.Equal(t, []string{"DescribeLoadBalancers"}, svcELBV2.calls)
+	assert.Equal(t, []string{"DescribeLoadBalancers"}, svcELB.calls)
+}
+
+func TestValidatePermissionsFailure(t *testing.T) {
+	svcCloudWatch := &fakeCloudWatch{err: errors.New("AccessDenied")}
+	svcELBV2 := &fakeELBV2{}
+	svcELB := &fakeELB{}
+
+	err := validatePermissions(svcCloudWatch, svcELBV2, svcELB)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cloudwatch:DescribeAlarms")
+	assert.NotContains(t, svcCloudWatch.calls, "PutMetricAlarm")
+	assert.NotContains(t, svcCloudWatch.calls, "DeleteAlarms")
+	assert.Len(t, svcELB.calls, 1)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+)
+
+// perAZAlarms reports whether PER_AZ_ALARMS is set to true, in which case
+// v2 load balancers also get a HealthyHostCount alarm per target group and
+// Availability Zone, catching the failure of a single zone.
+func perAZAlarms() bool {
+	return strings.EqualFold(os.Getenv("PER_AZ_ALARMS"), "true")
+}
+
+// alarmZones returns the Availability Zones of the load balancer that get
+// their own alarms, none unless PER_AZ_ALARMS is enabled.
+func alarmZones(lb *elbv2.LoadBalancer) []string {
+	if !perAZAlarms() {
+		return nil
+	}
+
+	var zones []string
+	for _, zone := range lb.AvailabilityZones {
+		if name := aws.StringValue(zone.ZoneName); name != "" {
+			zones = append(zones, name)
+		}
+	}
+	return zones
+}
+
+// withZoneAlarms adds to the alarms a copy of each of them per zone, with the
+// AvailabilityZone dimension and the zone appended to its name. The names
+// keep the "Alarm-<elbName>-" prefix the alarms are deleted by.
+func withZoneAlarms(inputs []*cloudwatch.PutMetricAlarmInput, zones []string) []*cloudwatch.PutMetricAlarmInput {
+	result := inputs
+	for _, input := range inputs {
+		for _, zone := range zones {
+			zoneInput := *input
+			zoneInput.AlarmName = aws.String(aws.StringValue(input.AlarmName) + "-" + zone)
+			zoneInput.Dimensions = append(append([]*cloudwatch.Dimension{}, input.Dimensions...), &cloudwatch.Dimension{
+				Name:  aws.String("AvailabilityZone"),
+				Value: aws.String(zone),
+			})
+			result = append(result, &zoneInput)
+		}
+	}
+	return result
+}
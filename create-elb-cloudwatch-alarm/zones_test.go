@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func zonedLoadBalancer(zones ...string) *elbv2.LoadBalancer {
+	lb := &elbv2.LoadBalancer{}
+	for _, zone := range zones {
+		lb.AvailabilityZones = append(lb.AvailabilityZones, &elbv2.AvailabilityZone{ZoneName: aws.String(zone)})
+	}
+	return lb
+}
+
+func TestAlarmZones(t *testing.T) {
+	lb := zonedLoadBalancer("us-east-1a", "us-east-1b")
+
+	t.Setenv("PER_AZ_ALARMS", "")
+	assert.Empty(t, alarmZones(lb))
+
+	t.Setenv("PER_AZ_ALARMS", "true")
+	assert.Equal(t, []string{"us-east-1a", "us-east-1b"}, alarmZones(lb))
+}
+
+func TestPerAZAlarmCreation(t *testing.T) {
+	t.Setenv("PER_AZ_ALARMS", "true")
+	svcCloudWatch, svcELBV2, svcELB := backfillFakes()
+	svcELBV2.loadBalancers = svcELBV2.loadBalancers[:1]
+	svcELBV2.loadBalancers[0].AvailabilityZones = zonedLoadBalancer("us-east-1a", "us-east-1b").AvailabilityZones
+	svcELB.loadBalancers = svcELB.loadBalancers[:1]
+
+	var inputs []*cloudwatch.PutMetricAlarmInput
+	svcCloudWatch.onPut = func(input *cloudwatch.PutMetricAlarmInput) { inputs = append(inputs, input) }
+
+	summary, err := backfillAlarms(svcCloudWatch, svcELBV2, svcELB, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"Alarm-app/web/1",
+		"Alarm-app/web/1-api",
+		"Alarm-app/web/1-us-east-1a",
+		"Alarm-app/web/1-us-east-1b",
+		"Alarm-app/web/1-api-us-east-1a",
+		"Alarm-app/web/1-api-us-east-1b",
+		"Alarm-classic-1",
+	}, svcCloudWatch.created, "classic load balancers get no per-AZ alarm")
+	assert.Equal(t, 7, summary.Created)
+
+	zoneInput := inputs[5]
+	assert.Equal(t, []*cloudwatch.Dimension{
+		{Name: aws.String("LoadBalancer"), Value: aws.String("app/web/1")},
+		{Name: aws.String("TargetGroup"), Value: aws.String("targetgroup/api/b")},
+		{Name: aws.String("AvailabilityZone"), Value: aws.String("us-east-1b")},
+	}, zoneInput.Dimensions)
+	assert.Len(t, inputs[1].Dimensions, 2, "the per target group alarm is left unchanged")
+}
+
+func TestPerAZAlarmDeletion(t *testing.T) {
+	svcCloudWatch := &fakeCloudWatch{alarms: []*cloudwatch.MetricAlarm{
+		{AlarmName: aws.String("Alarm-app/web/1-api")},
+		{AlarmName: aws.String("Alarm-app/web/1-us-east-1a")},
+		{AlarmName: aws.String("Alarm-app/web/1-api-us-east-1a")},
+	}}
+
+	require.NoError(t, deleteLoadBalancerAlarms(svcCloudWatch, "app/web/1"))
+
+	assert.Equal(t, []string{
+		"Alarm-app/web/1",
+		"Alarm-app/web/1-api",
+		"Alarm-app/web/1-us-east-1a",
+		"Alarm-app/web/1-api-us-east-1a",
+	}, svcCloudWatch.deleted)
+}
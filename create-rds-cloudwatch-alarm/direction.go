@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+const (
+	directionLow  = "low"
+	directionHigh = "high"
+	directionBoth = "both"
+
+	// highAlarmSuffix is appended to the name of the high connections alarm,
+	// the low one keeps the historical name.
+	highAlarmSuffix = "-high"
+)
+
+// alarmDirections returns the directions the DatabaseConnections alarms are
+// created for. ALARM_DIRECTION is "low" (the default), catching clusters
+// without connections, "high", catching too many connections, or "both".
+func alarmDirections() ([]string, error) {
+	switch direction := strings.ToLower(strings.TrimSpace(os.Getenv("ALARM_DIRECTION"))); direction {
+	case "", directionLow:
+		return []string{directionLow}, nil
+	case directionHigh:
+		return []string{directionHigh}, nil
+	case directionBoth:
+		return []string{directionLow, directionHigh}, nil
+	default:
+		return nil, fmt.Errorf("invalid ALARM_DIRECTION %q, expected low, high or both", direction)
+	}
+}
+
+// connectionsThreshold returns the threshold of the alarm of the direction:
+// LOW_CONNECTIONS_THRESHOLD, 0 by default, or HIGH_CONNECTIONS_THRESHOLD,
+// which must be set when high alarms are created.
+func connectionsThreshold(direction string) (float64, error) {
+	name := "LOW_CONNECTIONS_THRESHOLD"
+	if direction == directionHigh {
+		name = "HIGH_CONNECTIONS_THRESHOLD"
+	}
+
+	value := strings.TrimSpace(os.Getenv(name))
+	if value == "" {
+		if direction == directionHigh {
+			return 0, fmt.Errorf("%s must be set to create high connections alarms", name)
+		}
+		return 0, nil
+	}
+
+	threshold, err := strconv.ParseFloat(value, 64)
+	if err != nil || threshold < 0 {
+		return 0, fmt.Errorf("invalid %s %q, expected a non-negative number", name, value)
+	}
+	return threshold, nil
+}
+
+// clusterAlarmNames returns the names of all the alarms a cluster may have,
+// whatever the configured directions.
+func clusterAlarmNames(dbClusterName string) []string {
+	name := rdsAlarmPrefix + dbClusterName
+	return []string{name, name + highAlarmSuffix}
+}
+
+// newMetricAlarmInputs returns the DatabaseConnections alarms of the cluster
+// for each configured direction.
+func newMetricAlarmInputs(dbClusterName string) ([]*cloudwatch.PutMetricAlarmInput, error) {
+	directions, err := alarmDirections()
+	if err != nil {
+		return nil, err
+	}
+
+	var inputs []*cloudwatch.PutMetricAlarmInput
+	for _, direction := range directions {
+		threshold, err := connectionsThreshold(direction)
+		if err != nil {
+			return nil, err
+		}
+
+		input, err := newMetricAlarmInput(dbClusterName)
+		if err != nil {
+			return nil, err
+		}
+		input.Threshold = aws.Float64(threshold)
+		if direction == directionHigh {
+			input.AlarmName = aws.String(clusterAlarmNames(dbClusterName)[1])
+			input.ComparisonOperator = aws.String(cloudwatch.ComparisonOperatorGreaterThanOrEqualToThreshold)
+			input.AlarmDescription = aws.String("Alarm when having too many DB connections")
+		}
+		inputs = append(inputs, input)
+	}
+
+	return inputs, nil
+}
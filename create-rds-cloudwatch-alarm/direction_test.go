@@ -0,0 +1,117 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMetricAlarmInputsDirections(t *testing.T) {
+	t.Setenv("METRIC_STATISTICS", "")
+	t.Setenv("METRIC_EXTENDED_STATISTICS", "")
+
+	type alarm struct {
+		name      string
+		operator  string
+		threshold float64
+	}
+	low := alarm{"Alarm-RDS-my-cluster", cloudwatch.ComparisonOperatorLessThanOrEqualToThreshold, 0}
+	high := alarm{"Alarm-RDS-my-cluster-high", cloudwatch.ComparisonOperatorGreaterThanOrEqualToThreshold, 500}
+
+	testCases := []struct {
+		description   string
+		direction     string
+		lowThreshold  string
+		highThreshold string
+		expected      []alarm
+	}{
+		{"default", "", "", "500", []alarm{low}},
+		{"low", "low", "", "", []alarm{low}},
+		{"low with threshold", "LOW", "2", "", []alarm{{low.name, low.operator, 2}}},
+		{"high", "high", "", "500", []alarm{high}},
+		{"both", "both", "", "500", []alarm{low, high}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			t.Setenv("ALARM_DIRECTION", tc.direction)
+			t.Setenv("LOW_CONNECTIONS_THRESHOLD", tc.lowThreshold)
+			t.Setenv("HIGH_CONNECTIONS_THRESHOLD", tc.highThreshold)
+
+			inputs, err := newMetricAlarmInputs("my-cluster")
+			require.NoError(t, err)
+
+			var alarms []alarm
+			for _, input := range inputs {
+				alarms = append(alarms, alarm{*input.AlarmName, *input.ComparisonOperator, *input.Threshold})
+				assert.Equal(t, databaseConnectionsMetric, *input.MetricName)
+			}
+			assert.Equal(t, tc.expected, alarms)
+		})
+	}
+}
+
+func TestNewMetricAlarmInputsDirectionErrors(t *testing.T) {
+	testCases := []struct {
+		description   string
+		direction     string
+		lowThreshold  string
+		highThreshold string
+		expected      string
+	}{
+		{"invalid direction", "sideways", "", "", `invalid ALARM_DIRECTION "sideways", expected low, high or both`},
+		{"high without threshold", "high", "", "", "HIGH_CONNECTIONS_THRESHOLD must be set to create high connections alarms"},
+		{"invalid high threshold", "both", "", "many", `invalid HIGH_CONNECTIONS_THRESHOLD "many", expected a non-negative number`},
+		{"negative low threshold", "low", "-1", "", `invalid LOW_CONNECTIONS_THRESHOLD "-1", expected a non-negative number`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			t.Setenv("ALARM_DIRECTION", tc.direction)
+			t.Setenv("LOW_CONNECTIONS_THRESHOLD", tc.lowThreshold)
+			t.Setenv("HIGH_CONNECTIONS_THRESHOLD", tc.highThreshold)
+
+			_, err := newMetricAlarmInputs("my-cluster")
+			assert.EqualError(t, err, tc.expected)
+		})
+	}
+}
+
+func TestDeleteClusterAlarms(t *testing.T) {
+	svcCloudWatch := &fakeCloudWatch{alarms: []*cloudwatch.MetricAlarm{
+		{AlarmName: aws.String("Alarm-RDS-my-cluster")},
+		{AlarmName: aws.String("Alarm-RDS-my-cluster-high")},
+		{AlarmName: aws.String("Alarm-RDS-my-cluster-2")},
+	}}
+	require.NoError(t, deleteClusterAlarms(svcCloudWatch, "my-cluster"))
+	assert.Equal(t, []string{"Alarm-RDS-my-cluster", "Alarm-RDS-my-cluster-high"}, svcCloudWatch.deleted)
+
+	svcCloudWatch = &fakeCloudWatch{alarms: []*cloudwatch.MetricAlarm{
+		{AlarmName: aws.String("Alarm-RDS-my-cluster")},
+	}}
+	require.NoError(t, deleteClusterAlarms(svcCloudWatch, "my-cluster"))
+	assert.Equal(t, []string{"Alarm-RDS-my-cluster"}, svcCloudWatch.deleted, "only the existing alarms are deleted")
+
+	svcCloudWatch = &fakeCloudWatch{}
+	require.NoError(t, deleteClusterAlarms(svcCloudWatch, "my-cluster"))
+	assert.NotContains(t, svcCloudWatch.calls, "DeleteAlarms")
+}
+
+func TestReconcileHighAlarms(t *testing.T) {
+	dimension := func(cluster string) []*cloudwatch.Dimension {
+		return []*cloudwatch.Dimension{{Name: aws.String("DBClusterIdentifier"), Value: aws.String(cluster)}}
+	}
+	svcCloudWatch := &fakeCloudWatch{alarms: []*cloudwatch.MetricAlarm{
+		{AlarmName: aws.String("Alarm-RDS-live-1-high"), Dimensions: dimension("live-1")},
+		{AlarmName: aws.String("Alarm-RDS-gone-1-high"), Dimensions: dimension("gone-1")},
+	}}
+	svcRDS := &fakeRDS{clusters: []*rds.DBCluster{{DBClusterIdentifier: aws.String("live-1")}}}
+
+	orphans, err := reconcileAlarms(svcCloudWatch, svcRDS, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alarm-RDS-gone-1-high"}, orphans)
+}
@@ -10,14 +10,16 @@ import (
 	"os"
 	"strings"
 
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/statistic"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
 	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/awsconfig"
 )
 
 // Detail holds the structure of the detail field of a CloudWatch event.
@@ -52,71 +54,131 @@ func main() {
 }
 
 func handler(_ context.Context, event events.CloudWatchEvent) {
-	log.Infof("Detail = %s\n", event.Detail)
+	if validateMode() {
+		if err := runValidation(); err != nil {
+			log.WithError(err).Errorln("Permission validation failed")
+		}
+		return
+	}
 
-	if event.Source == "aws.rds" {
+	log.WithFields(log.Fields{"source": event.Source, "detail-type": event.DetailType}).Infof("Detail = %s", event.Detail)
+
+	trigger := classifyTrigger(event)
+	if trigger == triggerService {
 		var eventDetail Detail
 		err := json.Unmarshal(event.Detail, &eventDetail)
 		if err != nil {
 			log.WithError(err).Errorln("Error decoding the Event detail")
 			return
 		}
-		log.Infof("eventDetail = %+v\n", eventDetail)
+		logger := detailLogger(eventDetail)
+		logger.Infof("eventDetail = %+v", eventDetail)
 
 		switch eventDetail.EventName {
 		case "CreateDBInstance":
-			// filtering the rds multitenant and test clusters
-			if !strings.Contains(eventDetail.RequestParameters.DBClusterIdentifier, "rds-cluster-multitenant-") &&
-				!strings.Contains(eventDetail.RequestParameters.DBClusterIdentifier, "test-") {
-
-				log.Infof("Creating CloudWatch Alarm for %s\n", eventDetail.RequestParameters.DBClusterIdentifier)
-				err = createCloudWatchAlarm(eventDetail.RequestParameters.DBClusterIdentifier)
+			if !clusterExcluded(eventDetail.RequestParameters.DBClusterIdentifier) {
+				logger.Info("Creating CloudWatch Alarm")
+				err = createCloudWatchAlarm(eventDetail.RequestParameters.DBClusterIdentifier, eventDetail.UserIdentity.Arn)
 				if err != nil {
-					log.WithError(err).Errorln("Error creating the CloudWatch Alarm")
+					logger.WithError(err).Error("Error creating the CloudWatch Alarm")
 					return
 				}
 			} else {
-				log.Infof("Skipping the creation of CloudWatch Alarm for %s\n", eventDetail.RequestParameters.DBClusterIdentifier)
+				logger.Info("Skipping the creation of CloudWatch Alarm")
 			}
 		case "DeleteDBInstance":
-			// filtering the rds multitenant
-			if !strings.Contains(eventDetail.RequestParameters.DBClusterIdentifier, "rds-cluster-multitenant-") &&
-				!strings.Contains(eventDetail.RequestParameters.DBClusterIdentifier, "test-") {
-
-				log.Infof("Deleting CloudWatch Alarm for %s\n", eventDetail.RequestParameters.DBClusterIdentifier)
+			if !clusterExcluded(eventDetail.RequestParameters.DBClusterIdentifier) {
+				logger.Info("Deleting CloudWatch Alarm")
 				err = deleteCloudWatchAlarm(eventDetail.ResponseElements.DBClusterIdentifier)
 				if err != nil {
-					log.WithError(err).Errorln("Error deleting the CloudWatch Alarm")
+					logger.WithError(err).Error("Error deleting the CloudWatch Alarm")
 					return
 				}
 			} else {
-				log.Infof("Skipping the deletion of CloudWatch Alarm for %s\n", eventDetail.RequestParameters.DBClusterIdentifier)
+				logger.Info("Skipping the deletion of CloudWatch Alarm")
 			}
 		default:
-			log.Infof("Event did not match. Event = %s", eventDetail.EventName)
+			logger.Info("Event did not match")
 		}
 
 		return
 	}
-	// Trigger manually to go over all RDS and create missing CloudWatchAlarms
+
+	if trigger == triggerUnknown {
+		log.WithFields(log.Fields{"source": event.Source, "detail-type": event.DetailType}).Warn("Ignoring event from an unrecognized source")
+		return
+	}
+
+	if trigger == triggerManual && reconcileMode() {
+		log.WithField("dryRun", reconcileDryRun()).Info("Reconciling the CloudWatch Alarms")
+		if err := runReconcile(); err != nil {
+			log.WithError(err).Errorln("Failed to reconcile the CloudWatch Alarms")
+		}
+		return
+	}
+
+	// Scheduled and manual triggers go over all RDS clusters and create the missing CloudWatch Alarms
+	log.WithField("trigger", trigger.String()).Info("Running the backfill scan")
 	listRDS()
 }
 
-func createCloudWatchAlarm(dbClusterName string) error {
-	sess, err := session.NewSession(&aws.Config{})
+// clusterExcluded reports whether the cluster is left without alarms: the
+// multitenant and the test clusters are.
+func clusterExcluded(dbClusterIdentifier string) bool {
+	return strings.Contains(dbClusterIdentifier, "rds-cluster-multitenant-") ||
+		strings.Contains(dbClusterIdentifier, "test-")
+}
+
+// detailLogger returns a logger carrying the fields identifying the event
+// detail, so the logs can be filtered on them.
+func detailLogger(eventDetail Detail) *log.Entry {
+	return log.WithFields(log.Fields{
+		"eventName":           eventDetail.EventName,
+		"eventSource":         eventDetail.EventSource,
+		"dbClusterIdentifier": eventDetail.RequestParameters.DBClusterIdentifier,
+		"requester":           eventDetail.UserIdentity.Arn,
+	})
+}
+
+// createCloudWatchAlarm creates the DatabaseConnections alarms of the cluster.
+// requesterArn is the identity that created the cluster instance, if known.
+func createCloudWatchAlarm(dbClusterName, requesterArn string) error {
+	sess, err := awsconfig.NewSession()
 	if err != nil {
 		log.WithError(err).Errorln("Error creating aws session")
 		return err
 	}
 
+	inputs, err := newMetricAlarmInputs(dbClusterName)
+	if err != nil {
+		log.WithError(err).Errorln("Error building the cloudwatch alarm")
+		return err
+	}
+
+	svc := cloudwatch.New(sess)
+	for _, newMetricAlarm := range inputs {
+		tagCreatedBy(newMetricAlarm, requesterArn)
+
+		logger := log.WithFields(log.Fields{"dbClusterIdentifier": dbClusterName, "alarmName": *newMetricAlarm.AlarmName})
+		_, err = svc.PutMetricAlarm(newMetricAlarm)
+		if err != nil {
+			logger.WithError(err).Error("Error creating aws cloudwatch alarm")
+			return err
+		}
+		logger.Info("Created aws cloudwatch alarm")
+	}
+
+	return nil
+}
+
+func newMetricAlarmInput(dbClusterName string) (*cloudwatch.PutMetricAlarmInput, error) {
 	newMetricAlarm := &cloudwatch.PutMetricAlarmInput{
 		ActionsEnabled:     aws.Bool(true),
-		MetricName:         aws.String("DatabaseConnections"),
+		MetricName:         aws.String(databaseConnectionsMetric),
 		AlarmName:          aws.String(fmt.Sprintf("Alarm-RDS-%s", dbClusterName)),
 		ComparisonOperator: aws.String(cloudwatch.ComparisonOperatorLessThanOrEqualToThreshold),
 		EvaluationPeriods:  aws.Int64(1),
 		Period:             aws.Int64(900),
-		Statistic:          aws.String(cloudwatch.StatisticAverage),
 		Threshold:          aws.Float64(0),
 		AlarmDescription:   aws.String("Alarm when having no DB connections"),
 		Namespace:          aws.String("AWS/RDS"),
@@ -134,29 +196,50 @@ func createCloudWatchAlarm(dbClusterName string) error {
 		},
 	}
 
-	svc := cloudwatch.New(sess)
-	_, err = svc.PutMetricAlarm(newMetricAlarm)
-	if err != nil {
-		log.WithError(err).Errorln("Error creating aws cloudwatch alarm")
-		return err
+	if err := statistic.Apply(newMetricAlarm, defaultStatistics); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return newMetricAlarm, nil
 }
 
 func deleteCloudWatchAlarm(dbClusterName string) error {
-	sess, err := session.NewSession(&aws.Config{})
+	sess, err := awsconfig.NewSession()
 	if err != nil {
 		log.WithError(err).Errorln("Error creating aws session")
 		return err
 	}
 
-	svc := cloudwatch.New(sess)
+	return deleteClusterAlarms(cloudwatch.New(sess), dbClusterName)
+}
+
+// deleteClusterAlarms deletes the low and high connections alarms of the
+// cluster. Only the existing ones are deleted, as DeleteAlarms deletes
+// nothing when given an unknown alarm name.
+func deleteClusterAlarms(svc cloudwatchiface.CloudWatchAPI, dbClusterName string) error {
+	logger := log.WithField("dbClusterIdentifier", dbClusterName)
+	output, err := svc.DescribeAlarms(&cloudwatch.DescribeAlarmsInput{
+		AlarmNames: aws.StringSlice(clusterAlarmNames(dbClusterName)),
+	})
+	if err != nil {
+		logger.WithError(err).Error("Error listing aws cloudwatch alarms")
+		return err
+	}
+
+	var alarmNames []*string
+	for _, alarm := range output.MetricAlarms {
+		alarmNames = append(alarmNames, alarm.AlarmName)
+	}
+	if len(alarmNames) == 0 {
+		logger.Info("No aws cloudwatch alarm to delete")
+		return nil
+	}
+
 	_, err = svc.DeleteAlarms(&cloudwatch.DeleteAlarmsInput{
-		AlarmNames: []*string{aws.String(fmt.Sprintf("Alarm-RDS-%s", dbClusterName))},
+		AlarmNames: alarmNames,
 	})
 	if err != nil {
-		log.WithError(err).Errorln("Error deleting aws cloudwatch alarm")
+		logger.WithError(err).WithField("alarmNames", aws.StringValueSlice(alarmNames)).Error("Error deleting aws cloudwatch alarm")
 		return err
 	}
 
@@ -164,7 +247,7 @@ func deleteCloudWatchAlarm(dbClusterName string) error {
 }
 
 func listRDS() error {
-	sess, err := session.NewSession(&aws.Config{})
+	sess, err := awsconfig.NewSession()
 	if err != nil {
 		log.WithError(err).Errorln("Error creating aws session")
 		return err
@@ -181,8 +264,8 @@ func listRDS() error {
 	for _, dbCluster := range result.DBClusters {
 		// filtering the rds multitenant
 		if !strings.Contains(*dbCluster.DBClusterIdentifier, "rds-cluster-multitenant-") {
-			log.Infof("Creating CloudWatch Alarm for %+v\n", *dbCluster.DBClusterIdentifier)
-			err = createCloudWatchAlarm(*dbCluster.DBClusterIdentifier)
+			log.WithField("dbClusterIdentifier", *dbCluster.DBClusterIdentifier).Info("Creating CloudWatch Alarm")
+			err = createCloudWatchAlarm(*dbCluster.DBClusterIdentifier, "")
 			if err != nil {
 				return nil
 			}
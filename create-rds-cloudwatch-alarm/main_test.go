@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerLogFields(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	handler(context.Background(), events.CloudWatchEvent{
+		Source:     serviceEventSource,
+		DetailType: "AWS API Call via CloudTrail",
+		Detail: json.RawMessage(`{
+			"eventName": "CreateDBInstance",
+			"eventSource": "rds.amazonaws.com",
+			"userIdentity": {"arn": "arn:aws:iam::123:user/jane"},
+			"requestParameters": {"dBClusterIdentifier": "test-cluster"}
+		}`),
+	})
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, "Skipping the creation of CloudWatch Alarm", entry.Message)
+	assert.Equal(t, "CreateDBInstance", entry.Data["eventName"])
+	assert.Equal(t, "rds.amazonaws.com", entry.Data["eventSource"])
+	assert.Equal(t, "test-cluster", entry.Data["dbClusterIdentifier"])
+	assert.Equal(t, "arn:aws:iam::123:user/jane", entry.Data["requester"])
+}
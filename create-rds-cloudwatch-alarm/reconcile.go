@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/awsconfig"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// rdsAlarmPrefix is the prefix of the alarm names, followed by the
+	// cluster identifier.
+	rdsAlarmPrefix = "Alarm-RDS-"
+
+	// deleteAlarmsBatchSize is the maximum number of alarms DeleteAlarms
+	// accepts in a single call.
+	deleteAlarmsBatchSize = 100
+)
+
+// reconcileMode reports whether RECONCILE is set to true, in which case a
+// manual run deletes the alarms of clusters that no longer exist instead of
+// running the backfill scan.
+func reconcileMode() bool {
+	return strings.EqualFold(os.Getenv("RECONCILE"), "true")
+}
+
+// reconcileDryRun reports whether the reconciliation only logs the orphaned
+// alarms. Alarms are only deleted when RECONCILE_DRY_RUN is set to false.
+func reconcileDryRun() bool {
+	return !strings.EqualFold(os.Getenv("RECONCILE_DRY_RUN"), "false")
+}
+
+// runReconcile creates the AWS clients and reconciles the alarms.
+func runReconcile() error {
+	sess, err := awsconfig.NewSession()
+	if err != nil {
+		log.WithError(err).Errorln("Error creating aws session")
+		return err
+	}
+
+	_, err = reconcileAlarms(cloudwatch.New(sess), rds.New(sess), reconcileDryRun())
+	return err
+}
+
+// reconcileAlarms finds the cluster alarms whose cluster no longer exists,
+// e.g. because its deletion event was missed, and deletes them unless dryRun
+// is set. Alarms of excluded clusters are left alone. It returns the names of
+// the orphaned alarms.
+func reconcileAlarms(svcCloudWatch cloudwatchiface.CloudWatchAPI, svcRDS rdsiface.RDSAPI, dryRun bool) ([]string, error) {
+	existing := map[string]bool{}
+	err := svcRDS.DescribeDBClustersPages(&rds.DescribeDBClustersInput{}, func(page *rds.DescribeDBClustersOutput, _ bool) bool {
+		for _, dbCluster := range page.DBClusters {
+			existing[aws.StringValue(dbCluster.DBClusterIdentifier)] = true
+		}
+		return true
+	})
+	if err != nil {
+		log.WithError(err).Errorln("Failed to list the RDS clusters")
+		return nil, err
+	}
+
+	var orphans []string
+	err = svcCloudWatch.DescribeAlarmsPages(&cloudwatch.DescribeAlarmsInput{
+		AlarmNamePrefix: aws.String(rdsAlarmPrefix),
+	}, func(page *cloudwatch.DescribeAlarmsOutput, _ bool) bool {
+		for _, alarm := range page.MetricAlarms {
+			alarmName := aws.StringValue(alarm.AlarmName)
+			dbClusterIdentifier := alarmCluster(alarm)
+			if dbClusterIdentifier == "" || existing[dbClusterIdentifier] || clusterExcluded(dbClusterIdentifier) {
+				continue
+			}
+			log.WithFields(log.Fields{
+				"alarmName":           alarmName,
+				"dbClusterIdentifier": dbClusterIdentifier,
+				"dryRun":              dryRun,
+			}).Info("Found CloudWatch Alarm of a deleted cluster")
+			orphans = append(orphans, alarmName)
+		}
+		return true
+	})
+	if err != nil {
+		log.WithError(err).Errorln("Error listing aws cloudwatch alarms")
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{"orphans": len(orphans), "dryRun": dryRun}).Info("Reconciled CloudWatch Alarms")
+	if dryRun {
+		return orphans, nil
+	}
+
+	for start := 0; start < len(orphans); start += deleteAlarmsBatchSize {
+		end := min(start+deleteAlarmsBatchSize, len(orphans))
+		_, err = svcCloudWatch.DeleteAlarms(&cloudwatch.DeleteAlarmsInput{
+			AlarmNames: aws.StringSlice(orphans[start:end]),
+		})
+		if err != nil {
+			log.WithError(err).Errorln("Error deleting aws cloudwatch alarms")
+			return orphans, err
+		}
+	}
+
+	return orphans, nil
+}
+
+// alarmCluster returns the cluster an alarm watches, from its
+// DBClusterIdentifier dimension, or from its name for alarms without one.
+func alarmCluster(alarm *cloudwatch.MetricAlarm) string {
+	for _, dimension := range alarm.Dimensions {
+		if aws.StringValue(dimension.Name) == "DBClusterIdentifier" {
+			return aws.StringValue(dimension.Value)
+		}
+	}
+	return strings.TrimPrefix(aws.StringValue(alarm.AlarmName), rdsAlarmPrefix)
+}
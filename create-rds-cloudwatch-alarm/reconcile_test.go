@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The pages are split in two to exercise the pagination.
+
+func (f *fakeCloudWatch) DescribeAlarmsPages(_ *cloudwatch.DescribeAlarmsInput, fn func(*cloudwatch.DescribeAlarmsOutput, bool) bool) error {
+	f.calls = append(f.calls, "DescribeAlarmsPages")
+	half := len(f.alarms) / 2
+	if fn(&cloudwatch.DescribeAlarmsOutput{MetricAlarms: f.alarms[:half]}, false) {
+		fn(&cloudwatch.DescribeAlarmsOutput{MetricAlarms: f.alarms[half:]}, true)
+	}
+	return nil
+}
+
+func (f *fakeRDS) DescribeDBClustersPages(_ *rds.DescribeDBClustersInput, fn func(*rds.DescribeDBClustersOutput, bool) bool) error {
+	f.calls = append(f.calls, "DescribeDBClustersPages")
+	half := len(f.clusters) / 2
+	if fn(&rds.DescribeDBClustersOutput{DBClusters: f.clusters[:half]}, false) {
+		fn(&rds.DescribeDBClustersOutput{DBClusters: f.clusters[half:]}, true)
+	}
+	return nil
+}
+
+func reconcileFakes() (*fakeCloudWatch, *fakeRDS) {
+	var alarms []*cloudwatch.MetricAlarm
+	for _, name := range []string{
+		"Alarm-RDS-live-1",
+		"Alarm-RDS-gone-1",
+		"Alarm-RDS-live-2",
+		"Alarm-RDS-gone-2",
+		"Alarm-RDS-rds-cluster-multitenant-gone",
+		"Alarm-RDS-test-gone",
+	} {
+		alarms = append(alarms, &cloudwatch.MetricAlarm{AlarmName: aws.String(name)})
+	}
+
+	return &fakeCloudWatch{alarms: alarms}, &fakeRDS{clusters: []*rds.DBCluster{
+		{DBClusterIdentifier: aws.String("live-1")},
+		{DBClusterIdentifier: aws.String("other")},
+		{DBClusterIdentifier: aws.String("live-2")},
+	}}
+}
+
+func TestReconcileAlarms(t *testing.T) {
+	svcCloudWatch, svcRDS := reconcileFakes()
+
+	orphans, err := reconcileAlarms(svcCloudWatch, svcRDS, false)
+	require.NoError(t, err)
+
+	expected := []string{"Alarm-RDS-gone-1", "Alarm-RDS-gone-2"}
+	assert.Equal(t, expected, orphans)
+	assert.Equal(t, expected, svcCloudWatch.deleted)
+}
+
+func TestReconcileAlarmsDryRun(t *testing.T) {
+	svcCloudWatch, svcRDS := reconcileFakes()
+
+	orphans, err := reconcileAlarms(svcCloudWatch, svcRDS, true)
+	require.NoError(t, err)
+
+	assert.Len(t, orphans, 2)
+	assert.NotContains(t, svcCloudWatch.calls, "DeleteAlarms")
+}
+
+func TestClusterExcluded(t *testing.T) {
+	assert.True(t, clusterExcluded("rds-cluster-multitenant-1234"))
+	assert.True(t, clusterExcluded("test-cluster"))
+	assert.False(t, clusterExcluded("rds-cluster-abc"))
+}
@@ -0,0 +1,11 @@
+package main
+
+import "github.com/aws/aws-sdk-go/service/cloudwatch"
+
+const databaseConnectionsMetric = "DatabaseConnections"
+
+// defaultStatistics holds the statistic used for each metric unless it is
+// overridden through METRIC_STATISTICS.
+var defaultStatistics = map[string]string{
+	databaseConnectionsMetric: cloudwatch.StatisticAverage,
+}
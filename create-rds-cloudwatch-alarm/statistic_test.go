@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMetricAlarmInputStatistic(t *testing.T) {
+	t.Setenv("METRIC_STATISTICS", `{"DatabaseConnections": "Maximum"}`)
+
+	input, err := newMetricAlarmInput("my-cluster")
+	require.NoError(t, err)
+	assert.Equal(t, cloudwatch.StatisticMaximum, *input.Statistic)
+	assert.Equal(t, databaseConnectionsMetric, *input.MetricName)
+}
+
+func TestNewMetricAlarmInputExtendedStatistic(t *testing.T) {
+	t.Setenv("METRIC_STATISTICS", "")
+	t.Setenv("METRIC_EXTENDED_STATISTICS", `{"DatabaseConnections": "p99"}`)
+
+	input, err := newMetricAlarmInput("my-cluster")
+	require.NoError(t, err)
+	assert.Nil(t, input.Statistic)
+	assert.Equal(t, "p99", *input.ExtendedStatistic)
+}
+
+func TestNewMetricAlarmInputExtendedStatisticErrors(t *testing.T) {
+	testCases := []struct {
+		description string
+		statistics  string
+		extended    string
+	}{
+		{"both statistic and extended statistic", `{"DatabaseConnections": "Maximum"}`, `{"DatabaseConnections": "p99"}`},
+		{"invalid percentile", "", `{"DatabaseConnections": "p999"}`},
+		{"invalid json", "", `{invalid`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			t.Setenv("METRIC_STATISTICS", tc.statistics)
+			t.Setenv("METRIC_EXTENDED_STATISTICS", tc.extended)
+
+			_, err := newMetricAlarmInput("my-cluster")
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+)
+
+const createdByTagKey = "CreatedBy"
+
+// tagCreatedBy tags the alarm with the ARN of the identity whose CloudTrail
+// event led to its creation. Manual runs have no requester and are left
+// untagged. CloudWatch only applies tags when the alarm is first created.
+func tagCreatedBy(input *cloudwatch.PutMetricAlarmInput, requesterArn string) {
+	if requesterArn == "" {
+		return
+	}
+
+	input.Tags = append(input.Tags, &cloudwatch.Tag{
+		Key:   aws.String(createdByTagKey),
+		Value: aws.String(requesterArn),
+	})
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreatedByTag(t *testing.T) {
+	t.Setenv("METRIC_STATISTICS", "")
+	t.Setenv("METRIC_EXTENDED_STATISTICS", "")
+
+	var detail Detail
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"userIdentity": {"arn": "arn:aws:sts::123456789012:assumed-role/provisioner/session"},
+		"eventName": "CreateDBInstance",
+		"requestParameters": {"dBClusterIdentifier": "my-cluster"}
+	}`), &detail))
+
+	input, err := newMetricAlarmInput(detail.RequestParameters.DBClusterIdentifier)
+	require.NoError(t, err)
+	tagCreatedBy(input, detail.UserIdentity.Arn)
+	require.Len(t, input.Tags, 1)
+	assert.Equal(t, createdByTagKey, *input.Tags[0].Key)
+	assert.Equal(t, "arn:aws:sts::123456789012:assumed-role/provisioner/session", *input.Tags[0].Value)
+
+	input, err = newMetricAlarmInput("my-cluster")
+	require.NoError(t, err)
+	tagCreatedBy(input, "")
+	assert.Empty(t, input.Tags)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// serviceEventSource is the source of the CloudTrail events the lambda
+// creates and deletes alarms for.
+const serviceEventSource = "aws.rds"
+
+// scheduledEventDetailType is the detail type of EventBridge scheduled rules.
+const scheduledEventDetailType = "Scheduled Event"
+
+type triggerKind int
+
+const (
+	// triggerService is a CloudTrail event of the watched service.
+	triggerService triggerKind = iota
+	// triggerScheduled is an EventBridge scheduled rule.
+	triggerScheduled
+	// triggerManual is an invocation with an empty event, e.g. from the
+	// console or the CLI.
+	triggerManual
+	// triggerUnknown is any other event.
+	triggerUnknown
+)
+
+func (t triggerKind) String() string {
+	switch t {
+	case triggerService:
+		return "service"
+	case triggerScheduled:
+		return "scheduled"
+	case triggerManual:
+		return "manual"
+	default:
+		return "unknown"
+	}
+}
+
+// classifyTrigger tells what invoked the lambda. Scheduled and manual
+// triggers run the backfill scan, unknown ones are ignored.
+func classifyTrigger(event events.CloudWatchEvent) triggerKind {
+	switch {
+	case event.Source == serviceEventSource:
+		return triggerService
+	case event.Source == "aws.events" && event.DetailType == scheduledEventDetailType:
+		return triggerScheduled
+	case event.Source == "" && event.DetailType == "":
+		return triggerManual
+	default:
+		return triggerUnknown
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/cloudwatch/cloudwatchiface"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The fakes embed the client interfaces, so any call they do not implement,
+// mutating ones included, panics and fails the test.
+
+type fakeCloudWatch struct {
+	cloudwatchiface.CloudWatchAPI
+	calls   []string
+	err     error
+	alarms  []*cloudwatch.MetricAlarm
+	deleted []string
+}
+
+func (f *fakeCloudWatch) DescribeAlarms(input *cloudwatch.DescribeAlarmsInput) (*cloudwatch.DescribeAlarmsOutput, error) {
+	f.calls = append(f.calls, "DescribeAlarms")
+	output := &cloudwatch.DescribeAlarmsOutput{}
+	for _, alarm := range f.alarms {
+		for _, name := range input.AlarmNames {
+			if aws.StringValue(name) == aws.StringValue(alarm.AlarmName) {
+				output.MetricAlarms = append(output.MetricAlarms, alarm)
+			}
+		}
+	}
+	return output, f.err
+}
+
+func (f *fakeCloudWatch) PutMetricAlarm(*cloudwatch.PutMetricAlarmInput) (*cloudwatch.PutMetricAlarmOutput, error) {
+	f.calls = append(f.calls, "PutMetricAlarm")
+	return &cloudwatch.PutMetricAlarmOutput{}, nil
+}
+
+func (f *fakeCloudWatch) DeleteAlarms(input *cloudwatch.DeleteAlarmsInput) (*cloudwatch.DeleteAlarmsOutput, error) {
+	f.calls = append(f.calls, "DeleteAlarms")
+	f.deleted = append(f.deleted, aws.StringValueSlice(input.AlarmNames)...)
+	return &cloudwatch.DeleteAlarmsOutput{}, nil
+}
+
+type fakeRDS struct {
+	rdsiface.RDSAPI
+	calls    []string
+	clusters []*rds.DBCluster
+}
+
+func (f *fakeRDS) DescribeDBClusters(*rds.DescribeDBClustersInput) (*rds.DescribeDBClustersOutput, error) {
+	f.calls = append(f.calls, "DescribeDBClusters")
+	return &rds.DescribeDBClustersOutput{}, nil
+}
+
+func TestValidatePermissions(t *testing.T) {
+	svcCloudWatch := &fakeCloudWatch{}
+	svcRDS := &fakeRDS{}
+
+	require.NoError(t, validatePermissions(svcCloudWatch, svcRDS))
+	assert.Equal(t, []string{"DescribeAlarms"}, svcCloudWatch.calls)
+	assert.Equal(t, []string{"DescribeDBClusters"}, svcRDS.calls)
+}
+
+func TestValidatePermissionsFailure(t *testing.T) {
+	svcCloudWatch := &fakeCloudWatch{err: errors.New("AccessDenied")}
+	svcRDS := &fakeRDS{}
+
+	err := validatePermissions(svcCloudWatch, svcRDS)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cloudwatch:DescribeAlarms")
+	assert.NotContains(t, svcCloudWatch.calls, "PutMetricAlarm")
+	assert.NotContains(t, svcCloudWatch.calls, "DeleteAlarms")
+	assert.Len(t, svcRDS.calls, 1)
+}
@@ -2,16 +2,23 @@
 package main
 
 import (
+	"context"
+
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/awsconfig"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/lock"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/summary"
 
 	"github.com/pkg/errors"
 
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -23,30 +30,167 @@ func main() {
 	lambda.Start(handler)
 }
 
-func handler() error {
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String(os.Getenv("REGION"))},
-	)
+// minAgeHours is the age above which unused AMIs and orphaned snapshots are
+// cleaned up.
+const minAgeHours = 730
+
+// defaultMaxConcurrency is the number of AMIs cleaned up in parallel when
+// MAX_CONCURRENCY is not set.
+const defaultMaxConcurrency = 5
+
+// ec2API is the subset of the EC2 API used by deckhand.
+type ec2API interface {
+	DescribeImages(input *ec2.DescribeImagesInput) (*ec2.DescribeImagesOutput, error)
+	DescribeInstances(input *ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error)
+	DescribeSnapshots(input *ec2.DescribeSnapshotsInput) (*ec2.DescribeSnapshotsOutput, error)
+	DeregisterImage(input *ec2.DeregisterImageInput) (*ec2.DeregisterImageOutput, error)
+	DeleteSnapshot(input *ec2.DeleteSnapshotInput) (*ec2.DeleteSnapshotOutput, error)
+}
+
+// cleanupResult summarizes a cleanup run. Errors holds the per-AMI and
+// per-snapshot failures that did not stop the run.
+type cleanupResult struct {
+	DeregisteredImages []string
+	DeletedSnapshots   []string
+	// OrphanedSnapshots are the deleted snapshots whose AMI was deregistered
+	// outside of deckhand. They are also listed in DeletedSnapshots.
+	OrphanedSnapshots []string
+	InUseImages       []string
+	RetainedImages    []string
+	Errors            []error
+	// ReclaimedGB is the total volume size of the deleted snapshots.
+	ReclaimedGB int64
+
+	mu         sync.Mutex
+	imageNames map[string]string
+}
+
+func (r *cleanupResult) addDeregisteredImage(image *ec2.Image) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.DeregisteredImages = append(r.DeregisteredImages, *image.ImageId)
+	if r.imageNames == nil {
+		r.imageNames = map[string]string{}
+	}
+	r.imageNames[*image.ImageId] = aws.StringValue(image.Name)
+}
+
+func (r *cleanupResult) addDeletedSnapshot(snapshot *ec2.Snapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.DeletedSnapshots = append(r.DeletedSnapshots, *snapshot.SnapshotId)
+	r.ReclaimedGB += aws.Int64Value(snapshot.VolumeSize)
+}
+
+func (r *cleanupResult) addError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Errors = append(r.Errors, err)
+}
+
+// sort orders the result, which concurrent cleanup fills in any order.
+func (r *cleanupResult) sort() {
+	sort.Strings(r.DeregisteredImages)
+	sort.Strings(r.DeletedSnapshots)
+	sort.Strings(r.OrphanedSnapshots)
+	sort.Slice(r.Errors, func(i, j int) bool {
+		return r.Errors[i].Error() < r.Errors[j].Error()
+	})
+}
+
+// err aggregates the errors of the run into a single error.
+func (r *cleanupResult) err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+
+	messages := make([]string, 0, len(r.Errors))
+	for _, err := range r.Errors {
+		messages = append(messages, err.Error())
+	}
+
+	return errors.Errorf("%d cleanup operation(s) failed: %s", len(r.Errors), strings.Join(messages, "; "))
+}
+
+func (r *cleanupResult) logFields() log.Fields {
+	return log.Fields{
+		"deregistered_images": len(r.DeregisteredImages),
+		"deleted_snapshots":   len(r.DeletedSnapshots),
+		"orphaned_snapshots":  len(r.OrphanedSnapshots),
+		"in_use_images":       len(r.InUseImages),
+		"retained_images":     len(r.RetainedImages),
+		"reclaimed_gb":        r.ReclaimedGB,
+		"errors":              len(r.Errors),
+	}
+}
+
+// summarize adds the counts of the run to the invocation summary.
+func (r *cleanupResult) summarize(invocation *summary.Summary) {
+	invocation.Add("deregistered_images", len(r.DeregisteredImages))
+	invocation.Add("deleted_snapshots", len(r.DeletedSnapshots))
+	invocation.Add("orphaned_snapshots", len(r.OrphanedSnapshots))
+	invocation.Add("in_use_images", len(r.InUseImages))
+	invocation.Add("retained_images", len(r.RetainedImages))
+	invocation.Failed(len(r.Errors))
+}
+
+func handler() (err error) {
+	invocation := summary.Start("deckhand")
+	defer invocation.Log(&err)
+
+	sess, err := awsconfig.NewSession()
 	if err != nil {
 		log.WithError(err).Error("AWS session failed")
 		return err
 	}
+
+	lease := lock.FromEnv(sess, "deckhand")
+	if err = lease.Acquire(context.Background()); err != nil {
+		if errors.Is(err, lock.ErrHeld) {
+			log.Warn("Another deckhand run is in progress, exiting")
+			return nil
+		}
+		log.WithError(err).Error("Failed to acquire the deckhand lock")
+		return err
+	}
+	defer func() {
+		if releaseErr := lease.Release(context.Background()); releaseErr != nil {
+			log.WithError(releaseErr).Warn("Failed to release the deckhand lock")
+		}
+	}()
+
 	svc := ec2.New(sess)
 	uniqueUsedImages, err := getUniqueUsedImages(svc)
 	if err != nil {
 		log.WithError(err).Error("Failed to get unique used AMIs")
 		return err
 	}
-	err = deleteAMIs(svc, uniqueUsedImages)
-
+	result, err := deleteAMIs(svc, uniqueUsedImages)
 	if err != nil {
 		log.WithError(err).Error("Failed to delete AMIs")
 		return err
 	}
-	return nil
+
+	log.WithFields(result.logFields()).Info("AMI cleanup finished")
+	result.summarize(invocation)
+	if bucket := os.Getenv("REPORT_BUCKET"); bucket != "" {
+		err = uploadReport(s3.New(sess), bucket, os.Getenv("REPORT_KEY_PREFIX"), result.report(time.Now()))
+		if err != nil {
+			log.WithError(err).Error("Failed to upload the cleanup report")
+		}
+	}
+	if cleanupErr := result.err(); cleanupErr != nil {
+		log.WithError(cleanupErr).Error("Some AMIs or snapshots could not be cleaned up")
+		return cleanupErr
+	}
+	return err
 }
 
-func deleteAMIs(svc *ec2.EC2, uniqueUsedImages []string) error {
+// deleteAMIs deregisters the old unused AMIs and deletes their snapshots. A
+// failure for one AMI or snapshot is recorded in the result and the remaining
+// ones are still processed; the returned error is only set when the cleanup
+// could not start.
+func deleteAMIs(svc ec2API, uniqueUsedImages []string) (*cleanupResult, error) {
 	imagesInput := &ec2.DescribeImagesInput{
 		Owners: []*string{
 			aws.String(os.Getenv("OWNER_ID")),
@@ -60,56 +204,125 @@ func deleteAMIs(svc *ec2.EC2, uniqueUsedImages []string) error {
 	}
 	snapshots, err := getAllSnapshots(os.Getenv("OWNER_ID"), svc)
 	if err != nil {
-		return errors.Wrap(err, "Failed to get snapshots")
+		return nil, errors.Wrap(err, "Failed to get snapshots")
 	}
 	allImages, err := svc.DescribeImages(imagesInput)
 	if err != nil {
-		return errors.Wrap(err, "Failed to describe images")
+		return nil, errors.Wrap(err, "Failed to describe images")
 	}
-	oldImages, err := filterImagesByDateRange(allImages.Images, 730)
+	// Orphaned snapshots are found against every owned AMI, whatever its
+	// name, as listed before this run deregisters any.
+	ownedImages, err := svc.DescribeImages(&ec2.DescribeImagesInput{
+		Owners: []*string{aws.String(os.Getenv("OWNER_ID"))},
+	})
 	if err != nil {
-		return errors.Wrap(err, "Failed to filter images by date range")
+		return nil, errors.Wrap(err, "Failed to describe owned images")
 	}
-	dryRun := false
+	oldImages, err := filterImagesByDateRange(allImages.Images, minAgeHours)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to filter images by date range")
+	}
+	result := &cleanupResult{}
+	images := make(chan *ec2.Image)
+	var wg sync.WaitGroup
+	for w := 0; w < maxConcurrency(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for image := range images {
+				cleanupImage(svc, image, snapshots, result)
+			}
+		}()
+	}
+
+	retainKey, retainValue := retainTag()
 	for _, i := range oldImages {
+		if imageRetained(i, retainKey, retainValue) {
+			log.Info("Image " + *i.ImageId + " is tagged " + retainKey + "=" + retainValue + " and retained.")
+			result.RetainedImages = append(result.RetainedImages, *i.ImageId)
+			continue
+		}
 		imageForCleanup := contains(uniqueUsedImages, *i.ImageId)
 		if imageForCleanup != "" {
-			log.Info(*i.ImageId + ": De-registering AMI named \"" + *i.Name + "\"...")
-			cleanupImageInput := &ec2.DeregisterImageInput{
-				ImageId: &imageForCleanup,
-				DryRun:  &dryRun,
-			}
-			_, err := svc.DeregisterImage(cleanupImageInput)
-			if err != nil {
-				return errors.Wrapf(err, "Failed to deregister AMI %s", *i.ImageId)
-			}
-			var snapshotIDs []string
-			for _, snapshot := range snapshots {
-				if strings.Contains(*snapshot.Description, *i.ImageId) {
-					snapshotIDs = append(snapshotIDs, *snapshot.SnapshotId)
-				}
-			}
-			log.Info(*i.ImageId + ": Found " + strconv.Itoa(len(snapshotIDs)) + " snapshot(s) to delete")
-			for _, snapshotID := range snapshotIDs {
-				log.Info(*i.ImageId + ": Deleting snapshot " + snapshotID + "...")
-				_, deleteErr := svc.DeleteSnapshot(&ec2.DeleteSnapshotInput{
-					DryRun:     &dryRun,
-					SnapshotId: &snapshotID,
-				})
-
-				if deleteErr != nil {
-					return errors.Wrapf(err, "Failed to delete Snapshot %s", snapshotID)
-				}
-			}
+			images <- i
 		} else {
 			log.Info("Image " + *i.ImageId + " is used on a current running instance.")
+			result.InUseImages = append(result.InUseImages, *i.ImageId)
 		}
 
 	}
-	return nil
+	close(images)
+	wg.Wait()
+
+	deleteOrphanedSnapshots(svc, snapshots, ownedImages.Images, time.Now(), result)
+
+	result.sort()
+	return result, nil
+}
+
+// cleanupImage deregisters an unused AMI and deletes its snapshots, recording
+// the outcome in result. It is safe to call concurrently.
+func cleanupImage(svc ec2API, image *ec2.Image, snapshots []*ec2.Snapshot, result *cleanupResult) {
+	if dryRunEnabled() {
+		log.Info(*image.ImageId + ": Dry run, not de-registering AMI named \"" + *image.Name + "\"")
+		return
+	}
+	dryRun := false
+	log.Info(*image.ImageId + ": De-registering AMI named \"" + *image.Name + "\"...")
+	cleanupImageInput := &ec2.DeregisterImageInput{
+		ImageId: image.ImageId,
+		DryRun:  &dryRun,
+	}
+	_, err := svc.DeregisterImage(cleanupImageInput)
+	if err != nil {
+		// The AMI is still registered, so its snapshots are left alone.
+		log.WithError(err).Error(*image.ImageId + ": Failed to deregister AMI")
+		result.addError(errors.Wrapf(err, "Failed to deregister AMI %s", *image.ImageId))
+		return
+	}
+	result.addDeregisteredImage(image)
+	var imageSnapshots []*ec2.Snapshot
+	for _, snapshot := range snapshots {
+		if strings.Contains(*snapshot.Description, *image.ImageId) {
+			imageSnapshots = append(imageSnapshots, snapshot)
+		}
+	}
+	log.Info(*image.ImageId + ": Found " + strconv.Itoa(len(imageSnapshots)) + " snapshot(s) to delete")
+	for _, snapshot := range imageSnapshots {
+		snapshotID := *snapshot.SnapshotId
+		log.Info(*image.ImageId + ": Deleting snapshot " + snapshotID + "...")
+		_, deleteErr := svc.DeleteSnapshot(&ec2.DeleteSnapshotInput{
+			DryRun:     &dryRun,
+			SnapshotId: &snapshotID,
+		})
+
+		if deleteErr != nil {
+			log.WithError(deleteErr).Error(*image.ImageId + ": Failed to delete snapshot " + snapshotID)
+			result.addError(errors.Wrapf(deleteErr, "Failed to delete Snapshot %s", snapshotID))
+			continue
+		}
+		result.addDeletedSnapshot(snapshot)
+	}
+}
+
+// maxConcurrency returns how many AMIs are cleaned up in parallel, from
+// MAX_CONCURRENCY.
+func maxConcurrency() int {
+	value := os.Getenv("MAX_CONCURRENCY")
+	if value == "" {
+		return defaultMaxConcurrency
+	}
+
+	concurrency, err := strconv.Atoi(value)
+	if err != nil || concurrency < 1 {
+		log.Warnf("Invalid MAX_CONCURRENCY %q, using %d", value, defaultMaxConcurrency)
+		return defaultMaxConcurrency
+	}
+
+	return concurrency
 }
 
-func getUniqueUsedImages(svc *ec2.EC2) ([]string, error) {
+func getUniqueUsedImages(svc ec2API) ([]string, error) {
 	instancesInput := &ec2.DescribeInstancesInput{}
 	encountered := make(map[string]bool)
 	runningInstances, err := svc.DescribeInstances(instancesInput)
@@ -160,7 +373,7 @@ func filterImagesByDateRange(images []*ec2.Image, olderThanHours float64) ([]*ec
 	return filteredAmis, nil
 }
 
-func getAllSnapshots(awsAccountID string, svc *ec2.EC2) ([]*ec2.Snapshot, error) {
+func getAllSnapshots(awsAccountID string, svc ec2API) ([]*ec2.Snapshot, error) {
 	var noSnapshots []*ec2.Snapshot
 
 	respDscrSnapshots, err := svc.DescribeSnapshots(&ec2.DescribeSnapshotsInput{
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEC2 serves fixed images and snapshots and records the cleanup calls.
+type fakeEC2 struct {
+	images             []*ec2.Image
+	snapshots          []*ec2.Snapshot
+	failDeregister     map[string]bool
+	failDeleteSnapshot map[string]bool
+	// deregisterDelay keeps DeregisterImage calls in flight long enough to
+	// observe the concurrency.
+	deregisterDelay time.Duration
+
+	mu               sync.Mutex
+	inFlight         int
+	maxInFlight      int
+	deregistered     []string
+	deletedSnapshots []string
+}
+
+func (f *fakeEC2) DescribeImages(*ec2.DescribeImagesInput) (*ec2.DescribeImagesOutput, error) {
+	return &ec2.DescribeImagesOutput{Images: f.images}, nil
+}
+
+func (f *fakeEC2) DescribeInstances(*ec2.DescribeInstancesInput) (*ec2.DescribeInstancesOutput, error) {
+	return &ec2.DescribeInstancesOutput{}, nil
+}
+
+func (f *fakeEC2) DescribeSnapshots(*ec2.DescribeSnapshotsInput) (*ec2.DescribeSnapshotsOutput, error) {
+	return &ec2.DescribeSnapshotsOutput{Snapshots: f.snapshots}, nil
+}
+
+func (f *fakeEC2) DeregisterImage(input *ec2.DeregisterImageInput) (*ec2.DeregisterImageOutput, error) {
+	f.mu.Lock()
+	f.inFlight++
+	if f.inFlight > f.maxInFlight {
+		f.maxInFlight = f.inFlight
+	}
+	f.mu.Unlock()
+
+	time.Sleep(f.deregisterDelay)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inFlight--
+	if f.failDeregister[*input.ImageId] {
+		return nil, errors.New("UnauthorizedOperation")
+	}
+	f.deregistered = append(f.deregistered, *input.ImageId)
+	return &ec2.DeregisterImageOutput{}, nil
+}
+
+func (f *fakeEC2) DeleteSnapshot(input *ec2.DeleteSnapshotInput) (*ec2.DeleteSnapshotOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failDeleteSnapshot[*input.SnapshotId] {
+		return nil, errors.New("InvalidSnapshot.InUse")
+	}
+	f.deletedSnapshots = append(f.deletedSnapshots, *input.SnapshotId)
+	return &ec2.DeleteSnapshotOutput{}, nil
+}
+
+func oldImage(id string) *ec2.Image {
+	return &ec2.Image{
+		ImageId:      aws.String(id),
+		Name:         aws.String("mattermost-cloud-" + id),
+		CreationDate: aws.String(time.Now().AddDate(-1, 0, 0).Format(time.RFC3339Nano)),
+	}
+}
+
+func snapshot(id, imageID string) *ec2.Snapshot {
+	return &ec2.Snapshot{
+		SnapshotId:  aws.String(id),
+		Description: aws.String("Created by CreateImage for " + imageID),
+	}
+}
+
+func TestDeleteAMIsContinuesAfterFailure(t *testing.T) {
+	svc := &fakeEC2{
+		images: []*ec2.Image{oldImage("ami-1"), oldImage("ami-2"), oldImage("ami-3"), oldImage("ami-used")},
+		snapshots: []*ec2.Snapshot{
+			snapshot("snap-1", "ami-1"),
+			snapshot("snap-2", "ami-2"),
+			snapshot("snap-3", "ami-3"),
+		},
+		failDeregister: map[string]bool{"ami-2": true},
+	}
+
+	result, err := deleteAMIs(svc, []string{"ami-used"})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"ami-1", "ami-3"}, svc.deregistered)
+	assert.ElementsMatch(t, []string{"snap-1", "snap-3"}, svc.deletedSnapshots)
+	assert.Equal(t, []string{"ami-1", "ami-3"}, result.DeregisteredImages)
+	assert.Equal(t, []string{"snap-1", "snap-3"}, result.DeletedSnapshots)
+	assert.Equal(t, []string{"ami-used"}, result.InUseImages)
+	require.Len(t, result.Errors, 1)
+
+	aggregated := result.err()
+	require.Error(t, aggregated)
+	assert.Contains(t, aggregated.Error(), "ami-2")
+}
+
+func TestDeleteAMIsSnapshotFailure(t *testing.T) {
+	svc := &fakeEC2{
+		images:             []*ec2.Image{oldImage("ami-1")},
+		snapshots:          []*ec2.Snapshot{snapshot("snap-1", "ami-1"), snapshot("snap-2", "ami-1")},
+		failDeleteSnapshot: map[string]bool{"snap-1": true},
+	}
+
+	result, err := deleteAMIs(svc, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"snap-2"}, result.DeletedSnapshots)
+	require.Error(t, result.err())
+	assert.Contains(t, result.err().Error(), "snap-1")
+}
+
+func TestCleanupResultNoErrors(t *testing.T) {
+	assert.NoError(t, (&cleanupResult{DeregisteredImages: []string{"ami-1"}}).err())
+}
+
+func TestDeleteAMIsConcurrencyBound(t *testing.T) {
+	t.Setenv("MAX_CONCURRENCY", "3")
+
+	svc := &fakeEC2{deregisterDelay: 20 * time.Millisecond}
+	var expected []string
+	for i := 0; i < 12; i++ {
+		id := fmt.Sprintf("ami-%02d", i)
+		svc.images = append(svc.images, oldImage(id))
+		expected = append(expected, id)
+	}
+
+	result, err := deleteAMIs(svc, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, expected, result.DeregisteredImages)
+	assert.LessOrEqual(t, svc.maxInFlight, 3)
+	assert.Greater(t, svc.maxInFlight, 1)
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	t.Setenv("MAX_CONCURRENCY", "")
+	assert.Equal(t, defaultMaxConcurrency, maxConcurrency())
+
+	t.Setenv("MAX_CONCURRENCY", "10")
+	assert.Equal(t, 10, maxConcurrency())
+
+	for _, invalid := range []string{"0", "-1", "many"} {
+		t.Setenv("MAX_CONCURRENCY", invalid)
+		assert.Equal(t, defaultMaxConcurrency, maxConcurrency())
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// snapshotImagePattern matches the AMI ID in the description EC2 gives to
+// the snapshots it creates for an AMI, e.g.
+// "Created by CreateImage(i-0123) for ami-0456".
+var snapshotImagePattern = regexp.MustCompile(`\bami-[0-9a-f]+\b`)
+
+// dryRunEnabled reports whether DRY_RUN is set to true, in which case
+// deckhand only logs what it would deregister and delete.
+func dryRunEnabled() bool {
+	return strings.EqualFold(os.Getenv("DRY_RUN"), "true")
+}
+
+// orphanedSnapshots returns the snapshots older than minAgeHours whose
+// description references an AMI that is not among the images, i.e. an AMI
+// deregistered out-of-band.
+func orphanedSnapshots(snapshots []*ec2.Snapshot, images []*ec2.Image, now time.Time) []*ec2.Snapshot {
+	existing := make(map[string]bool, len(images))
+	for _, image := range images {
+		existing[aws.StringValue(image.ImageId)] = true
+	}
+
+	var orphans []*ec2.Snapshot
+	for _, snapshot := range snapshots {
+		imageID := snapshotImagePattern.FindString(aws.StringValue(snapshot.Description))
+		if imageID == "" || existing[imageID] {
+			continue
+		}
+		if snapshot.StartTime == nil || now.Sub(*snapshot.StartTime).Hours() <= minAgeHours {
+			continue
+		}
+		orphans = append(orphans, snapshot)
+	}
+
+	return orphans
+}
+
+// deleteOrphanedSnapshots deletes the orphaned snapshots, recording the
+// outcome in result.
+func deleteOrphanedSnapshots(svc ec2API, snapshots []*ec2.Snapshot, images []*ec2.Image, now time.Time, result *cleanupResult) {
+	orphans := orphanedSnapshots(snapshots, images, now)
+	log.Infof("Found %d orphaned snapshot(s) to delete", len(orphans))
+
+	for _, snapshot := range orphans {
+		logger := log.WithFields(log.Fields{
+			"snapshot":    aws.StringValue(snapshot.SnapshotId),
+			"description": aws.StringValue(snapshot.Description),
+		})
+		if dryRunEnabled() {
+			logger.Info("Dry run, not deleting orphaned snapshot")
+			continue
+		}
+
+		logger.Info("Deleting orphaned snapshot")
+		_, err := svc.DeleteSnapshot(&ec2.DeleteSnapshotInput{SnapshotId: snapshot.SnapshotId})
+		if err != nil {
+			logger.WithError(err).Error("Failed to delete orphaned snapshot")
+			result.addError(errors.Wrapf(err, "Failed to delete orphaned Snapshot %s", aws.StringValue(snapshot.SnapshotId)))
+			continue
+		}
+		result.addDeletedSnapshot(snapshot)
+		result.OrphanedSnapshots = append(result.OrphanedSnapshots, aws.StringValue(snapshot.SnapshotId))
+	}
+}
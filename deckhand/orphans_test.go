@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startedSnapshot(id, imageID string, started time.Time) *ec2.Snapshot {
+	s := snapshot(id, imageID)
+	s.StartTime = aws.Time(started)
+	return s
+}
+
+func TestDeleteAMIsOrphanedSnapshots(t *testing.T) {
+	old := time.Now().AddDate(-1, 0, 0)
+	svc := &fakeEC2{
+		images: []*ec2.Image{oldImage("ami-1"), oldImage("ami-used")},
+		snapshots: []*ec2.Snapshot{
+			startedSnapshot("snap-1", "ami-1", old),
+			startedSnapshot("snap-used", "ami-used", old),
+			startedSnapshot("snap-orphan", "ami-0dead", old),
+			startedSnapshot("snap-recent-orphan", "ami-0dead", time.Now()),
+			{SnapshotId: aws.String("snap-manual"), Description: aws.String("manual backup"), StartTime: aws.Time(old)},
+		},
+	}
+
+	result, err := deleteAMIs(svc, []string{"ami-used"})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"snap-1", "snap-orphan"}, svc.deletedSnapshots)
+	assert.Equal(t, []string{"snap-orphan"}, result.OrphanedSnapshots)
+	assert.Equal(t, []string{"snap-1", "snap-orphan"}, result.DeletedSnapshots)
+}
+
+func TestDeleteAMIsDryRun(t *testing.T) {
+	t.Setenv("DRY_RUN", "true")
+
+	svc := &fakeEC2{
+		images: []*ec2.Image{oldImage("ami-1")},
+		snapshots: []*ec2.Snapshot{
+			startedSnapshot("snap-1", "ami-1", time.Now().AddDate(-1, 0, 0)),
+			startedSnapshot("snap-orphan", "ami-0dead", time.Now().AddDate(-1, 0, 0)),
+		},
+	}
+
+	result, err := deleteAMIs(svc, nil)
+	require.NoError(t, err)
+
+	assert.Empty(t, svc.deregistered)
+	assert.Empty(t, svc.deletedSnapshots)
+	assert.Empty(t, result.OrphanedSnapshots)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// s3API is the subset of the S3 API used to upload the cleanup report.
+type s3API interface {
+	PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error)
+}
+
+// cleanupReport is the JSON record of a cleanup run uploaded to
+// REPORT_BUCKET for audit and cost tracking.
+type cleanupReport struct {
+	Timestamp          time.Time     `json:"timestamp"`
+	DeregisteredImages []reportImage `json:"deregistered_images"`
+	DeletedSnapshots   []string      `json:"deleted_snapshots"`
+	ReclaimedGB        int64         `json:"reclaimed_gb"`
+	SkippedInUseImages []string      `json:"skipped_in_use_images"`
+	RetainedImages     []string      `json:"retained_images"`
+	Errors             []string      `json:"errors"`
+}
+
+type reportImage struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// report returns the report of the run finished at now.
+func (r *cleanupResult) report(now time.Time) cleanupReport {
+	report := cleanupReport{
+		Timestamp:          now.UTC(),
+		DeregisteredImages: []reportImage{},
+		DeletedSnapshots:   append([]string{}, r.DeletedSnapshots...),
+		ReclaimedGB:        r.ReclaimedGB,
+		SkippedInUseImages: append([]string{}, r.InUseImages...),
+		RetainedImages:     append([]string{}, r.RetainedImages...),
+		Errors:             []string{},
+	}
+	for _, imageID := range r.DeregisteredImages {
+		report.DeregisteredImages = append(report.DeregisteredImages, reportImage{ID: imageID, Name: r.imageNames[imageID]})
+	}
+	for _, err := range r.Errors {
+		report.Errors = append(report.Errors, err.Error())
+	}
+
+	return report
+}
+
+// reportKey returns the S3 key of the report, under the prefix and named
+// after the run time.
+func reportKey(prefix string, timestamp time.Time) string {
+	return prefix + "deckhand-" + timestamp.UTC().Format("20060102T150405Z") + ".json"
+}
+
+// uploadReport writes the report as JSON to the bucket.
+func uploadReport(svc s3API, bucket, prefix string, report cleanupReport) error {
+	body, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode the cleanup report")
+	}
+
+	key := reportKey(prefix, report.Timestamp)
+	_, err = svc.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Failed to upload the cleanup report to s3://%s/%s", bucket, key)
+	}
+
+	log.WithField("key", key).Info("Uploaded the cleanup report")
+	return nil
+}
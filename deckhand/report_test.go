@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 records the uploaded objects.
+type fakeS3 struct {
+	err    error
+	inputs []*s3.PutObjectInput
+	bodies []string
+}
+
+func (f *fakeS3) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	body, err := io.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.inputs = append(f.inputs, input)
+	f.bodies = append(f.bodies, string(body))
+	return &s3.PutObjectOutput{}, f.err
+}
+
+func TestUploadReport(t *testing.T) {
+	sized := snapshot("snap-1", "ami-1")
+	sized.VolumeSize = aws.Int64(8)
+	svc := &fakeEC2{
+		images:             []*ec2.Image{oldImage("ami-1"), oldImage("ami-2"), oldImage("ami-used")},
+		snapshots:          []*ec2.Snapshot{sized, snapshot("snap-2", "ami-2")},
+		failDeleteSnapshot: map[string]bool{"snap-2": true},
+	}
+	result, err := deleteAMIs(svc, []string{"ami-used"})
+	require.NoError(t, err)
+
+	svcS3 := &fakeS3{}
+	timestamp := time.Date(2024, 3, 4, 5, 6, 7, 0, time.UTC)
+	require.NoError(t, uploadReport(svcS3, "reports", "deckhand/", result.report(timestamp)))
+
+	require.Len(t, svcS3.inputs, 1)
+	assert.Equal(t, "reports", *svcS3.inputs[0].Bucket)
+	assert.Equal(t, "deckhand/deckhand-20240304T050607Z.json", *svcS3.inputs[0].Key)
+	assert.JSONEq(t, `{
+		"timestamp": "2024-03-04T05:06:07Z",
+		"deregistered_images": [
+			{"id": "ami-1", "name": "mattermost-cloud-ami-1"},
+			{"id": "ami-2", "name": "mattermost-cloud-ami-2"}
+		],
+		"deleted_snapshots": ["snap-1"],
+		"reclaimed_gb": 8,
+		"skipped_in_use_images": ["ami-used"],
+		"retained_images": [],
+		"errors": ["Failed to delete Snapshot snap-2: InvalidSnapshot.InUse"]
+	}`, svcS3.bodies[0])
+}
+
+func TestUploadReportFailure(t *testing.T) {
+	svcS3 := &fakeS3{err: errors.New("AccessDenied")}
+
+	err := uploadReport(svcS3, "reports", "", (&cleanupResult{}).report(time.Unix(0, 0)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "s3://reports/deckhand-19700101T000000Z.json")
+}
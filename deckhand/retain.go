@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultRetainTag is the tag protecting an AMI from deregistration when
+// DECKHAND_RETAIN_TAG is not set.
+const defaultRetainTag = "deckhand:retain=true"
+
+// retainTag returns the key and value of the tag protecting an AMI from
+// deregistration, from DECKHAND_RETAIN_TAG in the key=value form.
+func retainTag() (string, string) {
+	value := os.Getenv("DECKHAND_RETAIN_TAG")
+	if value == "" {
+		value = defaultRetainTag
+	}
+
+	key, tagValue, found := strings.Cut(value, "=")
+	if !found || key == "" {
+		log.Warnf("Invalid DECKHAND_RETAIN_TAG %q, using %q", value, defaultRetainTag)
+		key, tagValue, _ = strings.Cut(defaultRetainTag, "=")
+	}
+
+	return key, tagValue
+}
+
+// imageRetained reports whether the AMI carries the retain tag, in which case
+// it is kept regardless of its age.
+func imageRetained(image *ec2.Image, key, value string) bool {
+	for _, tag := range image.Tags {
+		if aws.StringValue(tag.Key) == key && aws.StringValue(tag.Value) == value {
+			return true
+		}
+	}
+
+	return false
+}
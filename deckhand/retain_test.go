@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func taggedImage(id, key, value string) *ec2.Image {
+	image := oldImage(id)
+	image.Tags = []*ec2.Tag{{Key: aws.String(key), Value: aws.String(value)}}
+	return image
+}
+
+func TestDeleteAMIsRetainTag(t *testing.T) {
+	t.Setenv("DECKHAND_RETAIN_TAG", "")
+
+	svc := &fakeEC2{
+		images: []*ec2.Image{
+			oldImage("ami-1"),
+			taggedImage("ami-retained", "deckhand:retain", "true"),
+			taggedImage("ami-not-retained", "deckhand:retain", "false"),
+		},
+		snapshots: []*ec2.Snapshot{snapshot("snap-retained", "ami-retained")},
+	}
+
+	result, err := deleteAMIs(svc, nil)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"ami-1", "ami-not-retained"}, svc.deregistered)
+	assert.Empty(t, svc.deletedSnapshots)
+	assert.Equal(t, []string{"ami-retained"}, result.RetainedImages)
+}
+
+func TestRetainTag(t *testing.T) {
+	t.Setenv("DECKHAND_RETAIN_TAG", "")
+	key, value := retainTag()
+	assert.Equal(t, "deckhand:retain", key)
+	assert.Equal(t, "true", value)
+
+	t.Setenv("DECKHAND_RETAIN_TAG", "release=v7.8")
+	key, value = retainTag()
+	assert.Equal(t, "release", key)
+	assert.Equal(t, "v7.8", value)
+	assert.True(t, imageRetained(taggedImage("ami-1", "release", "v7.8"), key, value))
+	assert.False(t, imageRetained(taggedImage("ami-2", "release", "v7.9"), key, value))
+
+	t.Setenv("DECKHAND_RETAIN_TAG", "no-separator")
+	key, value = retainTag()
+	assert.Equal(t, "deckhand:retain", key)
+	assert.Equal(t, "true", value)
+}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/lock"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/summary"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
@@ -17,6 +19,7 @@ type EventHandler struct {
 	awsResourcer   Resourcer
 	expirationDays int
 	dryRun         bool
+	locker         lock.Locker
 }
 
 // NewEventHandler factory method to create a new
@@ -30,17 +33,39 @@ func NewEventHandler(expirationDays int, awsResourcer Resourcer, dryRun bool, lo
 	}
 }
 
+// WithLocker makes the handler hold locker while it runs,
+// so that concurrent runs exit early
+func (h *EventHandler) WithLocker(locker lock.Locker) *EventHandler {
+	h.locker = locker
+	return h
+}
+
 // Handle the event for cloudwatch events
-func (h *EventHandler) Handle(_ context.Context, event events.CloudWatchEvent) error {
+func (h *EventHandler) Handle(_ context.Context, event events.CloudWatchEvent) (err error) {
+	invocation := summary.Start("ebs-janitor")
+	defer invocation.Log(&err)
+
 	h.logger.WithField("eventID", event.ID).Info("event processing")
 
 	ctx, cancel := context.WithTimeout(context.Background(), awsTimeout)
 	defer cancel()
+
+	if h.locker != nil {
+		if err := h.locker.Acquire(ctx); err != nil {
+			if errors.Is(err, lock.ErrHeld) {
+				h.logger.WithField("eventID", event.ID).Warn("another janitor run is in progress, exiting")
+				return nil
+			}
+			return errors.Wrap(err, "failed to acquire the janitor lock")
+		}
+		defer h.releaseLock()
+	}
 	results, err := h.awsResourcer.ListVolumes(ctx, ec2.VolumeStateAvailable)
 	if err != nil {
 		return errors.Wrapf(err, "failed to list EBS for State: %s", ec2.VolumeStateAvailable)
 	}
 	h.logger.WithField("count", len(results)).Info("found available EBS")
+	invocation.Add("available_volumes", len(results))
 
 	for _, v := range results {
 		fields := log.Fields{
@@ -51,24 +76,38 @@ func (h *EventHandler) Handle(_ context.Context, event events.CloudWatchEvent) e
 		// skip under conditions
 		if shouldSkipVolume(v, h.expirationDays) {
 			h.logger.WithFields(fields).Info("skipped volume")
+			invocation.Add("skipped_volumes", 1)
 			continue
 		}
 		h.logger.WithFields(fields).Info("volume to be deleted")
 		ctx, cancel = context.WithTimeout(context.Background(), awsTimeout)
 		defer cancel()
 		if h.dryRun {
+			invocation.Add("dry_run_volumes", 1)
 			continue
 		}
 		if err := h.awsResourcer.DeleteVolume(ctx, v.VolumeId); err != nil {
 			h.logger.WithFields(fields).Error("failed to delete volume")
+			invocation.Failed(1)
 			return errors.Wrapf(err, "failed to delete volume with ID: %s", *v.VolumeId)
 		}
 		h.logger.WithFields(fields).Info("deleted volume")
+		invocation.Add("deleted_volumes", 1)
 	}
 	h.logger.WithField("eventID", event.ID).Info("event processed successfully")
 	return nil
 }
 
+// releaseLock releases the janitor lock with its own
+// timeout, as the run may have used up the handler's
+func (h *EventHandler) releaseLock() {
+	ctx, cancel := context.WithTimeout(context.Background(), awsTimeout)
+	defer cancel()
+	if err := h.locker.Release(ctx); err != nil {
+		h.logger.WithError(err).Warn("failed to release the janitor lock")
+	}
+}
+
 func shouldSkipVolume(v *ec2.Volume, expirationDays int) bool {
 	if *v.SnapshotId != "" {
 		return true
@@ -11,6 +11,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/golang/mock/gomock"
 	"github.com/mattermost/mattermost-cloud-lambdas/ebs-janitor/mocks"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/lock"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
@@ -124,3 +125,57 @@ func TestHandle(t *testing.T) {
 		})
 	}
 }
+
+// fakeLocker is a lock.Locker recording its use.
+type fakeLocker struct {
+	acquireErr error
+	acquired   bool
+	released   bool
+}
+
+func (f *fakeLocker) Acquire(context.Context) error {
+	if f.acquireErr != nil {
+		return f.acquireErr
+	}
+	f.acquired = true
+	return nil
+}
+
+func (f *fakeLocker) Release(context.Context) error {
+	f.released = true
+	return nil
+}
+
+func TestHandleLock(t *testing.T) {
+	t.Run("held by another run", func(t *testing.T) {
+		gmctrl := gomock.NewController(t)
+		awsResourcer := mocks.NewMockResourcer(gmctrl)
+		locker := &fakeLocker{acquireErr: lock.ErrHeld}
+		eventHandler := NewEventHandler(90, awsResourcer, false, logrus.New()).WithLocker(locker)
+
+		assert.NoError(t, eventHandler.Handle(context.TODO(), events.CloudWatchEvent{}))
+		assert.False(t, locker.released)
+	})
+
+	t.Run("acquire failure", func(t *testing.T) {
+		gmctrl := gomock.NewController(t)
+		awsResourcer := mocks.NewMockResourcer(gmctrl)
+		locker := &fakeLocker{acquireErr: errors.New("AccessDeniedException")}
+		eventHandler := NewEventHandler(90, awsResourcer, false, logrus.New()).WithLocker(locker)
+
+		err := eventHandler.Handle(context.TODO(), events.CloudWatchEvent{})
+		assert.EqualError(t, err, "failed to acquire the janitor lock: AccessDeniedException")
+	})
+
+	t.Run("acquired", func(t *testing.T) {
+		gmctrl := gomock.NewController(t)
+		awsResourcer := mocks.NewMockResourcer(gmctrl)
+		locker := &fakeLocker{}
+		eventHandler := NewEventHandler(90, awsResourcer, false, logrus.New()).WithLocker(locker)
+		awsResourcer.EXPECT().ListVolumes(gomock.Any(), gomock.Any()).Return([]*ec2.Volume{}, nil)
+
+		assert.NoError(t, eventHandler.Handle(context.TODO(), events.CloudWatchEvent{}))
+		assert.True(t, locker.acquired)
+		assert.True(t, locker.released)
+	})
+}
@@ -6,10 +6,12 @@ require (
 	github.com/aws/aws-lambda-go v1.47.0
 	github.com/aws/aws-sdk-go v1.55.5
 	github.com/golang/mock v1.6.0
+	github.com/mattermost/mattermost-cloud-lambdas/internal/lock v0.0.0
+	github.com/mattermost/mattermost-cloud-lambdas/internal/summary v0.0.0
 	github.com/pkg/errors v0.9.1
 	github.com/sirupsen/logrus v1.9.3
 	github.com/spf13/viper v1.19.0
-	github.com/stretchr/testify v1.9.0
+	github.com/stretchr/testify v1.10.0
 )
 
 require (
@@ -38,3 +40,7 @@ require (
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/lock => ../internal/lock
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/summary => ../internal/summary
@@ -14,6 +14,7 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/lock"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -51,7 +52,8 @@ func main() {
 	}
 	// setup the handler
 	awsResourcer := NewClient(sess)
-	handler := NewEventHandler(cfg.ExpirationDays, awsResourcer, cfg.Debug, logger)
+	handler := NewEventHandler(cfg.ExpirationDays, awsResourcer, cfg.Debug, logger).
+		WithLocker(lock.FromEnv(sess, "ebs-janitor"))
 	if cfg.Debug {
 		handler.Handle(context.Background(), events.CloudWatchEvent{}) //nolint
 		return
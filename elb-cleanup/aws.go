@@ -2,12 +2,19 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elb/elbiface"
 	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/mattermost/mattermost-cloud-lambdas/elb-cleanup/unused"
 	"github.com/pkg/errors"
 )
 
@@ -17,74 +24,159 @@ const awsTimeout = 50 * time.Second
 // Client for making AWS requests
 type Client struct {
 	// ec2 *ec2.EC2
-	elbv2 *elbv2.ELBV2
-	elb   *elb.ELB
+	elbv2 elbv2iface.ELBV2API
+	elb   elbiface.ELBAPI
+	// minAge exempts unused load balancers younger than it
+	minAge time.Duration
+	// concurrency bounds how many load balancers are
+	// evaluated in parallel
+	concurrency int
+}
+
+// evaluationErrors lists the load balancers that could not be
+// evaluated. ListUnusedElb returns it along with the unused load
+// balancers among the others, which can still be cleaned up
+type evaluationErrors []error
+
+func (e evaluationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return fmt.Sprintf("%d load balancer(s) could not be evaluated: %s", len(e), strings.Join(messages, "; "))
 }
 
 // Resourcer the interface for the AWS client
 type Resourcer interface {
-	ListUnusedElb(context context.Context) ([]elbv2.LoadBalancer, error)
+	ListUnusedElb(context context.Context) ([]unused.LoadBalancer, error)
 	DeleteElb(context context.Context, loadBalancerArn *string) error
-	ListUnUsedClassiclb(context context.Context) ([]*elb.LoadBalancerDescription, error)
+	ListUnUsedClassiclb(context context.Context) ([]unused.LoadBalancer, error)
 	DeleteClassiclb(context context.Context, LoadBalancerName *string) error
 }
 
-// NewClient factory method to create AWS client
-func NewClient(sess *session.Session) *Client {
+// NewClient factory method to create AWS client. Unused
+// load balancers younger than minAge are reported as exempt,
+// and at most concurrency load balancers are evaluated in
+// parallel
+func NewClient(sess *session.Session, minAge time.Duration, concurrency int) *Client {
+	if concurrency < 1 {
+		concurrency = 1
+	}
 	return &Client{
-		elbv2: elbv2.New(sess),
-		elb:   elb.New(sess),
+		elbv2:       elbv2.New(sess),
+		elb:         elb.New(sess),
+		minAge:      minAge,
+		concurrency: concurrency,
 	}
 }
 
-// ListUnusedElb it will find any unused ELBs
-func (c *Client) ListUnusedElb(_ context.Context) ([]elbv2.LoadBalancer, error) {
+// ListUnusedElb it will find any unused ELBs along with the reason each
+// one is unused. Load balancers that fail to be evaluated are left out
+// and reported as evaluationErrors along with the others
+func (c *Client) ListUnusedElb(_ context.Context) ([]unused.LoadBalancer, error) {
 	input := &elbv2.DescribeLoadBalancersInput{
 		LoadBalancerArns: []*string{},
 	}
 
-	result, err := c.elbv2.DescribeLoadBalancers(input)
+	var loadBalancers []*elbv2.LoadBalancer
+	err := c.elbv2.DescribeLoadBalancersPages(input, func(page *elbv2.DescribeLoadBalancersOutput, _ bool) bool {
+		loadBalancers = append(loadBalancers, page.LoadBalancers...)
+		return true
+	})
 	if err != nil {
 		return nil, errors.Wrap(err, "failed elbv2.DescribeLoadBalancer")
 	}
 
-	var unUsedLBs []elbv2.LoadBalancer
-	var isUnused bool
-	for _, lb := range result.LoadBalancers {
-		isUnused = true
-		targetGroups, err := c.elbv2.DescribeTargetGroups(&elbv2.DescribeTargetGroupsInput{LoadBalancerArn: lb.LoadBalancerArn})
-		if err != nil {
-			if aerr, ok := err.(awserr.Error); ok {
-				switch aerr.Code() {
-				case elbv2.ErrCodeTargetGroupNotFoundException:
-					unUsedLBs = append(unUsedLBs, *lb)
-					continue
-				}
-			} else {
-				return nil, errors.Wrap(err, "failed elbv2.DescribeTargetGroups")
+	now := time.Now()
+	results := make([]*unused.LoadBalancer, len(loadBalancers))
+	failures := make([]error, len(loadBalancers))
+	var wg sync.WaitGroup
+	queue := make(chan int)
+	for w := 0; w < c.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				results[i], failures[i] = c.evaluateElb(loadBalancers[i], now)
 			}
+		}()
+	}
+	for i := range loadBalancers {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	var unUsedLBs []unused.LoadBalancer
+	var evaluation evaluationErrors
+	for i, result := range results {
+		if failures[i] != nil {
+			evaluation = append(evaluation, errors.Wrapf(failures[i], "failed to evaluate ELB: %s", aws.StringValue(loadBalancers[i].LoadBalancerArn)))
+			continue
 		}
+		if result != nil {
+			unUsedLBs = append(unUsedLBs, *result)
+		}
+	}
+	if len(evaluation) > 0 {
+		return unUsedLBs, evaluation
+	}
 
-		for _, targetGroup := range targetGroups.TargetGroups {
-			output, err := c.elbv2.DescribeTargetHealth(&elbv2.DescribeTargetHealthInput{TargetGroupArn: targetGroup.TargetGroupArn})
-			if err != nil {
-				return nil, errors.Wrap(err, "failed elbv2.DescribeTargetHealth")
-			}
+	return unUsedLBs, nil
+}
 
-			for _, target := range output.TargetHealthDescriptions {
-				if target.Target.Id != nil {
-					isUnused = false
-					break // move to next load balancer
+// evaluateElb looks up the target groups, targets and listeners
+// of a load balancer and returns it with the reason it is unused,
+// or nil if it is in use
+func (c *Client) evaluateElb(lb *elbv2.LoadBalancer, now time.Time) (*unused.LoadBalancer, error) {
+	facts := unused.Facts{
+		Type:        aws.StringValue(lb.Type),
+		CreatedTime: aws.TimeValue(lb.CreatedTime),
+	}
 
-				}
-			}
+	var targetGroups []*elbv2.TargetGroup
+	output, err := c.elbv2.DescribeTargetGroups(&elbv2.DescribeTargetGroupsInput{LoadBalancerArn: lb.LoadBalancerArn})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); !ok || aerr.Code() != elbv2.ErrCodeTargetGroupNotFoundException {
+			return nil, errors.Wrap(err, "failed elbv2.DescribeTargetGroups")
+		}
+	} else {
+		targetGroups = output.TargetGroups
+	}
+	facts.TargetGroups = len(targetGroups)
+
+	for _, targetGroup := range targetGroups {
+		output, err := c.elbv2.DescribeTargetHealth(&elbv2.DescribeTargetHealthInput{TargetGroupArn: targetGroup.TargetGroupArn})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed elbv2.DescribeTargetHealth")
 		}
-		if isUnused {
-			unUsedLBs = append(unUsedLBs, *lb)
+
+		for _, target := range output.TargetHealthDescriptions {
+			if target.Target.Id != nil {
+				facts.Targets++
+			}
 		}
 	}
+	if facts.Targets > 0 {
+		return nil, nil // in use, no need to look at its listeners
+	}
 
-	return unUsedLBs, nil
+	listeners, err := c.elbv2.DescribeListeners(&elbv2.DescribeListenersInput{LoadBalancerArn: lb.LoadBalancerArn})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed elbv2.DescribeListeners")
+	}
+	facts.Listeners = len(listeners.Listeners)
+
+	reason, ok := unused.Evaluate(facts, now, c.minAge)
+	if !ok {
+		return nil, nil
+	}
+
+	return &unused.LoadBalancer{
+		ID:     aws.StringValue(lb.LoadBalancerArn),
+		Type:   facts.Type,
+		Reason: reason,
+	}, nil
 }
 
 // DeleteElb it will delete ELB based on ARN
@@ -97,24 +189,39 @@ func (c *Client) DeleteElb(_ context.Context, loadBalancerArn *string) error {
 	return nil
 }
 
-// ListUnUsedClassiclb find unused classic LBs
-func (c *Client) ListUnUsedClassiclb(_ context.Context) ([]*elb.LoadBalancerDescription, error) {
+// ListUnUsedClassiclb find unused classic LBs along with the reason each
+// one is unused
+func (c *Client) ListUnUsedClassiclb(_ context.Context) ([]unused.LoadBalancer, error) {
 	input := &elb.DescribeLoadBalancersInput{
 		LoadBalancerNames: []*string{},
 	}
-	result, err := c.elb.DescribeLoadBalancers(input)
+	var loadBalancers []*elb.LoadBalancerDescription
+	err := c.elb.DescribeLoadBalancersPages(input, func(page *elb.DescribeLoadBalancersOutput, _ bool) bool {
+		loadBalancers = append(loadBalancers, page.LoadBalancerDescriptions...)
+		return true
+	})
 	if err != nil {
 		return nil, errors.Wrap(err, "failed elb.DescribeLoadBalancer")
 	}
 
-	var classicLBDescription []*elb.LoadBalancerDescription
-
-	for _, lb := range result.LoadBalancerDescriptions {
-		if len(lb.Instances) == 0 {
-			classicLBDescription = append(classicLBDescription, lb)
+	now := time.Now()
+	var classicLBs []unused.LoadBalancer
+	for _, lb := range loadBalancers {
+		facts := unused.Facts{
+			Type:        unused.TypeClassic,
+			CreatedTime: aws.TimeValue(lb.CreatedTime),
+			Listeners:   len(lb.ListenerDescriptions),
+			Targets:     len(lb.Instances),
+		}
+		if reason, ok := unused.Evaluate(facts, now, c.minAge); ok {
+			classicLBs = append(classicLBs, unused.LoadBalancer{
+				ID:     aws.StringValue(lb.LoadBalancerName),
+				Type:   facts.Type,
+				Reason: reason,
+			})
 		}
 	}
-	return classicLBDescription, nil
+	return classicLBs, nil
 }
 
 // DeleteClassiclb it will delete the unused classic LB based on LB Name
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elb/elbiface"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/elbv2/elbv2iface"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeELBV2 embeds the client interface, so any call it does not implement
+// panics and fails the test. Every load balancer has no listeners and no
+// target groups, except the one whose target health cannot be described,
+// and DescribeTargetGroups records how many calls overlap.
+type fakeELBV2 struct {
+	elbv2iface.ELBV2API
+	loadBalancers    []*elbv2.LoadBalancer
+	failTargetHealth string
+	deleted          []string
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (f *fakeELBV2) DescribeLoadBalancersPages(_ *elbv2.DescribeLoadBalancersInput, fn func(*elbv2.DescribeLoadBalancersOutput, bool) bool) error {
+	fn(&elbv2.DescribeLoadBalancersOutput{LoadBalancers: f.loadBalancers}, true)
+	return nil
+}
+
+func (f *fakeELBV2) DescribeTargetGroups(input *elbv2.DescribeTargetGroupsInput) (*elbv2.DescribeTargetGroupsOutput, error) {
+	f.mu.Lock()
+	f.inFlight++
+	f.maxInFlight = max(f.maxInFlight, f.inFlight)
+	f.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	f.mu.Lock()
+	f.inFlight--
+	f.mu.Unlock()
+	if aws.StringValue(input.LoadBalancerArn) == f.failTargetHealth {
+		return &elbv2.DescribeTargetGroupsOutput{TargetGroups: []*elbv2.TargetGroup{{TargetGroupArn: aws.String("tg")}}}, nil
+	}
+	return &elbv2.DescribeTargetGroupsOutput{}, nil
+}
+
+func (f *fakeELBV2) DescribeTargetHealth(*elbv2.DescribeTargetHealthInput) (*elbv2.DescribeTargetHealthOutput, error) {
+	return nil, errors.New("Throttling: Rate exceeded")
+}
+
+func (f *fakeELBV2) DeleteLoadBalancer(input *elbv2.DeleteLoadBalancerInput) (*elbv2.DeleteLoadBalancerOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleted = append(f.deleted, aws.StringValue(input.LoadBalancerArn))
+	return &elbv2.DeleteLoadBalancerOutput{}, nil
+}
+
+// fakeELB is a classic load balancer client with a single unused load
+// balancer.
+type fakeELB struct {
+	elbiface.ELBAPI
+	deleted []string
+}
+
+func (f *fakeELB) DescribeLoadBalancersPages(_ *elb.DescribeLoadBalancersInput, fn func(*elb.DescribeLoadBalancersOutput, bool) bool) error {
+	fn(&elb.DescribeLoadBalancersOutput{LoadBalancerDescriptions: []*elb.LoadBalancerDescription{
+		{LoadBalancerName: aws.String("classic")},
+	}}, true)
+	return nil
+}
+
+func (f *fakeELB) DeleteLoadBalancer(input *elb.DeleteLoadBalancerInput) (*elb.DeleteLoadBalancerOutput, error) {
+	f.deleted = append(f.deleted, aws.StringValue(input.LoadBalancerName))
+	return &elb.DeleteLoadBalancerOutput{}, nil
+}
+
+func (f *fakeELBV2) DescribeListeners(*elbv2.DescribeListenersInput) (*elbv2.DescribeListenersOutput, error) {
+	return &elbv2.DescribeListenersOutput{}, nil
+}
+
+func TestListUnusedElbConcurrency(t *testing.T) {
+	fake := &fakeELBV2{}
+	for i := 0; i < 8; i++ {
+		fake.loadBalancers = append(fake.loadBalancers, &elbv2.LoadBalancer{
+			LoadBalancerArn: aws.String(fmt.Sprintf("arn-%d", i)),
+			Type:            aws.String("application"),
+		})
+	}
+	client := &Client{elbv2: fake, concurrency: 3}
+
+	lbs, err := client.ListUnusedElb(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, lbs, 8)
+	for i, lb := range lbs {
+		assert.Equal(t, fmt.Sprintf("arn-%d", i), lb.ID, "the order of the load balancers is kept")
+	}
+	assert.Greater(t, fake.maxInFlight, 1)
+	assert.LessOrEqual(t, fake.maxInFlight, 3)
+}
+
+func TestHandleDeletesAroundEvaluationFailures(t *testing.T) {
+	fakeV2 := &fakeELBV2{failTargetHealth: "arn-1"}
+	for i := 0; i < 3; i++ {
+		fakeV2.loadBalancers = append(fakeV2.loadBalancers, &elbv2.LoadBalancer{
+			LoadBalancerArn: aws.String(fmt.Sprintf("arn-%d", i)),
+			Type:            aws.String("application"),
+		})
+	}
+	fakeClassic := &fakeELB{}
+	client := &Client{elbv2: fakeV2, elb: fakeClassic, concurrency: 2}
+
+	lbs, err := client.ListUnusedElb(context.Background())
+	var evaluation evaluationErrors
+	require.ErrorAs(t, err, &evaluation)
+	require.Len(t, evaluation, 1)
+	assert.Len(t, lbs, 2)
+
+	err = NewEventHandler(client, false, 2, logrus.New()).Handle(context.Background(), events.CloudWatchEvent{})
+	require.Error(t, err)
+	assert.Equal(t, "1 load balancer cleanup(s) failed: failed to evaluate ELB: arn-1: failed elbv2.DescribeTargetHealth: Throttling: Rate exceeded", err.Error())
+	assert.ElementsMatch(t, []string{"arn-0", "arn-2"}, fakeV2.deleted)
+	assert.Equal(t, []string{"classic"}, fakeClassic.deleted)
+}
@@ -15,6 +15,12 @@ var cfg config
 type config struct {
 	Debug  bool
 	Region string
+	// Concurrency bounds how many load balancers are
+	// evaluated and deleted in parallel
+	Concurrency int
+	// MinAgeHours exempts unused load balancers created
+	// less than this many hours ago. Zero disables it
+	MinAgeHours int
 }
 
 // Validate makes sure that the config makes sense
@@ -22,6 +28,12 @@ func (c *config) Validate() error {
 	if len(c.Region) == 0 {
 		return errors.New("AWS Region should be set & has a valid value")
 	}
+	if c.Concurrency < 1 {
+		return errors.New("Concurrency should be a positive number")
+	}
+	if c.MinAgeHours < 0 {
+		return errors.New("MinAgeHours should not be negative")
+	}
 	return nil
 }
 
@@ -34,6 +46,8 @@ func init() {
 		"debug":       false,
 		"environment": "dev",
 		"region":      "us-east-1",
+		"concurrency": 5,
+		"minagehours": 0,
 	}
 	for key, value := range defaults {
 		viper.SetDefault(key, value)
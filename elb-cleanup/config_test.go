@@ -20,6 +20,15 @@ func TestConfig(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, true, cfg.Debug)
 		assert.Equal(t, "us-east1", cfg.Region)
+		assert.Equal(t, 5, cfg.Concurrency)
+	})
+	t.Run("invalid concurrency test", func(t *testing.T) {
+		viper.Set("region", "us-east1")
+		viper.Set("concurrency", 0)
+		defer viper.Set("concurrency", 5)
+
+		err := LoadConfig(logrus.New())
+		require.EqualError(t, err, "invalid config: Concurrency should be a positive number")
 	})
 	t.Run("invalid config test", func(t *testing.T) {
 		viper.Set("debug", true)
@@ -2,8 +2,13 @@ package main
 
 import (
 	"context"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/mattermost/mattermost-cloud-lambdas/elb-cleanup/unused"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/lock"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
@@ -14,18 +19,32 @@ type EventHandler struct {
 	logger       log.FieldLogger
 	awsResourcer Resourcer
 	dryRun       bool
+	concurrency  int
+	locker       lock.Locker
 }
 
 // NewEventHandler factory method to create a new
-// event handler
-func NewEventHandler(awsResourcer Resourcer, dryRun bool, logger log.FieldLogger) *EventHandler {
+// event handler. concurrency bounds how many load
+// balancers are deleted in parallel
+func NewEventHandler(awsResourcer Resourcer, dryRun bool, concurrency int, logger log.FieldLogger) *EventHandler {
+	if concurrency < 1 {
+		concurrency = 1
+	}
 	return &EventHandler{
 		logger:       logger,
 		awsResourcer: awsResourcer,
 		dryRun:       dryRun,
+		concurrency:  concurrency,
 	}
 }
 
+// WithLocker makes the handler hold locker while it runs,
+// so that concurrent runs exit early
+func (h *EventHandler) WithLocker(locker lock.Locker) *EventHandler {
+	h.locker = locker
+	return h
+}
+
 // Handle the event for cloudwatch events
 func (h *EventHandler) Handle(_ context.Context, event events.CloudWatchEvent) error {
 	h.logger.Info("Unused Load Balancer(s) cleanup function called")
@@ -33,27 +52,45 @@ func (h *EventHandler) Handle(_ context.Context, event events.CloudWatchEvent) e
 	ctx, cancel := context.WithTimeout(context.Background(), awsTimeout)
 	defer cancel()
 
+	if h.locker != nil {
+		if err := h.locker.Acquire(ctx); err != nil {
+			if errors.Is(err, lock.ErrHeld) {
+				h.logger.Warn("Another cleanup run is in progress, exiting")
+				return nil
+			}
+			return errors.Wrap(err, "failed to acquire the cleanup lock")
+		}
+		defer h.releaseLock()
+	}
+
+	// ELBs that could not be evaluated are kept and reported
+	// with the failed deletions
+	var failures []error
 	unUsedElbs, err := h.awsResourcer.ListUnusedElb(ctx)
-	if err != nil {
+	var evaluation evaluationErrors
+	if errors.As(err, &evaluation) {
+		for _, failure := range evaluation {
+			h.logger.WithError(failure).Error("Failed to evaluate load balancer")
+		}
+		failures = append(failures, evaluation...)
+	} else if err != nil {
 		return errors.Wrapf(err, "failed to list ELBs")
 	}
 
 	h.logger.Info("Total Unused ElBs: ", len(unUsedElbs))
-	if len(unUsedElbs) > 0 {
-		for _, lb := range unUsedElbs {
-			if !h.dryRun {
-				// Delete unused ELBs
-				err = h.awsResourcer.DeleteElb(ctx, lb.LoadBalancerArn)
-				if err != nil {
-					return errors.Wrapf(err, "failed to delete ELB: %s", *lb.LoadBalancerArn)
-				}
-				h.logger.Info("Deleted Unused ELB ", *lb.LoadBalancerArn)
-			} else {
-				h.logger.Info("Unused ELB is ", *lb.LoadBalancerArn)
-
-			}
+	failures = append(failures, h.forEach(h.deletable(unUsedElbs), func(lb unused.LoadBalancer) error {
+		logger := lbLogger(h.logger, lb)
+		if h.dryRun {
+			logger.Info("Unused ELB")
+			return nil
 		}
-	}
+		// Delete unused ELBs
+		if err := h.awsResourcer.DeleteElb(ctx, &lb.ID); err != nil {
+			return errors.Wrapf(err, "failed to delete ELB: %s", lb.ID)
+		}
+		logger.Info("Deleted Unused ELB")
+		return nil
+	})...)
 
 	// classic LB
 	unUsedClassiclbs, err := h.awsResourcer.ListUnUsedClassiclb(ctx)
@@ -62,21 +99,121 @@ func (h *EventHandler) Handle(_ context.Context, event events.CloudWatchEvent) e
 	}
 
 	h.logger.Info("Total Unused classic LBs: ", len(unUsedClassiclbs))
-	if len(unUsedClassiclbs) > 0 {
-		for _, classicLB := range unUsedClassiclbs {
-			// Delete classic ELBs
-			if !h.dryRun {
-				err = h.awsResourcer.DeleteClassiclb(ctx, classicLB.LoadBalancerName)
-				if err != nil {
-					return errors.Wrapf(err, "failed to delete classic LBs %s", *classicLB.LoadBalancerName)
-				}
-				h.logger.Info("Deleted Unused classic LB ", *classicLB.LoadBalancerName)
-			} else {
-				h.logger.Info("Unused classic LB is ", *classicLB.LoadBalancerName)
-			}
+	failures = append(failures, h.forEach(h.deletable(unUsedClassiclbs), func(lb unused.LoadBalancer) error {
+		logger := lbLogger(h.logger, lb)
+		if h.dryRun {
+			logger.Info("Unused classic LB")
+			return nil
+		}
+		// Delete classic ELBs
+		if err := h.awsResourcer.DeleteClassiclb(ctx, &lb.ID); err != nil {
+			return errors.Wrapf(err, "failed to delete classic LBs %s", lb.ID)
 		}
+		logger.Info("Deleted Unused classic LB")
+		return nil
+	})...)
+
+	h.logger.WithFields(summaryFields(append(unUsedElbs, unUsedClassiclbs...))).Info("Unused load balancers summary")
+
+	if len(failures) > 0 {
+		return aggregateErrors(failures)
 	}
 
 	h.logger.WithField("eventID", event.ID).Info("event processed successfully")
 	return nil
 }
+
+// releaseLock releases the cleanup lock with its own
+// timeout, as the run may have used up the handler's
+func (h *EventHandler) releaseLock() {
+	ctx, cancel := context.WithTimeout(context.Background(), awsTimeout)
+	defer cancel()
+	if err := h.locker.Release(ctx); err != nil {
+		h.logger.WithError(err).Warn("Failed to release the cleanup lock")
+	}
+}
+
+// deletable filters out the exempt load balancers, logging why they are
+// kept.
+func (h *EventHandler) deletable(lbs []unused.LoadBalancer) []unused.LoadBalancer {
+	var deletable []unused.LoadBalancer
+	for _, lb := range lbs {
+		if lb.Reason.Exempt() {
+			lbLogger(h.logger, lb).Info("Keeping unused load balancer")
+			continue
+		}
+		deletable = append(deletable, lb)
+	}
+	return deletable
+}
+
+// lbLogger returns a logger with the identity, type and
+// reason of an unused load balancer.
+func lbLogger(logger log.FieldLogger, lb unused.LoadBalancer) log.FieldLogger {
+	return logger.WithFields(log.Fields{
+		"loadBalancer": lb.ID,
+		"type":         lb.Type,
+		"reason":       string(lb.Reason),
+	})
+}
+
+// summaryFields counts the unused load balancers per reason
+// and per type.
+func summaryFields(lbs []unused.LoadBalancer) log.Fields {
+	byReason := map[string]int{}
+	byType := map[string]int{}
+	for _, lb := range lbs {
+		byReason[string(lb.Reason)]++
+		byType[lb.Type]++
+	}
+	return log.Fields{
+		"total":    len(lbs),
+		"byReason": byReason,
+		"byType":   byType,
+	}
+}
+
+// forEach runs fn for every item with at most h.concurrency calls in
+// flight. A failing call does not stop the others; the failures are
+// returned once every item has been processed.
+func (h *EventHandler) forEach(items []unused.LoadBalancer, fn func(item unused.LoadBalancer) error) []error {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures []error
+	)
+	queue := make(chan unused.LoadBalancer)
+	for w := 0; w < h.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range queue {
+				if err := fn(item); err != nil {
+					h.logger.WithError(err).Error("Failed to clean up load balancer")
+					mu.Lock()
+					failures = append(failures, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, item := range items {
+		queue <- item
+	}
+	close(queue)
+	wg.Wait()
+
+	return failures
+}
+
+// aggregateErrors combines the failures of a run into a single error.
+func aggregateErrors(failures []error) error {
+	messages := make([]string, 0, len(failures))
+	for _, err := range failures {
+		messages = append(messages, err.Error())
+	}
+	sort.Strings(messages)
+
+	return errors.Errorf("%d load balancer cleanup(s) failed: %s", len(failures), strings.Join(messages, "; "))
+}
@@ -3,31 +3,38 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/elb"
-	"github.com/aws/aws-sdk-go/service/elbv2"
 	"github.com/golang/mock/gomock"
 	"github.com/mattermost/mattermost-cloud-lambdas/elb-cleanup/mocks"
+	"github.com/mattermost/mattermost-cloud-lambdas/elb-cleanup/unused"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/lock"
 	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHandle(t *testing.T) {
 	gmctrl := gomock.NewController(t)
 	awsResourcer := mocks.NewMockResourcer(gmctrl)
-	eventHandler := NewEventHandler(awsResourcer, true, logrus.New())
+	eventHandler := NewEventHandler(awsResourcer, true, 1, logrus.New())
 	defer gmctrl.Finish()
 
-	sampleLB := elbv2.LoadBalancer{
-		LoadBalancerArn:       aws.String("arn:aws:elasticloadbalancing:us-west-2:123456789012:loadbalancer/app/my-load-balancer/50dc6c495c0c9188"),
-		DNSName:               aws.String("my-load-balancer-1234566.us-west-2.elb.amazonaws.com"),
-		CanonicalHostedZoneId: aws.String("Z2P70J7EXAMPLE"),
-		VpcId:                 aws.String("vpc-test-id"),
-		LoadBalancerName:      aws.String("web"),
-		Type:                  aws.String("application"),
+	sampleLB := unused.LoadBalancer{
+		ID:     "arn:aws:elasticloadbalancing:us-west-2:123456789012:loadbalancer/app/my-load-balancer/50dc6c495c0c9188",
+		Type:   "application",
+		Reason: unused.ReasonNoTargets,
+	}
+	sampleClassicLB := unused.LoadBalancer{
+		ID:     "web",
+		Type:   unused.TypeClassic,
+		Reason: unused.ReasonNoInstances,
 	}
 	testCases := []struct {
 		description string
@@ -41,7 +48,7 @@ func TestHandle(t *testing.T) {
 			setup: func(_ context.Context) {
 				awsResourcer.EXPECT().
 					ListUnusedElb(gomock.Any()).
-					Return([]elbv2.LoadBalancer{}, errors.New("failed to list ELBs")).MaxTimes(1)
+					Return([]unused.LoadBalancer{}, errors.New("failed to list ELBs")).MaxTimes(1)
 
 			},
 			expected: func(err error) {
@@ -55,16 +62,16 @@ func TestHandle(t *testing.T) {
 			setup: func(_ context.Context) {
 				awsResourcer.EXPECT().
 					ListUnusedElb(gomock.Any()).
-					Return([]elbv2.LoadBalancer{
+					Return([]unused.LoadBalancer{
 						sampleLB,
 					}, nil).MaxTimes(3)
 				awsResourcer.EXPECT().
 					ListUnUsedClassiclb(gomock.Any()).
-					Return([]*elb.LoadBalancerDescription{}, nil)
+					Return([]unused.LoadBalancer{}, nil)
 				awsResourcer.EXPECT().
 					DeleteClassiclb(gomock.Any(), gomock.Any()).
 					Return(nil).MaxTimes(5)
-				awsResourcer.EXPECT().DeleteElb(gomock.Any(), sampleLB.LoadBalancerArn).
+				awsResourcer.EXPECT().DeleteElb(gomock.Any(), &sampleLB.ID).
 					Return(nil).MaxTimes(2)
 
 			},
@@ -79,7 +86,7 @@ func TestHandle(t *testing.T) {
 			setup: func(_ context.Context) {
 				awsResourcer.EXPECT().
 					ListUnUsedClassiclb(gomock.Any()).
-					Return([]*elb.LoadBalancerDescription{}, errors.New("failed to list Classic LBs")).MaxTimes(1)
+					Return([]unused.LoadBalancer{}, errors.New("failed to list Classic LBs")).MaxTimes(1)
 			},
 			expected: func(err error) {
 				assert.NotNil(t, err)
@@ -92,16 +99,16 @@ func TestHandle(t *testing.T) {
 			setup: func(_ context.Context) {
 				awsResourcer.EXPECT().
 					ListUnusedElb(gomock.Any()).
-					Return([]elbv2.LoadBalancer{
+					Return([]unused.LoadBalancer{
 						sampleLB,
 					}, nil).MaxTimes(4)
 				awsResourcer.EXPECT().
 					ListUnUsedClassiclb(gomock.Any()).
-					Return([]*elb.LoadBalancerDescription{{LoadBalancerName: sampleLB.LoadBalancerName}}, nil).MaxTimes(2)
+					Return([]unused.LoadBalancer{sampleClassicLB}, nil).MaxTimes(2)
 				awsResourcer.EXPECT().
-					DeleteClassiclb(gomock.Any(), sampleLB.LoadBalancerName).
+					DeleteClassiclb(gomock.Any(), &sampleClassicLB.ID).
 					Return(nil).MaxTimes(2)
-				awsResourcer.EXPECT().DeleteElb(gomock.Any(), sampleLB.LoadBalancerArn).Return(nil).MaxTimes(2)
+				awsResourcer.EXPECT().DeleteElb(gomock.Any(), &sampleLB.ID).Return(nil).MaxTimes(2)
 
 			},
 			expected: func(err error) {
@@ -120,3 +127,142 @@ func TestHandle(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleConcurrency(t *testing.T) {
+	gmctrl := gomock.NewController(t)
+	awsResourcer := mocks.NewMockResourcer(gmctrl)
+	eventHandler := NewEventHandler(awsResourcer, false, 3, logrus.New())
+
+	var (
+		unusedLBs   []unused.LoadBalancer
+		classicLBs  []unused.LoadBalancer
+		mu          sync.Mutex
+		inFlight    int
+		maxInFlight int
+		deleted     []string
+	)
+	for i := 0; i < 12; i++ {
+		unusedLBs = append(unusedLBs, unused.LoadBalancer{ID: fmt.Sprintf("arn-%02d", i), Type: "network", Reason: unused.ReasonNoTargetGroups})
+		classicLBs = append(classicLBs, unused.LoadBalancer{ID: fmt.Sprintf("classic-%02d", i), Type: unused.TypeClassic, Reason: unused.ReasonNoInstances})
+	}
+	track := func(name string) error {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		inFlight--
+		if name == "arn-05" || name == "classic-07" {
+			return errors.New("ResourceInUse")
+		}
+		deleted = append(deleted, name)
+		return nil
+	}
+
+	awsResourcer.EXPECT().ListUnusedElb(gomock.Any()).Return(unusedLBs, nil)
+	awsResourcer.EXPECT().ListUnUsedClassiclb(gomock.Any()).Return(classicLBs, nil)
+	awsResourcer.EXPECT().DeleteElb(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, arn *string) error { return track(*arn) }).Times(12)
+	awsResourcer.EXPECT().DeleteClassiclb(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, name *string) error { return track(*name) }).Times(12)
+
+	err := eventHandler.Handle(context.TODO(), events.CloudWatchEvent{})
+	require.Error(t, err)
+	assert.Equal(t, "2 load balancer cleanup(s) failed: failed to delete ELB: arn-05: ResourceInUse; failed to delete classic LBs classic-07: ResourceInUse", err.Error())
+
+	assert.Len(t, deleted, 22)
+	assert.LessOrEqual(t, maxInFlight, 3)
+	assert.Greater(t, maxInFlight, 1)
+}
+
+func TestHandleSkipsExempt(t *testing.T) {
+	gmctrl := gomock.NewController(t)
+	awsResourcer := mocks.NewMockResourcer(gmctrl)
+	logger, hook := test.NewNullLogger()
+	eventHandler := NewEventHandler(awsResourcer, false, 1, logger)
+
+	awsResourcer.EXPECT().ListUnusedElb(gomock.Any()).Return([]unused.LoadBalancer{
+		{ID: "arn-old", Type: "application", Reason: unused.ReasonNoListeners},
+		{ID: "arn-new", Type: "network", Reason: unused.ReasonTooNew},
+	}, nil)
+	awsResourcer.EXPECT().ListUnUsedClassiclb(gomock.Any()).Return([]unused.LoadBalancer{
+		{ID: "classic", Type: unused.TypeClassic, Reason: unused.ReasonNoInstances},
+	}, nil)
+	awsResourcer.EXPECT().DeleteElb(gomock.Any(), gomock.Eq(aws.String("arn-old"))).Return(nil)
+	awsResourcer.EXPECT().DeleteClassiclb(gomock.Any(), gomock.Eq(aws.String("classic"))).Return(nil)
+
+	require.NoError(t, eventHandler.Handle(context.TODO(), events.CloudWatchEvent{}))
+
+	var kept, deleted []logrus.Fields
+	var summary logrus.Fields
+	for _, entry := range hook.AllEntries() {
+		switch entry.Message {
+		case "Keeping unused load balancer":
+			kept = append(kept, entry.Data)
+		case "Deleted Unused ELB", "Deleted Unused classic LB":
+			deleted = append(deleted, entry.Data)
+		case "Unused load balancers summary":
+			summary = entry.Data
+		}
+	}
+	require.Len(t, kept, 1)
+	assert.Equal(t, "arn-new", kept[0]["loadBalancer"])
+	assert.Equal(t, "exempt: created too recently", kept[0]["reason"])
+	require.Len(t, deleted, 2)
+	assert.ElementsMatch(t, []string{"no listeners", "no registered instances"}, []string{deleted[0]["reason"].(string), deleted[1]["reason"].(string)})
+	assert.ElementsMatch(t, []string{"application", "classic"}, []string{deleted[0]["type"].(string), deleted[1]["type"].(string)})
+
+	require.NotNil(t, summary)
+	assert.Equal(t, 3, summary["total"])
+	assert.Equal(t, map[string]int{"no listeners": 1, "exempt: created too recently": 1, "no registered instances": 1}, summary["byReason"])
+	assert.Equal(t, map[string]int{"application": 1, "network": 1, "classic": 1}, summary["byType"])
+}
+
+// fakeLocker is a lock.Locker recording its use.
+type fakeLocker struct {
+	acquireErr error
+	acquired   bool
+	released   bool
+}
+
+func (f *fakeLocker) Acquire(context.Context) error {
+	if f.acquireErr != nil {
+		return f.acquireErr
+	}
+	f.acquired = true
+	return nil
+}
+
+func (f *fakeLocker) Release(context.Context) error {
+	f.released = true
+	return nil
+}
+
+func TestHandleLock(t *testing.T) {
+	t.Run("held by another run", func(t *testing.T) {
+		gmctrl := gomock.NewController(t)
+		awsResourcer := mocks.NewMockResourcer(gmctrl)
+		locker := &fakeLocker{acquireErr: lock.ErrHeld}
+		eventHandler := NewEventHandler(awsResourcer, false, 1, logrus.New()).WithLocker(locker)
+
+		assert.NoError(t, eventHandler.Handle(context.TODO(), events.CloudWatchEvent{}))
+		assert.False(t, locker.released)
+	})
+
+	t.Run("acquired", func(t *testing.T) {
+		gmctrl := gomock.NewController(t)
+		awsResourcer := mocks.NewMockResourcer(gmctrl)
+		locker := &fakeLocker{}
+		eventHandler := NewEventHandler(awsResourcer, false, 1, logrus.New()).WithLocker(locker)
+		awsResourcer.EXPECT().ListUnusedElb(gomock.Any()).Return(nil, nil)
+		awsResourcer.EXPECT().ListUnUsedClassiclb(gomock.Any()).Return(nil, nil)
+
+		assert.NoError(t, eventHandler.Handle(context.TODO(), events.CloudWatchEvent{}))
+		assert.True(t, locker.acquired)
+		assert.True(t, locker.released)
+	})
+}
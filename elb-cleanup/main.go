@@ -7,10 +7,12 @@ package main
 
 import (
 	"os"
+	"time"
 
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/lock"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -54,8 +56,9 @@ func main() {
 	}
 
 	// setup the handler
-	awsResourcer := NewClient(sess)
-	handler := NewEventHandler(awsResourcer, cfg.Debug, logger)
+	awsResourcer := NewClient(sess, time.Duration(cfg.MinAgeHours)*time.Hour, cfg.Concurrency)
+	handler := NewEventHandler(awsResourcer, cfg.Debug, cfg.Concurrency, logger).
+		WithLocker(lock.FromEnv(sess, "elb-cleanup"))
 
 	lambda.Start(handler.Handle)
 }
@@ -8,9 +8,8 @@ import (
 	context "context"
 	reflect "reflect"
 
-	elb "github.com/aws/aws-sdk-go/service/elb"
-	elbv2 "github.com/aws/aws-sdk-go/service/elbv2"
 	gomock "github.com/golang/mock/gomock"
+	unused "github.com/mattermost/mattermost-cloud-lambdas/elb-cleanup/unused"
 )
 
 // MockResourcer is a mock of Resourcer interface.
@@ -65,10 +64,10 @@ func (mr *MockResourcerMockRecorder) DeleteElb(context, loadBalancerArn interfac
 }
 
 // ListUnUsedClassiclb mocks base method.
-func (m *MockResourcer) ListUnUsedClassiclb(context context.Context) ([]*elb.LoadBalancerDescription, error) {
+func (m *MockResourcer) ListUnUsedClassiclb(context context.Context) ([]unused.LoadBalancer, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ListUnUsedClassiclb", context)
-	ret0, _ := ret[0].([]*elb.LoadBalancerDescription)
+	ret0, _ := ret[0].([]unused.LoadBalancer)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -80,10 +79,10 @@ func (mr *MockResourcerMockRecorder) ListUnUsedClassiclb(context interface{}) *g
 }
 
 // ListUnusedElb mocks base method.
-func (m *MockResourcer) ListUnusedElb(context context.Context) ([]elbv2.LoadBalancer, error) {
+func (m *MockResourcer) ListUnusedElb(context context.Context) ([]unused.LoadBalancer, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ListUnusedElb", context)
-	ret0, _ := ret[0].([]elbv2.LoadBalancer)
+	ret0, _ := ret[0].([]unused.LoadBalancer)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -0,0 +1,79 @@
+// Package unused describes why elb-cleanup considers a load balancer unused.
+package unused
+
+import "time"
+
+// Reason is why a load balancer is considered unused, or exempt from the
+// cleanup.
+type Reason string
+
+const (
+	// ReasonNoListeners is a load balancer with no listener, which cannot
+	// receive any traffic.
+	ReasonNoListeners Reason = "no listeners"
+	// ReasonNoTargetGroups is an ALB or NLB without target groups.
+	ReasonNoTargetGroups Reason = "no target groups"
+	// ReasonNoTargets is an ALB or NLB whose target groups have no
+	// registered target.
+	ReasonNoTargets Reason = "no registered targets"
+	// ReasonNoInstances is a classic load balancer without instances.
+	ReasonNoInstances Reason = "no registered instances"
+	// ReasonTooNew is an unused load balancer created too recently to be
+	// deleted, as its targets may still be registering.
+	ReasonTooNew Reason = "exempt: created too recently"
+)
+
+// Exempt reports whether the load balancer is kept despite being unused.
+func (r Reason) Exempt() bool {
+	return r == ReasonTooNew
+}
+
+// TypeClassic is the type of classic load balancers. ALBs, NLBs and GWLBs
+// use the elbv2 type names.
+const TypeClassic = "classic"
+
+// LoadBalancer is an unused load balancer and the reason it is unused.
+type LoadBalancer struct {
+	// ID is the ARN of v2 load balancers and the name of classic ones.
+	ID     string
+	Type   string
+	Reason Reason
+}
+
+// Facts are what the evaluation of a load balancer is based on.
+type Facts struct {
+	Type        string
+	CreatedTime time.Time
+	Listeners   int
+	// TargetGroups is only relevant for v2 load balancers.
+	TargetGroups int
+	// Targets are the registered targets, or instances of classic load
+	// balancers.
+	Targets int
+}
+
+// Evaluate returns why the load balancer is unused, and false if it is in
+// use. Unused load balancers younger than minAge are exempt; a zero minAge
+// exempts none.
+func Evaluate(facts Facts, now time.Time, minAge time.Duration) (Reason, bool) {
+	var reason Reason
+	switch {
+	case facts.Type == TypeClassic && facts.Targets == 0:
+		reason = ReasonNoInstances
+	case facts.Type != TypeClassic && facts.TargetGroups == 0:
+		reason = ReasonNoTargetGroups
+	case facts.Type != TypeClassic && facts.Targets == 0:
+		reason = ReasonNoTargets
+	default:
+		return "", false
+	}
+
+	if facts.Listeners == 0 {
+		reason = ReasonNoListeners
+	}
+	if minAge > 0 && !facts.CreatedTime.IsZero() && now.Sub(facts.CreatedTime) < minAge {
+		reason = ReasonTooNew
+	}
+
+	return reason, true
+}
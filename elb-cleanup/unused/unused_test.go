@@ -0,0 +1,49 @@
+package unused
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	old := now.Add(-48 * time.Hour)
+
+	testCases := []struct {
+		description string
+		facts       Facts
+		minAge      time.Duration
+		reason      Reason
+		unused      bool
+	}{
+		{"alb in use", Facts{Type: "application", CreatedTime: old, Listeners: 1, TargetGroups: 1, Targets: 2}, 0, "", false},
+		{"alb without target groups", Facts{Type: "application", CreatedTime: old, Listeners: 1}, 0, ReasonNoTargetGroups, true},
+		{"nlb without targets", Facts{Type: "network", CreatedTime: old, Listeners: 1, TargetGroups: 2}, 0, ReasonNoTargets, true},
+		{"alb without listeners nor targets", Facts{Type: "application", CreatedTime: old, TargetGroups: 1}, 0, ReasonNoListeners, true},
+		{"alb without listeners in use", Facts{Type: "application", CreatedTime: old, TargetGroups: 1, Targets: 1}, 0, "", false},
+		{"classic in use", Facts{Type: TypeClassic, CreatedTime: old, Listeners: 1, Targets: 1}, 0, "", false},
+		{"classic without instances", Facts{Type: TypeClassic, CreatedTime: old, Listeners: 1}, 0, ReasonNoInstances, true},
+		{"recent unused alb", Facts{Type: "application", CreatedTime: now.Add(-time.Hour), Listeners: 1}, 24 * time.Hour, ReasonTooNew, true},
+		{"old unused alb with min age", Facts{Type: "application", CreatedTime: old, Listeners: 1}, 24 * time.Hour, ReasonNoTargetGroups, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			reason, unused := Evaluate(tc.facts, now, tc.minAge)
+			assert.Equal(t, tc.unused, unused)
+			assert.Equal(t, tc.reason, reason)
+		})
+	}
+}
+
+func TestReasonStrings(t *testing.T) {
+	assert.Equal(t, "no listeners", string(ReasonNoListeners))
+	assert.Equal(t, "no target groups", string(ReasonNoTargetGroups))
+	assert.Equal(t, "no registered targets", string(ReasonNoTargets))
+	assert.Equal(t, "no registered instances", string(ReasonNoInstances))
+	assert.Equal(t, "exempt: created too recently", string(ReasonTooNew))
+	assert.True(t, ReasonTooNew.Exempt())
+	assert.False(t, ReasonNoTargets.Exempt())
+}
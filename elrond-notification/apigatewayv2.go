@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/apigateway"
+)
+
+// handlerV2 is the HTTP API entrypoint. It reuses handler for the actual work.
+func handlerV2(request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	response, err := handler(apigateway.ProxyRequestFromV2(request))
+
+	return apigateway.V2ResponseFromProxy(response), err
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerV2(t *testing.T) {
+	response, err := handlerV2(events.APIGatewayV2HTTPRequest{
+		RawPath: "/webhook",
+		Headers: map[string]string{"content-type": "application/json"},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodPost},
+		},
+		Body:            base64.StdEncoding.EncodeToString([]byte(samplePayload)),
+		IsBase64Encoded: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	response, err = handlerV2(events.APIGatewayV2HTTPRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	elrond "github.com/mattermost/elrond/model"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleRingWebhookEnvironment(t *testing.T) {
+	testCases := []struct {
+		name             string
+		extraData        map[string]string
+		expectedPayloads int
+	}{
+		{"no environment in payload", nil, 1},
+		{"matching environment", map[string]string{"Environment": "test"}, 1},
+		{"mismatched environment", map[string]string{"Environment": "prod"}, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mattermost := testutil.NewMattermostServer(t)
+			t.Setenv("ENVIRONMENT", "TEST")
+			t.Setenv("MATTERMOST_ELROND_WEBHOOK_TEST", mattermost.URL)
+			t.Setenv("MATTERMOST_WEBHOOK_ALERT_TEST", mattermost.URL)
+
+			err := handleRingWebhook(&elrond.WebhookPayload{
+				Type:      elrond.TypeRing,
+				ID:        "ring-id",
+				NewState:  elrond.RingStateStable,
+				ExtraData: tc.extraData,
+			})
+			require.NoError(t, err)
+			assert.Len(t, testutil.Payloads[mmSlashResponse](t, mattermost), tc.expectedPayloads)
+		})
+	}
+}
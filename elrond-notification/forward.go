@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/retry"
+	log "github.com/sirupsen/logrus"
+)
+
+const rawForwardAttempts = 3
+
+// rawForwardRetryDelay is the initial delay between raw forward attempts.
+var rawForwardRetryDelay = time.Second
+
+// rawForwardTimeout bounds the whole raw forward, retries included. The
+// forward runs before the handler replies, so it has to end well within the
+// 29 seconds API Gateway waits for the lambda: otherwise elrond gets a 504
+// for a notification that was already sent, and its retry duplicates it.
+var rawForwardTimeout = 10 * time.Second
+
+// rawForwardAttemptTimeout bounds a single raw forward attempt.
+var rawForwardAttemptTimeout = 3 * time.Second
+
+// forwardRawPayload POSTs the original webhook payload JSON to RAW_FORWARD_URL
+// when it is set, for downstream tooling that wants the elrond payload as is.
+func forwardRawPayload(body string) {
+	forwardURL := os.Getenv("RAW_FORWARD_URL")
+	if forwardURL == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rawForwardTimeout)
+	defer cancel()
+
+	err := retry.Do(rawForwardAttempts, rawForwardRetryDelay, func() error {
+		if err := ctx.Err(); err != nil {
+			return retry.Permanent(err)
+		}
+		return postRawPayload(ctx, forwardURL, body)
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to forward raw webhook payload")
+		return
+	}
+
+	log.Debug("Raw webhook payload forwarded")
+}
+
+func postRawPayload(ctx context.Context, forwardURL, body string) error {
+	ctx, cancel := context.WithTimeout(ctx, rawForwardAttemptTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, forwardURL, bytes.NewBufferString(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Custom-Header", "elrond-webhook-notifier")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerForwardsRawPayload(t *testing.T) {
+	previousDelay := rawForwardRetryDelay
+	rawForwardRetryDelay = time.Millisecond
+	defer func() { rawForwardRetryDelay = previousDelay }()
+
+	mattermost := testutil.NewMattermostServer(t)
+	t.Setenv("ENVIRONMENT", "TEST")
+	t.Setenv("MATTERMOST_ELROND_WEBHOOK_TEST", mattermost.URL)
+	t.Setenv("MATTERMOST_WEBHOOK_ALERT_TEST", mattermost.URL)
+
+	var attempts int
+	var forwarded string
+	raw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		forwarded = string(b)
+	}))
+	defer raw.Close()
+	t.Setenv("RAW_FORWARD_URL", raw.URL)
+
+	response, err := handler(events.APIGatewayProxyRequest{Body: samplePayload})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	payloads := testutil.Payloads[mmSlashResponse](t, mattermost)
+	require.Len(t, payloads, 1)
+	assert.Equal(t, "Elrond-TEST", payloads[0].Username)
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, samplePayload, forwarded)
+}
+
+func TestForwardRawPayloadTimeout(t *testing.T) {
+	defer func(delay, timeout, attemptTimeout time.Duration) {
+		rawForwardRetryDelay, rawForwardTimeout, rawForwardAttemptTimeout = delay, timeout, attemptTimeout
+	}(rawForwardRetryDelay, rawForwardTimeout, rawForwardAttemptTimeout)
+	rawForwardRetryDelay = time.Millisecond
+	rawForwardTimeout = 150 * time.Millisecond
+	rawForwardAttemptTimeout = 100 * time.Millisecond
+
+	release := make(chan struct{})
+	var attempts atomic.Int32
+	raw := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		attempts.Add(1)
+		<-release
+	}))
+	defer raw.Close()
+	defer close(release)
+	t.Setenv("RAW_FORWARD_URL", raw.URL)
+
+	start := time.Now()
+	forwardRawPayload(samplePayload)
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(2), attempts.Load())
+}
@@ -8,6 +8,7 @@ require (
 	github.com/mattermost/elrond v0.7.5
 	github.com/pkg/errors v0.9.1
 	github.com/sirupsen/logrus v1.9.3
+	github.com/stretchr/testify v1.10.0
 )
 
 require (
@@ -32,11 +33,19 @@ require (
 	github.com/json-iterator/go v1.1.12 // indirect
 	github.com/mailru/easyjson v0.9.0 // indirect
 	github.com/mattermost/mattermost-cloud v0.88.0 // indirect
+	github.com/mattermost/mattermost-cloud-lambdas/internal/apigateway v0.0.0
+	github.com/mattermost/mattermost-cloud-lambdas/internal/colors v0.0.0
+	github.com/mattermost/mattermost-cloud-lambdas/internal/metrics v0.0.0
+	github.com/mattermost/mattermost-cloud-lambdas/internal/pagerduty v0.0.0
+	github.com/mattermost/mattermost-cloud-lambdas/internal/retry v0.0.0
+	github.com/mattermost/mattermost-cloud-lambdas/internal/testutil v0.0.0
+	github.com/mattermost/mattermost-cloud-lambdas/internal/webhook v0.0.0
 	github.com/mattermost/mattermost-operator v1.22.1 // indirect
 	github.com/mattermost/rotator v0.2.1-0.20230830064954-61490ed26761 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/pborman/uuid v1.2.1 // indirect
+	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	golang.org/x/net v0.33.0 // indirect
 	golang.org/x/oauth2 v0.24.0 // indirect
@@ -67,3 +76,17 @@ replace k8s.io/apimachinery => k8s.io/apimachinery v0.22.0
 replace github.com/imdario/mergo => dario.cat/mergo v1.0.1
 
 replace github.com/googleapis/gnostic => github.com/google/gnostic v0.5.5
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/testutil => ../internal/testutil
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/metrics => ../internal/metrics
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/retry => ../internal/retry
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/webhook => ../internal/webhook
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/colors => ../internal/colors
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/apigateway => ../internal/apigateway
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/pagerduty => ../internal/pagerduty
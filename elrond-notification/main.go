@@ -17,11 +17,20 @@ import (
 	"github.com/aws/aws-lambda-go/lambda"
 	elrond "github.com/mattermost/elrond/model"
 
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/apigateway"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/colors"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/metrics"
+	cloudpagerduty "github.com/mattermost/mattermost-cloud-lambdas/internal/pagerduty"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/webhook"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
 func main() {
+	if apigateway.UseHTTPAPIPayload() {
+		lambda.Start(handlerV2)
+		return
+	}
 	lambda.Start(handler)
 }
 
@@ -36,13 +45,21 @@ func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 		return sendErrorResponse(errors.New("request is empty"))
 	}
 
-	payload, err := elrond.WebhookPayloadFromReader(strings.NewReader(request.Body))
+	body, err := webhook.RequestBody(request)
 	if err != nil {
+		webhook.CaptureRawRequest(request, err)
+		return sendErrorResponse(err)
+	}
+
+	payload, err := elrond.WebhookPayloadFromReader(strings.NewReader(body))
+	if err != nil {
+		webhook.CaptureRawRequest(request, err)
 		return sendErrorResponse(errors.Wrap(err, "failed to parse the body"))
 	}
 	log.Debug(payload)
 
 	processWebhookEvent(payload)
+	forwardRawPayload(body)
 
 	return events.APIGatewayProxyResponse{
 		Body:       "{\"status\": \"ok\"}",
@@ -76,6 +93,17 @@ func handleRingWebhook(payload *elrond.WebhookPayload) error {
 		return errors.New("missing environment from payload")
 	}
 
+	// A lambda configured for the wrong Elrond must not notify this
+	// environment's channels.
+	if payloadEnv := payload.ExtraData["Environment"]; payloadEnv != "" && !strings.EqualFold(payloadEnv, elrondEnv) {
+		log.WithFields(log.Fields{
+			"ring":                payload.ID,
+			"payload_environment": payloadEnv,
+			"lambda_environment":  elrondEnv,
+		}).Warn("Skipping ring webhook for another environment")
+		return nil
+	}
+
 	mmWebhook := os.Getenv(fmt.Sprintf("MATTERMOST_ELROND_WEBHOOK_%s", elrondEnv))
 	if mmWebhook == "" {
 		return errors.New("missing Mattermost Webhook variable")
@@ -87,7 +115,7 @@ func handleRingWebhook(payload *elrond.WebhookPayload) error {
 	}
 
 	attach := mmAttachment{
-		Color: "#006400",
+		Color: colors.Success(),
 	}
 
 	alert := false
@@ -96,7 +124,7 @@ func handleRingWebhook(payload *elrond.WebhookPayload) error {
 		payload.NewState == elrond.RingStateReleaseRollbackFailed || payload.NewState == elrond.RingStateSoakingFailed ||
 		payload.NewState == elrond.RingStateReleaseFailed || payload.NewState == elrond.InstallationGroupReleaseFailed ||
 		payload.NewState == elrond.InstallationGroupReleaseSoakingFailed {
-		attach.Color = "#FF0000"
+		attach.Color = colors.Failure()
 		alert = true
 	}
 
@@ -172,11 +200,13 @@ type mmSlashResponse struct {
 }
 
 func (attachment *mmAttachment) AddField(field mmField) *mmAttachment {
+	field.Value = webhook.Truncate(field.Value, webhook.MaxFieldLength())
 	attachment.Fields = append(attachment.Fields, &field)
 	return attachment
 }
 
 func sendMattermostWebhook(webhookURL string, payload mmSlashResponse) error {
+	payload = truncatedPayload(payload, webhook.MaxPayloadLength())
 	marshalContent, _ := json.Marshal(payload)
 	var jsonStr = marshalContent
 	req, _ := http.NewRequest("POST", webhookURL, bytes.NewBuffer(jsonStr))
@@ -184,7 +214,9 @@ func sendMattermostWebhook(webhookURL string, payload mmSlashResponse) error {
 	req.Header.Set("Content-Type", "application/json")
 
 	client := &http.Client{}
+	start := time.Now()
 	resp, err := client.Do(req)
+	metrics.RecordNotificationLatency(metrics.TargetMattermost, start)
 	if err != nil {
 		return err
 	}
@@ -208,7 +240,6 @@ func sendPagerDutyNotification(payload *elrond.WebhookPayload) error {
 	tm := time.Unix(0, payload.Timestamp)
 	alertReq := &pagerduty.V2Payload{
 		Summary:  fmt.Sprintf("%s - %s - %s %s", payload.Type, payload.ID, payload.Name, payload.NewState),
-		Source:   "Alarm System",
 		Severity: "critical",
 		Details: map[string]string{
 			"Type":      payload.Type,
@@ -218,6 +249,7 @@ func sendPagerDutyNotification(payload *elrond.WebhookPayload) error {
 			"Env":       elrondEnv,
 		},
 	}
+	cloudpagerduty.SetOrigin(alertReq, defaultPagerDutySource)
 
 	event := pagerduty.V2Event{
 		RoutingKey: integrationKey,
@@ -226,7 +258,9 @@ func sendPagerDutyNotification(payload *elrond.WebhookPayload) error {
 	}
 
 	// Send the event to PagerDuty
+	start := time.Now()
 	_, err := pagerduty.ManageEventWithContext(context.Background(), event)
+	metrics.RecordNotificationLatency(metrics.TargetPagerDuty, start)
 	if err != nil {
 		log.WithError(err).Error("Failed to send PagerDuty notification")
 		return errors.New("Failed to send PagerDuty notification")
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// abortRuleSuperseded matches pipelines whose branch has moved to a newer
+	// commit since the pipeline started.
+	abortRuleSuperseded = "superseded"
+	// abortRuleBranchPrefix matches pipelines on the named branch, e.g.
+	// "branch:renovate/chart".
+	abortRuleBranchPrefix = "branch:"
+)
+
+// autoAbortEnabled reports whether ENABLE_AUTO_ABORT is set to true.
+func autoAbortEnabled() bool {
+	return strings.EqualFold(os.Getenv("ENABLE_AUTO_ABORT"), "true")
+}
+
+// abortRuleMatches reports whether a single AUTO_ABORT_RULES rule matches the
+// pipeline.
+func abortRuleMatches(client *gitlabClient, rule string, webhookData PipelineEvent) (bool, error) {
+	switch {
+	case rule == abortRuleSuperseded:
+		if webhookData.ObjectAttributes.Tag || webhookData.ObjectAttributes.Sha == "" {
+			return false, nil
+		}
+		head, err := client.getBranchHead(webhookData.Project.ID, webhookData.ObjectAttributes.Ref)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to get the branch head")
+		}
+		return head != "" && head != webhookData.ObjectAttributes.Sha, nil
+	case strings.HasPrefix(rule, abortRuleBranchPrefix):
+		return webhookData.ObjectAttributes.Ref == strings.TrimPrefix(rule, abortRuleBranchPrefix), nil
+	default:
+		return false, errors.Errorf("unknown auto abort rule %q", rule)
+	}
+}
+
+// autoAbortManualJob cancels the manual job through the GitLab API when
+// ENABLE_AUTO_ABORT is set and one of the comma-separated AUTO_ABORT_RULES
+// matches. It reports whether the job was cancelled, in which case no
+// approval notification is needed.
+func autoAbortManualJob(webhookData PipelineEvent, build Builds) bool {
+	if !autoAbortEnabled() {
+		return false
+	}
+
+	logger := log.WithFields(log.Fields{
+		"project": webhookData.Project.PathWithNamespace,
+		"branch":  webhookData.ObjectAttributes.Ref,
+		"job":     build.ID,
+	})
+
+	client := newGitLabClient()
+	if client == nil {
+		logger.Warn("Auto abort is enabled but the GitLab API is not configured")
+		return false
+	}
+
+	for _, rule := range parseList(os.Getenv("AUTO_ABORT_RULES")) {
+		matched, err := abortRuleMatches(client, rule, webhookData)
+		if err != nil {
+			logger.WithError(err).WithField("rule", rule).Warn("Failed to evaluate auto abort rule")
+			continue
+		}
+		if !matched {
+			continue
+		}
+
+		if err := client.cancelJob(webhookData.Project.ID, build.ID); err != nil {
+			logger.WithError(err).WithField("rule", rule).Error("Failed to cancel the manual job")
+			return false
+		}
+
+		logger.WithField("rule", rule).Info("Cancelled the manual job")
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const cancelPath = "/api/v4/projects/7/jobs/9/cancel"
+
+func TestAutoAbortManualJob(t *testing.T) {
+	build := Builds{ID: 9, Name: "deploy", Status: "manual", Manual: true}
+
+	testCases := []struct {
+		name          string
+		enabled       string
+		rules         string
+		branchHead    string
+		expectAborted bool
+	}{
+		{"disabled", "false", "superseded", "def456", false},
+		{"superseded", "true", "superseded", "def456", true},
+		{"not superseded", "true", "superseded", "abc123", false},
+		{"branch rule", "true", "branch:master", "abc123", true},
+		{"other branch rule", "true", "branch:release", "abc123", false},
+		{"unknown rule is skipped", "true", "whatever, branch:master", "abc123", true},
+		{"no rules", "true", "", "def456", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("ENABLE_AUTO_ABORT", tc.enabled)
+			t.Setenv("AUTO_ABORT_RULES", tc.rules)
+			requested := newGitLabServer(t, map[string]interface{}{
+				"/api/v4/projects/7/repository/branches/master": map[string]interface{}{"commit": map[string]string{"id": tc.branchHead}},
+				cancelPath: map[string]interface{}{"id": 9, "status": "canceled"},
+			})
+
+			assert.Equal(t, tc.expectAborted, autoAbortManualJob(enrichmentEvent(), build))
+			if tc.expectAborted {
+				assert.Contains(t, *requested, "POST "+cancelPath)
+			} else {
+				assert.NotContains(t, *requested, "POST "+cancelPath)
+			}
+		})
+	}
+}
+
+func TestAutoAbortManualJobNotConfigured(t *testing.T) {
+	t.Setenv("ENABLE_AUTO_ABORT", "true")
+	t.Setenv("AUTO_ABORT_RULES", "branch:master")
+	t.Setenv("GITLAB_API_URL", "")
+	t.Setenv("GITLAB_API_TOKEN", "")
+
+	assert.False(t, autoAbortManualJob(enrichmentEvent(), Builds{ID: 9}))
+}
+
+func TestHandlePipelineEventAutoAbort(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_NOTIFICATION_HOOK", mattermost.URL)
+	t.Setenv("PROJECT_NOTIFICATION_HOOKS", "")
+	t.Setenv("PAGERDUTY_INTEGRATION_KEY", "")
+	t.Setenv("ENABLE_AUTO_ABORT", "true")
+	t.Setenv("AUTO_ABORT_RULES", "branch:master")
+
+	t.Run("cancelled", func(t *testing.T) {
+		requested := newGitLabServer(t, map[string]interface{}{
+			cancelPath: map[string]interface{}{"id": 9, "status": "canceled"},
+		})
+
+		handlePipelineEvent(enrichmentEvent())
+		assert.Contains(t, *requested, "POST "+cancelPath)
+		assert.Empty(t, mattermost.Bodies())
+	})
+
+	t.Run("cancel failure notifies", func(t *testing.T) {
+		newGitLabServer(t, nil)
+
+		handlePipelineEvent(enrichmentEvent())
+		require.Len(t, mattermost.Bodies(), 1)
+	})
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerV2(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	t.Setenv("MATTERMOST_NOTIFICATION_HOOK", server.URL)
+	t.Setenv("BRANCH_ALLOWLIST", "master")
+
+	request := events.APIGatewayV2HTTPRequest{
+		RawPath: "/webhook",
+		Headers: map[string]string{"x-gitlab-event": "Pipeline Hook"},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodPost},
+		},
+		Body: `{"object_kind":"pipeline","object_attributes":{"ref":"feature"},"project":{"path_with_namespace":"mattermost/cloud"}}`,
+	}
+
+	response, err := handlerV2(request)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, 0, calls)
+
+	request.Headers = nil
+	response, err = handlerV2(request)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+}
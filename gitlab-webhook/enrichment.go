@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// pipelineDetailFields returns the attachment fields telling who triggered
+// the pipeline and its merge request. The webhook data is used when it has
+// them and the GitLab API is queried otherwise, if configured. API errors
+// only drop the fields they would have filled.
+func pipelineDetailFields(webhookData PipelineEvent) []*model.SlackAttachmentField {
+	triggeredBy := webhookData.User.Username
+	mergeRequest := formatMergeRequest(webhookData.MergeRequest.Iid, webhookData.MergeRequest.Title, webhookData.MergeRequest.URL)
+
+	if client := newGitLabClient(); client != nil && (triggeredBy == "" || mergeRequest == "") {
+		logger := log.WithFields(log.Fields{
+			"project":  webhookData.Project.PathWithNamespace,
+			"pipeline": webhookData.ObjectAttributes.ID,
+		})
+
+		if triggeredBy == "" {
+			pipeline, err := client.getPipeline(webhookData.Project.ID, webhookData.ObjectAttributes.ID)
+			if err != nil {
+				logger.WithError(err).Warn("Failed to fetch the pipeline from GitLab")
+			} else {
+				triggeredBy = pipeline.User.Username
+			}
+		}
+
+		if mergeRequest == "" && webhookData.ObjectAttributes.Sha != "" {
+			mergeRequests, err := client.getCommitMergeRequests(webhookData.Project.ID, webhookData.ObjectAttributes.Sha)
+			if err != nil {
+				logger.WithError(err).Warn("Failed to fetch the commit merge requests from GitLab")
+			} else if len(mergeRequests) > 0 {
+				mergeRequest = formatMergeRequest(mergeRequests[0].Iid, mergeRequests[0].Title, mergeRequests[0].WebURL)
+			}
+		}
+	}
+
+	var fields []*model.SlackAttachmentField
+	if triggeredBy != "" {
+		fields = append(fields, &model.SlackAttachmentField{Title: "Triggered By", Value: triggeredBy, Short: true})
+	}
+	if mergeRequest != "" {
+		fields = append(fields, &model.SlackAttachmentField{Title: "Merge Request", Value: mergeRequest, Short: true})
+	}
+
+	return fields
+}
+
+func formatMergeRequest(iid int, title, url string) string {
+	if iid == 0 {
+		return ""
+	}
+	if url == "" {
+		return fmt.Sprintf("!%d %s", iid, title)
+	}
+
+	return fmt.Sprintf("[!%d %s](%s)", iid, title, url)
+}
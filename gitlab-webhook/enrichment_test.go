@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newGitLabServer starts a fake GitLab API serving the given JSON responses
+// by path and records the requested paths.
+func newGitLabServer(t *testing.T, responses map[string]interface{}) *[]string {
+	t.Helper()
+
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.Method+" "+r.URL.Path)
+		if r.Header.Get("PRIVATE-TOKEN") != "gitlab-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("GITLAB_API_URL", server.URL+"/api/v4/")
+	t.Setenv("GITLAB_API_TOKEN", "gitlab-token")
+
+	return &requested
+}
+
+func enrichmentEvent() PipelineEvent {
+	return PipelineEvent{
+		ObjectAttributes: ObjectAttributes{ID: 42, Ref: "master", Sha: "abc123"},
+		Project:          Project{ID: 7, PathWithNamespace: "mattermost/cloud", WebURL: "https://gitlab.example.com/mattermost/cloud"},
+		Builds:           []Builds{{ID: 9, Name: "deploy", Status: "manual", Manual: true}},
+	}
+}
+
+func TestPipelineDetailFields(t *testing.T) {
+	t.Run("not configured", func(t *testing.T) {
+		t.Setenv("GITLAB_API_URL", "")
+		t.Setenv("GITLAB_API_TOKEN", "")
+
+		assert.Empty(t, pipelineDetailFields(enrichmentEvent()))
+	})
+
+	t.Run("enriched from the API", func(t *testing.T) {
+		requested := newGitLabServer(t, map[string]interface{}{
+			"/api/v4/projects/7/pipelines/42": map[string]interface{}{"user": map[string]string{"username": "jdoe"}},
+			"/api/v4/projects/7/repository/commits/abc123/merge_requests": []map[string]interface{}{
+				{"iid": 12, "title": "Bump chart", "web_url": "https://gitlab.example.com/mattermost/cloud/-/merge_requests/12"},
+			},
+		})
+
+		fields := pipelineDetailFields(enrichmentEvent())
+		require.Len(t, fields, 2)
+		assert.Equal(t, "Triggered By", fields[0].Title)
+		assert.Equal(t, "jdoe", fields[0].Value)
+		assert.Equal(t, "Merge Request", fields[1].Title)
+		assert.Equal(t, "[!12 Bump chart](https://gitlab.example.com/mattermost/cloud/-/merge_requests/12)", fields[1].Value)
+		assert.Len(t, *requested, 2)
+	})
+
+	t.Run("api errors degrade to webhook data", func(t *testing.T) {
+		requested := newGitLabServer(t, nil)
+
+		event := enrichmentEvent()
+		event.User.Username = "webhook-user"
+
+		fields := pipelineDetailFields(event)
+		require.Len(t, fields, 1)
+		assert.Equal(t, "webhook-user", fields[0].Value)
+		assert.Equal(t, []string{"GET /api/v4/projects/7/repository/commits/abc123/merge_requests"}, *requested)
+	})
+
+	t.Run("webhook data is complete", func(t *testing.T) {
+		requested := newGitLabServer(t, nil)
+
+		event := enrichmentEvent()
+		event.User.Username = "webhook-user"
+		event.MergeRequest = MergeRequest{Iid: 3, Title: "Fix", URL: "https://mr/3"}
+
+		fields := pipelineDetailFields(event)
+		require.Len(t, fields, 2)
+		assert.Equal(t, "[!3 Fix](https://mr/3)", fields[1].Value)
+		assert.Empty(t, *requested)
+	})
+}
+
+func TestHandlePipelineEventEnrichment(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_NOTIFICATION_HOOK", mattermost.URL)
+	t.Setenv("PROJECT_NOTIFICATION_HOOKS", "")
+	t.Setenv("PAGERDUTY_INTEGRATION_KEY", "")
+	newGitLabServer(t, map[string]interface{}{
+		"/api/v4/projects/7/pipelines/42": map[string]interface{}{"user": map[string]string{"username": "jdoe"}},
+	})
+
+	handlePipelineEvent(enrichmentEvent())
+
+	payloads := testutil.Payloads[model.CommandResponse](t, mattermost)
+	require.Len(t, payloads, 1)
+	fields := payloads[0].Attachments[0].Fields
+	require.Len(t, fields, 3)
+	assert.Equal(t, "Triggered By", fields[2].Title)
+	assert.Equal(t, "jdoe", fields[2].Value)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// parseList splits a comma-separated environment value into its trimmed,
+// non-empty elements.
+func parseList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+// isAllowed reports whether any of the candidates is present in the
+// allowlist. An empty allowlist allows everything.
+func isAllowed(allowlist []string, candidates ...string) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+
+	for _, allowed := range allowlist {
+		for _, candidate := range candidates {
+			if candidate != "" && candidate == allowed {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// shouldNotify checks the pipeline project and branch against the
+// PROJECT_ALLOWLIST and BRANCH_ALLOWLIST environment variables.
+func shouldNotify(webhookData PipelineEvent) bool {
+	projects := parseList(os.Getenv("PROJECT_ALLOWLIST"))
+	if !isAllowed(projects, webhookData.Project.PathWithNamespace, webhookData.Project.Name) {
+		return false
+	}
+
+	branches := parseList(os.Getenv("BRANCH_ALLOWLIST"))
+	return isAllowed(branches, webhookData.ObjectAttributes.Ref)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldNotify(t *testing.T) {
+	event := PipelineEvent{
+		ObjectAttributes: ObjectAttributes{Ref: "master"},
+		Project:          Project{Name: "cloud", PathWithNamespace: "mattermost/cloud"},
+	}
+
+	testCases := []struct {
+		description string
+		projects    string
+		branches    string
+		expected    bool
+	}{
+		{"no allowlists", "", "", true},
+		{"allowed project", "mattermost/cloud, mattermost/other", "", true},
+		{"allowed project by name", "cloud", "", true},
+		{"blocked project", "mattermost/other", "", false},
+		{"allowed branch", "", "main,master", true},
+		{"blocked branch", "", "main", false},
+		{"allowed project and branch", "mattermost/cloud", "master", true},
+		{"allowed project blocked branch", "mattermost/cloud", "release", false},
+		{"blocked project allowed branch", "mattermost/other", "master", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			t.Setenv("PROJECT_ALLOWLIST", tc.projects)
+			t.Setenv("BRANCH_ALLOWLIST", tc.branches)
+			assert.Equal(t, tc.expected, shouldNotify(event))
+		})
+	}
+}
+
+func TestHandlePipelineEventFiltered(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	t.Setenv("MATTERMOST_NOTIFICATION_HOOK", server.URL)
+	t.Setenv("BRANCH_ALLOWLIST", "master")
+
+	event := PipelineEvent{
+		Project: Project{PathWithNamespace: "mattermost/cloud"},
+		Builds:  []Builds{{ID: 1, Name: "deploy", Status: "manual", Manual: true}},
+	}
+
+	event.ObjectAttributes.Ref = "feature"
+	handlePipelineEvent(event)
+	assert.Equal(t, 0, calls)
+
+	event.ObjectAttributes.Ref = "master"
+	handlePipelineEvent(event)
+	assert.Equal(t, 1, calls)
+}
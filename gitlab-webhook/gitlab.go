@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const gitlabAPITimeout = 10 * time.Second
+
+// gitlabClient is a minimal client for the GitLab REST API.
+type gitlabClient struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// newGitLabClient returns a client for GITLAB_API_URL, the API root such as
+// https://gitlab.com/api/v4, authenticated with GITLAB_API_TOKEN. It returns
+// nil when either is not set.
+func newGitLabClient() *gitlabClient {
+	baseURL := strings.TrimSuffix(os.Getenv("GITLAB_API_URL"), "/")
+	token := os.Getenv("GITLAB_API_TOKEN")
+	if baseURL == "" || token == "" {
+		return nil
+	}
+
+	return &gitlabClient{
+		baseURL:    baseURL,
+		token:      token,
+		httpClient: &http.Client{Timeout: gitlabAPITimeout},
+	}
+}
+
+// do sends a request to the API path and decodes the JSON response into out,
+// unless out is nil.
+func (c *gitlabClient) do(method, path string, out interface{}) error {
+	req, err := http.NewRequest(method, c.baseURL+path, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create GitLab API request")
+	}
+	req.Header.Set("PRIVATE-TOKEN", c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to send GitLab API request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.Errorf("unexpected GitLab API response status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errors.Wrap(err, "failed to decode GitLab API response")
+	}
+
+	return nil
+}
+
+// pipelineDetails is the part of the GitLab pipeline API response used to
+// enrich notifications.
+type pipelineDetails struct {
+	User User `json:"user"`
+}
+
+// commitMergeRequest is the part of a merge request returned by the commit
+// merge requests API.
+type commitMergeRequest struct {
+	Iid    int    `json:"iid"`
+	Title  string `json:"title"`
+	WebURL string `json:"web_url"`
+}
+
+func (c *gitlabClient) getPipeline(projectID, pipelineID int) (*pipelineDetails, error) {
+	var pipeline pipelineDetails
+	err := c.do(http.MethodGet, fmt.Sprintf("/projects/%d/pipelines/%d", projectID, pipelineID), &pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pipeline, nil
+}
+
+func (c *gitlabClient) getCommitMergeRequests(projectID int, sha string) ([]commitMergeRequest, error) {
+	var mergeRequests []commitMergeRequest
+	err := c.do(http.MethodGet, fmt.Sprintf("/projects/%d/repository/commits/%s/merge_requests", projectID, sha), &mergeRequests)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeRequests, nil
+}
+
+// branchDetails is the part of the GitLab branch API response used to find
+// the head of a branch.
+type branchDetails struct {
+	Commit struct {
+		ID string `json:"id"`
+	} `json:"commit"`
+}
+
+func (c *gitlabClient) getBranchHead(projectID int, branch string) (string, error) {
+	var details branchDetails
+	err := c.do(http.MethodGet, fmt.Sprintf("/projects/%d/repository/branches/%s", projectID, url.PathEscape(branch)), &details)
+	if err != nil {
+		return "", err
+	}
+
+	return details.Commit.ID, nil
+}
+
+func (c *gitlabClient) cancelJob(projectID, jobID int) error {
+	return c.do(http.MethodPost, fmt.Sprintf("/projects/%d/jobs/%d/cancel", projectID, jobID), nil)
+}
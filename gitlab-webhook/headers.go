@@ -0,0 +1,24 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// headerValue returns the value of a request header regardless of the casing
+// API Gateway delivered it in, falling back to the multi-value headers.
+func headerValue(request events.APIGatewayProxyRequest, name string) string {
+	for key, value := range request.Headers {
+		if strings.EqualFold(key, name) && value != "" {
+			return value
+		}
+	}
+	for key, values := range request.MultiValueHeaders {
+		if strings.EqualFold(key, name) && len(values) > 0 {
+			return values[0]
+		}
+	}
+
+	return ""
+}
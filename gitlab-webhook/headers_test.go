@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderValue(t *testing.T) {
+	testCases := []struct {
+		name    string
+		request events.APIGatewayProxyRequest
+	}{
+		{"canonical", events.APIGatewayProxyRequest{Headers: map[string]string{"X-Gitlab-Event": "Pipeline Hook"}}},
+		{"lowercase", events.APIGatewayProxyRequest{Headers: map[string]string{"x-gitlab-event": "Pipeline Hook"}}},
+		{"uppercase", events.APIGatewayProxyRequest{Headers: map[string]string{"X-GITLAB-EVENT": "Pipeline Hook"}}},
+		{"multi value", events.APIGatewayProxyRequest{MultiValueHeaders: map[string][]string{"x-gitlab-event": {"Pipeline Hook"}}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, "Pipeline Hook", headerValue(tc.request, "X-Gitlab-Event"))
+		})
+	}
+
+	assert.Empty(t, headerValue(events.APIGatewayProxyRequest{Headers: map[string]string{"X-Gitlab-Token": "secret"}}, "X-Gitlab-Event"))
+}
+
+func TestHandlerHeaderCasing(t *testing.T) {
+	t.Setenv("BRANCH_ALLOWLIST", "master")
+
+	for _, header := range []string{"X-Gitlab-Event", "x-gitlab-event", "X-GITLAB-EVENT"} {
+		t.Run(header, func(t *testing.T) {
+			response, err := handler(events.APIGatewayProxyRequest{
+				Headers: map[string]string{header: "Pipeline Hook"},
+				Body:    `{"object_kind":"pipeline","object_attributes":{"ref":"feature"}}`,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+		})
+	}
+}
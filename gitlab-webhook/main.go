@@ -12,11 +12,20 @@ import (
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/apigateway"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/webhook"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
+// gitlabTokenHeader carries the secret token of the GitLab webhook.
+const gitlabTokenHeader = "X-Gitlab-Token"
+
 func main() {
+	if apigateway.UseHTTPAPIPayload() {
+		lambda.Start(handlerV2)
+		return
+	}
 	lambda.Start(handler)
 }
 
@@ -31,7 +40,7 @@ func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 		return sendErrorResponse(errors.New("request is empty"))
 	}
 
-	eventType := request.Headers["X-Gitlab-Event"]
+	eventType := headerValue(request, "X-Gitlab-Event")
 	if eventType == "" {
 		log.Debug(request.Headers)
 		return sendErrorResponse(errors.New("no GitLab Event headers"))
@@ -43,6 +52,7 @@ func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 		err := json.NewDecoder(strings.NewReader(request.Body)).Decode(&webhookData)
 		if err != nil {
 			log.Error(err.Error())
+			webhook.CaptureRawRequest(request, err, gitlabTokenHeader)
 			return sendErrorResponse(err)
 		}
 		log.Debug(webhookData)
@@ -60,9 +70,29 @@ func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 
 func handlePipelineEvent(webhookData PipelineEvent) {
 	log.Info("GitLab Webhook received...")
+	if !shouldNotify(webhookData) {
+		log.WithFields(log.Fields{
+			"project": webhookData.Project.PathWithNamespace,
+			"branch":  webhookData.ObjectAttributes.Ref,
+		}).Info("Pipeline event filtered out by project or branch allowlist")
+		return
+	}
+
+	if err := handlePipelinePaging(webhookData); err != nil {
+		log.WithError(err).Error("Failed to page for the pipeline")
+	}
+
 	for _, build := range webhookData.Builds {
 		if build.Status == "manual" && build.Manual {
-			sendMattermostNotification(build.Name, fmt.Sprintf("Approve here: %s/-/jobs/%d", webhookData.Project.WebURL, build.ID))
+			if autoAbortManualJob(webhookData, build) {
+				return
+			}
+			if err := sendMattermostNotification(resolveHook(webhookData.Project.PathWithNamespace), build.Name, fmt.Sprintf("Approve here: %s/-/jobs/%d", webhookData.Project.WebURL, build.ID), pipelineDetailFields(webhookData)); err != nil {
+				log.WithError(err).WithFields(log.Fields{
+					"project": webhookData.Project.PathWithNamespace,
+					"job":     build.ID,
+				}).Error("Failed to send the manual approval notification")
+			}
 			return
 		}
 	}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
-	"os"
+	"time"
 
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/colors"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/metrics"
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/pkg/errors"
 )
@@ -24,34 +26,37 @@ func send(webhookURL string, payload model.CommandResponse) error {
 	req.Header.Set("Content-Type", "application/json")
 
 	client := &http.Client{}
+	start := time.Now()
 	resp, err := client.Do(req)
+	metrics.RecordNotificationLatency(metrics.TargetMattermost, start)
 	if err != nil {
 		return errors.Wrap(err, "failed to send HTTP request")
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return errors.Wrapf(err, "unexpected response status: %s", resp.Status)
+		return errors.Errorf("unexpected response status: %s", resp.Status)
 	}
 
 	return nil
 }
 
-func sendMattermostNotification(jobName, message string) error {
+func sendMattermostNotification(webhookURL, jobName, message string, details []*model.SlackAttachmentField) error {
 	attachment := &model.SlackAttachment{
-		Color: "#00FF33",
+		Color: colors.Success(),
 		Fields: []*model.SlackAttachmentField{
 			{Title: "New Pipeline to approve", Value: "To abort this job, set the **TO_ABORT** environment variable to `true`", Short: false},
 			{Title: jobName, Value: message, Short: false},
 		},
 	}
+	attachment.Fields = append(attachment.Fields, details...)
 
 	payload := model.CommandResponse{
 		Username:    "GitLab Pipeline Manual Approval",
 		IconURL:     "https://upload.wikimedia.org/wikipedia/commons/thumb/1/18/GitLab_Logo.svg/1108px-GitLab_Logo.svg.png",
 		Attachments: []*model.SlackAttachment{attachment},
 	}
-	err := send(os.Getenv("MATTERMOST_NOTIFICATION_HOOK"), payload)
+	err := send(webhookURL, payload)
 	if err != nil {
 		return errors.Wrap(err, "failed tο send Mattermost error payload")
 	}
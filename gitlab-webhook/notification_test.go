@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSend(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	require.NoError(t, send(mattermost.URL, model.CommandResponse{Text: "approve"}))
+
+	mattermost.SetStatusCode(http.StatusInternalServerError)
+	err := send(mattermost.URL, model.CommandResponse{Text: "approve"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "500 Internal Server Error")
+}
+
+func TestSendMattermostNotificationStatusError(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	mattermost.SetStatusCode(http.StatusInternalServerError)
+
+	assert.Error(t, sendMattermostNotification(mattermost.URL, "deploy", "Approve here", nil))
+}
+
+func TestHandlePipelineEventNotificationFailure(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	mattermost := testutil.NewMattermostServer(t)
+	mattermost.SetStatusCode(http.StatusInternalServerError)
+	t.Setenv("MATTERMOST_NOTIFICATION_HOOK", mattermost.URL)
+	t.Setenv("PROJECT_NOTIFICATION_HOOKS", "")
+	t.Setenv("PAGERDUTY_INTEGRATION_KEY", "")
+	t.Setenv("GITLAB_API_URL", "")
+	t.Setenv("GITLAB_API_TOKEN", "")
+
+	handlePipelineEvent(enrichmentEvent())
+
+	require.Len(t, mattermost.Bodies(), 1)
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, "Failed to send the manual approval notification", entry.Message)
+	assert.Equal(t, "mattermost/cloud", entry.Data["project"])
+	assert.Equal(t, 9, entry.Data["job"])
+}
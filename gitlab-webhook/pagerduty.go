@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	pagerduty "github.com/PagerDuty/go-pagerduty"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/metrics"
+	cloudpagerduty "github.com/mattermost/mattermost-cloud-lambdas/internal/pagerduty"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	pipelineStatusFailed  = "failed"
+	pipelineStatusSuccess = "success"
+)
+
+// defaultPagerDutySource is the source of the PagerDuty events when
+// PAGERDUTY_SOURCE is unset.
+const defaultPagerDutySource = "gitlab-webhook"
+
+// pagerDutyClient sends the PagerDuty events.
+var pagerDutyClient = pagerduty.NewClient("")
+
+// pipelineDedupKey identifies the PagerDuty incident of a project branch, so
+// a later successful pipeline on the branch resolves it.
+func pipelineDedupKey(webhookData PipelineEvent) string {
+	return fmt.Sprintf("gitlab-%s-%s", webhookData.Project.PathWithNamespace, webhookData.ObjectAttributes.Ref)
+}
+
+// shouldPage reports whether pipelines of the branch page PagerDuty, i.e.
+// PAGERDUTY_INTEGRATION_KEY is set and the branch is in PAGE_ON_BRANCHES.
+func shouldPage(webhookData PipelineEvent) bool {
+	if os.Getenv("PAGERDUTY_INTEGRATION_KEY") == "" || webhookData.ObjectAttributes.Tag {
+		return false
+	}
+
+	branches := parseList(os.Getenv("PAGE_ON_BRANCHES"))
+	if len(branches) == 0 {
+		return false
+	}
+
+	return isAllowed(branches, webhookData.ObjectAttributes.Ref)
+}
+
+// handlePipelinePaging triggers a PagerDuty incident when a pipeline fails on
+// a paging branch and resolves it when a pipeline on the branch succeeds.
+func handlePipelinePaging(webhookData PipelineEvent) error {
+	if !shouldPage(webhookData) {
+		return nil
+	}
+
+	event := pagerduty.V2Event{
+		RoutingKey: os.Getenv("PAGERDUTY_INTEGRATION_KEY"),
+		DedupKey:   pipelineDedupKey(webhookData),
+	}
+
+	switch webhookData.ObjectAttributes.Status {
+	case pipelineStatusFailed:
+		event.Action = "trigger"
+		event.Payload = &pagerduty.V2Payload{
+			Summary:  fmt.Sprintf("GitLab pipeline failed on %s %s", webhookData.Project.PathWithNamespace, webhookData.ObjectAttributes.Ref),
+			Severity: "critical",
+			Details: map[string]interface{}{
+				"Pipeline": fmt.Sprintf("%s/-/pipelines/%d", webhookData.Project.WebURL, webhookData.ObjectAttributes.ID),
+				"Commit":   webhookData.Commit.URL,
+				"User":     webhookData.User.Username,
+			},
+		}
+		cloudpagerduty.SetOrigin(event.Payload, defaultPagerDutySource)
+	case pipelineStatusSuccess:
+		event.Action = "resolve"
+	default:
+		return nil
+	}
+
+	start := time.Now()
+	_, err := pagerDutyClient.ManageEventWithContext(context.Background(), &event)
+	metrics.RecordNotificationLatency(metrics.TargetPagerDuty, start)
+	if err != nil {
+		return errors.Wrapf(err, "failed to %s PagerDuty incident %s", event.Action, event.DedupKey)
+	}
+
+	log.WithFields(log.Fields{
+		"action":    event.Action,
+		"dedup_key": event.DedupKey,
+	}).Info("PagerDuty event sent successfully")
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newPagerDutyServer starts a fake PagerDuty Events API for gitlab-webhook to
+// page.
+func newPagerDutyServer(t *testing.T) *testutil.PagerDutyServer {
+	t.Helper()
+
+	server := testutil.NewPagerDutyServer(t)
+	t.Setenv("PAGERDUTY_INTEGRATION_KEY", "routing-key")
+
+	previousClient := pagerDutyClient
+	pagerDutyClient = server.Client()
+	t.Cleanup(func() { pagerDutyClient = previousClient })
+
+	return server
+}
+
+func pipelineEvent(branch, status string) PipelineEvent {
+	return PipelineEvent{
+		ObjectAttributes: ObjectAttributes{ID: 42, Ref: branch, Status: status},
+		Project:          Project{Name: "cloud", PathWithNamespace: "mattermost/cloud", WebURL: "https://gitlab.example.com/mattermost/cloud"},
+	}
+}
+
+func TestShouldPage(t *testing.T) {
+	t.Setenv("PAGERDUTY_INTEGRATION_KEY", "routing-key")
+
+	t.Setenv("PAGE_ON_BRANCHES", "")
+	assert.False(t, shouldPage(pipelineEvent("master", pipelineStatusFailed)))
+
+	t.Setenv("PAGE_ON_BRANCHES", "master, production")
+	assert.True(t, shouldPage(pipelineEvent("production", pipelineStatusFailed)))
+	assert.False(t, shouldPage(pipelineEvent("feature", pipelineStatusFailed)))
+
+	tag := pipelineEvent("master", pipelineStatusFailed)
+	tag.ObjectAttributes.Tag = true
+	assert.False(t, shouldPage(tag))
+
+	t.Setenv("PAGERDUTY_INTEGRATION_KEY", "")
+	assert.False(t, shouldPage(pipelineEvent("master", pipelineStatusFailed)))
+}
+
+func TestHandlePipelinePaging(t *testing.T) {
+	pagerDuty := newPagerDutyServer(t)
+	t.Setenv("PAGE_ON_BRANCHES", "master")
+
+	require.NoError(t, handlePipelinePaging(pipelineEvent("master", pipelineStatusFailed)))
+	require.NoError(t, handlePipelinePaging(pipelineEvent("master", "running")))
+	require.NoError(t, handlePipelinePaging(pipelineEvent("feature", pipelineStatusFailed)))
+	require.NoError(t, handlePipelinePaging(pipelineEvent("master", pipelineStatusSuccess)))
+
+	events := pagerDuty.Events()
+	require.Len(t, events, 2)
+
+	assert.Equal(t, "trigger", events[0].Action)
+	assert.Equal(t, "gitlab-mattermost/cloud-master", events[0].DedupKey)
+	assert.Equal(t, "routing-key", events[0].RoutingKey)
+	require.NotNil(t, events[0].Payload)
+	assert.Contains(t, events[0].Payload.Summary, "mattermost/cloud master")
+	assert.Equal(t, "gitlab-webhook", events[0].Payload.Source)
+	assert.Equal(t, "https://gitlab.example.com/mattermost/cloud/-/pipelines/42", events[0].Payload.Details.(map[string]interface{})["Pipeline"])
+
+	assert.Equal(t, "resolve", events[1].Action)
+	assert.Equal(t, events[0].DedupKey, events[1].DedupKey)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// parseProjectHooks parses the PROJECT_NOTIFICATION_HOOKS JSON object which
+// maps a project path with namespace to a Mattermost webhook URL.
+func parseProjectHooks(value string) (map[string]string, error) {
+	hooks := map[string]string{}
+	if value == "" {
+		return hooks, nil
+	}
+
+	if err := json.Unmarshal([]byte(value), &hooks); err != nil {
+		return nil, errors.Wrap(err, "failed to parse project notification hooks")
+	}
+
+	return hooks, nil
+}
+
+// resolveHook returns the Mattermost webhook configured for the given project,
+// falling back to MATTERMOST_NOTIFICATION_HOOK.
+func resolveHook(projectPath string) string {
+	defaultHook := os.Getenv("MATTERMOST_NOTIFICATION_HOOK")
+
+	hooks, err := parseProjectHooks(os.Getenv("PROJECT_NOTIFICATION_HOOKS"))
+	if err != nil {
+		log.WithError(err).Error("Falling back to the default notification hook")
+		return defaultHook
+	}
+
+	if hook, ok := hooks[projectPath]; ok && hook != "" {
+		return hook
+	}
+
+	return defaultHook
+}
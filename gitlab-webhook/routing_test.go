@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProjectHooks(t *testing.T) {
+	hooks, err := parseProjectHooks("")
+	require.NoError(t, err)
+	assert.Empty(t, hooks)
+
+	hooks, err = parseProjectHooks(`{"mattermost/cloud": "https://hooks/cloud"}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"mattermost/cloud": "https://hooks/cloud"}, hooks)
+
+	_, err = parseProjectHooks("not-json")
+	assert.Error(t, err)
+}
+
+func TestResolveHook(t *testing.T) {
+	t.Setenv("MATTERMOST_NOTIFICATION_HOOK", "https://hooks/default")
+	t.Setenv("PROJECT_NOTIFICATION_HOOKS", `{"mattermost/cloud": "https://hooks/cloud", "mattermost/infra": "https://hooks/infra"}`)
+
+	testCases := []struct {
+		project  string
+		expected string
+	}{
+		{"mattermost/cloud", "https://hooks/cloud"},
+		{"mattermost/infra", "https://hooks/infra"},
+		{"mattermost/other", "https://hooks/default"},
+		{"", "https://hooks/default"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.project, func(t *testing.T) {
+			assert.Equal(t, tc.expected, resolveHook(tc.project))
+		})
+	}
+}
+
+func TestResolveHookInvalidMapping(t *testing.T) {
+	t.Setenv("MATTERMOST_NOTIFICATION_HOOK", "https://hooks/default")
+	t.Setenv("PROJECT_NOTIFICATION_HOOKS", "{invalid")
+
+	assert.Equal(t, "https://hooks/default", resolveHook("mattermost/cloud"))
+}
@@ -6,6 +6,9 @@ require (
 	github.com/aws/aws-lambda-go v1.47.0
 	github.com/aws/aws-sdk-go v1.55.5
 	github.com/lib/pq v1.10.9
+	github.com/mattermost/mattermost-cloud-lambdas/internal/retry v0.0.0
 )
 
 require github.com/jmespath/go-jmespath v0.4.0 // indirect
+
+replace github.com/mattermost/mattermost-cloud-lambdas/internal/retry => ../internal/retry
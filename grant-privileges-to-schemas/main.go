@@ -1,6 +1,7 @@
 // Package main provides a Lambda function to manage PostgreSQL permissions for schemas and databases
 // within multi-tenant RDS clusters. It fetches credentials, logical database mappings, and applies
-// appropriate permissions for reader and writer roles.
+// appropriate permissions for reader and writer roles. DB_USERNAME must be a member of the roles
+// owning the schemas to set their default privileges.
 package main
 
 import (
@@ -9,6 +10,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,15 +20,17 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/rds"
-	"github.com/aws/aws-sdk-go/service/secretsmanager"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 const (
-	readerUser = "teleport_db_reader"
-	writerUser = "teleport_db_writer"
+	defaultReaderRole = "teleport_db_reader"
+	defaultWriterRole = "teleport_db_writer"
 )
 
+// identifierPattern matches the unquoted PostgreSQL identifiers accepted as role names.
+var identifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
 // Environment variables
 var (
 	dbUsername        = os.Getenv("DB_USERNAME")
@@ -32,16 +38,11 @@ var (
 	provisionerDBURL  = os.Getenv("PROVISIONER_DB_URL")
 	provisionerDBUser = os.Getenv("PROVISIONER_DB_USER")
 	excludedClusters  = parseExcludedClusters(os.Getenv("EXCLUDED_CLUSTERS"))
+	dryRun            = strings.EqualFold(os.Getenv("DRY_RUN"), "true")
+	readerUser        = envOrDefault("READER_ROLE", defaultReaderRole)
+	writerUser        = envOrDefault("WRITER_ROLE", defaultWriterRole)
 )
 
-// Secrets manager client
-var smClient *secretsmanager.SecretsManager
-
-func init() {
-	sess := session.Must(session.NewSession())
-	smClient = secretsmanager.New(sess)
-}
-
 // parseExcludedClusters parses a comma-separated list of excluded clusters.
 func parseExcludedClusters(excluded string) map[string]struct{} {
 	clusters := strings.Split(excluded, ",")
@@ -55,28 +56,41 @@ func parseExcludedClusters(excluded string) map[string]struct{} {
 	return excludedMap
 }
 
+// envOrDefault returns the value of an environment variable, or fallback when it is not set.
+func envOrDefault(name, fallback string) string {
+	if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// validateRoles checks that the reader and writer roles are safe to use unquoted in statements.
+func validateRoles() error {
+	for _, role := range []string{readerUser, writerUser} {
+		if !identifierPattern.MatchString(role) {
+			return fmt.Errorf("invalid role name %q: must be a lowercase identifier of letters, digits and underscores", role)
+		}
+	}
+	return nil
+}
+
 // isExcludedCluster checks if a cluster is in the excluded list.
 func isExcludedCluster(cluster string) bool {
 	_, exists := excludedClusters[cluster]
 	return exists
 }
 
-// GetSecret retrieves the secret value from AWS Secrets Manager.
-func GetSecret(secretName string) (string, error) {
-	input := &secretsmanager.GetSecretValueInput{
-		SecretId: aws.String(secretName),
-	}
-	result, err := smClient.GetSecretValue(input)
-	if err != nil {
-		return "", fmt.Errorf("failed to retrieve secret %s: %w", secretName, err)
-	}
-	return *result.SecretString, nil
-}
-
-// getActivityDate retrieves and parses the activity date.
+// getActivityDate retrieves and parses the activity date. An explicit ACTIVITY_DATE takes
+// precedence over ACTIVITY_LOOKBACK_HOURS.
 func getActivityDate() (int64, error) {
 	dateStr := os.Getenv("ACTIVITY_DATE")
 
+	if dateStr == "" {
+		if lookback := os.Getenv("ACTIVITY_LOOKBACK_HOURS"); lookback != "" {
+			return lookbackCutoff(lookback, time.Now())
+		}
+	}
+
 	if dateStr == "now" {
 		dateStr = time.Now().Format("2006-01-02")
 	} else if dateStr == "" {
@@ -90,6 +104,16 @@ func getActivityDate() (int64, error) {
 	return parsedDate.UTC().UnixMilli(), nil
 }
 
+// lookbackCutoff returns the createat cutoff, in milliseconds, of schemas created in the
+// last hours before now.
+func lookbackCutoff(hours string, now time.Time) (int64, error) {
+	lookback, err := strconv.Atoi(hours)
+	if err != nil || lookback <= 0 {
+		return 0, fmt.Errorf("invalid ACTIVITY_LOOKBACK_HOURS %q: must be a positive number of hours", hours)
+	}
+	return now.Add(-time.Duration(lookback) * time.Hour).UTC().UnixMilli(), nil
+}
+
 // getWriterEndpoint fetches the writer endpoint for a given RDS cluster.
 func getWriterEndpoint(clusterIdentifier string) (string, error) {
 	sess := session.Must(session.NewSession())
@@ -153,68 +177,148 @@ func fetchSchemasAndClusters(provisionerDB *sql.DB, activityDate int64) (map[str
 	return schemaToDB, dbToCluster, nil
 }
 
-// applyPermissionsToDatabase applies the necessary permissions to schemas and tables.
-func applyPermissionsToDatabase(db *sql.DB, schemas map[string]string, logicalDatabase string, cluster string) error {
-	for schema, targetDB := range schemas {
-		if targetDB != logicalDatabase {
-			continue
-		}
+// database is the subset of a logical database connection used to apply permissions.
+type database interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	schemaOwner(schema string) (string, error)
+}
 
-		log.Printf("Running privileges on schema %s which lives in %s, in cluster %s", schema, logicalDatabase, cluster)
+// clusterDB is a connection to a logical database of a cluster.
+type clusterDB struct {
+	*sql.DB
+}
+
+// schemaOwner returns the role owning a schema, which is the installation user creating its objects.
+func (db clusterDB) schemaOwner(schema string) (string, error) {
+	var owner string
+	err := db.QueryRow("SELECT pg_get_userbyid(nspowner) FROM pg_namespace WHERE nspname = $1;", schema).Scan(&owner)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up the owner of schema %s: %w", schema, err)
+	}
+	return owner, nil
+}
+
+// dryRunOwner stands for the schema owner in dry runs, which do not connect to the clusters.
+func dryRunOwner(schema string) string {
+	return fmt.Sprintf("<owner of %s>", schema)
+}
 
+// schemaGrants returns the statements giving the reader and writer users access to a schema,
+// its tables and its sequences. The GRANT statements only cover the sequences that exist when
+// they run, so the default privileges of the schema owner, given as a quoted identifier, extend
+// them to the sequences it creates later, such as those of new tables and migrations. Altering
+// the default privileges of another role requires DB_USERNAME to be a member of that role. The
+// default privileges are left out when owner is empty.
+func schemaGrants(schema, owner string) []string {
+	statements := []string{
 		// Grant permissions for reader user
-		if _, err := db.Exec(fmt.Sprintf("GRANT USAGE ON SCHEMA %s TO %s;", schema, readerUser)); err != nil {
-			log.Printf("Failed to grant USAGE on schema %s to %s: %v", schema, readerUser, err)
-		} else {
-			log.Printf("Granted USAGE on schema %s to %s", schema, readerUser)
-		}
+		fmt.Sprintf("GRANT USAGE ON SCHEMA %s TO %s;", schema, readerUser),
+		fmt.Sprintf("GRANT SELECT ON ALL TABLES IN SCHEMA %s TO %s;", schema, readerUser),
+		fmt.Sprintf("GRANT SELECT ON ALL SEQUENCES IN SCHEMA %s TO %s;", schema, readerUser),
+		// Grant permissions for writer user
+		fmt.Sprintf("GRANT USAGE, CREATE ON SCHEMA %s TO %s;", schema, writerUser),
+		fmt.Sprintf("GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA %s TO %s;", schema, writerUser),
+		fmt.Sprintf("GRANT USAGE, SELECT, UPDATE ON ALL SEQUENCES IN SCHEMA %s TO %s;", schema, writerUser),
+	}
+	if owner == "" {
+		return statements
+	}
 
-		if _, err := db.Exec(fmt.Sprintf("GRANT SELECT ON ALL TABLES IN SCHEMA %s TO %s;", schema, readerUser)); err != nil {
-			log.Printf("Failed to grant SELECT on all tables in schema %s to %s: %v", schema, readerUser, err)
-		} else {
-			log.Printf("Granted SELECT on all tables in schema %s to %s", schema, readerUser)
+	return append(statements,
+		fmt.Sprintf("ALTER DEFAULT PRIVILEGES FOR ROLE %s IN SCHEMA %s GRANT SELECT ON SEQUENCES TO %s;", owner, schema, readerUser),
+		fmt.Sprintf("ALTER DEFAULT PRIVILEGES FOR ROLE %s IN SCHEMA %s GRANT USAGE, SELECT, UPDATE ON SEQUENCES TO %s;", owner, schema, writerUser),
+	)
+}
+
+// applyPermissionsToDatabase applies the necessary permissions to schemas and tables, and returns
+// the statements it ran. In dry run mode the statements are only logged and db is not used.
+func applyPermissionsToDatabase(db database, schemas map[string]string, logicalDatabase string, cluster string, dryRun bool) []string {
+	var names []string
+	for schema, targetDB := range schemas {
+		if targetDB == logicalDatabase {
+			names = append(names, schema)
 		}
+	}
+	sort.Strings(names)
 
-		// Grant permissions for writer user
-		if _, err := db.Exec(fmt.Sprintf("GRANT USAGE, CREATE ON SCHEMA %s TO %s;", schema, writerUser)); err != nil {
-			log.Printf("Failed to grant USAGE, CREATE on schema %s to %s: %v", schema, writerUser, err)
-		} else {
-			log.Printf("Granted USAGE, CREATE on schema %s to %s", schema, writerUser)
+	var statements []string
+	for _, schema := range names {
+		log.Printf("Running privileges on schema %s which lives in %s, in cluster %s", schema, logicalDatabase, cluster)
+
+		owner := dryRunOwner(schema)
+		if !dryRun {
+			var err error
+			if owner, err = db.schemaOwner(schema); err != nil {
+				log.Printf("Skipping the default privileges of schema %s: %v", schema, err)
+			} else {
+				owner = pq.QuoteIdentifier(owner)
+			}
 		}
 
-		if _, err := db.Exec(fmt.Sprintf("GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA %s TO %s;", schema, writerUser)); err != nil {
-			log.Printf("Failed to grant ALL PRIVILEGES on all tables in schema %s to %s: %v", schema, writerUser, err)
-		} else {
-			log.Printf("Granted ALL PRIVILEGES on all tables in schema %s to %s", schema, writerUser)
+		for _, statement := range schemaGrants(schema, owner) {
+			statements = append(statements, statement)
+			if dryRun {
+				log.Printf("Dry run, would execute: %s", statement)
+				continue
+			}
+			if _, err := db.Exec(statement); err != nil {
+				log.Printf("Failed to execute %q: %v", statement, err)
+			} else {
+				log.Printf("Executed: %s", statement)
+			}
 		}
 	}
 
-	return nil
+	return statements
+}
+
+// plannedGrants lists the GRANT statements of a dry run per cluster and logical database.
+type plannedGrants map[string]map[string][]string
+
+func (p plannedGrants) add(cluster, logicalDatabase string, statements []string) {
+	if len(statements) == 0 {
+		return
+	}
+	if p[cluster] == nil {
+		p[cluster] = make(map[string][]string)
+	}
+	p[cluster][logicalDatabase] = statements
 }
 
-// Handler is the main entry point for the Lambda function.
-func Handler(_ context.Context) error {
+// Handler is the main entry point for the Lambda function. In dry run mode it returns the
+// GRANT statements it would have executed instead of connecting to the clusters.
+func Handler(_ context.Context) (plannedGrants, error) {
+	if err := validateRoles(); err != nil {
+		return nil, err
+	}
+	resetSecretCache()
+
 	provisionerSecret := fmt.Sprintf("provisioner-%s", environment)
-	provisionerPassword, err := GetSecret(provisionerSecret)
+	provisionerPassword, err := getPassword(provisionerSecret)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve provisioner DB password: %w", err)
+		return nil, fmt.Errorf("failed to retrieve provisioner DB password: %w", err)
 	}
 
-	provisionerConnStr := fmt.Sprintf("host=%s user=%s password=%s dbname=cloud sslmode=disable", provisionerDBURL, provisionerDBUser, provisionerPassword)
-	provisionerDB, err := sql.Open("postgres", provisionerConnStr)
+	provisionerDB, err := sql.Open("postgres", provisionerConnString(provisionerPassword, dryRun))
 	if err != nil {
-		return fmt.Errorf("failed to connect to provisioner database: %w", err)
+		return nil, fmt.Errorf("failed to connect to provisioner database: %w", err)
 	}
 	defer provisionerDB.Close()
 
 	activityDate, err := getActivityDate()
 	if err != nil {
-		return fmt.Errorf("failed to parse activity date: %w", err)
+		return nil, fmt.Errorf("failed to parse activity date: %w", err)
 	}
 
 	schemaToDB, dbToCluster, err := fetchSchemasAndClusters(provisionerDB, activityDate)
 	if err != nil {
-		return fmt.Errorf("failed to fetch schemas and clusters: %w", err)
+		return nil, fmt.Errorf("failed to fetch schemas and clusters: %w", err)
+	}
+
+	if dryRun {
+		plan := planGrants(schemaToDB, dbToCluster)
+		log.Printf("Dry run finished, %d cluster(s) would be updated.", len(plan))
+		return plan, nil
 	}
 
 	for logicalDatabase, cluster := range dbToCluster {
@@ -229,7 +333,7 @@ func Handler(_ context.Context) error {
 			continue
 		}
 
-		password, err := GetSecret(cluster)
+		password, err := getPassword(cluster)
 		if err != nil {
 			log.Printf("Failed to retrieve password for cluster %s: %v", cluster, err)
 			continue
@@ -243,13 +347,34 @@ func Handler(_ context.Context) error {
 		}
 		defer db.Close()
 
-		if err := applyPermissionsToDatabase(db, schemaToDB, logicalDatabase, cluster); err != nil {
-			log.Printf("Failed to apply permissions to database %s: %v", logicalDatabase, err)
-		}
+		applyPermissionsToDatabase(clusterDB{db}, schemaToDB, logicalDatabase, cluster, false)
 	}
 
 	log.Println("Permissions successfully applied across all databases and clusters.")
-	return nil
+	return nil, nil
+}
+
+// planGrants lists the statements a run would execute, without connecting to the clusters.
+func planGrants(schemaToDB, dbToCluster map[string]string) plannedGrants {
+	plan := make(plannedGrants)
+	for logicalDatabase, cluster := range dbToCluster {
+		if isExcludedCluster(cluster) {
+			log.Printf("Skipping excluded cluster %s", cluster)
+			continue
+		}
+		plan.add(cluster, logicalDatabase, applyPermissionsToDatabase(nil, schemaToDB, logicalDatabase, cluster, true))
+	}
+	return plan
+}
+
+// provisionerConnString returns the provisioner database connection string. Dry runs open
+// read-only transactions, as they only discover the schemas.
+func provisionerConnString(password string, readOnly bool) string {
+	connStr := fmt.Sprintf("host=%s user=%s password=%s dbname=cloud sslmode=disable", provisionerDBURL, provisionerDBUser, password)
+	if readOnly {
+		connStr += " default_transaction_read_only=on"
+	}
+	return connStr
 }
 
 func main() {
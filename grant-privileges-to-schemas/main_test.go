@@ -0,0 +1,212 @@
+package main
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDB records the statements it is asked to execute. Schemas are owned by a role named after
+// them unless they are listed in missingOwners.
+type fakeDB struct {
+	executed      []string
+	missingOwners []string
+}
+
+func (f *fakeDB) Exec(query string, _ ...any) (sql.Result, error) {
+	f.executed = append(f.executed, query)
+	return nil, nil
+}
+
+func (f *fakeDB) schemaOwner(schema string) (string, error) {
+	for _, missing := range f.missingOwners {
+		if schema == missing {
+			return "", sql.ErrNoRows
+		}
+	}
+	return schema + "_owner", nil
+}
+
+var testSchemas = map[string]string{
+	"id_b": "cloud_1",
+	"id_a": "cloud_1",
+	"id_c": "cloud_2",
+}
+
+func TestApplyPermissionsDryRun(t *testing.T) {
+	db := &fakeDB{}
+
+	statements := applyPermissionsToDatabase(db, testSchemas, "cloud_1", "rds-cluster-1", true)
+
+	if len(db.executed) != 0 {
+		t.Fatalf("dry run executed %d statement(s): %v", len(db.executed), db.executed)
+	}
+	expected := append(schemaGrants("id_a", dryRunOwner("id_a")), schemaGrants("id_b", dryRunOwner("id_b"))...)
+	if !reflect.DeepEqual(statements, expected) {
+		t.Errorf("unexpected statements:\n got: %v\nwant: %v", statements, expected)
+	}
+}
+
+func TestApplyPermissions(t *testing.T) {
+	db := &fakeDB{}
+
+	statements := applyPermissionsToDatabase(db, testSchemas, "cloud_2", "rds-cluster-2", false)
+
+	expected := []string{
+		"GRANT USAGE ON SCHEMA id_c TO teleport_db_reader;",
+		"GRANT SELECT ON ALL TABLES IN SCHEMA id_c TO teleport_db_reader;",
+		"GRANT SELECT ON ALL SEQUENCES IN SCHEMA id_c TO teleport_db_reader;",
+		"GRANT USAGE, CREATE ON SCHEMA id_c TO teleport_db_writer;",
+		"GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA id_c TO teleport_db_writer;",
+		"GRANT USAGE, SELECT, UPDATE ON ALL SEQUENCES IN SCHEMA id_c TO teleport_db_writer;",
+		`ALTER DEFAULT PRIVILEGES FOR ROLE "id_c_owner" IN SCHEMA id_c GRANT SELECT ON SEQUENCES TO teleport_db_reader;`,
+		`ALTER DEFAULT PRIVILEGES FOR ROLE "id_c_owner" IN SCHEMA id_c GRANT USAGE, SELECT, UPDATE ON SEQUENCES TO teleport_db_writer;`,
+	}
+	if !reflect.DeepEqual(db.executed, expected) {
+		t.Errorf("unexpected executed statements:\n got: %v\nwant: %v", db.executed, expected)
+	}
+	if !reflect.DeepEqual(statements, expected) {
+		t.Errorf("unexpected returned statements:\n got: %v\nwant: %v", statements, expected)
+	}
+}
+
+func TestPlanGrants(t *testing.T) {
+	excludedClusters = parseExcludedClusters("rds-cluster-2")
+	defer func() { excludedClusters = map[string]struct{}{} }()
+
+	plan := planGrants(testSchemas, map[string]string{
+		"cloud_1": "rds-cluster-1",
+		"cloud_2": "rds-cluster-2",
+	})
+
+	expected := plannedGrants{
+		"rds-cluster-1": {"cloud_1": append(schemaGrants("id_a", dryRunOwner("id_a")), schemaGrants("id_b", dryRunOwner("id_b"))...)},
+	}
+	if !reflect.DeepEqual(plan, expected) {
+		t.Errorf("unexpected plan:\n got: %v\nwant: %v", plan, expected)
+	}
+}
+
+func TestProvisionerConnString(t *testing.T) {
+	if strings.Contains(provisionerConnString("secret", false), "read_only") {
+		t.Error("connection string should not be read-only outside of dry runs")
+	}
+	if !strings.HasSuffix(provisionerConnString("secret", true), " default_transaction_read_only=on") {
+		t.Error("dry run connection string should be read-only")
+	}
+}
+
+func TestConfiguredRoles(t *testing.T) {
+	defer func(reader, writer string) { readerUser, writerUser = reader, writer }(readerUser, writerUser)
+	t.Setenv("READER_ROLE", "staging_reader")
+	t.Setenv("WRITER_ROLE", "")
+	readerUser = envOrDefault("READER_ROLE", defaultReaderRole)
+	writerUser = envOrDefault("WRITER_ROLE", defaultWriterRole)
+
+	if err := validateRoles(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"GRANT USAGE ON SCHEMA id_a TO staging_reader;",
+		"GRANT SELECT ON ALL TABLES IN SCHEMA id_a TO staging_reader;",
+		"GRANT SELECT ON ALL SEQUENCES IN SCHEMA id_a TO staging_reader;",
+		"GRANT USAGE, CREATE ON SCHEMA id_a TO teleport_db_writer;",
+		"GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA id_a TO teleport_db_writer;",
+		"GRANT USAGE, SELECT, UPDATE ON ALL SEQUENCES IN SCHEMA id_a TO teleport_db_writer;",
+		`ALTER DEFAULT PRIVILEGES FOR ROLE "owner" IN SCHEMA id_a GRANT SELECT ON SEQUENCES TO staging_reader;`,
+		`ALTER DEFAULT PRIVILEGES FOR ROLE "owner" IN SCHEMA id_a GRANT USAGE, SELECT, UPDATE ON SEQUENCES TO teleport_db_writer;`,
+	}
+	if statements := schemaGrants("id_a", `"owner"`); !reflect.DeepEqual(statements, expected) {
+		t.Errorf("unexpected statements:\n got: %v\nwant: %v", statements, expected)
+	}
+}
+
+func TestValidateRoles(t *testing.T) {
+	defer func(reader, writer string) { readerUser, writerUser = reader, writer }(readerUser, writerUser)
+
+	for _, role := range []string{"reader; DROP TABLE users", "Reader", "1reader", "", strings.Repeat("r", 64)} {
+		readerUser, writerUser = defaultReaderRole, role
+		if err := validateRoles(); err == nil {
+			t.Errorf("expected role %q to be rejected", role)
+		}
+	}
+
+	readerUser, writerUser = "_reader", "writer_2"
+	if err := validateRoles(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSequenceGrants(t *testing.T) {
+	db := &fakeDB{}
+	applyPermissionsToDatabase(db, testSchemas, "cloud_2", "rds-cluster-2", false)
+
+	var sequenceGrants []string
+	for _, statement := range db.executed {
+		if strings.Contains(statement, "SEQUENCES") {
+			sequenceGrants = append(sequenceGrants, statement)
+		}
+	}
+
+	expected := []string{
+		"GRANT SELECT ON ALL SEQUENCES IN SCHEMA id_c TO teleport_db_reader;",
+		"GRANT USAGE, SELECT, UPDATE ON ALL SEQUENCES IN SCHEMA id_c TO teleport_db_writer;",
+		`ALTER DEFAULT PRIVILEGES FOR ROLE "id_c_owner" IN SCHEMA id_c GRANT SELECT ON SEQUENCES TO teleport_db_reader;`,
+		`ALTER DEFAULT PRIVILEGES FOR ROLE "id_c_owner" IN SCHEMA id_c GRANT USAGE, SELECT, UPDATE ON SEQUENCES TO teleport_db_writer;`,
+	}
+	if !reflect.DeepEqual(sequenceGrants, expected) {
+		t.Errorf("unexpected sequence grants:\n got: %v\nwant: %v", sequenceGrants, expected)
+	}
+}
+
+func TestSequenceGrantsWithoutOwner(t *testing.T) {
+	db := &fakeDB{missingOwners: []string{"id_a"}}
+	applyPermissionsToDatabase(db, testSchemas, "cloud_1", "rds-cluster-1", false)
+
+	expected := append(schemaGrants("id_a", ""), schemaGrants("id_b", `"id_b_owner"`)...)
+	if !reflect.DeepEqual(db.executed, expected) {
+		t.Errorf("unexpected executed statements:\n got: %v\nwant: %v", db.executed, expected)
+	}
+}
+
+func TestLookbackCutoff(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC)
+
+	cutoff, err := lookbackCutoff("6", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := time.Date(2024, 6, 1, 6, 30, 0, 0, time.UTC).UnixMilli(); cutoff != expected {
+		t.Errorf("unexpected cutoff: got %d, want %d", cutoff, expected)
+	}
+
+	for _, hours := range []string{"0", "-3", "six", "1.5"} {
+		if _, err := lookbackCutoff(hours, now); err == nil {
+			t.Errorf("expected lookback %q to be rejected", hours)
+		}
+	}
+}
+
+func TestGetActivityDate(t *testing.T) {
+	t.Setenv("ACTIVITY_DATE", "2023-01-15")
+	t.Setenv("ACTIVITY_LOOKBACK_HOURS", "6")
+	date, err := getActivityDate()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC).UnixMilli(); date != expected {
+		t.Errorf("explicit ACTIVITY_DATE should win: got %d, want %d", date, expected)
+	}
+
+	t.Setenv("ACTIVITY_DATE", "")
+	before := time.Now().Add(-6 * time.Hour).UnixMilli()
+	date, err = getActivityDate()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if after := time.Now().Add(-6 * time.Hour).UnixMilli(); date < before || date > after {
+		t.Errorf("lookback cutoff %d not within [%d, %d]", date, before, after)
+	}
+}
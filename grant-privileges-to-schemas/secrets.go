@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/retry"
+)
+
+// secretAttempts is how many times a throttled GetSecretValue call is tried.
+const secretAttempts = 5
+
+// defaultPasswordKey is the field holding the password in JSON secrets.
+const defaultPasswordKey = "password"
+
+// secretRetryDelay is the initial delay between throttled GetSecretValue calls.
+var secretRetryDelay = 500 * time.Millisecond
+
+// Secrets manager client
+var smClient secretsmanageriface.SecretsManagerAPI
+
+// secretCache holds the secrets retrieved during the current run, as every
+// logical database of a cluster shares the cluster secret.
+var secretCache = make(map[string]string)
+
+func init() {
+	sess := session.Must(session.NewSession())
+	smClient = secretsmanager.New(sess)
+}
+
+// resetSecretCache forgets the secrets of a previous run, so that rotated
+// secrets are picked up by warm Lambda instances.
+func resetSecretCache() {
+	secretCache = make(map[string]string)
+}
+
+// GetSecret retrieves the secret value from AWS Secrets Manager, retrying
+// throttled calls with backoff.
+func GetSecret(secretName string) (string, error) {
+	if secret, ok := secretCache[secretName]; ok {
+		return secret, nil
+	}
+
+	input := &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretName),
+	}
+	var secret string
+	err := retry.Do(secretAttempts, secretRetryDelay, func() error {
+		result, err := smClient.GetSecretValue(input)
+		if err != nil {
+			if !request.IsErrorThrottle(err) {
+				return retry.Permanent(err)
+			}
+			log.Printf("Throttled retrieving secret %s, retrying", secretName)
+			return err
+		}
+		secret = aws.StringValue(result.SecretString)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve secret %s: %w", secretName, err)
+	}
+
+	secretCache[secretName] = secret
+	return secret, nil
+}
+
+// getPassword retrieves a secret holding a database password. JSON secrets,
+// such as those managed by RDS, hold the password in their
+// SECRET_PASSWORD_KEY field, "password" by default.
+func getPassword(secretName string) (string, error) {
+	secret, err := GetSecret(secretName)
+	if err != nil {
+		return "", err
+	}
+	return secretPassword(secret, envOrDefault("SECRET_PASSWORD_KEY", defaultPasswordKey)), nil
+}
+
+// secretPassword extracts the key field of a JSON secret, or returns the
+// secret itself when it is not a JSON object holding a string key field.
+func secretPassword(secret, key string) string {
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+		return secret
+	}
+	if password, ok := fields[key].(string); ok {
+		return password
+	}
+	return secret
+}
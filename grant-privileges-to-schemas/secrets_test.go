@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+)
+
+// fakeSecretsManager returns errs in order, then the secret.
+type fakeSecretsManager struct {
+	secretsmanageriface.SecretsManagerAPI
+
+	errs   []error
+	secret string
+	calls  int
+}
+
+func (f *fakeSecretsManager) GetSecretValue(*secretsmanager.GetSecretValueInput) (*secretsmanager.GetSecretValueOutput, error) {
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(f.secret)}, nil
+}
+
+func useFakeSecretsManager(t *testing.T, fake *fakeSecretsManager) {
+	t.Helper()
+	previousClient, previousDelay := smClient, secretRetryDelay
+	smClient, secretRetryDelay = fake, time.Millisecond
+	resetSecretCache()
+	t.Cleanup(func() {
+		smClient, secretRetryDelay = previousClient, previousDelay
+		resetSecretCache()
+	})
+}
+
+func throttled() error {
+	return awserr.New("ThrottlingException", "Rate exceeded", nil)
+}
+
+func TestGetSecretRetriesThrottling(t *testing.T) {
+	fake := &fakeSecretsManager{errs: []error{throttled(), throttled()}, secret: "hunter2"}
+	useFakeSecretsManager(t, fake)
+
+	secret, err := GetSecret("rds-cluster-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if secret != "hunter2" {
+		t.Errorf("unexpected secret %q", secret)
+	}
+	if fake.calls != 3 {
+		t.Errorf("expected 3 calls, got %d", fake.calls)
+	}
+
+	if _, err := GetSecret("rds-cluster-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fake.calls != 3 {
+		t.Errorf("cached secret should not be retrieved again, got %d calls", fake.calls)
+	}
+}
+
+func TestGetSecretGivesUp(t *testing.T) {
+	fake := &fakeSecretsManager{errs: []error{throttled(), throttled(), throttled(), throttled(), throttled()}}
+	useFakeSecretsManager(t, fake)
+
+	_, err := GetSecret("rds-cluster-1")
+	if err == nil || err.Error() != "failed to retrieve secret rds-cluster-1: failed after 5 attempts: ThrottlingException: Rate exceeded" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if fake.calls != secretAttempts {
+		t.Errorf("expected %d calls, got %d", secretAttempts, fake.calls)
+	}
+}
+
+func TestGetSecretDoesNotRetryOtherErrors(t *testing.T) {
+	notFound := awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "Secret not found", nil)
+	fake := &fakeSecretsManager{errs: []error{notFound}}
+	useFakeSecretsManager(t, fake)
+
+	_, err := GetSecret("rds-cluster-1")
+	if !errors.Is(err, notFound) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if fake.calls != 1 {
+		t.Errorf("expected a single call, got %d", fake.calls)
+	}
+}
+
+func TestSecretPassword(t *testing.T) {
+	testCases := []struct {
+		name     string
+		secret   string
+		key      string
+		expected string
+	}{
+		{"plain", "hunter2", "password", "hunter2"},
+		{"plain with braces", "{hunter2", "password", "{hunter2"},
+		{"json", `{"username":"admin","password":"hunter2"}`, "password", "hunter2"},
+		{"json custom key", `{"username":"admin","dbPassword":"hunter2"}`, "dbPassword", "hunter2"},
+		{"json missing key", `{"username":"admin"}`, "password", `{"username":"admin"}`},
+		{"json non string key", `{"password":1234}`, "password", `{"password":1234}`},
+		{"json string", `"hunter2"`, "password", `"hunter2"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if password := secretPassword(tc.secret, tc.key); password != tc.expected {
+				t.Errorf("got %q, want %q", password, tc.expected)
+			}
+		})
+	}
+}
+
+func TestGetPassword(t *testing.T) {
+	useFakeSecretsManager(t, &fakeSecretsManager{secret: `{"username":"admin","secret":"hunter2"}`})
+	t.Setenv("SECRET_PASSWORD_KEY", "secret")
+
+	password, err := getPassword("rds-cluster-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if password != "hunter2" {
+		t.Errorf("got %q, want %q", password, "hunter2")
+	}
+}
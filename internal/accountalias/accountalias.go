@@ -0,0 +1,99 @@
+// Package accountalias looks up the IAM alias of the AWS account a lambda runs
+// in, so notifications can name the account instead of only its ID.
+package accountalias
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/awsconfig"
+	log "github.com/sirupsen/logrus"
+)
+
+const accessDeniedCode = "AccessDenied"
+
+// Enabled reports whether INCLUDE_ACCOUNT_ALIAS is set to true, in which case
+// notifications include the alias of the account the lambda runs in.
+func Enabled() bool {
+	return strings.EqualFold(os.Getenv("INCLUDE_ACCOUNT_ALIAS"), "true")
+}
+
+// Cache holds the account alias once it is looked up, so that a warm
+// container only calls IAM on its first notification. The zero value is ready
+// to use.
+type Cache struct {
+	// NewClient creates the IAM client. It defaults to a client using the
+	// session from awsconfig, and is set by tests to a fake.
+	NewClient func() (iamiface.IAMAPI, error)
+
+	mu     sync.Mutex
+	loaded bool
+	alias  string
+}
+
+func newIAMClient() (iamiface.IAMAPI, error) {
+	sess, err := awsconfig.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return iam.New(sess), nil
+}
+
+// Alias returns the alias of the account, or nothing if the alias is disabled
+// or cannot be looked up. A missing iam:ListAccountAliases permission is
+// cached like an account without alias, other failures are retried on the
+// next notification.
+func (c *Cache) Alias() string {
+	if !Enabled() {
+		return ""
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.loaded {
+		return c.alias
+	}
+
+	newClient := c.NewClient
+	if newClient == nil {
+		newClient = newIAMClient
+	}
+	svc, err := newClient()
+	if err != nil {
+		log.WithError(err).Warn("Failed to create the IAM client, sending the notification without account alias")
+		return ""
+	}
+
+	alias, err := lookup(svc)
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == accessDeniedCode {
+			log.WithError(err).Warn("Missing iam:ListAccountAliases permission, sending notifications without account alias")
+			c.loaded = true
+			return ""
+		}
+		log.WithError(err).Warn("Failed to look up the account alias, sending the notification without it")
+		return ""
+	}
+
+	c.loaded = true
+	c.alias = alias
+	return alias
+}
+
+// lookup returns the alias of the account, which has at most one.
+func lookup(svc iamiface.IAMAPI) (string, error) {
+	output, err := svc.ListAccountAliases(&iam.ListAccountAliasesInput{})
+	if err != nil {
+		return "", err
+	}
+	if len(output.AccountAliases) == 0 {
+		return "", nil
+	}
+
+	return aws.StringValue(output.AccountAliases[0]), nil
+}
@@ -0,0 +1,99 @@
+package accountalias
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeIAM embeds the client interface, so any call it does not implement
+// panics and fails the test.
+type fakeIAM struct {
+	iamiface.IAMAPI
+	aliases []string
+	err     error
+	calls   int
+}
+
+func (f *fakeIAM) ListAccountAliases(*iam.ListAccountAliasesInput) (*iam.ListAccountAliasesOutput, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &iam.ListAccountAliasesOutput{AccountAliases: aws.StringSlice(f.aliases)}, nil
+}
+
+func fakeCache(fake *fakeIAM) *Cache {
+	return &Cache{NewClient: func() (iamiface.IAMAPI, error) { return fake, nil }}
+}
+
+func TestEnabled(t *testing.T) {
+	for value, expected := range map[string]bool{"": false, "false": false, "1": false, "true": true, "TRUE": true} {
+		t.Setenv("INCLUDE_ACCOUNT_ALIAS", value)
+		assert.Equal(t, expected, Enabled(), value)
+	}
+}
+
+func TestAlias(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		fake := &fakeIAM{aliases: []string{"mattermost-prod"}}
+		cache := fakeCache(fake)
+
+		assert.Empty(t, cache.Alias())
+		assert.Zero(t, fake.calls)
+	})
+
+	t.Run("cached", func(t *testing.T) {
+		t.Setenv("INCLUDE_ACCOUNT_ALIAS", "true")
+		fake := &fakeIAM{aliases: []string{"mattermost-prod"}}
+		cache := fakeCache(fake)
+
+		assert.Equal(t, "mattermost-prod", cache.Alias())
+		assert.Equal(t, "mattermost-prod", cache.Alias())
+		assert.Equal(t, 1, fake.calls)
+	})
+
+	t.Run("no alias", func(t *testing.T) {
+		t.Setenv("INCLUDE_ACCOUNT_ALIAS", "true")
+		fake := &fakeIAM{}
+		cache := fakeCache(fake)
+
+		assert.Empty(t, cache.Alias())
+		assert.Empty(t, cache.Alias())
+		assert.Equal(t, 1, fake.calls)
+	})
+
+	t.Run("access denied", func(t *testing.T) {
+		t.Setenv("INCLUDE_ACCOUNT_ALIAS", "true")
+		fake := &fakeIAM{err: awserr.New(accessDeniedCode, "not authorized to perform iam:ListAccountAliases", nil)}
+		cache := fakeCache(fake)
+
+		assert.Empty(t, cache.Alias())
+		assert.Empty(t, cache.Alias())
+		assert.Equal(t, 1, fake.calls, "a missing permission is not retried")
+	})
+
+	t.Run("transient error", func(t *testing.T) {
+		t.Setenv("INCLUDE_ACCOUNT_ALIAS", "true")
+		fake := &fakeIAM{err: errors.New("connection reset")}
+		cache := fakeCache(fake)
+
+		assert.Empty(t, cache.Alias())
+		fake.err = nil
+		fake.aliases = []string{"mattermost-prod"}
+		assert.Equal(t, "mattermost-prod", cache.Alias())
+		assert.Equal(t, 2, fake.calls)
+	})
+
+	t.Run("client error", func(t *testing.T) {
+		t.Setenv("INCLUDE_ACCOUNT_ALIAS", "true")
+		cache := &Cache{NewClient: func() (iamiface.IAMAPI, error) { return nil, errors.New("no region") }}
+
+		assert.Empty(t, cache.Alias())
+	})
+}
@@ -0,0 +1,75 @@
+// Package apigateway lets the lambdas behind API Gateway accept both payload
+// formats: handlers are written against the REST API (1.0) shape, and HTTP API
+// (2.0) requests are converted to it.
+package apigateway
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// PayloadVersionEnv selects the API Gateway payload format the lambda is
+// invoked with: "1.0" (REST API, the default) or "2.0" (HTTP API).
+const PayloadVersionEnv = "API_GATEWAY_PAYLOAD_VERSION"
+
+// UseHTTPAPIPayload reports whether the lambda is invoked by an HTTP API.
+func UseHTTPAPIPayload() bool {
+	return os.Getenv(PayloadVersionEnv) == "2.0"
+}
+
+// ProxyRequestFromV2 converts an HTTP API request into the REST API shape the
+// handlers work with.
+func ProxyRequestFromV2(request events.APIGatewayV2HTTPRequest) events.APIGatewayProxyRequest {
+	// HTTP APIs lowercase header names; restore the canonical form REST APIs
+	// usually pass through.
+	headers := make(map[string]string, len(request.Headers)+1)
+	for key, value := range request.Headers {
+		headers[http.CanonicalHeaderKey(key)] = value
+	}
+	// HTTP APIs also deliver cookies separately from the other headers.
+	if len(request.Cookies) > 0 {
+		headers["Cookie"] = strings.Join(request.Cookies, "; ")
+	}
+
+	path := request.RawPath
+	if path == "" {
+		path = request.RequestContext.HTTP.Path
+	}
+
+	return events.APIGatewayProxyRequest{
+		Resource:              request.RouteKey,
+		Path:                  path,
+		HTTPMethod:            request.RequestContext.HTTP.Method,
+		Headers:               headers,
+		QueryStringParameters: request.QueryStringParameters,
+		PathParameters:        request.PathParameters,
+		StageVariables:        request.StageVariables,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			AccountID:  request.RequestContext.AccountID,
+			APIID:      request.RequestContext.APIID,
+			DomainName: request.RequestContext.DomainName,
+			RequestID:  request.RequestContext.RequestID,
+			Stage:      request.RequestContext.Stage,
+			Identity: events.APIGatewayRequestIdentity{
+				SourceIP:  request.RequestContext.HTTP.SourceIP,
+				UserAgent: request.RequestContext.HTTP.UserAgent,
+			},
+		},
+		Body:            request.Body,
+		IsBase64Encoded: request.IsBase64Encoded,
+	}
+}
+
+// V2ResponseFromProxy converts a REST API response into the HTTP API shape.
+func V2ResponseFromProxy(response events.APIGatewayProxyResponse) events.APIGatewayV2HTTPResponse {
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode:        response.StatusCode,
+		Headers:           response.Headers,
+		MultiValueHeaders: response.MultiValueHeaders,
+		Body:              response.Body,
+		IsBase64Encoded:   response.IsBase64Encoded,
+	}
+}
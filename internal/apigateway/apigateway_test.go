@@ -0,0 +1,66 @@
+package apigateway
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyRequestFromV2(t *testing.T) {
+	request := ProxyRequestFromV2(events.APIGatewayV2HTTPRequest{
+		RouteKey: "POST /webhook",
+		RawPath:  "/webhook",
+		Headers:  map[string]string{"content-type": "application/json", "x-gitlab-event": "Pipeline Hook"},
+		Cookies:  []string{"a=1", "b=2"},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			RequestID: "request-id",
+			HTTP:      events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodPost, Path: "/webhook", SourceIP: "203.0.113.7"},
+		},
+		QueryStringParameters: map[string]string{"key": "value"},
+		Body:                  "e30=",
+		IsBase64Encoded:       true,
+	})
+
+	assert.Equal(t, http.MethodPost, request.HTTPMethod)
+	assert.Equal(t, "/webhook", request.Path)
+	assert.Equal(t, "application/json", request.Headers["Content-Type"])
+	assert.Equal(t, "Pipeline Hook", request.Headers["X-Gitlab-Event"])
+	assert.Equal(t, "a=1; b=2", request.Headers["Cookie"])
+	assert.Equal(t, "value", request.QueryStringParameters["key"])
+	assert.Equal(t, "request-id", request.RequestContext.RequestID)
+	assert.Equal(t, "203.0.113.7", request.RequestContext.Identity.SourceIP)
+	assert.Equal(t, "e30=", request.Body)
+	assert.True(t, request.IsBase64Encoded)
+}
+
+func TestProxyRequestFromV2Path(t *testing.T) {
+	request := ProxyRequestFromV2(events.APIGatewayV2HTTPRequest{
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Path: "/api/installation"},
+		},
+	})
+
+	assert.Equal(t, "/api/installation", request.Path)
+}
+
+func TestV2ResponseFromProxy(t *testing.T) {
+	response := V2ResponseFromProxy(events.APIGatewayProxyResponse{
+		StatusCode: http.StatusAccepted,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       `{"ok":true}`,
+	})
+
+	assert.Equal(t, http.StatusAccepted, response.StatusCode)
+	assert.Equal(t, "application/json", response.Headers["Content-Type"])
+	assert.Equal(t, `{"ok":true}`, response.Body)
+}
+
+func TestUseHTTPAPIPayload(t *testing.T) {
+	t.Setenv(PayloadVersionEnv, "")
+	assert.False(t, UseHTTPAPIPayload())
+
+	t.Setenv(PayloadVersionEnv, "2.0")
+	assert.True(t, UseHTTPAPIPayload())
+}
@@ -0,0 +1,14 @@
+module github.com/mattermost/mattermost-cloud-lambdas/internal/apigateway
+
+go 1.23
+
+require (
+	github.com/aws/aws-lambda-go v1.47.0
+	github.com/stretchr/testify v1.10.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,38 @@
+// Package awsconfig resolves the AWS region the lambdas run against and
+// creates their AWS sessions, so that the region is picked the same way in
+// Lambda and when running locally.
+package awsconfig
+
+import (
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/pkg/errors"
+)
+
+// regionVariables are the environment variables holding the region, in order
+// of precedence. AWS_REGION is set by the Lambda runtime.
+var regionVariables = []string{"REGION", "AWS_REGION", "AWS_DEFAULT_REGION"}
+
+// Region returns the first region set in REGION, AWS_REGION or
+// AWS_DEFAULT_REGION.
+func Region() (string, error) {
+	for _, name := range regionVariables {
+		if region := os.Getenv(name); region != "" {
+			return region, nil
+		}
+	}
+
+	return "", errors.New("no AWS region set, set one of REGION, AWS_REGION or AWS_DEFAULT_REGION")
+}
+
+// NewSession creates an AWS session in the resolved region.
+func NewSession() (*session.Session, error) {
+	region, err := Region()
+	if err != nil {
+		return nil, err
+	}
+
+	return session.NewSession(&aws.Config{Region: aws.String(region)})
+}
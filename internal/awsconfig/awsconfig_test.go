@@ -0,0 +1,55 @@
+package awsconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegion(t *testing.T) {
+	testCases := []struct {
+		name             string
+		region           string
+		awsRegion        string
+		awsDefaultRegion string
+		expected         string
+	}{
+		{"region wins", "eu-west-1", "us-east-1", "us-west-2", "eu-west-1"},
+		{"aws region", "", "us-east-1", "us-west-2", "us-east-1"},
+		{"aws default region", "", "", "us-west-2", "us-west-2"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("REGION", tc.region)
+			t.Setenv("AWS_REGION", tc.awsRegion)
+			t.Setenv("AWS_DEFAULT_REGION", tc.awsDefaultRegion)
+
+			region, err := Region()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, region)
+		})
+	}
+}
+
+func TestRegionUnset(t *testing.T) {
+	t.Setenv("REGION", "")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+
+	_, err := Region()
+	require.Error(t, err)
+
+	_, err = NewSession()
+	require.Error(t, err)
+}
+
+func TestNewSession(t *testing.T) {
+	t.Setenv("REGION", "")
+	t.Setenv("AWS_REGION", "us-east-1")
+
+	sess, err := NewSession()
+	require.NoError(t, err)
+	assert.Equal(t, "us-east-1", *sess.Config.Region)
+}
@@ -0,0 +1,16 @@
+module github.com/mattermost/mattermost-cloud-lambdas/internal/awsconfig
+
+go 1.23
+
+require (
+	github.com/aws/aws-sdk-go v1.55.5
+	github.com/pkg/errors v0.9.1
+	github.com/stretchr/testify v1.10.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/jmespath/go-jmespath v0.4.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
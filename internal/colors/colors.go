@@ -0,0 +1,54 @@
+// Package colors reads the Mattermost attachment colors of the notification
+// lambdas from COLOR_SUCCESS, COLOR_FAILURE and COLOR_WARNING.
+package colors
+
+import (
+	"os"
+	"regexp"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The colors used when the environment variables are unset.
+const (
+	DefaultSuccess = "#006400"
+	DefaultFailure = "#FF0000"
+	DefaultWarning = "#FFA500"
+)
+
+var hexColorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Valid reports whether color is a 3 or 6 digit hex color such as #FF0000.
+func Valid(color string) bool {
+	return hexColorPattern.MatchString(color)
+}
+
+// FromEnv returns the attachment color set in the environment variable, or
+// defaultColor when it is unset or not a valid hex color.
+func FromEnv(name, defaultColor string) string {
+	color := os.Getenv(name)
+	if color == "" {
+		return defaultColor
+	}
+	if !Valid(color) {
+		log.WithField(name, color).Warn("Invalid hex color, using the default")
+		return defaultColor
+	}
+
+	return color
+}
+
+// Success is the color of resolved or successful events.
+func Success() string {
+	return FromEnv("COLOR_SUCCESS", DefaultSuccess)
+}
+
+// Failure is the color of failures and alerts.
+func Failure() string {
+	return FromEnv("COLOR_FAILURE", DefaultFailure)
+}
+
+// Warning is the color of events that need attention but are not failures.
+func Warning() string {
+	return FromEnv("COLOR_WARNING", DefaultWarning)
+}
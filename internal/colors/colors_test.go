@@ -0,0 +1,39 @@
+package colors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromEnv(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{"unset", "", DefaultSuccess},
+		{"six digits", "#1A2b3C", "#1A2b3C"},
+		{"three digits", "#abc", "#abc"},
+		{"missing hash", "1A2B3C", DefaultSuccess},
+		{"invalid digits", "#GGGGGG", DefaultSuccess},
+		{"named color", "green", DefaultSuccess},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("COLOR_SUCCESS", tc.value)
+			assert.Equal(t, tc.expected, Success())
+		})
+	}
+}
+
+func TestColors(t *testing.T) {
+	assert.Equal(t, DefaultFailure, Failure())
+	assert.Equal(t, DefaultWarning, Warning())
+
+	t.Setenv("COLOR_FAILURE", "#AA0000")
+	t.Setenv("COLOR_WARNING", "#AAAA00")
+	assert.Equal(t, "#AA0000", Failure())
+	assert.Equal(t, "#AAAA00", Warning())
+}
@@ -0,0 +1,142 @@
+// Package lock provides a lease lock stored in DynamoDB, so that a scheduled
+// and a manual run of a destructive lambda never clean up at the same time.
+// Locking is enabled by setting LOCK_TABLE to a table whose partition key is
+// the string attribute LockID.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/pkg/errors"
+)
+
+// DefaultTTL is how long a lease is held when its run never releases it. It
+// is the longest a Lambda can run, so a crashed run cannot block the next one.
+const DefaultTTL = 15 * time.Minute
+
+// ErrHeld is returned by Acquire when another run holds the lease.
+var ErrHeld = errors.New("lock is held by another run")
+
+// Locker is implemented by Lease. Handlers depend on it so that tests can
+// replace the lock.
+type Locker interface {
+	Acquire(ctx context.Context) error
+	Release(ctx context.Context) error
+}
+
+// Lease is a named lease lock. A nil Lease never blocks, so lambdas can use
+// the result of FromEnv whether locking is enabled or not.
+type Lease struct {
+	svc   dynamodbiface.DynamoDBAPI
+	table string
+	name  string
+	owner string
+	ttl   time.Duration
+	now   func() time.Time
+}
+
+// New creates the lease name stored in table, held for at most ttl.
+func New(svc dynamodbiface.DynamoDBAPI, table, name string, ttl time.Duration) *Lease {
+	return &Lease{
+		svc:   svc,
+		table: table,
+		name:  name,
+		owner: newOwner(),
+		ttl:   ttl,
+		now:   time.Now,
+	}
+}
+
+// FromEnv creates the lease name in the LOCK_TABLE table, or returns nil when
+// LOCK_TABLE is not set.
+func FromEnv(sess *session.Session, name string) *Lease {
+	table := os.Getenv("LOCK_TABLE")
+	if table == "" {
+		return nil
+	}
+
+	return New(dynamodb.New(sess), table, name, DefaultTTL)
+}
+
+// Acquire takes the lease, or returns ErrHeld if another run holds an
+// unexpired one.
+func (l *Lease) Acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	now := l.now()
+	_, err := l.svc.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(l.table),
+		Item: map[string]*dynamodb.AttributeValue{
+			"LockID":    {S: aws.String(l.name)},
+			"Owner":     {S: aws.String(l.owner)},
+			"ExpiresAt": {N: aws.String(strconv.FormatInt(now.Add(l.ttl).Unix(), 10))},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(#lockID) OR #expiresAt < :now"),
+		ExpressionAttributeNames: map[string]*string{
+			"#lockID":    aws.String("LockID"),
+			"#expiresAt": aws.String("ExpiresAt"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":now": {N: aws.String(strconv.FormatInt(now.Unix(), 10))},
+		},
+	})
+	if isConditionFailed(err) {
+		return ErrHeld
+	}
+
+	return errors.Wrapf(err, "failed to acquire lock %s", l.name)
+}
+
+// Release gives the lease up if it is still held by this run.
+func (l *Lease) Release(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	_, err := l.svc.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(l.table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"LockID": {S: aws.String(l.name)},
+		},
+		ConditionExpression: aws.String("#owner = :owner"),
+		// Owner is a DynamoDB reserved word, so it can only be referenced
+		// through a placeholder.
+		ExpressionAttributeNames: map[string]*string{
+			"#owner": aws.String("Owner"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":owner": {S: aws.String(l.owner)},
+		},
+	})
+	if isConditionFailed(err) {
+		return errors.Errorf("lock %s expired and was taken by another run", l.name)
+	}
+
+	return errors.Wrapf(err, "failed to release lock %s", l.name)
+}
+
+func isConditionFailed(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
+
+// newOwner returns a random identifier of the current run.
+func newOwner() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,220 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type lockItem struct {
+	owner     string
+	expiresAt int64
+}
+
+// fakeDynamoDB evaluates the conditions used by Lease against an in-memory
+// table.
+type fakeDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+
+	mu    sync.Mutex
+	items map[string]lockItem
+}
+
+func newFakeDynamoDB() *fakeDynamoDB {
+	return &fakeDynamoDB{items: map[string]lockItem{}}
+}
+
+// expressionIdentifier matches the attribute references of a condition
+// expression, with their optional # or : prefix.
+var expressionIdentifier = regexp.MustCompile(`[#:]?[A-Za-z_][A-Za-z0-9_]*`)
+
+// expressionKeywords are the functions and operators Lease may use in its
+// condition expressions.
+var expressionKeywords = map[string]bool{"attribute_not_exists": true, "AND": true, "OR": true, "NOT": true}
+
+// validateExpression rejects an expression the way DynamoDB does when it
+// names an attribute directly, which fails for reserved words such as Owner,
+// or uses an undefined or unused placeholder.
+func validateExpression(expression string, names map[string]*string, values map[string]*dynamodb.AttributeValue) error {
+	used := map[string]bool{}
+	for _, identifier := range expressionIdentifier.FindAllString(expression, -1) {
+		switch {
+		case strings.HasPrefix(identifier, "#"):
+			if names[identifier] == nil {
+				return awserr.New("ValidationException", "undefined attribute name "+identifier, nil)
+			}
+		case strings.HasPrefix(identifier, ":"):
+			if values[identifier] == nil {
+				return awserr.New("ValidationException", "undefined attribute value "+identifier, nil)
+			}
+		case !expressionKeywords[identifier]:
+			return awserr.New("ValidationException", "attribute "+identifier+" must use a placeholder", nil)
+		}
+		used[identifier] = true
+	}
+	for name := range names {
+		if !used[name] {
+			return awserr.New("ValidationException", "unused attribute name "+name, nil)
+		}
+	}
+
+	return nil
+}
+
+func conditionFailed() error {
+	return awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+}
+
+func (f *fakeDynamoDB) PutItemWithContext(_ aws.Context, input *dynamodb.PutItemInput, _ ...request.Option) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := validateExpression(aws.StringValue(input.ConditionExpression), input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	name := aws.StringValue(input.Item["LockID"].S)
+	now, _ := strconv.ParseInt(aws.StringValue(input.ExpressionAttributeValues[":now"].N), 10, 64)
+	if existing, ok := f.items[name]; ok && existing.expiresAt >= now {
+		return nil, conditionFailed()
+	}
+
+	expiresAt, _ := strconv.ParseInt(aws.StringValue(input.Item["ExpiresAt"].N), 10, 64)
+	f.items[name] = lockItem{owner: aws.StringValue(input.Item["Owner"].S), expiresAt: expiresAt}
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) DeleteItemWithContext(_ aws.Context, input *dynamodb.DeleteItemInput, _ ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := validateExpression(aws.StringValue(input.ConditionExpression), input.ExpressionAttributeNames, input.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	name := aws.StringValue(input.Key["LockID"].S)
+	if f.items[name].owner != aws.StringValue(input.ExpressionAttributeValues[":owner"].S) {
+		return nil, conditionFailed()
+	}
+
+	delete(f.items, name)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func TestLeaseContention(t *testing.T) {
+	svc := newFakeDynamoDB()
+	first := New(svc, "locks", "deckhand", DefaultTTL)
+	second := New(svc, "locks", "deckhand", DefaultTTL)
+	other := New(svc, "locks", "ebs-janitor", DefaultTTL)
+	ctx := context.Background()
+
+	require.NoError(t, first.Acquire(ctx))
+	assert.ErrorIs(t, second.Acquire(ctx), ErrHeld)
+	require.NoError(t, other.Acquire(ctx), "leases with different names are independent")
+
+	require.NoError(t, first.Release(ctx))
+	require.NoError(t, second.Acquire(ctx))
+	assert.ErrorIs(t, first.Acquire(ctx), ErrHeld)
+}
+
+func TestLeaseConcurrentAcquire(t *testing.T) {
+	svc := newFakeDynamoDB()
+	ctx := context.Background()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		acquired int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := New(svc, "locks", "elb-cleanup", DefaultTTL).Acquire(ctx)
+			if err == nil {
+				mu.Lock()
+				acquired++
+				mu.Unlock()
+				return
+			}
+			assert.ErrorIs(t, err, ErrHeld)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, acquired)
+}
+
+func TestLeaseExpired(t *testing.T) {
+	svc := newFakeDynamoDB()
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	crashed := New(svc, "locks", "deckhand", DefaultTTL)
+	crashed.now = func() time.Time { return now }
+	require.NoError(t, crashed.Acquire(ctx))
+
+	next := New(svc, "locks", "deckhand", DefaultTTL)
+	next.now = func() time.Time { return now.Add(DefaultTTL - time.Minute) }
+	assert.ErrorIs(t, next.Acquire(ctx), ErrHeld)
+
+	next.now = func() time.Time { return now.Add(DefaultTTL + time.Minute) }
+	require.NoError(t, next.Acquire(ctx))
+
+	assert.EqualError(t, crashed.Release(ctx), "lock deckhand expired and was taken by another run")
+	require.NoError(t, next.Release(ctx))
+}
+
+func TestLeaseErrors(t *testing.T) {
+	svc := &failingDynamoDB{err: errors.New("AccessDeniedException")}
+	lease := New(svc, "locks", "deckhand", DefaultTTL)
+
+	assert.EqualError(t, lease.Acquire(context.Background()), "failed to acquire lock deckhand: AccessDeniedException")
+	assert.EqualError(t, lease.Release(context.Background()), "failed to release lock deckhand: AccessDeniedException")
+}
+
+type failingDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+	err error
+}
+
+func (f *failingDynamoDB) PutItemWithContext(aws.Context, *dynamodb.PutItemInput, ...request.Option) (*dynamodb.PutItemOutput, error) {
+	return nil, f.err
+}
+
+func (f *failingDynamoDB) DeleteItemWithContext(aws.Context, *dynamodb.DeleteItemInput, ...request.Option) (*dynamodb.DeleteItemOutput, error) {
+	return nil, f.err
+}
+
+func TestNilLease(t *testing.T) {
+	t.Setenv("LOCK_TABLE", "")
+	lease := FromEnv(nil, "deckhand")
+
+	assert.Nil(t, lease)
+	assert.NoError(t, lease.Acquire(context.Background()))
+	assert.NoError(t, lease.Release(context.Background()))
+}
+
+func TestValidateExpression(t *testing.T) {
+	value := map[string]*dynamodb.AttributeValue{":owner": {S: aws.String("run")}}
+
+	assert.Error(t, validateExpression("Owner = :owner", nil, value), "reserved word used directly")
+	assert.Error(t, validateExpression("#owner = :owner", nil, value), "undefined placeholder")
+	assert.Error(t, validateExpression("#owner = :owner", map[string]*string{"#owner": aws.String("Owner"), "#other": aws.String("Other")}, value), "unused placeholder")
+	assert.NoError(t, validateExpression("#owner = :owner", map[string]*string{"#owner": aws.String("Owner")}, value))
+}
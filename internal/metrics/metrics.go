@@ -0,0 +1,86 @@
+// Package metrics records the notification delivery metrics shared by the
+// lambdas. Metrics are written as log lines in the CloudWatch Embedded Metric
+// Format, so CloudWatch extracts them from the function logs without any
+// additional API calls. Nothing is recorded unless ENABLE_METRICS is true.
+package metrics
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// TargetMattermost is the Target dimension of Mattermost webhook sends.
+	TargetMattermost = "Mattermost"
+	// TargetPagerDuty is the Target dimension of PagerDuty event sends.
+	TargetPagerDuty = "PagerDuty"
+
+	// NotificationLatency is the name of the send duration metric.
+	NotificationLatency = "NotificationLatency"
+
+	defaultNamespace = "MattermostCloudLambdas"
+)
+
+// Enabled reports whether ENABLE_METRICS is set to true.
+func Enabled() bool {
+	return strings.EqualFold(os.Getenv("ENABLE_METRICS"), "true")
+}
+
+// RecordNotificationLatency records the time elapsed since start as the
+// NotificationLatency of a send to target.
+func RecordNotificationLatency(target string, start time.Time) {
+	latency := time.Since(start)
+	Record(NotificationLatency, "Milliseconds", float64(latency)/float64(time.Millisecond), map[string]string{"Target": target})
+}
+
+// Record records a single metric value with the given dimensions.
+func Record(name, unit string, value float64, dimensions map[string]string) {
+	if !Enabled() {
+		return
+	}
+
+	log.WithFields(metricFields(name, unit, value, dimensions, time.Now())).Info(name)
+}
+
+// metricFields returns the Embedded Metric Format log fields of a metric
+// value. The function name is added as a dimension when known. The namespace
+// defaults to MattermostCloudLambdas and can be changed with
+// METRICS_NAMESPACE.
+func metricFields(name, unit string, value float64, dimensions map[string]string, now time.Time) log.Fields {
+	namespace := os.Getenv("METRICS_NAMESPACE")
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+
+	fields := log.Fields{name: value}
+
+	dimensionNames := make([]string, 0, len(dimensions)+1)
+	for dimension, dimensionValue := range dimensions {
+		dimensionNames = append(dimensionNames, dimension)
+		fields[dimension] = dimensionValue
+	}
+	sort.Strings(dimensionNames)
+	if functionName := os.Getenv("AWS_LAMBDA_FUNCTION_NAME"); functionName != "" {
+		dimensionNames = append(dimensionNames, "FunctionName")
+		fields["FunctionName"] = functionName
+	}
+
+	fields["_aws"] = map[string]interface{}{
+		"Timestamp": now.UnixMilli(),
+		"CloudWatchMetrics": []map[string]interface{}{
+			{
+				"Namespace":  namespace,
+				"Dimensions": [][]string{dimensionNames},
+				"Metrics": []map[string]string{
+					{"Name": name, "Unit": unit},
+				},
+			},
+		},
+	}
+
+	return fields
+}
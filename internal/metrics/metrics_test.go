@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordNotificationLatency(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	t.Setenv("ENABLE_METRICS", "true")
+	t.Setenv("AWS_LAMBDA_FUNCTION_NAME", "alerts")
+
+	RecordNotificationLatency(TargetMattermost, time.Now().Add(-50*time.Millisecond))
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, log.InfoLevel, entry.Level)
+	assert.Equal(t, NotificationLatency, entry.Message)
+	assert.Equal(t, TargetMattermost, entry.Data["Target"])
+	assert.Equal(t, "alerts", entry.Data["FunctionName"])
+
+	latency, ok := entry.Data[NotificationLatency].(float64)
+	require.True(t, ok)
+	assert.GreaterOrEqual(t, latency, 50.0)
+	assert.Less(t, latency, 5000.0)
+
+	metadata := entry.Data["_aws"].(map[string]interface{})
+	directives := metadata["CloudWatchMetrics"].([]map[string]interface{})
+	assert.Equal(t, defaultNamespace, directives[0]["Namespace"])
+	assert.Equal(t, [][]string{{"Target", "FunctionName"}}, directives[0]["Dimensions"])
+}
+
+func TestRecordNotificationLatencyDisabled(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	t.Setenv("ENABLE_METRICS", "")
+
+	RecordNotificationLatency(TargetPagerDuty, time.Now())
+
+	assert.Empty(t, hook.AllEntries())
+}
+
+func TestMetricFields(t *testing.T) {
+	t.Setenv("METRICS_NAMESPACE", "Custom")
+	t.Setenv("AWS_LAMBDA_FUNCTION_NAME", "")
+
+	fields := metricFields("Age", "Seconds", 1.5, map[string]string{"Target": TargetPagerDuty, "Subnet": "subnet-1"}, time.UnixMilli(1700000000000))
+
+	assert.Equal(t, 1.5, fields["Age"])
+	assert.Equal(t, "subnet-1", fields["Subnet"])
+	metadata := fields["_aws"].(map[string]interface{})
+	assert.Equal(t, int64(1700000000000), metadata["Timestamp"])
+	directives := metadata["CloudWatchMetrics"].([]map[string]interface{})
+	assert.Equal(t, "Custom", directives[0]["Namespace"])
+	assert.Equal(t, [][]string{{"Subnet", "Target"}}, directives[0]["Dimensions"])
+	assert.Equal(t, []map[string]string{{"Name": "Age", "Unit": "Seconds"}}, directives[0]["Metrics"])
+}
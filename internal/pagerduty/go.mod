@@ -0,0 +1,15 @@
+module github.com/mattermost/mattermost-cloud-lambdas/internal/pagerduty
+
+go 1.23
+
+require (
+	github.com/PagerDuty/go-pagerduty v1.8.0
+	github.com/stretchr/testify v1.10.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/google/go-querystring v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,22 @@
+// Package pagerduty fills in where the PagerDuty events of the lambdas come
+// from, from PAGERDUTY_SOURCE, PAGERDUTY_COMPONENT and PAGERDUTY_GROUP.
+package pagerduty
+
+import (
+	"os"
+
+	pagerduty "github.com/PagerDuty/go-pagerduty"
+)
+
+// SetOrigin sets where a PagerDuty event comes from: the source from
+// PAGERDUTY_SOURCE, defaulting to defaultSource, which is the name of the
+// lambda, and the optional component and group from PAGERDUTY_COMPONENT and
+// PAGERDUTY_GROUP.
+func SetOrigin(payload *pagerduty.V2Payload, defaultSource string) {
+	payload.Source = os.Getenv("PAGERDUTY_SOURCE")
+	if payload.Source == "" {
+		payload.Source = defaultSource
+	}
+	payload.Component = os.Getenv("PAGERDUTY_COMPONENT")
+	payload.Group = os.Getenv("PAGERDUTY_GROUP")
+}
@@ -0,0 +1,23 @@
+package pagerduty
+
+import (
+	"testing"
+
+	pagerduty "github.com/PagerDuty/go-pagerduty"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetOrigin(t *testing.T) {
+	t.Setenv("PAGERDUTY_SOURCE", "")
+	t.Setenv("PAGERDUTY_COMPONENT", "")
+	t.Setenv("PAGERDUTY_GROUP", "")
+	payload := &pagerduty.V2Payload{Source: "Alarm System", Component: "stale"}
+	SetOrigin(payload, "my-lambda")
+	assert.Equal(t, &pagerduty.V2Payload{Source: "my-lambda"}, payload)
+
+	t.Setenv("PAGERDUTY_SOURCE", "custom")
+	t.Setenv("PAGERDUTY_COMPONENT", "component")
+	t.Setenv("PAGERDUTY_GROUP", "group")
+	SetOrigin(payload, "my-lambda")
+	assert.Equal(t, &pagerduty.V2Payload{Source: "custom", Component: "component", Group: "group"}, payload)
+}
@@ -0,0 +1,56 @@
+// Package retry retries the calls of the lambdas to AWS and to webhooks with
+// a jittered exponential backoff.
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// permanentError marks an error that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent wraps err so that Do returns it right away instead of retrying.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+// Do calls fn up to attempts times, doubling the jittered sleep between
+// attempts, until it succeeds or returns an error wrapped with Permanent. The
+// last attempt is not followed by a sleep.
+func Do(attempts int, sleep time.Duration, fn func() error) error {
+	var err error
+	for i := 0; i < attempts; i++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		var permanent permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if i == attempts-1 {
+			break
+		}
+		if sleep > 0 {
+			time.Sleep(sleep + time.Duration(rand.Int63n(int64(sleep))))
+		}
+		sleep = sleep * 2
+	}
+
+	return fmt.Errorf("failed after %d attempts: %w", attempts, err)
+}
@@ -0,0 +1,62 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDo(t *testing.T) {
+	t.Run("succeeds after retries", func(t *testing.T) {
+		var calls int
+		err := Do(3, time.Millisecond, func() error {
+			calls++
+			if calls < 3 {
+				return assert.AnError
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		var calls int
+		err := Do(3, time.Millisecond, func() error {
+			calls++
+			return assert.AnError
+		})
+		require.Error(t, err)
+		assert.Equal(t, "failed after 3 attempts: "+assert.AnError.Error(), err.Error())
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("permanent", func(t *testing.T) {
+		var calls int
+		permanent := errors.New("bad request")
+		err := Do(3, time.Millisecond, func() error {
+			calls++
+			return Permanent(permanent)
+		})
+		assert.Equal(t, permanent, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("no sleep", func(t *testing.T) {
+		var calls int
+		err := Do(2, 0, func() error {
+			calls++
+			return assert.AnError
+		})
+		require.Error(t, err)
+		assert.Equal(t, 2, calls)
+	})
+}
+
+func TestPermanentNil(t *testing.T) {
+	assert.NoError(t, Permanent(nil))
+}
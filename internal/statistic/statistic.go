@@ -0,0 +1,129 @@
+// Package statistic picks the statistic of the CloudWatch alarms created by
+// the alarm lambdas, from per-lambda defaults and the METRIC_STATISTICS and
+// METRIC_EXTENDED_STATISTICS environment variables.
+package statistic
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// percentileRegexp matches the shape of CloudWatch percentile extended
+// statistics such as p99 or p99.9. isValidPercentile also checks the range.
+var percentileRegexp = regexp.MustCompile(`^p[0-9]{1,2}(\.[0-9]{1,2})?$`)
+
+// isValidStatistic reports whether statistic is a CloudWatch statistic name.
+func isValidStatistic(statistic string) bool {
+	for _, value := range cloudwatch.Statistic_Values() {
+		if statistic == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isValidPercentile reports whether extended is a percentile between p0 and
+// p100, both excluded.
+func isValidPercentile(extended string) bool {
+	if !percentileRegexp.MatchString(extended) {
+		return false
+	}
+	percentile, err := strconv.ParseFloat(extended[1:], 64)
+
+	return err == nil && percentile > 0 && percentile < 100
+}
+
+// metricStatistic returns the statistic to use for the given metric: its
+// METRIC_STATISTICS override when valid, else its default, else Average.
+func metricStatistic(metricName string, defaults, overrides map[string]string) string {
+	statistic := cloudwatch.StatisticAverage
+	if defaultStatistic, ok := defaults[metricName]; ok {
+		statistic = defaultStatistic
+	}
+
+	override, ok := overrides[metricName]
+	if !ok {
+		return statistic
+	}
+	if !isValidStatistic(override) {
+		log.Errorf("Invalid statistic %q for metric %s, using the default statistic", override, metricName)
+		return statistic
+	}
+
+	return override
+}
+
+// metricExtendedStatistic returns the percentile configured for the given
+// metric through the METRIC_EXTENDED_STATISTICS JSON object, e.g.
+// {"TargetResponseTime": "p99"}, or an empty string when none is set.
+func metricExtendedStatistic(metricName string) (string, error) {
+	overrides, err := parseOverrides("METRIC_EXTENDED_STATISTICS")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse METRIC_EXTENDED_STATISTICS")
+	}
+
+	extended := overrides[metricName]
+	if extended != "" && !isValidPercentile(extended) {
+		return "", errors.Errorf("invalid extended statistic %q for metric %s", extended, metricName)
+	}
+
+	return extended, nil
+}
+
+// Apply sets either the Statistic or the ExtendedStatistic of the alarm for
+// its metric. defaults holds the statistic of each metric unless it is
+// overridden through the METRIC_STATISTICS JSON object, e.g.
+// {"HealthyHostCount": "Minimum"}; other metrics use Average. CloudWatch
+// requires exactly one of them, so setting both for the same metric is
+// rejected.
+func Apply(input *cloudwatch.PutMetricAlarmInput, defaults map[string]string) error {
+	metricName := aws.StringValue(input.MetricName)
+
+	overrides, err := parseOverrides("METRIC_STATISTICS")
+	if err != nil {
+		log.WithError(err).Error("Failed to parse METRIC_STATISTICS, using the default statistic")
+		overrides = map[string]string{}
+	}
+
+	extended, err := metricExtendedStatistic(metricName)
+	if err != nil {
+		return err
+	}
+
+	if extended == "" {
+		input.Statistic = aws.String(metricStatistic(metricName, defaults, overrides))
+		input.ExtendedStatistic = nil
+		return nil
+	}
+
+	if _, ok := overrides[metricName]; ok {
+		return errors.Errorf("metric %s cannot have both a statistic and an extended statistic", metricName)
+	}
+
+	input.Statistic = nil
+	input.ExtendedStatistic = aws.String(extended)
+	return nil
+}
+
+func parseOverrides(name string) (map[string]string, error) {
+	overrides := map[string]string{}
+
+	value := os.Getenv(name)
+	if value == "" {
+		return overrides, nil
+	}
+
+	if err := json.Unmarshal([]byte(value), &overrides); err != nil {
+		return nil, err
+	}
+
+	return overrides, nil
+}
@@ -0,0 +1,102 @@
+package statistic
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testDefaults = map[string]string{"HealthyHostCount": cloudwatch.StatisticMinimum}
+
+func apply(t *testing.T, metricName string) (*cloudwatch.PutMetricAlarmInput, error) {
+	t.Helper()
+
+	input := &cloudwatch.PutMetricAlarmInput{MetricName: aws.String(metricName)}
+	return input, Apply(input, testDefaults)
+}
+
+func TestApplyStatistic(t *testing.T) {
+	testCases := []struct {
+		description string
+		overrides   string
+		metric      string
+		expected    string
+	}{
+		{"default", "", "HealthyHostCount", cloudwatch.StatisticMinimum},
+		{"default for other metrics", "", "RequestCount", cloudwatch.StatisticAverage},
+		{"override", `{"HealthyHostCount": "Maximum"}`, "HealthyHostCount", cloudwatch.StatisticMaximum},
+		{"override for other metric", `{"RequestCount": "Sum"}`, "HealthyHostCount", cloudwatch.StatisticMinimum},
+		{"invalid statistic", `{"HealthyHostCount": "Median"}`, "HealthyHostCount", cloudwatch.StatisticMinimum},
+		{"invalid json", `{invalid`, "HealthyHostCount", cloudwatch.StatisticMinimum},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			t.Setenv("METRIC_STATISTICS", tc.overrides)
+			t.Setenv("METRIC_EXTENDED_STATISTICS", "")
+
+			input, err := apply(t, tc.metric)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, aws.StringValue(input.Statistic))
+			assert.Nil(t, input.ExtendedStatistic)
+		})
+	}
+}
+
+func TestApplyLogsInvalidStatistics(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+	t.Setenv("METRIC_STATISTICS", `{invalid`)
+	t.Setenv("METRIC_EXTENDED_STATISTICS", `{"HealthyHostCount": "p99"}`)
+
+	input, err := apply(t, "HealthyHostCount")
+	require.NoError(t, err)
+	assert.Equal(t, "p99", aws.StringValue(input.ExtendedStatistic))
+	require.NotNil(t, hook.LastEntry())
+	assert.Equal(t, "Failed to parse METRIC_STATISTICS, using the default statistic", hook.LastEntry().Message)
+}
+
+func TestApplyExtendedStatistic(t *testing.T) {
+	t.Setenv("METRIC_STATISTICS", "")
+	t.Setenv("METRIC_EXTENDED_STATISTICS", `{"HealthyHostCount": "p99.9"}`)
+
+	input, err := apply(t, "HealthyHostCount")
+	require.NoError(t, err)
+	assert.Nil(t, input.Statistic)
+	assert.Equal(t, "p99.9", aws.StringValue(input.ExtendedStatistic))
+}
+
+func TestApplyExtendedStatisticErrors(t *testing.T) {
+	testCases := []struct {
+		description string
+		statistics  string
+		extended    string
+	}{
+		{"both statistic and extended statistic", `{"HealthyHostCount": "Maximum"}`, `{"HealthyHostCount": "p99"}`},
+		{"invalid percentile", "", `{"HealthyHostCount": "p999"}`},
+		{"invalid json", "", `{invalid`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			t.Setenv("METRIC_STATISTICS", tc.statistics)
+			t.Setenv("METRIC_EXTENDED_STATISTICS", tc.extended)
+
+			_, err := apply(t, "HealthyHostCount")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestIsValidPercentile(t *testing.T) {
+	for _, valid := range []string{"p1", "p50", "p99", "p99.9", "p99.99", "p0.5"} {
+		assert.True(t, isValidPercentile(valid), valid)
+	}
+	for _, invalid := range []string{"p0", "p0.0", "p100", "p100.5", "p999", "p99.999", "99", "tm99", ""} {
+		assert.False(t, isValidPercentile(invalid), invalid)
+	}
+}
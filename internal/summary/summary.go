@@ -0,0 +1,121 @@
+// Package summary writes the health summary that ends each lambda
+// invocation: a single JSON log line with the duration, the outcome and the
+// item counts of the run. Every lambda writes the same shape whatever its own
+// log format is, so one CloudWatch Logs Insights query covers all of them,
+// e.g.
+//
+//	filter msg = "invocation summary" | stats count(*) by function, outcome
+package summary
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Message is the msg field of every summary line.
+const Message = "invocation summary"
+
+// Outcome is how an invocation ended.
+type Outcome string
+
+const (
+	// OutcomeOK is the outcome of an invocation that returned no error.
+	OutcomeOK Outcome = "ok"
+	// OutcomePartial is the outcome of an invocation where some items failed.
+	OutcomePartial Outcome = "partial"
+	// OutcomeError is the outcome of an invocation that returned an error
+	// without any item failing.
+	OutcomeError Outcome = "error"
+)
+
+// output is where summaries are written. Lambda sends stdout to CloudWatch.
+var output io.Writer = os.Stdout
+
+// Summary collects the counts of an invocation until it is logged.
+type Summary struct {
+	function string
+	start    time.Time
+
+	mu     sync.Mutex
+	counts map[string]int
+	failed int
+}
+
+// line is the JSON shape of a summary. Level, msg and time match the logrus
+// JSON formatter used by most lambdas.
+type line struct {
+	Level      string         `json:"level"`
+	Msg        string         `json:"msg"`
+	Time       string         `json:"time"`
+	Function   string         `json:"function"`
+	Outcome    Outcome        `json:"outcome"`
+	DurationMS int64          `json:"duration_ms"`
+	Counts     map[string]int `json:"counts"`
+	Failed     int            `json:"failed"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// Start starts the summary of an invocation of function. It is meant to be
+// logged in a defer with the handler's named error result:
+//
+//	invocation := summary.Start("deckhand")
+//	defer invocation.Log(&err)
+func Start(function string) *Summary {
+	return &Summary{
+		function: function,
+		start:    time.Now(),
+		counts:   map[string]int{},
+	}
+}
+
+// Add adds n to the named item count.
+func (s *Summary) Add(name string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counts[name] += n
+}
+
+// Failed records n items that could not be processed. The outcome of an
+// invocation with failed items is partial, even if it returns an error.
+func (s *Summary) Failed(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed += n
+}
+
+// Log writes the summary line. err points at the error returned by the
+// handler, so that a deferred call sees its final value.
+func (s *Summary) Log(err *error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	l := line{
+		Level:      "info",
+		Msg:        Message,
+		Time:       now.Format(time.RFC3339),
+		Function:   s.function,
+		Outcome:    OutcomeOK,
+		DurationMS: now.Sub(s.start).Milliseconds(),
+		Counts:     s.counts,
+		Failed:     s.failed,
+	}
+	if err != nil && *err != nil {
+		l.Level = "error"
+		l.Outcome = OutcomeError
+		l.Error = (*err).Error()
+	}
+	if s.failed > 0 {
+		l.Level = "warning"
+		l.Outcome = OutcomePartial
+	}
+
+	data, marshalErr := json.Marshal(l)
+	if marshalErr != nil {
+		return
+	}
+	_, _ = output.Write(append(data, '\n'))
+}
@@ -0,0 +1,107 @@
+package summary
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func captureOutput(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	previous := output
+	output = buf
+	t.Cleanup(func() { output = previous })
+	return buf
+}
+
+func decodeLine(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+	require.Equal(t, 1, strings.Count(buf.String(), "\n"), "expected a single line")
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &fields))
+	return fields
+}
+
+func TestLogShape(t *testing.T) {
+	buf := captureOutput(t)
+
+	s := Start("deckhand")
+	s.Add("deleted_snapshots", 2)
+	s.Add("deleted_snapshots", 1)
+	s.Add("retained_images", 0)
+	var err error
+	s.Log(&err)
+
+	fields := decodeLine(t, buf)
+	assert.ElementsMatch(t, []string{"level", "msg", "time", "function", "outcome", "duration_ms", "counts", "failed"}, keys(fields))
+	assert.Equal(t, "info", fields["level"])
+	assert.Equal(t, Message, fields["msg"])
+	assert.Equal(t, "deckhand", fields["function"])
+	assert.Equal(t, string(OutcomeOK), fields["outcome"])
+	assert.GreaterOrEqual(t, fields["duration_ms"], 0.0)
+	assert.Equal(t, map[string]interface{}{"deleted_snapshots": 3.0, "retained_images": 0.0}, fields["counts"])
+	assert.Equal(t, 0.0, fields["failed"])
+}
+
+func TestLogOutcome(t *testing.T) {
+	for name, tc := range map[string]struct {
+		failed  int
+		err     error
+		outcome Outcome
+		level   string
+	}{
+		"ok":                 {outcome: OutcomeOK, level: "info"},
+		"error":              {err: errors.New("boom"), outcome: OutcomeError, level: "error"},
+		"partial":            {failed: 1, outcome: OutcomePartial, level: "warning"},
+		"partial with error": {failed: 2, err: errors.New("2 failed"), outcome: OutcomePartial, level: "warning"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			buf := captureOutput(t)
+
+			s := Start("ebs-janitor")
+			s.Failed(tc.failed)
+			err := tc.err
+			s.Log(&err)
+
+			fields := decodeLine(t, buf)
+			assert.Equal(t, string(tc.outcome), fields["outcome"])
+			assert.Equal(t, tc.level, fields["level"])
+			assert.Equal(t, float64(tc.failed), fields["failed"])
+			if tc.err != nil {
+				assert.Equal(t, tc.err.Error(), fields["error"])
+			} else {
+				assert.NotContains(t, fields, "error")
+			}
+		})
+	}
+}
+
+func TestLogDeferred(t *testing.T) {
+	buf := captureOutput(t)
+
+	run := func() (err error) {
+		invocation := Start("deckhand")
+		defer invocation.Log(&err)
+		return errors.New("late failure")
+	}
+	require.Error(t, run())
+
+	fields := decodeLine(t, buf)
+	assert.Equal(t, string(OutcomeError), fields["outcome"])
+	assert.Equal(t, "late failure", fields["error"])
+	assert.Equal(t, map[string]interface{}{}, fields["counts"])
+}
+
+func keys(fields map[string]interface{}) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,5 @@
+// Package testutil provides fakes and event builders shared by the lambda tests:
+// a Mattermost webhook server and a PagerDuty Events API server that record what
+// they receive, and builders for the SNS, CloudWatch and API Gateway events the
+// lambdas are invoked with.
+package testutil
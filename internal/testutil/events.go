@@ -0,0 +1,116 @@
+package testutil
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// SNSEvent builds an SNS event with one record per message.
+func SNSEvent(messages ...string) events.SNSEvent {
+	var event events.SNSEvent
+	for i, message := range messages {
+		event.Records = append(event.Records, events.SNSEventRecord{
+			EventSource:  "aws:sns",
+			EventVersion: "1.0",
+			SNS: events.SNSEntity{
+				MessageID: fmt.Sprintf("message-%d", i+1),
+				Type:      "Notification",
+				Message:   message,
+				Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+		})
+	}
+
+	return event
+}
+
+// CloudWatchAlarm is the message CloudWatch publishes to SNS when an alarm
+// changes state.
+type CloudWatchAlarm struct {
+	AlarmName        string           `json:"AlarmName"`
+	AlarmDescription string           `json:"AlarmDescription"`
+	AWSAccountID     string           `json:"AWSAccountId"`
+	NewStateValue    string           `json:"NewStateValue"`
+	NewStateReason   string           `json:"NewStateReason"`
+	StateChangeTime  string           `json:"StateChangeTime"`
+	Region           string           `json:"Region"`
+	OldStateValue    string           `json:"OldStateValue"`
+	Trigger          CloudWatchMetric `json:"Trigger"`
+}
+
+// CloudWatchMetric is the trigger of a CloudWatchAlarm.
+type CloudWatchMetric struct {
+	MetricName         string                `json:"MetricName"`
+	Namespace          string                `json:"Namespace"`
+	Statistic          string                `json:"Statistic"`
+	Dimensions         []CloudWatchDimension `json:"Dimensions"`
+	Period             int                   `json:"Period"`
+	EvaluationPeriods  int                   `json:"EvaluationPeriods"`
+	ComparisonOperator string                `json:"ComparisonOperator"`
+	Threshold          float64               `json:"Threshold"`
+}
+
+// CloudWatchDimension is a metric dimension of a CloudWatchMetric.
+type CloudWatchDimension struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CloudWatchAlarmSNSEvent builds the SNS event delivered for a CloudWatch
+// alarm state change.
+func CloudWatchAlarmSNSEvent(t testing.TB, alarm CloudWatchAlarm) events.SNSEvent {
+	t.Helper()
+
+	message, err := json.Marshal(alarm)
+	if err != nil {
+		t.Fatalf("failed to marshal CloudWatch alarm: %s", err)
+	}
+
+	return SNSEvent(string(message))
+}
+
+// CloudWatchEvent builds a CloudWatch (EventBridge) event with the given
+// source, detail type and detail.
+func CloudWatchEvent(t testing.TB, source, detailType string, detail interface{}) events.CloudWatchEvent {
+	t.Helper()
+
+	raw, err := json.Marshal(detail)
+	if err != nil {
+		t.Fatalf("failed to marshal CloudWatch event detail: %s", err)
+	}
+
+	return events.CloudWatchEvent{
+		Version:    "0",
+		ID:         "event-id",
+		DetailType: detailType,
+		Source:     source,
+		AccountID:  "123456789012",
+		Time:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Region:     "us-east-1",
+		Detail:     raw,
+	}
+}
+
+// APIGatewayRequest builds an API Gateway proxy request with a plain body.
+func APIGatewayRequest(method, path, body string, headers map[string]string) events.APIGatewayProxyRequest {
+	return events.APIGatewayProxyRequest{
+		HTTPMethod: method,
+		Path:       path,
+		Headers:    headers,
+		Body:       body,
+	}
+}
+
+// Base64APIGatewayRequest builds an API Gateway proxy request whose body is
+// base64 encoded, as API Gateway does for binary payloads.
+func Base64APIGatewayRequest(method, path, body string, headers map[string]string) events.APIGatewayProxyRequest {
+	request := APIGatewayRequest(method, path, base64.StdEncoding.EncodeToString([]byte(body)), headers)
+	request.IsBase64Encoded = true
+
+	return request
+}
@@ -0,0 +1,77 @@
+package testutil
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// MattermostServer is a fake Mattermost incoming webhook that records the body
+// of every request it receives.
+type MattermostServer struct {
+	URL string
+
+	mu         sync.Mutex
+	bodies     [][]byte
+	statusCode int
+}
+
+// NewMattermostServer starts a fake webhook that answers 200 OK. It is closed
+// when the test finishes.
+func NewMattermostServer(t testing.TB) *MattermostServer {
+	t.Helper()
+
+	s := &MattermostServer{statusCode: http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read webhook body: %s", err)
+		}
+
+		s.mu.Lock()
+		s.bodies = append(s.bodies, body)
+		statusCode := s.statusCode
+		s.mu.Unlock()
+
+		w.WriteHeader(statusCode)
+	}))
+	t.Cleanup(server.Close)
+	s.URL = server.URL
+
+	return s
+}
+
+// SetStatusCode changes the status the webhook answers with.
+func (s *MattermostServer) SetStatusCode(statusCode int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statusCode = statusCode
+}
+
+// Bodies returns the raw request bodies received so far.
+func (s *MattermostServer) Bodies() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([][]byte(nil), s.bodies...)
+}
+
+// Payloads decodes every request body received by the server into T, which is
+// usually the payload type of the lambda under test.
+func Payloads[T any](t testing.TB, s *MattermostServer) []T {
+	t.Helper()
+
+	var payloads []T
+	for _, body := range s.Bodies() {
+		var payload T
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Fatalf("failed to decode webhook payload %q: %s", body, err)
+		}
+		payloads = append(payloads, payload)
+	}
+
+	return payloads
+}
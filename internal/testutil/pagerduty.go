@@ -0,0 +1,71 @@
+package testutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	pagerduty "github.com/PagerDuty/go-pagerduty"
+)
+
+// PagerDutyServer is a fake PagerDuty Events API v2 that records every event
+// enqueued. Point a client at it with
+// pagerduty.WithV2EventsAPIEndpoint(server.URL).
+type PagerDutyServer struct {
+	URL string
+
+	mu     sync.Mutex
+	events []pagerduty.V2Event
+}
+
+// NewPagerDutyServer starts a fake Events API. It is closed when the test
+// finishes.
+func NewPagerDutyServer(t testing.TB) *PagerDutyServer {
+	t.Helper()
+
+	s := &PagerDutyServer{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/enqueue" {
+			http.NotFound(w, r)
+			return
+		}
+
+		var event pagerduty.V2Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("failed to decode PagerDuty event: %s", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		s.mu.Lock()
+		s.events = append(s.events, event)
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_ = json.NewEncoder(w).Encode(pagerduty.V2EventResponse{
+			Status:   "success",
+			Message:  "Event processed",
+			DedupKey: event.DedupKey,
+		})
+	}))
+	t.Cleanup(server.Close)
+	s.URL = server.URL
+
+	return s
+}
+
+// Client returns a PagerDuty client that sends events to the fake server.
+func (s *PagerDutyServer) Client() *pagerduty.Client {
+	return pagerduty.NewClient("", pagerduty.WithV2EventsAPIEndpoint(s.URL))
+}
+
+// Events returns the events received so far.
+func (s *PagerDutyServer) Events() []pagerduty.V2Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]pagerduty.V2Event(nil), s.events...)
+}
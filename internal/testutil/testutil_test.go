@@ -0,0 +1,68 @@
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	pagerduty "github.com/PagerDuty/go-pagerduty"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMattermostServer(t *testing.T) {
+	server := NewMattermostServer(t)
+
+	resp, err := http.Post(server.URL, "application/json", bytes.NewBufferString(`{"text":"hello"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	server.SetStatusCode(http.StatusInternalServerError)
+	resp, err = http.Post(server.URL, "application/json", bytes.NewBufferString(`{"text":"again"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	type payload struct {
+		Text string `json:"text"`
+	}
+	assert.Equal(t, []payload{{Text: "hello"}, {Text: "again"}}, Payloads[payload](t, server))
+}
+
+func TestPagerDutyServer(t *testing.T) {
+	server := NewPagerDutyServer(t)
+
+	_, err := server.Client().ManageEventWithContext(context.Background(), &pagerduty.V2Event{
+		RoutingKey: "key",
+		Action:     "trigger",
+		Payload:    &pagerduty.V2Payload{Summary: "summary", Source: "test", Severity: "critical"},
+	})
+	require.NoError(t, err)
+
+	events := server.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, "trigger", events[0].Action)
+	assert.Equal(t, "summary", events[0].Payload.Summary)
+}
+
+func TestEventBuilders(t *testing.T) {
+	snsEvent := CloudWatchAlarmSNSEvent(t, CloudWatchAlarm{AlarmName: "alarm", NewStateValue: "ALARM"})
+	require.Len(t, snsEvent.Records, 1)
+
+	var alarm CloudWatchAlarm
+	require.NoError(t, json.Unmarshal([]byte(snsEvent.Records[0].SNS.Message), &alarm))
+	assert.Equal(t, "ALARM", alarm.NewStateValue)
+
+	cwEvent := CloudWatchEvent(t, "aws.rds", "RDS DB Cluster Event", map[string]string{"EventID": "RDS-EVENT-0001"})
+	assert.JSONEq(t, `{"EventID":"RDS-EVENT-0001"}`, string(cwEvent.Detail))
+
+	request := Base64APIGatewayRequest(http.MethodPost, "/webhook", "body", nil)
+	assert.True(t, request.IsBase64Encoded)
+	decoded, err := base64.StdEncoding.DecodeString(request.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "body", string(decoded))
+}
@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"os"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	log "github.com/sirupsen/logrus"
+)
+
+// RedactedValue replaces the value of every sensitive header.
+const RedactedValue = "[REDACTED]"
+
+// defaultRedactedHeaders are always redacted from captured requests.
+var defaultRedactedHeaders = []string{"Authorization"}
+
+// debugCaptureEnabled reports whether raw requests should be logged when they
+// fail to parse. It is enabled by DEBUG_CAPTURE=true or LOG_LEVEL=debug.
+func debugCaptureEnabled() bool {
+	return strings.EqualFold(os.Getenv("DEBUG_CAPTURE"), "true") ||
+		strings.EqualFold(os.Getenv("LOG_LEVEL"), "debug")
+}
+
+// redactedHeaders returns the header names to redact, combining the defaults,
+// the headers given by the lambda and the comma-separated REDACT_HEADERS
+// environment variable.
+func redactedHeaders(sensitive []string) []string {
+	headers := append(append([]string{}, defaultRedactedHeaders...), sensitive...)
+	for _, header := range strings.Split(os.Getenv("REDACT_HEADERS"), ",") {
+		header = strings.TrimSpace(header)
+		if header != "" {
+			headers = append(headers, header)
+		}
+	}
+
+	return headers
+}
+
+func isRedacted(key string, headers []string) bool {
+	for _, header := range headers {
+		if strings.EqualFold(key, header) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RedactHeaders returns a copy of the headers with the values of Authorization,
+// the sensitive headers and the REDACT_HEADERS replaced. Header names are
+// matched case-insensitively.
+func RedactHeaders(headers map[string]string, sensitive ...string) map[string]string {
+	names := redactedHeaders(sensitive)
+	redacted := make(map[string]string, len(headers))
+	for key, value := range headers {
+		redacted[key] = value
+		if isRedacted(key, names) {
+			redacted[key] = RedactedValue
+		}
+	}
+
+	return redacted
+}
+
+// RedactMultiValueHeaders is RedactHeaders for multi-value headers.
+func RedactMultiValueHeaders(headers map[string][]string, sensitive ...string) map[string][]string {
+	names := redactedHeaders(sensitive)
+	redacted := make(map[string][]string, len(headers))
+	for key, values := range headers {
+		redacted[key] = values
+		if isRedacted(key, names) {
+			redacted[key] = make([]string, len(values))
+			for i := range values {
+				redacted[key][i] = RedactedValue
+			}
+		}
+	}
+
+	return redacted
+}
+
+// CaptureRawRequest logs the raw body and redacted headers of a request that
+// failed to parse, when debug capture is enabled. sensitive names the headers
+// to redact on top of Authorization and REDACT_HEADERS.
+func CaptureRawRequest(request events.APIGatewayProxyRequest, err error, sensitive ...string) {
+	if !debugCaptureEnabled() {
+		return
+	}
+
+	log.WithError(err).WithFields(log.Fields{
+		"headers":             RedactHeaders(request.Headers, sensitive...),
+		"multi_value_headers": RedactMultiValueHeaders(request.MultiValueHeaders, sensitive...),
+		"body":                request.Body,
+	}).Warn("Captured raw request that failed to parse")
+}
@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactHeaders(t *testing.T) {
+	t.Setenv("REDACT_HEADERS", "X-Api-Key")
+
+	headers := RedactHeaders(map[string]string{
+		"authorization":  "Bearer secret",
+		"X-Gitlab-Token": "gitlab-secret",
+		"x-api-key":      "api-secret",
+		"Content-Type":   "application/json",
+	}, "X-Gitlab-Token")
+
+	assert.Equal(t, RedactedValue, headers["authorization"])
+	assert.Equal(t, RedactedValue, headers["X-Gitlab-Token"])
+	assert.Equal(t, RedactedValue, headers["x-api-key"])
+	assert.Equal(t, "application/json", headers["Content-Type"])
+
+	headers = RedactHeaders(map[string]string{"X-Gitlab-Token": "gitlab-secret"})
+	assert.Equal(t, "gitlab-secret", headers["X-Gitlab-Token"], "only the given headers are redacted")
+}
+
+func TestRedactMultiValueHeaders(t *testing.T) {
+	t.Setenv("REDACT_HEADERS", "")
+
+	original := map[string][]string{
+		"Authorization": {"Bearer secret", "Basic secret"},
+		"Accept":        {"application/json", "text/plain"},
+	}
+	headers := RedactMultiValueHeaders(original)
+
+	assert.Equal(t, []string{RedactedValue, RedactedValue}, headers["Authorization"])
+	assert.Equal(t, []string{"application/json", "text/plain"}, headers["Accept"])
+	assert.Equal(t, "Bearer secret", original["Authorization"][0], "the original headers are not modified")
+}
+
+func TestCaptureRawRequest(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	request := events.APIGatewayProxyRequest{
+		Headers:           map[string]string{"Authorization": "Bearer secret"},
+		MultiValueHeaders: map[string][]string{"Authorization": {"Bearer secret"}},
+		Body:              "{not-json",
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		hook.Reset()
+		t.Setenv("DEBUG_CAPTURE", "")
+		t.Setenv("LOG_LEVEL", "")
+		CaptureRawRequest(request, errors.New("parse error"))
+		assert.Empty(t, hook.AllEntries())
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		hook.Reset()
+		t.Setenv("DEBUG_CAPTURE", "true")
+		CaptureRawRequest(request, errors.New("parse error"))
+
+		require.Len(t, hook.AllEntries(), 1)
+		entry := hook.LastEntry()
+		assert.Equal(t, log.WarnLevel, entry.Level)
+		assert.Equal(t, "{not-json", entry.Data["body"])
+		assert.Equal(t, RedactedValue, entry.Data["headers"].(map[string]string)["Authorization"])
+		assert.Equal(t, []string{RedactedValue}, entry.Data["multi_value_headers"].(map[string][]string)["Authorization"])
+	})
+}
@@ -0,0 +1,23 @@
+package webhook
+
+import (
+	"encoding/base64"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/pkg/errors"
+)
+
+// RequestBody returns the request body, decoding it when API Gateway
+// delivered it base64-encoded.
+func RequestBody(request events.APIGatewayProxyRequest) (string, error) {
+	if !request.IsBase64Encoded {
+		return request.Body, nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(request.Body)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decode base64 body")
+	}
+
+	return string(decoded), nil
+}
@@ -0,0 +1,28 @@
+package webhook
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestBody(t *testing.T) {
+	payload := `{"id":"cluster-id","new_state":"stable"}`
+
+	body, err := RequestBody(events.APIGatewayProxyRequest{Body: payload})
+	require.NoError(t, err)
+	assert.Equal(t, payload, body)
+
+	body, err = RequestBody(events.APIGatewayProxyRequest{
+		Body:            base64.StdEncoding.EncodeToString([]byte(payload)),
+		IsBase64Encoded: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, payload, body)
+
+	_, err = RequestBody(events.APIGatewayProxyRequest{Body: "not base64!", IsBase64Encoded: true})
+	assert.Error(t, err)
+}
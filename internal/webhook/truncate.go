@@ -0,0 +1,94 @@
+// Package webhook holds the helpers shared by the lambdas that receive
+// webhooks and post them to Mattermost.
+package webhook
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"unicode/utf8"
+)
+
+const (
+	// TruncatedMarker is appended to every truncated value.
+	TruncatedMarker = "…(truncated)"
+
+	defaultMaxFieldLength   = 4000
+	defaultMaxPayloadLength = 16000
+)
+
+// envLimit reads a positive byte limit from the environment, falling back to
+// the default when it is unset or invalid.
+func envLimit(name string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+
+	return value
+}
+
+// MaxFieldLength is the maximum size of a single attachment field value,
+// configured with MAX_FIELD_LENGTH.
+func MaxFieldLength() int {
+	return envLimit("MAX_FIELD_LENGTH", defaultMaxFieldLength)
+}
+
+// MaxPayloadLength is the maximum size of the marshaled Mattermost payload,
+// configured with MAX_PAYLOAD_LENGTH.
+func MaxPayloadLength() int {
+	return envLimit("MAX_PAYLOAD_LENGTH", defaultMaxPayloadLength)
+}
+
+// Truncate shortens value to at most limit bytes, including the truncation
+// marker, without splitting a multi-byte character.
+func Truncate(value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+
+	cut := limit - len(TruncatedMarker)
+	if cut < 0 {
+		cut = 0
+	}
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+
+	return value[:cut] + TruncatedMarker
+}
+
+// TruncatePayload shrinks the largest of values until the marshaled payload
+// fits within limit bytes or nothing else can be truncated. values must point
+// into payload, and into a copy the caller owns, since they are modified in
+// place.
+func TruncatePayload(payload interface{}, values []*string, limit int) {
+	for {
+		content, err := json.Marshal(payload)
+		if err != nil || len(content) <= limit {
+			return
+		}
+
+		largest := largestValue(values)
+		if largest == nil || len(*largest) <= len(TruncatedMarker) {
+			return
+		}
+
+		target := len(*largest) - (len(content) - limit)
+		if target < len(TruncatedMarker) {
+			target = len(TruncatedMarker)
+		}
+		*largest = Truncate(*largest, target)
+	}
+}
+
+func largestValue(values []*string) *string {
+	var largest *string
+	for _, value := range values {
+		if largest == nil || len(*value) > len(*largest) {
+			largest = value
+		}
+	}
+
+	return largest
+}
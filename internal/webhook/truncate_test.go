@@ -0,0 +1,51 @@
+package webhook
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", Truncate("short", 100))
+
+	truncated := Truncate(strings.Repeat("a", 100), 50)
+	assert.Len(t, truncated, 50)
+	assert.True(t, strings.HasSuffix(truncated, TruncatedMarker))
+
+	truncated = Truncate(strings.Repeat("é", 100), 51)
+	assert.True(t, utf8.ValidString(truncated))
+	assert.LessOrEqual(t, len(truncated), 51)
+}
+
+func TestMaxFieldLength(t *testing.T) {
+	assert.Equal(t, defaultMaxFieldLength, MaxFieldLength())
+
+	t.Setenv("MAX_FIELD_LENGTH", "100")
+	assert.Equal(t, 100, MaxFieldLength())
+
+	t.Setenv("MAX_FIELD_LENGTH", "-1")
+	assert.Equal(t, defaultMaxFieldLength, MaxFieldLength())
+}
+
+func TestTruncatePayload(t *testing.T) {
+	payload := struct {
+		Values []string `json:"values"`
+	}{
+		Values: []string{"id", strings.Repeat("y", 3000), strings.Repeat("z", 3000)},
+	}
+	values := []*string{&payload.Values[0], &payload.Values[1], &payload.Values[2]}
+
+	TruncatePayload(&payload, values, 2000)
+
+	content, err := json.Marshal(payload)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(content), 2000)
+	assert.Equal(t, "id", payload.Values[0])
+	assert.True(t, strings.HasSuffix(payload.Values[1], TruncatedMarker))
+	assert.True(t, strings.HasSuffix(payload.Values[2], TruncatedMarker))
+}
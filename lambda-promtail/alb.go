@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+// Positions of the ALB access log fields promoted to labels. See
+// https://docs.aws.amazon.com/elasticloadbalancing/latest/application/load-balancer-access-logs.html#access-log-entry-syntax
+const (
+	albFieldClient           = 3
+	albFieldELBStatusCode    = 8
+	albFieldTargetStatusCode = 9
+)
+
+// albPlaceholder is logged by ALB for fields without a value.
+const albPlaceholder = "-"
+
+// splitALBLogLine splits an ALB access log line on spaces, keeping quoted
+// fields together and unquoting them.
+func splitALBLogLine(line string) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	inQuotes, inField := false, false
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case inQuotes && c == '\\' && i+1 < len(line):
+			i++
+			field.WriteByte(line[i])
+		case c == '"':
+			inQuotes = !inQuotes
+			inField = true
+		case c == ' ' && !inQuotes:
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		default:
+			field.WriteByte(c)
+			inField = true
+		}
+	}
+
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quoted field in ALB log line")
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+
+	return fields, nil
+}
+
+// albLogLabels parses an ALB access log line and returns the status codes
+// and client IP as labels. Fields holding the "-" placeholder are left out.
+func albLogLabels(line string) (model.LabelSet, error) {
+	fields, err := splitALBLogLine(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) <= albFieldTargetStatusCode {
+		return nil, fmt.Errorf("expected at least %d fields in ALB log line, got %d", albFieldTargetStatusCode+1, len(fields))
+	}
+
+	client := fields[albFieldClient]
+	if host, _, err := net.SplitHostPort(client); err == nil {
+		client = host
+	}
+
+	labels := model.LabelSet{}
+	for name, value := range map[model.LabelName]string{
+		"elb_status_code":    fields[albFieldELBStatusCode],
+		"target_status_code": fields[albFieldTargetStatusCode],
+		"client_ip":          client,
+	} {
+		if value != "" && value != albPlaceholder {
+			labels[name] = model.LabelValue(value)
+		}
+	}
+
+	return labels, nil
+}
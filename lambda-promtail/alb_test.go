@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ALB access log samples from the AWS documentation.
+const (
+	albHTTPLine     = `http 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 200 200 34 366 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.46.0" - - arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337262-36d228ad5d99923122bbe354" "-" "-" 0 2018-07-02T22:22:48.364000Z "forward" "-" "-" "10.0.0.1:80" "200" "-" "-"`
+	albHTTPSLine    = `https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.086 0.048 0.037 200 200 0 57 "GET https://www.example.com:443/ HTTP/1.1" "curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337281-1d84f3d73c47ec4e58577259" "www.example.com" "arn:aws:acm:us-east-2:123456789012:certificate/12345678-1234-1234-1234-123456789012" 1 2018-07-02T22:22:48.364000Z "authenticate,forward" "-" "-" "10.0.0.1:80" "200" "-" "-"`
+	albNoTargetLine = `https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 192.168.131.39:2817 - -1 -1 -1 503 - 0 0 "GET https://www.example.com:443/ HTTP/1.1" "Mozilla/5.0 (Windows NT 10.0; Win64; x64)" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 - "Root=1-58337281-1d84f3d73c47ec4e58577259" "www.example.com" "-" 0 2018-07-02T22:22:48.364000Z "forward" "-" "-" "-" "-" "-" "-"`
+	albIPv6Line     = `h2 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 [2001:db8::1]:2817 10.0.0.1:80 0.000 0.002 0.000 404 404 34 366 "GET https://www.example.com:443/missing HTTP/2.0" "curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 "Root=1-58337327-72bd00b0343d75b906739c42" "-" "-" 1 2018-07-02T22:22:48.364000Z "redirect" "https://example.com:80/" "-" "10.0.0.1:80" "404" "-" "-"`
+)
+
+func TestSplitALBLogLine(t *testing.T) {
+	fields, err := splitALBLogLine(albHTTPLine)
+	require.NoError(t, err)
+	require.Len(t, fields, 29)
+	assert.Equal(t, "192.168.131.39:2817", fields[3])
+	assert.Equal(t, "GET http://www.example.com:80/ HTTP/1.1", fields[12])
+	assert.Equal(t, "curl/7.46.0", fields[13])
+	assert.Equal(t, "-", fields[18])
+
+	fields, err = splitALBLogLine(`a "b \"quoted\" c" "" d`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", `b "quoted" c`, "", "d"}, fields)
+
+	_, err = splitALBLogLine(`a "unterminated`)
+	assert.Error(t, err)
+}
+
+func TestALBLogLabels(t *testing.T) {
+	testCases := []struct {
+		name     string
+		line     string
+		expected model.LabelSet
+	}{
+		{"http", albHTTPLine, model.LabelSet{"elb_status_code": "200", "target_status_code": "200", "client_ip": "192.168.131.39"}},
+		{"https", albHTTPSLine, model.LabelSet{"elb_status_code": "200", "target_status_code": "200", "client_ip": "192.168.131.39"}},
+		{"no target", albNoTargetLine, model.LabelSet{"elb_status_code": "503", "client_ip": "192.168.131.39"}},
+		{"ipv6 client", albIPv6Line, model.LabelSet{"elb_status_code": "404", "target_status_code": "404", "client_ip": "2001:db8::1"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			labels, err := albLogLabels(tc.line)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, labels)
+		})
+	}
+
+	_, err := albLogLabels("http 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188")
+	assert.Error(t, err)
+}
+
+func gzipLines(t *testing.T, lines ...string) io.ReadCloser {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	for _, line := range lines {
+		_, err := gz.Write([]byte(line + "\n"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, gz.Close())
+
+	return io.NopCloser(&buf)
+}
+
+func TestParseS3LogALBLabels(t *testing.T) {
+	setTenantConfig(t, "", "")
+	previousALBLog := albLog
+	defer func() { albLog = previousALBLog }()
+
+	for _, enabled := range []bool{false, true} {
+		albLog = enabled
+		b, err := newBatch(context.Background())
+		require.NoError(t, err)
+
+		require.NoError(t, parseS3Log(context.Background(), b, map[string]string{"lb": "my-loadbalancer"}, gzipLines(t, albHTTPLine, albNoTargetLine)))
+
+		var lines []string
+		var labels []string
+		for _, stream := range b.streams {
+			labels = append(labels, stream.Labels)
+			for _, e := range stream.Entries {
+				lines = append(lines, e.Line)
+			}
+		}
+		assert.ElementsMatch(t, []string{albHTTPLine, albNoTargetLine}, lines)
+
+		if !enabled {
+			require.Len(t, labels, 1)
+			assert.NotContains(t, labels[0], "elb_status_code")
+			continue
+		}
+		require.Len(t, labels, 2)
+		assert.Contains(t, labels, `{__aws_log_type="s3_lb", __aws_s3_log_lb="my-loadbalancer", __aws_s3_log_lb_owner="", client_ip="192.168.131.39", elb_status_code="200", target_status_code="200"}`)
+		assert.Contains(t, labels, `{__aws_log_type="s3_lb", __aws_s3_log_lb="my-loadbalancer", __aws_s3_log_lb_owner="", client_ip="192.168.131.39", elb_status_code="503"}`)
+	}
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"fmt"
 	log "github.com/sirupsen/logrus"
 	"strings"
 	"time"
@@ -15,11 +14,26 @@ import (
 func parseCWEvent(ctx context.Context, b *batch, ev *events.CloudwatchLogsEvent) error {
 	data, err := ev.AWSLogs.Parse()
 	if err != nil {
-		fmt.Println("error parsing log event: ", err)
+		log.WithError(err).Error("Error parsing log event")
 		return err
 	}
 
+	if dropLogStreamRegex != nil && dropLogStreamRegex.MatchString(data.LogStream) {
+		log.WithFields(log.Fields{
+			"log_group":  data.LogGroup,
+			"log_stream": data.LogStream,
+			"events":     len(data.LogEvents),
+		}).Debug("Dropping events from log stream")
+		b.stats.addDropped(len(data.LogEvents))
+		return nil
+	}
+
 	for _, event := range data.LogEvents {
+		if dropCWMessage(event.Message) {
+			b.stats.addDropped(1)
+			continue
+		}
+
 		labels := model.LabelSet{
 			model.LabelName("__aws_cloudwatch_log_group"): model.LabelValue(data.LogGroup),
 			model.LabelName("__aws_cloudwatch_owner"):     model.LabelValue(data.Owner),
@@ -36,7 +50,7 @@ func parseCWEvent(ctx context.Context, b *batch, ev *events.CloudwatchLogsEvent)
 		timestamp := time.UnixMilli(event.Timestamp)
 
 		err := b.add(ctx, entry{labels, logproto.Entry{
-			Line:      event.Message,
+			Line:      truncateLine(event.Message),
 			Timestamp: timestamp,
 		}})
 		if err != nil {
@@ -47,17 +61,25 @@ func parseCWEvent(ctx context.Context, b *batch, ev *events.CloudwatchLogsEvent)
 	return nil
 }
 
-func processCWEvent(ctx context.Context, ev *events.CloudwatchLogsEvent) error {
+// dropCWMessage reports whether a CloudWatch log event carries nothing worth
+// keeping: an empty or whitespace-only message, or one shorter than
+// MIN_LINE_BYTES, such as the control-plane events some log groups
+// interleave with the application logs.
+func dropCWMessage(message string) bool {
+	if strings.TrimSpace(message) == "" {
+		return true
+	}
+
+	return len(message) < minLineBytes
+}
+
+func processCWEvent(ctx context.Context, ev *events.CloudwatchLogsEvent, stats *lineStats) error {
 	batch, _ := newBatch(ctx)
+	batch.stats = stats
+	stopFlushTimer := batch.startFlushTimer(ctx)
+	defer stopFlushTimer()
 
 	err := parseCWEvent(ctx, batch, ev)
-	if err != nil {
-		return err
-	}
 
-	err = sendToPromtail(ctx, batch)
-	if err != nil {
-		return err
-	}
-	return nil
+	return flushRemaining(ctx, batch, err)
 }
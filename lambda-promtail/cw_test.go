@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCWEventDropLogStream(t *testing.T) {
+	batchSize = 1 << 20
+	dropLogStreamRegex = regexp.MustCompile(`^sidecar/`)
+	defer func() { dropLogStreamRegex = nil }()
+
+	testCases := []struct {
+		logStream string
+		expected  int
+	}{
+		{"sidecar/envoy/123", 0},
+		{"app/web/123", 1},
+		{"app/sidecar/123", 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.logStream, func(t *testing.T) {
+			ev := newCWEvent(t, events.CloudwatchLogsData{
+				LogGroup:  "/ecs/service",
+				LogStream: tc.logStream,
+				LogEvents: []events.CloudwatchLogsLogEvent{{ID: "1", Timestamp: 1700000000000, Message: "line"}},
+			})
+
+			b, err := newBatch(context.Background())
+			require.NoError(t, err)
+			require.NoError(t, parseCWEvent(context.Background(), b, ev))
+			assert.Len(t, batchEntries(b), tc.expected)
+		})
+	}
+}
+
+func TestSetupArgumentsInvalidDropLogStreamRegex(t *testing.T) {
+	t.Setenv("WRITE_ADDRESS", "https://loki.example.com/loki/api/v1/push")
+	t.Setenv("DROP_LOG_STREAM_REGEX", "([")
+	defer func() { dropLogStreamRegex = nil }()
+
+	err := setupArguments()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "DROP_LOG_STREAM_REGEX")
+}
+
+func TestParseCWEventDropsEmptyAndShortLines(t *testing.T) {
+	batchSize = 1 << 20
+	minLineBytes = 5
+	defer func() { minLineBytes = 0 }()
+
+	ev := newCWEvent(t, events.CloudwatchLogsData{
+		LogGroup:  "/ecs/service",
+		LogStream: "app/web/123",
+		LogEvents: []events.CloudwatchLogsLogEvent{
+			{ID: "1", Timestamp: 1700000000000, Message: ""},
+			{ID: "2", Timestamp: 1700000000000, Message: " \t\n"},
+			{ID: "3", Timestamp: 1700000000000, Message: "ok"},
+			{ID: "4", Timestamp: 1700000000000, Message: "hello"},
+			{ID: "5", Timestamp: 1700000000000, Message: "request served"},
+		},
+	})
+
+	b, err := newBatch(context.Background())
+	require.NoError(t, err)
+	stats := &lineStats{}
+	b.stats = stats
+	require.NoError(t, parseCWEvent(context.Background(), b, ev))
+
+	entries := batchEntries(b)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "hello", entries[0].Line)
+	assert.Equal(t, "request served", entries[1].Line)
+	assert.Equal(t, 3, stats.dropped)
+}
+
+func TestDropCWMessage(t *testing.T) {
+	assert.True(t, dropCWMessage(""))
+	assert.True(t, dropCWMessage("   "))
+	assert.False(t, dropCWMessage("a"))
+
+	minLineBytes = 3
+	defer func() { minLineBytes = 0 }()
+	assert.True(t, dropCWMessage("ab"))
+	assert.False(t, dropCWMessage("abc"))
+}
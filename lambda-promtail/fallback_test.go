@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pushRecord is what a fake Loki saw of a push.
+type pushRecord struct {
+	tenant   string
+	username string
+	password string
+}
+
+// newLokiServer starts a fake Loki answering every push with status and
+// returns its URL and the pushes it received.
+func newLokiServer(t *testing.T, status int) (*url.URL, func() []pushRecord) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var pushes []pushRecord
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ := r.BasicAuth()
+		mu.Lock()
+		pushes = append(pushes, pushRecord{tenant: r.Header.Get("X-Scope-OrgID"), username: username, password: password})
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	addr, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	return addr, func() []pushRecord {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]pushRecord(nil), pushes...)
+	}
+}
+
+func setWriteAddresses(t *testing.T, addrs ...*url.URL) {
+	t.Helper()
+
+	previousAddresses, previousUsername, previousPassword := writeAddresses, username, password
+	writeAddresses, username, password = addrs, "promtail", "secret"
+	t.Cleanup(func() { writeAddresses, username, password = previousAddresses, previousUsername, previousPassword })
+}
+
+func TestSendToPromtailFallback(t *testing.T) {
+	setTenantConfig(t, "static-tenant", "")
+	primary, primaryPushes := newLokiServer(t, http.StatusBadRequest)
+	secondary, secondaryPushes := newLokiServer(t, http.StatusNoContent)
+	setWriteAddresses(t, primary, secondary)
+
+	b, err := newBatch(context.Background(), tenantEntry(model.LabelSet{"app": "a"}))
+	require.NoError(t, err)
+	stats := &lineStats{}
+	b.stats = stats
+
+	require.NoError(t, sendToPromtail(context.Background(), b))
+
+	expected := []pushRecord{{tenant: "static-tenant", username: "promtail", password: "secret"}}
+	assert.Equal(t, expected, primaryPushes())
+	assert.Equal(t, expected, secondaryPushes())
+	assert.Equal(t, lineStats{forwarded: 1}, *stats)
+}
+
+func TestSendToPromtailPrimarySucceeds(t *testing.T) {
+	setTenantConfig(t, "", "")
+	primary, primaryPushes := newLokiServer(t, http.StatusNoContent)
+	secondary, secondaryPushes := newLokiServer(t, http.StatusNoContent)
+	setWriteAddresses(t, primary, secondary)
+
+	b, err := newBatch(context.Background(), tenantEntry(model.LabelSet{"app": "a"}))
+	require.NoError(t, err)
+
+	require.NoError(t, sendToPromtail(context.Background(), b))
+	assert.Len(t, primaryPushes(), 1)
+	assert.Empty(t, secondaryPushes())
+}
+
+func TestSendToPromtailAllAddressesFail(t *testing.T) {
+	setTenantConfig(t, "", "")
+	primary, _ := newLokiServer(t, http.StatusBadRequest)
+	secondary, _ := newLokiServer(t, http.StatusBadRequest)
+	setWriteAddresses(t, primary, secondary)
+
+	b, err := newBatch(context.Background(), tenantEntry(model.LabelSet{"app": "a"}))
+	require.NoError(t, err)
+	stats := &lineStats{}
+	b.stats = stats
+
+	err = flushRemaining(context.Background(), b, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "all 2 write addresses failed")
+	assert.Equal(t, lineStats{failed: 1}, *stats)
+}
+
+func TestParseWriteAddresses(t *testing.T) {
+	addrs, err := parseWriteAddresses("https://primary.example.com/loki/api/v1/push, https://fallback.example.com/loki/api/v1/push")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://primary.example.com/loki/api/v1/push", "https://fallback.example.com/loki/api/v1/push"}, redactedAddresses(addrs))
+
+	_, err = parseWriteAddresses("https://primary.example.com/loki/api/v1/push,fallback.example.com")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid WRITE_ADDRESS")
+
+	_, err = parseWriteAddresses(" , ")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "required environmental variable WRITE_ADDRESS not present")
+}
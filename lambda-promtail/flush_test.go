@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessCWEventFlushesPartialBatch(t *testing.T) {
+	recorded := newTenantRecorder(t)
+	setTenantConfig(t, "", "")
+
+	stats := &lineStats{}
+	require.NoError(t, processCWEvent(context.Background(), cwEventWithLines(t, "app/web/1", 2), stats))
+
+	assert.Len(t, recorded(), 1)
+	assert.Equal(t, 2, stats.forwarded)
+}
+
+func TestProcessCWEventSkipsEmptyFlush(t *testing.T) {
+	recorded := newTenantRecorder(t)
+	setTenantConfig(t, "", "")
+
+	require.NoError(t, processCWEvent(context.Background(), newCWEvent(t, events.CloudwatchLogsData{LogStream: "app/web/1"}), nil))
+
+	assert.Empty(t, recorded())
+}
+
+func TestFlushRemainingOnProcessingError(t *testing.T) {
+	recorded := newTenantRecorder(t)
+	setTenantConfig(t, "", "")
+
+	b, err := newBatch(context.Background(), tenantEntry(model.LabelSet{"app": "a"}))
+	require.NoError(t, err)
+
+	processErr := errors.New("failed to read object")
+	assert.Equal(t, processErr, flushRemaining(context.Background(), b, processErr))
+	assert.Len(t, recorded(), 1)
+	assert.Empty(t, b.streams)
+	assert.Zero(t, b.size)
+}
+
+func TestFlushTimer(t *testing.T) {
+	recorded := newTenantRecorder(t)
+	setTenantConfig(t, "", "")
+	previousInterval := flushInterval
+	flushInterval = 10 * time.Millisecond
+	defer func() { flushInterval = previousInterval }()
+
+	b, err := newBatch(context.Background())
+	require.NoError(t, err)
+	stats := &lineStats{}
+	b.stats = stats
+	stop := b.startFlushTimer(context.Background())
+
+	require.NoError(t, b.add(context.Background(), tenantEntry(model.LabelSet{"app": "a"})))
+	require.Eventually(t, func() bool { return len(recorded()) == 1 }, time.Second, 5*time.Millisecond)
+
+	stop()
+	require.NoError(t, b.add(context.Background(), tenantEntry(model.LabelSet{"app": "a"})))
+	time.Sleep(5 * flushInterval)
+
+	assert.Len(t, recorded(), 1)
+	assert.Equal(t, 1, stats.forwarded)
+	assert.Len(t, b.streams, 1)
+}
+
+func TestFlushTimerDisabled(t *testing.T) {
+	previousInterval := flushInterval
+	flushInterval = 0
+	defer func() { flushInterval = previousInterval }()
+
+	b, err := newBatch(context.Background())
+	require.NoError(t, err)
+
+	b.startFlushTimer(context.Background())()
+}
@@ -11,12 +11,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/prometheus/common/model"
-	log "github.com/sirupsen/logrus"
 	"net/url"
 	"os"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	log "github.com/sirupsen/logrus"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
@@ -33,66 +36,189 @@ const (
 )
 
 var (
-	writeAddress                                 *url.URL
+	writeAddresses                               []*url.URL
 	username, password, extraLabelsRaw, tenantID string
 	keepStream                                   bool
 	includeMessageAsLabel                        bool
+	albLog                                       bool
 	batchSize                                    int
-	s3Clients                                    map[string]*s3.Client
+	flushInterval                                time.Duration
+	maxLineBytes                                 int
+	minLineBytes                                 int
+	dropLogStreamRegex                           *regexp.Regexp
+	dynamicTenantLabel                           string
+	metricsNamespace                             string
+	s3KeyPrefixAllowlist, s3KeySuffixAllowlist   []string
+	s3Clients                                    map[s3ClientKey]*s3.Client
+	s3RoleArns                                   map[string]string
 	extraLabels                                  model.LabelSet
 )
 
-func setupArguments() {
-	var err error
+func init() {
+	log.SetFormatter(&log.JSONFormatter{})
+	log.SetOutput(os.Stdout)
+}
 
-	addr := os.Getenv("WRITE_ADDRESS")
-	if addr == "" {
-		log.WithError(err).Error("required environmental variable WRITE_ADDRESS not present, format: https://<hostname>/loki/api/v1/push")
-	}
+func setupArguments() error {
+	var err error
 
-	writeAddress, err = url.Parse(addr)
+	writeAddresses, err = parseWriteAddresses(os.Getenv("WRITE_ADDRESS"))
 	if err != nil {
-		log.WithError(err).Error("unable to parse URL provided")
+		return err
 	}
 
-	fmt.Println("write address: ", writeAddress.String())
-
 	extraLabelsRaw = os.Getenv("EXTRA_LABELS")
 	extraLabels, err = parseExtraLabels(extraLabelsRaw)
 	if err != nil {
-		log.WithError(err)
+		return err
 	}
 
 	username = os.Getenv("USERNAME")
 	password = os.Getenv("PASSWORD")
 	// If either username or password is set then both must be.
 	if (username != "" && password == "") || (username == "" && password != "") {
-		log.WithError(err).Error("both username and password must be set if either one is set")
+		return errors.New("both username and password must be set if either one is set")
 	}
 
 	tenantID = os.Getenv("TENANT_ID")
+	metricsNamespace = os.Getenv("METRICS_NAMESPACE")
+	dynamicTenantLabel = os.Getenv("DYNAMIC_TENANT_LABEL")
+	if dynamicTenantLabel != "" && !model.LabelName(dynamicTenantLabel).IsValid() {
+		return fmt.Errorf("invalid label name for environment variable DYNAMIC_TENANT_LABEL: %q", dynamicTenantLabel)
+	}
 
 	keep := os.Getenv("KEEP_STREAM")
 	// Anything other than case-insensitive 'true' is treated as 'false'.
-	if strings.EqualFold(keep, "true") {
-		keepStream = true
-	}
-	fmt.Println("keep stream: ", keepStream)
+	keepStream = strings.EqualFold(keep, "true")
 
 	messageIncluded := os.Getenv("INCLUDE_MESSAGE")
 	// Anything other than case-insensitive 'true' is treated as 'false'.
-	if strings.EqualFold(messageIncluded, "true") {
-		includeMessageAsLabel = true
-	}
-	fmt.Println("Include Message As Label: ", includeMessageAsLabel)
+	includeMessageAsLabel = strings.EqualFold(messageIncluded, "true")
+
+	// Anything other than case-insensitive 'true' is treated as 'false'.
+	albLog = strings.EqualFold(os.Getenv("ALB_LOG"), "true")
 
 	batch := os.Getenv("BATCH_SIZE")
 	batchSize = 131072
 	if batch != "" {
-		batchSize, _ = strconv.Atoi(batch)
+		batchSize, err = strconv.Atoi(batch)
+		if err != nil || batchSize <= 0 {
+			return fmt.Errorf("invalid value for environment variable BATCH_SIZE: %q", batch)
+		}
+	}
+
+	flushInterval = 0
+	if interval := os.Getenv("FLUSH_INTERVAL_MS"); interval != "" {
+		var ms int
+		ms, err = strconv.Atoi(interval)
+		if err != nil || ms < 0 {
+			return fmt.Errorf("invalid value for environment variable FLUSH_INTERVAL_MS: %q", interval)
+		}
+		flushInterval = time.Duration(ms) * time.Millisecond
 	}
 
-	s3Clients = make(map[string]*s3.Client)
+	maxLineBytes = 0
+	if maxLine := os.Getenv("MAX_LINE_BYTES"); maxLine != "" {
+		maxLineBytes, err = strconv.Atoi(maxLine)
+		if err != nil || maxLineBytes < 0 {
+			return fmt.Errorf("invalid value for environment variable MAX_LINE_BYTES: %q", maxLine)
+		}
+	}
+
+	minLineBytes = 0
+	if minLine := os.Getenv("MIN_LINE_BYTES"); minLine != "" {
+		minLineBytes, err = strconv.Atoi(minLine)
+		if err != nil || minLineBytes < 0 {
+			return fmt.Errorf("invalid value for environment variable MIN_LINE_BYTES: %q", minLine)
+		}
+	}
+
+	dropLogStreamRegex = nil
+	if dropStream := os.Getenv("DROP_LOG_STREAM_REGEX"); dropStream != "" {
+		dropLogStreamRegex, err = regexp.Compile(dropStream)
+		if err != nil {
+			return fmt.Errorf("invalid value for environment variable DROP_LOG_STREAM_REGEX: %w", err)
+		}
+	}
+
+	s3KeyPrefixAllowlist = parseList(os.Getenv("S3_KEY_PREFIX_ALLOWLIST"))
+	s3KeySuffixAllowlist = parseList(os.Getenv("S3_KEY_SUFFIX_ALLOWLIST"))
+
+	s3RoleArns, err = parseS3RoleArns(os.Getenv("S3_ROLE_ARNS"))
+	if err != nil {
+		return err
+	}
+	s3Clients = make(map[s3ClientKey]*s3.Client)
+
+	// The password is deliberately never logged.
+	log.WithFields(log.Fields{
+		"write_address":            redactedAddresses(writeAddresses),
+		"username":                 username,
+		"tenant_id":                tenantID,
+		"dynamic_tenant_label":     dynamicTenantLabel,
+		"metrics_namespace":        metricsNamespace,
+		"keep_stream":              keepStream,
+		"include_message_as_label": includeMessageAsLabel,
+		"alb_log":                  albLog,
+		"batch_size":               batchSize,
+		"flush_interval":           flushInterval.String(),
+		"max_line_bytes":           maxLineBytes,
+		"min_line_bytes":           minLineBytes,
+		"drop_log_stream_regex":    os.Getenv("DROP_LOG_STREAM_REGEX"),
+		"s3_key_prefix_allowlist":  s3KeyPrefixAllowlist,
+		"s3_key_suffix_allowlist":  s3KeySuffixAllowlist,
+		"s3_role_arns":             s3RoleArns,
+	}).Info("lambda-promtail configured")
+
+	return nil
+}
+
+// parseWriteAddresses parses WRITE_ADDRESS, a comma-separated list of Loki
+// push endpoints. The first one is the primary, the others are fallbacks
+// tried in order when pushing to the previous ones fails.
+func parseWriteAddresses(value string) ([]*url.URL, error) {
+	addrs := parseList(value)
+	if len(addrs) == 0 {
+		return nil, errors.New("required environmental variable WRITE_ADDRESS not present, format: https://<hostname>/loki/api/v1/push")
+	}
+
+	writeAddresses := make([]*url.URL, 0, len(addrs))
+	for _, addr := range addrs {
+		writeAddress, err := url.Parse(addr)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse WRITE_ADDRESS: %w", err)
+		}
+		if writeAddress.Scheme == "" || writeAddress.Host == "" {
+			return nil, fmt.Errorf("invalid WRITE_ADDRESS %q, format: https://<hostname>/loki/api/v1/push", writeAddress.Redacted())
+		}
+		writeAddresses = append(writeAddresses, writeAddress)
+	}
+
+	return writeAddresses, nil
+}
+
+// redactedAddresses returns the addresses with their passwords redacted, for
+// logging.
+func redactedAddresses(addrs []*url.URL) []string {
+	redacted := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		redacted = append(redacted, addr.Redacted())
+	}
+
+	return redacted
+}
+
+// parseList splits a comma-separated environment variable value.
+func parseList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
 }
 
 func parseExtraLabels(extraLabelsRaw string) (model.LabelSet, error) {
@@ -113,7 +239,7 @@ func parseExtraLabels(extraLabelsRaw string) (model.LabelSet, error) {
 	if err != nil {
 		return nil, err
 	}
-	fmt.Println("extra labels:", extractedLabels)
+	log.WithField("extra_labels", extractedLabels.String()).Debug("Parsed extra labels")
 	return extractedLabels, nil
 }
 
@@ -141,7 +267,7 @@ func checkEventType(ev map[string]interface{}) (interface{}, error) {
 
 		_, err = reader.Seek(0, 0)
 		if err != nil {
-			log.WithError(err)
+			log.WithError(err).Error("Failed to rewind the event reader")
 		}
 	}
 
@@ -151,21 +277,26 @@ func checkEventType(ev map[string]interface{}) (interface{}, error) {
 func handler(ctx context.Context, ev map[string]interface{}) error {
 	event, err := checkEventType(ev)
 	if err != nil {
-		log.WithError(err)
+		log.WithError(err).Error("Failed to determine the event type")
 		return err
 	}
 
+	stats := &lineStats{}
+	defer stats.logSummary()
+
 	switch event := event.(type) {
 	case *events.S3Event:
-		return processS3Event(ctx, event)
+		return processS3Event(ctx, event, stats)
 	case *events.CloudwatchLogsEvent:
-		return processCWEvent(ctx, event)
+		return processCWEvent(ctx, event, stats)
 	}
 
 	return err
 }
 
 func main() {
-	setupArguments()
+	if err := setupArguments(); err != nil {
+		log.WithError(err).Fatal("Invalid lambda-promtail configuration")
+	}
 	lambda.Start(handler)
 }
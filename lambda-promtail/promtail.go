@@ -8,15 +8,19 @@ import (
 	"github.com/pkg/errors"
 	"io"
 	"net/http"
+	"net/url"
 	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/golang/snappy"
 	"github.com/grafana/dskit/backoff"
 	"github.com/grafana/loki/pkg/logproto"
 	"github.com/prometheus/common/model"
+	log "github.com/sirupsen/logrus"
 )
 
 const (
@@ -28,6 +32,8 @@ const (
 	reservedLabelTenantID = "__tenant_id__"
 
 	userAgent = "lambda-promtail"
+
+	truncatedMarker = "...(truncated)"
 )
 
 type entry struct {
@@ -36,44 +42,93 @@ type entry struct {
 }
 
 type batch struct {
+	// mu guards the batch against the flush timer.
+	mu      sync.Mutex
 	streams map[string]*logproto.Stream
+	// tenants maps each stream key to the Loki tenant it is pushed to.
+	tenants map[string]string
 	size    int
+	// stats records the outcome of every line sent from this batch.
+	stats *lineStats
 }
 
 func newBatch(ctx context.Context, entries ...entry) (*batch, error) {
 	b := &batch{
 		streams: map[string]*logproto.Stream{},
+		tenants: map[string]string{},
 	}
 
 	for _, entry := range entries {
 		err := b.add(ctx, entry)
-		return b, err
+		if err != nil {
+			return b, err
+		}
 	}
 
 	return b, nil
 }
 
 func (b *batch) add(ctx context.Context, e entry) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	tenant := entryTenant(e.labels)
 	labels := labelsMapToString(e.labels, reservedLabelTenantID)
-	stream, ok := b.streams[labels]
+	key := tenant + labels
+	stream, ok := b.streams[key]
 	if !ok {
-		b.streams[labels] = &logproto.Stream{
+		b.streams[key] = &logproto.Stream{
 			Labels:  labels,
 			Entries: []logproto.Entry{},
 		}
-		stream = b.streams[labels]
+		b.tenants[key] = tenant
+		stream = b.streams[key]
 	}
 
 	stream.Entries = append(stream.Entries, e.entry)
 	b.size += len(e.entry.Line)
 
 	if b.size > batchSize {
-		return b.flushBatch(ctx)
+		return b.flushLocked(ctx)
 	}
 
 	return nil
 }
 
+// entryTenant returns the Loki tenant for an entry. The value of the
+// DYNAMIC_TENANT_LABEL label, or of the reserved __tenant_id__ label, takes
+// precedence over the static TENANT_ID.
+func entryTenant(labels model.LabelSet) string {
+	if dynamicTenantLabel != "" {
+		if value := labels[model.LabelName(dynamicTenantLabel)]; value != "" {
+			return string(value)
+		}
+	}
+	if value := labels[reservedLabelTenantID]; value != "" {
+		return string(value)
+	}
+
+	return tenantID
+}
+
+// truncateLine shortens lines longer than MAX_LINE_BYTES so a single line
+// cannot cause Loki to reject the whole push. A limit of 0 disables it.
+func truncateLine(line string) string {
+	if maxLineBytes <= 0 || len(line) <= maxLineBytes {
+		return line
+	}
+
+	cut := maxLineBytes - len(truncatedMarker)
+	if cut < 0 {
+		cut = 0
+	}
+	for cut > 0 && !utf8.RuneStart(line[cut]) {
+		cut--
+	}
+
+	return line[:cut] + truncatedMarker
+}
+
 func labelsMapToString(ls model.LabelSet, without ...model.LabelName) string {
 	lstrs := make([]string, 0, len(ls))
 Outer:
@@ -90,59 +145,206 @@ Outer:
 	return fmt.Sprintf("{%s}", strings.Join(lstrs, ", "))
 }
 
-func (b *batch) encode() ([]byte, int, error) {
-	req, entriesCount := b.createPushRequest()
-	buf, err := proto.Marshal(req)
-	if err != nil {
-		return nil, 0, err
+// encode returns the snappy-encoded push request for each tenant in the batch.
+func (b *batch) encode() (map[string][]byte, int, error) {
+	requests, entriesCount := b.createPushRequests()
+
+	bufs := make(map[string][]byte, len(requests))
+	for tenant, req := range requests {
+		buf, err := proto.Marshal(req)
+		if err != nil {
+			return nil, 0, err
+		}
+		bufs[tenant] = snappy.Encode(nil, buf)
 	}
 
-	buf = snappy.Encode(nil, buf)
-	return buf, entriesCount, nil
+	return bufs, entriesCount, nil
 }
 
-func (b *batch) createPushRequest() (*logproto.PushRequest, int) {
-	req := logproto.PushRequest{
-		Streams: make([]logproto.Stream, 0, len(b.streams)),
-	}
+func (b *batch) createPushRequests() (map[string]*logproto.PushRequest, int) {
+	requests := map[string]*logproto.PushRequest{}
 
 	entriesCount := 0
-	for _, stream := range b.streams {
+	for key, stream := range b.streams {
+		tenant := b.tenants[key]
+		req, ok := requests[tenant]
+		if !ok {
+			req = &logproto.PushRequest{}
+			requests[tenant] = req
+		}
 		req.Streams = append(req.Streams, *stream)
 		entriesCount += len(stream.Entries)
 	}
-	return &req, entriesCount
+
+	return requests, entriesCount
 }
 
 func (b *batch) flushBatch(ctx context.Context) error {
-	err := sendToPromtail(ctx, b)
-	if err != nil {
-		return err
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.flushLocked(ctx)
+}
+
+// flushLocked sends the batch. The streams of each tenant leave the batch as
+// soon as they are pushed, so after a partial failure the next flush only
+// sends the tenants that failed.
+func (b *batch) flushLocked(ctx context.Context) error {
+	return sendToPromtail(ctx, b)
+}
+
+// startFlushTimer flushes the batch every FLUSH_INTERVAL_MS, so lines do not
+// sit unsent until the batch fills up during long invocations. The returned
+// function stops the timer and waits for an in-flight flush to finish; it
+// must be called before the invocation returns.
+func (b *batch) startFlushTimer(ctx context.Context) (stop func()) {
+	if flushInterval <= 0 {
+		return func() {}
 	}
 
-	b.streams = make(map[string]*logproto.Stream)
+	ticker := time.NewTicker(flushInterval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := b.flushBatch(ctx); err != nil {
+					log.WithError(err).Error("Failed to flush batch on timer")
+				}
+			}
+		}
+	}()
 
-	return nil
+	return func() {
+		ticker.Stop()
+		close(done)
+		<-stopped
+	}
+}
+
+// flushRemaining sends whatever is left in the batch, even when processing
+// stopped early on processErr, so lines that never filled a batch are not lost
+// when the execution environment is frozen. processErr takes precedence over
+// a flush error. Lines still in the batch after the final flush are counted
+// as failed; earlier failed flushes leave them in the batch to be retried.
+func flushRemaining(ctx context.Context, b *batch, processErr error) error {
+	err := b.flushBatch(ctx)
+	if err != nil {
+		b.stats.addFailed(b.pending())
+	}
+	if processErr != nil {
+		if err != nil {
+			log.WithError(err).Error("Failed to flush remaining batch")
+		}
+		return processErr
+	}
+
+	return err
 }
 
 func sendToPromtail(ctx context.Context, b *batch) error {
-	buf, _, err := b.encode()
+	bufs, _, err := b.encode()
 	if err != nil {
 		return errors.Wrap(err, "")
 	}
 
+	tenants := make([]string, 0, len(bufs))
+	for tenant := range bufs {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+
+	counts := b.tenantEntries()
+	for _, tenant := range tenants {
+		err = sendWithFallback(ctx, bufs[tenant], tenant)
+		if err != nil {
+			return errors.Wrapf(err, "failed to send logs for tenant %q", tenant)
+		}
+		b.stats.addForwarded(counts[tenant])
+		b.removeTenant(tenant)
+	}
+
+	return nil
+}
+
+// removeTenant drops the streams of tenant from the batch once they are sent.
+func (b *batch) removeTenant(tenant string) {
+	for key, streamTenant := range b.tenants {
+		if streamTenant != tenant {
+			continue
+		}
+		for _, e := range b.streams[key].Entries {
+			b.size -= len(e.Line)
+		}
+		delete(b.streams, key)
+		delete(b.tenants, key)
+	}
+}
+
+// pending returns the number of entries not yet sent.
+func (b *batch) pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	count := 0
+	for _, stream := range b.streams {
+		count += len(stream.Entries)
+	}
+
+	return count
+}
+
+// tenantEntries returns the number of entries held for each tenant.
+func (b *batch) tenantEntries() map[string]int {
+	counts := map[string]int{}
+	for key, stream := range b.streams {
+		counts[b.tenants[key]] += len(stream.Entries)
+	}
+
+	return counts
+}
+
+// sendWithFallback pushes to each WRITE_ADDRESS in order until one accepts
+// the logs. It only fails when every address does.
+func sendWithFallback(ctx context.Context, buf []byte, tenant string) error {
+	var err error
+	for i, writeAddress := range writeAddresses {
+		err = sendWithRetry(ctx, writeAddress, buf, tenant)
+		if err == nil {
+			return nil
+		}
+		if i < len(writeAddresses)-1 {
+			log.WithError(err).WithField("write_address", writeAddress.Redacted()).Warn("Failed to send batch, trying the next write address")
+		}
+	}
+
+	if len(writeAddresses) > 1 {
+		return errors.Wrapf(err, "all %d write addresses failed", len(writeAddresses))
+	}
+
+	return err
+}
+
+func sendWithRetry(ctx context.Context, writeAddress *url.URL, buf []byte, tenant string) error {
+	var err error
 	backoff := backoff.New(ctx, backoff.Config{MinBackoff: minBackoff, MaxBackoff: maxBackoff, MaxRetries: maxRetries})
 	var status int
 	for {
 		// send uses `timeout` internally, so `context.Background` is good enough.
-		status, err = send(context.Background(), buf)
+		status, err = send(context.Background(), writeAddress, buf, tenant)
 
 		// Only retry 429s, 500s and connection-level errors.
 		if status > 0 && status != 429 && status/100 != 5 {
 			break
 		}
 
-		fmt.Printf("error sending batch, will retry, status: %d error: %s\n", status, err)
+		log.WithError(err).WithField("status", status).Warn("Error sending batch, will retry")
 		backoff.Wait()
 
 		// Make sure it sends at least once before checking for retry.
@@ -158,7 +360,7 @@ func sendToPromtail(ctx context.Context, b *batch) error {
 	return nil
 }
 
-func send(ctx context.Context, buf []byte) (int, error) {
+func send(ctx context.Context, writeAddress *url.URL, buf []byte, tenant string) (int, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
@@ -169,8 +371,8 @@ func send(ctx context.Context, buf []byte) (int, error) {
 	req.Header.Set("Content-Type", contentType)
 	req.Header.Set("User-Agent", userAgent)
 
-	if tenantID != "" {
-		req.Header.Set("X-Scope-OrgID", tenantID)
+	if tenant != "" {
+		req.Header.Set("X-Scope-OrgID", tenant)
 	}
 
 	if username != "" && password != "" {
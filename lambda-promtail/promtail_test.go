@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCWEvent encodes the log data the way CloudWatch Logs delivers it.
+func newCWEvent(t *testing.T, data events.CloudwatchLogsData) *events.CloudwatchLogsEvent {
+	t.Helper()
+
+	raw, err := json.Marshal(data)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err = gz.Write(raw)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	return &events.CloudwatchLogsEvent{
+		AWSLogs: events.CloudwatchLogsRawData{Data: base64.StdEncoding.EncodeToString(buf.Bytes())},
+	}
+}
+
+// batchEntries returns all entries currently held by the batch.
+func batchEntries(b *batch) []logproto.Entry {
+	var entries []logproto.Entry
+	for _, stream := range b.streams {
+		entries = append(entries, stream.Entries...)
+	}
+
+	return entries
+}
+
+func TestTruncateLine(t *testing.T) {
+	maxLineBytes = 0
+	assert.Equal(t, strings.Repeat("a", 100), truncateLine(strings.Repeat("a", 100)))
+
+	maxLineBytes = 50
+	defer func() { maxLineBytes = 0 }()
+
+	assert.Equal(t, "short", truncateLine("short"))
+
+	truncated := truncateLine(strings.Repeat("a", 100))
+	assert.Len(t, truncated, 50)
+	assert.True(t, strings.HasSuffix(truncated, truncatedMarker))
+}
+
+func TestParseCWEventTruncatesLines(t *testing.T) {
+	batchSize = 1 << 20
+	maxLineBytes = 64
+	defer func() { maxLineBytes = 0 }()
+
+	ev := newCWEvent(t, events.CloudwatchLogsData{
+		LogGroup:  "/aws/lambda/test",
+		LogStream: "stream",
+		LogEvents: []events.CloudwatchLogsLogEvent{
+			{ID: "1", Timestamp: 1700000000000, Message: "short line"},
+			{ID: "2", Timestamp: 1700000000001, Message: strings.Repeat("x", 1000)},
+		},
+	})
+
+	b, err := newBatch(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, parseCWEvent(context.Background(), b, ev))
+
+	entries := batchEntries(b)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "short line", entries[0].Line)
+	assert.Len(t, entries[1].Line, 64)
+	assert.True(t, strings.HasSuffix(entries[1].Line, truncatedMarker))
+}
@@ -4,10 +4,10 @@ import (
 	"bufio"
 	"compress/gzip"
 	"context"
-	"fmt"
 	log "github.com/sirupsen/logrus"
 	"io"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -15,7 +15,6 @@ import (
 	"github.com/prometheus/common/model"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
@@ -30,18 +29,30 @@ var (
 	timestampRegex = regexp.MustCompile(`\w+ (?P<timestamp>\d+-\d+-\d+T\d+:\d+:\d+\.\d+Z)`)
 )
 
-func getS3Object(ctx context.Context, labels map[string]string) (io.ReadCloser, error) {
-	var s3Client *s3.Client
+// s3KeyAllowed reports whether an object key matches S3_KEY_PREFIX_ALLOWLIST
+// and S3_KEY_SUFFIX_ALLOWLIST. An empty allowlist matches every key.
+func s3KeyAllowed(key string) bool {
+	return matchesAny(key, s3KeyPrefixAllowlist, strings.HasPrefix) &&
+		matchesAny(key, s3KeySuffixAllowlist, strings.HasSuffix)
+}
 
-	if c, ok := s3Clients[labels["bucket_region"]]; ok {
-		s3Client = c
-	} else {
-		cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(labels["bucket_region"]))
-		if err != nil {
-			return nil, err
+func matchesAny(key string, allowlist []string, match func(string, string) bool) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+	for _, allowed := range allowlist {
+		if match(key, allowed) {
+			return true
 		}
-		s3Client = s3.NewFromConfig(cfg)
-		s3Clients[labels["bucket_region"]] = s3Client
+	}
+
+	return false
+}
+
+func getS3Object(ctx context.Context, labels map[string]string) (io.ReadCloser, error) {
+	s3Client, err := getS3Client(ctx, labels)
+	if err != nil {
+		return nil, err
 	}
 
 	obj, err := s3Client.GetObject(ctx,
@@ -52,7 +63,11 @@ func getS3Object(ctx context.Context, labels map[string]string) (io.ReadCloser,
 		})
 
 	if err != nil {
-		fmt.Printf("Failed to get object %s from bucket %s on account %s\n", labels["key"], labels["bucket"], labels["bucketOwner"])
+		log.WithError(err).WithFields(log.Fields{
+			"key":     labels["key"],
+			"bucket":  labels["bucket"],
+			"account": labels["bucketOwner"],
+		}).Error("Failed to get object from bucket")
 		return nil, err
 	}
 
@@ -86,8 +101,18 @@ func parseS3Log(ctx context.Context, b *batch, labels map[string]string, obj io.
 			return err
 		}
 
-		err = b.add(ctx, entry{ls, logproto.Entry{
-			Line:      logLine,
+		lineLabels := ls
+		if albLog {
+			albLabels, err := albLogLabels(logLine)
+			if err != nil {
+				log.WithError(err).Debug("Failed to parse ALB log line")
+			} else {
+				lineLabels = ls.Merge(albLabels)
+			}
+		}
+
+		err = b.add(ctx, entry{lineLabels, logproto.Entry{
+			Line:      truncateLine(logLine),
 			Timestamp: timestamp,
 		}})
 		if err != nil {
@@ -118,11 +143,28 @@ func getLabels(record events.S3EventRecord) (map[string]string, error) {
 	return labels, nil
 }
 
-func processS3Event(ctx context.Context, ev *events.S3Event) error {
+func processS3Event(ctx context.Context, ev *events.S3Event, stats *lineStats) error {
 
 	batch, _ := newBatch(ctx)
+	batch.stats = stats
+	stopFlushTimer := batch.startFlushTimer(ctx)
+	defer stopFlushTimer()
+
+	err := parseS3Records(ctx, batch, ev)
 
+	return flushRemaining(ctx, batch, err)
+}
+
+func parseS3Records(ctx context.Context, b *batch, ev *events.S3Event) error {
 	for _, record := range ev.Records {
+		if !s3KeyAllowed(record.S3.Object.Key) {
+			log.WithFields(log.Fields{
+				"bucket": record.S3.Bucket.Name,
+				"key":    record.S3.Object.Key,
+			}).Debug("Skipping S3 object outside the key allowlist")
+			continue
+		}
+
 		labels, err := getLabels(record)
 		if err != nil {
 			return err
@@ -133,17 +175,12 @@ func processS3Event(ctx context.Context, ev *events.S3Event) error {
 			return err
 		}
 
-		err = parseS3Log(ctx, batch, labels, obj)
+		err = parseS3Log(ctx, b, labels, obj)
 		if err != nil {
 			return err
 		}
 
 	}
 
-	err := sendToPromtail(ctx, batch)
-	if err != nil {
-		return err
-	}
-
 	return nil
 }
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const albLogKey = "AWSLogs/123456789012/elasticloadbalancing/us-east-1/2022/01/24/123456789012_elasticloadbalancing_us-east-1_app.my-loadbalancer.b13ea9d19f16d015_20220124T0000Z_0.0.0.0_2et2e1mx.log.gz"
+
+// newS3Server starts a fake S3 serving a gzipped ALB log for every object and
+// returns the object paths that were downloaded.
+func newS3Server(t *testing.T) func() []string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte("https 2022-01-24T00:00:01.123456Z app/my-loadbalancer/b13ea9d19f16d015 1.2.3.4:1234 - 200\n"))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	var mu sync.Mutex
+	var downloaded []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		downloaded = append(downloaded, r.URL.Path)
+		mu.Unlock()
+		w.Write(buf.Bytes())
+	}))
+	t.Cleanup(server.Close)
+
+	previousClients := s3Clients
+	s3Clients = map[s3ClientKey]*s3.Client{
+		{region: "us-east-1"}: s3.New(s3.Options{
+			Region:       "us-east-1",
+			BaseEndpoint: aws.String(server.URL),
+			UsePathStyle: true,
+			Credentials:  aws.AnonymousCredentials{},
+		}),
+	}
+	t.Cleanup(func() { s3Clients = previousClients })
+
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), downloaded...)
+	}
+}
+
+func s3Record(key string) events.S3EventRecord {
+	var record events.S3EventRecord
+	record.AWSRegion = "us-east-1"
+	record.S3.Bucket.Name = "logs"
+	record.S3.Object.Key = key
+	return record
+}
+
+func TestS3KeyAllowed(t *testing.T) {
+	defer func() { s3KeyPrefixAllowlist, s3KeySuffixAllowlist = nil, nil }()
+
+	assert.True(t, s3KeyAllowed("anything"))
+
+	s3KeyPrefixAllowlist = []string{"alb/", "nlb/"}
+	s3KeySuffixAllowlist = []string{".log.gz"}
+
+	assert.True(t, s3KeyAllowed("alb/file.log.gz"))
+	assert.True(t, s3KeyAllowed("nlb/file.log.gz"))
+	assert.False(t, s3KeyAllowed("cloudtrail/file.log.gz"))
+	assert.False(t, s3KeyAllowed("alb/file.json"))
+}
+
+func TestProcessS3EventKeyAllowlist(t *testing.T) {
+	recorded := newTenantRecorder(t)
+	setTenantConfig(t, "", "")
+	downloaded := newS3Server(t)
+
+	s3KeyPrefixAllowlist = []string{"alb/"}
+	s3KeySuffixAllowlist = []string{".log.gz"}
+	defer func() { s3KeyPrefixAllowlist, s3KeySuffixAllowlist = nil, nil }()
+
+	stats := &lineStats{}
+	err := processS3Event(context.Background(), &events.S3Event{Records: []events.S3EventRecord{
+		s3Record("alb/" + albLogKey),
+		s3Record("cloudtrail/" + albLogKey),
+		s3Record("alb/" + albLogKey + ".tmp"),
+	}}, stats)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"/logs/alb/" + albLogKey}, downloaded())
+	assert.Len(t, recorded(), 1)
+	assert.Equal(t, 1, stats.forwarded)
+}
+
+func TestSetupArgumentsS3KeyAllowlists(t *testing.T) {
+	t.Setenv("WRITE_ADDRESS", "https://loki.example.com/loki/api/v1/push")
+	t.Setenv("S3_KEY_PREFIX_ALLOWLIST", "alb/, nlb/")
+	t.Setenv("S3_KEY_SUFFIX_ALLOWLIST", ".log.gz")
+	defer func() { s3KeyPrefixAllowlist, s3KeySuffixAllowlist = nil, nil }()
+
+	require.NoError(t, setupArguments())
+	assert.Equal(t, []string{"alb/", "nlb/"}, s3KeyPrefixAllowlist)
+	assert.Equal(t, []string{".log.gz"}, s3KeySuffixAllowlist)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// s3ClientKey identifies a cached S3 client. The bucket is only set for
+// buckets read through an assumed role; the other buckets share one client
+// per region.
+type s3ClientKey struct {
+	bucket string
+	region string
+}
+
+// newSTSClient creates the STS client used to assume the cross-account roles.
+var newSTSClient = func(cfg aws.Config) stscreds.AssumeRoleAPIClient {
+	return sts.NewFromConfig(cfg)
+}
+
+// parseS3RoleArns parses the S3_ROLE_ARNS JSON object which maps the name of a
+// bucket to the role to assume to read it. The bucket is what the role grants
+// access to: the account ID in a load balancer log key names the account of
+// the load balancer, which is not necessarily the one owning the bucket.
+func parseS3RoleArns(value string) (map[string]string, error) {
+	roleArns := map[string]string{}
+	if value == "" {
+		return roleArns, nil
+	}
+
+	if err := json.Unmarshal([]byte(value), &roleArns); err != nil {
+		return nil, fmt.Errorf("invalid value for environment variable S3_ROLE_ARNS: %w", err)
+	}
+
+	return roleArns, nil
+}
+
+// getS3Client returns the cached S3 client for the bucket region and, when a
+// role is configured for the bucket, that bucket. The assumed role credentials
+// are cached and refreshed by the client itself.
+func getS3Client(ctx context.Context, labels map[string]string) (*s3.Client, error) {
+	key := s3ClientKey{region: labels["bucket_region"]}
+	roleArn := s3RoleArns[labels["bucket"]]
+	if roleArn != "" {
+		key.bucket = labels["bucket"]
+	}
+
+	if c, ok := s3Clients[key]; ok {
+		return c, nil
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(key.region))
+	if err != nil {
+		return nil, err
+	}
+	if roleArn != "" {
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(newSTSClient(cfg), roleArn))
+	}
+
+	s3Client := s3.NewFromConfig(cfg)
+	s3Clients[key] = s3Client
+
+	return s3Client, nil
+}
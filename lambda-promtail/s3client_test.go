@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSTS records the roles assumed through it.
+type fakeSTS struct {
+	mu      sync.Mutex
+	assumed []string
+}
+
+func (f *fakeSTS) AssumeRole(_ context.Context, input *sts.AssumeRoleInput, _ ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.assumed = append(f.assumed, aws.ToString(input.RoleArn))
+
+	return &sts.AssumeRoleOutput{Credentials: &ststypes.Credentials{
+		AccessKeyId:     aws.String("assumed-key"),
+		SecretAccessKey: aws.String("assumed-secret"),
+		SessionToken:    aws.String("assumed-token"),
+		Expiration:      aws.Time(time.Now().Add(time.Hour)),
+	}}, nil
+}
+
+func (f *fakeSTS) roles() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.assumed...)
+}
+
+func TestParseS3RoleArns(t *testing.T) {
+	roleArns, err := parseS3RoleArns("")
+	require.NoError(t, err)
+	assert.Empty(t, roleArns)
+
+	roleArns, err = parseS3RoleArns(`{"lb-logs": "arn:aws:iam::111111111111:role/logs"}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"lb-logs": "arn:aws:iam::111111111111:role/logs"}, roleArns)
+
+	_, err = parseS3RoleArns("lb-logs=arn")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "S3_ROLE_ARNS")
+}
+
+func TestGetS3Client(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "lambda-key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "lambda-secret")
+	stsClient := &fakeSTS{}
+	previousSTSClient := newSTSClient
+	newSTSClient = func(aws.Config) stscreds.AssumeRoleAPIClient { return stsClient }
+	previousClients, previousRoleArns := s3Clients, s3RoleArns
+	s3Clients = map[s3ClientKey]*s3.Client{}
+	s3RoleArns = map[string]string{"lb-logs": "arn:aws:iam::111111111111:role/logs"}
+	defer func() {
+		newSTSClient = previousSTSClient
+		s3Clients, s3RoleArns = previousClients, previousRoleArns
+	}()
+
+	ctx := context.Background()
+	crossAccount, err := getS3Client(ctx, map[string]string{"bucket": "lb-logs", "account_id": "333333333333", "bucket_region": "us-east-1"})
+	require.NoError(t, err)
+	cached, err := getS3Client(ctx, map[string]string{"bucket": "lb-logs", "account_id": "444444444444", "bucket_region": "us-east-1"})
+	require.NoError(t, err)
+	assert.Same(t, crossAccount, cached)
+
+	otherRegion, err := getS3Client(ctx, map[string]string{"bucket": "lb-logs", "bucket_region": "eu-west-1"})
+	require.NoError(t, err)
+	assert.NotSame(t, crossAccount, otherRegion)
+
+	// The account of the load balancer does not select the role, only the
+	// bucket the logs are read from does.
+	sameAccount, err := getS3Client(ctx, map[string]string{"bucket": "other-logs", "account_id": "111111111111", "bucket_region": "us-east-1"})
+	require.NoError(t, err)
+	unlabeled, err := getS3Client(ctx, map[string]string{"bucket_region": "us-east-1"})
+	require.NoError(t, err)
+	assert.Same(t, sameAccount, unlabeled)
+
+	assert.Len(t, s3Clients, 3)
+	assert.Contains(t, s3Clients, s3ClientKey{bucket: "lb-logs", region: "us-east-1"})
+	assert.Contains(t, s3Clients, s3ClientKey{bucket: "lb-logs", region: "eu-west-1"})
+	assert.Contains(t, s3Clients, s3ClientKey{region: "us-east-1"})
+
+	credentials, err := crossAccount.Options().Credentials.Retrieve(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "assumed-key", credentials.AccessKeyID)
+	_, err = crossAccount.Options().Credentials.Retrieve(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"arn:aws:iam::111111111111:role/logs"}, stsClient.roles())
+
+	credentials, err = sameAccount.Options().Credentials.Retrieve(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "lambda-key", credentials.AccessKeyID)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// lineStats counts what happened to the log lines of a single invocation.
+// A nil *lineStats is valid and counts nothing.
+type lineStats struct {
+	forwarded int
+	dropped   int
+	failed    int
+}
+
+func (s *lineStats) addForwarded(n int) {
+	if s != nil {
+		s.forwarded += n
+	}
+}
+
+func (s *lineStats) addDropped(n int) {
+	if s != nil {
+		s.dropped += n
+	}
+}
+
+func (s *lineStats) addFailed(n int) {
+	if s != nil {
+		s.failed += n
+	}
+}
+
+// summaryFields returns the log fields describing the invocation. When
+// METRICS_NAMESPACE is set the fields also follow the CloudWatch Embedded
+// Metric Format, so the summary line is turned into CloudWatch metrics
+// without any additional API calls.
+func (s *lineStats) summaryFields(now time.Time) log.Fields {
+	fields := log.Fields{
+		"lines_forwarded": s.forwarded,
+		"lines_dropped":   s.dropped,
+		"lines_failed":    s.failed,
+	}
+
+	if metricsNamespace == "" {
+		return fields
+	}
+
+	dimensions := []string{}
+	if functionName := os.Getenv("AWS_LAMBDA_FUNCTION_NAME"); functionName != "" {
+		dimensions = append(dimensions, "FunctionName")
+		fields["FunctionName"] = functionName
+	}
+
+	fields["LinesForwarded"] = s.forwarded
+	fields["LinesDropped"] = s.dropped
+	fields["LinesFailed"] = s.failed
+	fields["_aws"] = map[string]interface{}{
+		"Timestamp": now.UnixMilli(),
+		"CloudWatchMetrics": []map[string]interface{}{
+			{
+				"Namespace":  metricsNamespace,
+				"Dimensions": [][]string{dimensions},
+				"Metrics": []map[string]string{
+					{"Name": "LinesForwarded", "Unit": "Count"},
+					{"Name": "LinesDropped", "Unit": "Count"},
+					{"Name": "LinesFailed", "Unit": "Count"},
+				},
+			},
+		},
+	}
+
+	return fields
+}
+
+func (s *lineStats) logSummary() {
+	log.WithFields(s.summaryFields(time.Now())).Info("Invocation summary")
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func cwEventWithLines(t *testing.T, logStream string, lines int) *events.CloudwatchLogsEvent {
+	t.Helper()
+
+	data := events.CloudwatchLogsData{LogGroup: "/ecs/service", LogStream: logStream}
+	for i := 0; i < lines; i++ {
+		data.LogEvents = append(data.LogEvents, events.CloudwatchLogsLogEvent{Timestamp: 1700000000000, Message: "line"})
+	}
+
+	return newCWEvent(t, data)
+}
+
+func TestLineStatsCounts(t *testing.T) {
+	newTenantRecorder(t)
+	setTenantConfig(t, "", "")
+	dropLogStreamRegex = regexp.MustCompile(`^sidecar/`)
+	defer func() { dropLogStreamRegex = nil }()
+
+	stats := &lineStats{}
+	require.NoError(t, processCWEvent(context.Background(), cwEventWithLines(t, "app/web/1", 3), stats))
+	require.NoError(t, processCWEvent(context.Background(), cwEventWithLines(t, "sidecar/envoy/1", 2), stats))
+
+	assert.Equal(t, lineStats{forwarded: 3, dropped: 2}, *stats)
+}
+
+func TestLineStatsCountsFailedLines(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	previousAddresses := writeAddresses
+	writeAddress, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	writeAddresses = []*url.URL{writeAddress}
+	defer func() { writeAddresses = previousAddresses }()
+	setTenantConfig(t, "", "")
+
+	stats := &lineStats{}
+	require.Error(t, processCWEvent(context.Background(), cwEventWithLines(t, "app/web/1", 4), stats))
+
+	assert.Equal(t, lineStats{failed: 4}, *stats)
+}
+
+func TestLineStatsSummaryFields(t *testing.T) {
+	stats := &lineStats{forwarded: 5, dropped: 2, failed: 1}
+	now := time.UnixMilli(1700000000000)
+
+	metricsNamespace = ""
+	fields := stats.summaryFields(now)
+	assert.Equal(t, 5, fields["lines_forwarded"])
+	assert.NotContains(t, fields, "_aws")
+
+	metricsNamespace = "LambdaPromtail"
+	defer func() { metricsNamespace = "" }()
+	t.Setenv("AWS_LAMBDA_FUNCTION_NAME", "lambda-promtail")
+
+	fields = stats.summaryFields(now)
+	assert.Equal(t, 5, fields["LinesForwarded"])
+	assert.Equal(t, 2, fields["LinesDropped"])
+	assert.Equal(t, 1, fields["LinesFailed"])
+	assert.Equal(t, "lambda-promtail", fields["FunctionName"])
+
+	emf := fields["_aws"].(map[string]interface{})
+	assert.Equal(t, int64(1700000000000), emf["Timestamp"])
+	metrics := emf["CloudWatchMetrics"].([]map[string]interface{})
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "LambdaPromtail", metrics[0]["Namespace"])
+	assert.Equal(t, [][]string{{"FunctionName"}}, metrics[0]["Dimensions"])
+}
+
+func TestLineStatsCountsPartialFailureOnce(t *testing.T) {
+	setTenantConfig(t, "", "team")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Scope-OrgID") == "red" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	previousAddresses := writeAddresses
+	writeAddress, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	writeAddresses = []*url.URL{writeAddress}
+	defer func() { writeAddresses = previousAddresses }()
+
+	b, err := newBatch(context.Background(),
+		tenantEntry(model.LabelSet{"app": "a", "team": "blue"}),
+		tenantEntry(model.LabelSet{"app": "a", "team": "red"}),
+		tenantEntry(model.LabelSet{"app": "b", "team": "red"}),
+	)
+	require.NoError(t, err)
+	b.stats = &lineStats{}
+
+	// A flush that fails part way, as when the batch fills up, followed by
+	// the final flush.
+	require.Error(t, b.flushBatch(context.Background()))
+	require.Error(t, flushRemaining(context.Background(), b, nil))
+
+	assert.Equal(t, lineStats{forwarded: 1, failed: 2}, *b.stats)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTenantRecorder starts a Loki stub that records the X-Scope-OrgID header
+// of every push and points writeAddresses at it.
+func newTenantRecorder(t *testing.T) func() []string {
+	t.Helper()
+
+	var mu sync.Mutex
+	var tenants []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		tenants = append(tenants, r.Header.Get("X-Scope-OrgID"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	previousAddresses := writeAddresses
+	writeAddress, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	writeAddresses = []*url.URL{writeAddress}
+	t.Cleanup(func() { writeAddresses = previousAddresses })
+
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		recorded := append([]string(nil), tenants...)
+		sort.Strings(recorded)
+		return recorded
+	}
+}
+
+func setTenantConfig(t *testing.T, static, dynamicLabel string) {
+	t.Helper()
+
+	previousTenant, previousLabel, previousBatchSize := tenantID, dynamicTenantLabel, batchSize
+	tenantID, dynamicTenantLabel, batchSize = static, dynamicLabel, 1<<20
+	t.Cleanup(func() { tenantID, dynamicTenantLabel, batchSize = previousTenant, previousLabel, previousBatchSize })
+}
+
+func tenantEntry(labels model.LabelSet) entry {
+	e := entry{labels: labels}
+	e.entry.Timestamp = time.Now()
+	e.entry.Line = "line"
+	return e
+}
+
+func TestSendToPromtailStaticTenant(t *testing.T) {
+	recorded := newTenantRecorder(t)
+	setTenantConfig(t, "static-tenant", "")
+
+	b, err := newBatch(context.Background(),
+		tenantEntry(model.LabelSet{"app": "a"}),
+		tenantEntry(model.LabelSet{"app": "b"}),
+	)
+	require.NoError(t, err)
+	require.NoError(t, sendToPromtail(context.Background(), b))
+
+	assert.Equal(t, []string{"static-tenant"}, recorded())
+}
+
+func TestSendToPromtailNoTenant(t *testing.T) {
+	recorded := newTenantRecorder(t)
+	setTenantConfig(t, "", "")
+
+	b, err := newBatch(context.Background(), tenantEntry(model.LabelSet{"app": "a"}))
+	require.NoError(t, err)
+	require.NoError(t, sendToPromtail(context.Background(), b))
+
+	assert.Equal(t, []string{""}, recorded())
+}
+
+func TestSendToPromtailDynamicTenant(t *testing.T) {
+	recorded := newTenantRecorder(t)
+	setTenantConfig(t, "fallback", "team")
+
+	b, err := newBatch(context.Background(),
+		tenantEntry(model.LabelSet{"app": "a", "team": "red"}),
+		tenantEntry(model.LabelSet{"app": "b", "team": "red"}),
+		tenantEntry(model.LabelSet{"app": "a", "team": "blue"}),
+		tenantEntry(model.LabelSet{"app": "c"}),
+	)
+	require.NoError(t, err)
+	require.NoError(t, sendToPromtail(context.Background(), b))
+
+	assert.Equal(t, []string{"blue", "fallback", "red"}, recorded())
+}
+
+func TestEntryTenant(t *testing.T) {
+	setTenantConfig(t, "static", "team")
+
+	assert.Equal(t, "red", entryTenant(model.LabelSet{"team": "red", reservedLabelTenantID: "reserved"}))
+	assert.Equal(t, "reserved", entryTenant(model.LabelSet{reservedLabelTenantID: "reserved"}))
+	assert.Equal(t, "static", entryTenant(model.LabelSet{"app": "a"}))
+}
+
+func TestFlushAfterPartialFailureOnlyResendsFailedTenants(t *testing.T) {
+	setTenantConfig(t, "", "team")
+
+	var mu sync.Mutex
+	var pushes []string
+	rejectRed := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		tenant := r.Header.Get("X-Scope-OrgID")
+		if tenant == "red" && rejectRed {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		pushes = append(pushes, tenant)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	previousAddresses := writeAddresses
+	writeAddress, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	writeAddresses = []*url.URL{writeAddress}
+	defer func() { writeAddresses = previousAddresses }()
+
+	b, err := newBatch(context.Background(),
+		tenantEntry(model.LabelSet{"app": "a", "team": "blue"}),
+		tenantEntry(model.LabelSet{"app": "a", "team": "red"}),
+	)
+	require.NoError(t, err)
+
+	require.Error(t, b.flushBatch(context.Background()))
+	assert.Equal(t, []string{"blue"}, pushes)
+	assert.Equal(t, len("line"), b.size, "only the red line is left in the batch")
+
+	mu.Lock()
+	rejectRed = false
+	mu.Unlock()
+	require.NoError(t, b.flushBatch(context.Background()))
+	assert.Equal(t, []string{"blue", "red"}, pushes, "blue is not pushed twice")
+	assert.Empty(t, b.streams)
+	assert.Zero(t, b.size)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	cloud "github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterWebhookColors(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_WEBHOOK_TEST", mattermost.URL)
+	t.Setenv("MATTERMOST_WEBHOOK_ALERT_TEST", mattermost.URL)
+	t.Setenv("PAGERDUTY_INTEGRATION_KEY", "")
+	t.Setenv("COLOR_SUCCESS", "#00AA00")
+	t.Setenv("COLOR_FAILURE", "#AA0000")
+
+	payload := &cloud.WebhookPayload{
+		Type:      cloud.TypeCluster,
+		ID:        "cluster-id",
+		NewState:  cloud.ClusterStateStable,
+		ExtraData: map[string]string{"Environment": "test"},
+	}
+	require.NoError(t, handleClusterWebhook(payload))
+
+	payload.NewState = cloud.ClusterStateCreationFailed
+	require.NoError(t, handleClusterWebhook(payload))
+
+	received := testutil.Payloads[mmSlashResponse](t, mattermost)
+	require.Len(t, received, 3)
+	assert.Equal(t, "#00AA00", received[0].Attachments[0].Color)
+	assert.Equal(t, "#AA0000", received[1].Attachments[0].Color)
+	assert.Equal(t, "#AA0000", received[2].Attachments[0].Color)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	cloud "github.com/mattermost/mattermost-cloud/model"
+)
+
+// maxSeenEvents bounds how many event keys a warm container remembers.
+const maxSeenEvents = 1000
+
+// seenEvents remembers the webhook events handled by this warm container, so
+// an event redelivered by API Gateway or SNS is not notified twice.
+var seenEvents = newEventCache(maxSeenEvents)
+
+// eventCache is a bounded set of event keys that evicts the oldest key first.
+type eventCache struct {
+	mu    sync.Mutex
+	size  int
+	keys  map[string]struct{}
+	order []string
+}
+
+func newEventCache(size int) *eventCache {
+	return &eventCache{
+		size: size,
+		keys: make(map[string]struct{}, size),
+	}
+}
+
+// markSeen records key and reports whether it had already been seen.
+func (c *eventCache) markSeen(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.keys[key]; ok {
+		return true
+	}
+
+	if len(c.order) >= c.size {
+		delete(c.keys, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.keys[key] = struct{}{}
+	c.order = append(c.order, key)
+
+	return false
+}
+
+// forget removes key, so the event is handled again when it is redelivered.
+func (c *eventCache) forget(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.keys[key]; !ok {
+		return
+	}
+	delete(c.keys, key)
+	for i, k := range c.order {
+		if k == key {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// eventKey identifies a webhook event. The payload EventID is used when the
+// provisioner sends one, otherwise a hash of the fields that make up an event.
+func eventKey(payload *cloud.WebhookPayload) string {
+	if payload.EventID != "" {
+		return payload.EventID
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%d", payload.Type, payload.ID, payload.NewState, payload.Timestamp)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	cloud "github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerDeduplicatesRedeliveredEvents(t *testing.T) {
+	previous := seenEvents
+	seenEvents = newEventCache(maxSeenEvents)
+	defer func() { seenEvents = previous }()
+
+	mattermost := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_WEBHOOK_TEST", mattermost.URL)
+	t.Setenv("MATTERMOST_WEBHOOK_ALERT_TEST", mattermost.URL)
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"event_id":"event-1","type":"cluster","id":"cluster-id","new_state":"stable","old_state":"creation-in-progress","timestamp":1600000000000000000,"extra_data":{"Environment":"test"}}`,
+	}
+
+	for i := 0; i < 2; i++ {
+		response, err := handler(request)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+	}
+
+	payloads := testutil.Payloads[mmSlashResponse](t, mattermost)
+	require.Len(t, payloads, 1)
+	assert.Equal(t, "Provisioner-TEST", payloads[0].Username)
+}
+
+func TestHandlerProcessesRedeliveredFailedEvents(t *testing.T) {
+	previous := seenEvents
+	seenEvents = newEventCache(maxSeenEvents)
+	defer func() { seenEvents = previous }()
+
+	mattermost := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_WEBHOOK_TEST", mattermost.URL)
+	t.Setenv("MATTERMOST_WEBHOOK_ALERT_TEST", mattermost.URL)
+	t.Setenv("PAGERDUTY_INTEGRATION_KEY", "")
+
+	request := events.APIGatewayProxyRequest{
+		Body: `{"event_id":"event-1","type":"cluster","id":"cluster-id","new_state":"stable","old_state":"creation-in-progress","timestamp":1600000000000000000,"extra_data":{"Environment":"test"}}`,
+	}
+
+	mattermost.SetStatusCode(http.StatusBadRequest)
+	_, err := handler(request)
+	require.NoError(t, err)
+
+	mattermost.SetStatusCode(http.StatusOK)
+	for i := 0; i < 2; i++ {
+		_, err = handler(request)
+		require.NoError(t, err)
+	}
+
+	assert.Len(t, mattermost.Bodies(), 2, "the failed event is sent again, then deduplicated")
+}
+
+func TestEventKey(t *testing.T) {
+	payload := &cloud.WebhookPayload{Type: cloud.TypeCluster, ID: "cluster-id", NewState: "stable", Timestamp: 1}
+	key := eventKey(payload)
+	assert.Equal(t, key, eventKey(&cloud.WebhookPayload{Type: cloud.TypeCluster, ID: "cluster-id", NewState: "stable", Timestamp: 1}))
+	assert.NotEqual(t, key, eventKey(&cloud.WebhookPayload{Type: cloud.TypeCluster, ID: "cluster-id", NewState: "stable", Timestamp: 2}))
+
+	payload.EventID = "event-1"
+	assert.Equal(t, "event-1", eventKey(payload))
+}
+
+func TestEventCacheEvictsOldest(t *testing.T) {
+	cache := newEventCache(2)
+
+	assert.False(t, cache.markSeen("a"))
+	assert.False(t, cache.markSeen("b"))
+	assert.True(t, cache.markSeen("a"))
+	assert.False(t, cache.markSeen("c"))
+	assert.False(t, cache.markSeen("a"))
+}
+
+func TestEventCacheForget(t *testing.T) {
+	cache := newEventCache(2)
+
+	assert.False(t, cache.markSeen("a"))
+	cache.forget("a")
+	cache.forget("unknown")
+	assert.False(t, cache.markSeen("a"))
+	assert.False(t, cache.markSeen("b"))
+	assert.False(t, cache.markSeen("c"))
+	assert.False(t, cache.markSeen("a"), "a was evicted, forget kept the order consistent")
+}
@@ -9,18 +9,28 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	pagerduty "github.com/PagerDuty/go-pagerduty"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/apigateway"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/metrics"
+	cloudpagerduty "github.com/mattermost/mattermost-cloud-lambdas/internal/pagerduty"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/retry"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/webhook"
 	cloud "github.com/mattermost/mattermost-cloud/model"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
 func main() {
+	if apigateway.UseHTTPAPIPayload() {
+		lambda.Start(handlerV2)
+		return
+	}
 	lambda.Start(handler)
 }
 
@@ -35,13 +45,37 @@ func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 		return sendErrorResponse(errors.New("request is empty"))
 	}
 
-	payload, err := cloud.WebhookPayloadFromReader(strings.NewReader(request.Body))
+	body, err := webhook.RequestBody(request)
+	if err != nil {
+		webhook.CaptureRawRequest(request, err)
+		return sendErrorResponse(err)
+	}
+
+	payload, err := cloud.WebhookPayloadFromReader(strings.NewReader(body))
 	if err != nil {
+		webhook.CaptureRawRequest(request, err)
 		return sendErrorResponse(errors.Wrap(err, "failed to parse the body"))
 	}
 	log.Debug(payload)
 
-	processWebhookEvent(payload)
+	key := eventKey(payload)
+	if seenEvents.markSeen(key) {
+		log.WithFields(log.Fields{
+			"id":        payload.ID,
+			"type":      payload.Type,
+			"new_state": payload.NewState,
+		}).Info("Skipping duplicate webhook event")
+		return events.APIGatewayProxyResponse{
+			Body:       "{\"status\": \"duplicate\"}",
+			StatusCode: 200,
+		}, nil
+	}
+
+	if err := processWebhookEvent(payload); err != nil {
+		log.WithError(err).Error("Failed to process the webhook event")
+		// Forget the event so that a redelivery is processed again.
+		seenEvents.forget(key)
+	}
 
 	return events.APIGatewayProxyResponse{
 		Body:       "{\"status\": \"ok\"}",
@@ -50,28 +84,35 @@ func handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyRespo
 
 }
 
-func processWebhookEvent(payload *cloud.WebhookPayload) {
+// processWebhookEvent notifies about the event and resolves its PagerDuty
+// incident when the new state calls for it. The incident is resolved even if
+// the notification failed.
+func processWebhookEvent(payload *cloud.WebhookPayload) error {
 	str, err := payload.ToJSON()
 	if err != nil {
-		log.Errorf("Failed to marshal fields to JSON, %v", err)
-		return
+		return errors.Wrap(err, "failed to marshal fields to JSON")
 	}
 	log.Debug(str)
 
 	switch payload.Type {
 	case cloud.TypeCluster:
-		err = handleClusterWebhook(payload)
-		if err != nil {
-			log.Errorf("Failed to handle the cluster webhook, %v", err)
-		}
+		err = errors.Wrap(handleClusterWebhook(payload), "failed to handle the cluster webhook")
 	case cloud.TypeInstallation:
-		err = handleInstallationWebhook(payload)
-		if err != nil {
-			log.Errorf("Failed to handle the installation webhook, %v", err)
-		}
+		err = errors.Wrap(handleInstallationWebhook(payload), "failed to handle the installation webhook")
 	default:
-		return
+		return nil
+	}
+
+	if shouldResolve(payload) {
+		if resolveErr := resolvePagerDutyIncident(payload); resolveErr != nil {
+			log.WithError(resolveErr).Error("Failed to resolve the PagerDuty incident")
+			if err == nil {
+				err = errors.Wrap(resolveErr, "failed to resolve the PagerDuty incident")
+			}
+		}
 	}
+
+	return err
 }
 
 func handleClusterWebhook(payload *cloud.WebhookPayload) error {
@@ -95,7 +136,7 @@ func handleClusterWebhook(payload *cloud.WebhookPayload) error {
 	}
 
 	attach := mmAttachment{
-		Color: "#006400",
+		Color: stateColor(payload.Type, payload.NewState),
 	}
 
 	alert := false
@@ -103,7 +144,6 @@ func handleClusterWebhook(payload *cloud.WebhookPayload) error {
 	if payload.NewState == cloud.ClusterStateResizeFailed || payload.NewState == cloud.ClusterStateCreationFailed ||
 		payload.NewState == cloud.ClusterStateDeletionFailed || payload.NewState == cloud.ClusterStateUpgradeFailed ||
 		payload.NewState == cloud.ClusterStateProvisioningFailed {
-		attach.Color = "#FF0000"
 		alert = true
 	}
 
@@ -133,7 +173,9 @@ func handleClusterWebhook(payload *cloud.WebhookPayload) error {
 	}
 
 	if alert {
-		sendMattermostWebhook(mmWebhookAlert, mmPayload)
+		if err := sendMattermostWebhook(mmWebhookAlert, mmPayload); err != nil {
+			log.WithError(err).Error("Failed to send the cluster alert to Mattermost")
+		}
 		sendPagerDutyNotification(payload)
 	}
 
@@ -161,13 +203,12 @@ func handleInstallationWebhook(payload *cloud.WebhookPayload) error {
 	}
 
 	attach := mmAttachment{
-		Color: "#80B3FA",
+		Color: stateColor(payload.Type, payload.NewState),
 	}
 
 	alert := false
 	if payload.NewState == cloud.InstallationStateCreationFailed || payload.NewState == cloud.InstallationStateDeletionFailed ||
 		payload.NewState == cloud.InstallationStateUpdateFailed || payload.NewState == cloud.InstallationStateCreationNoCompatibleClusters {
-		attach.Color = "#FF0000"
 		alert = true
 	}
 
@@ -201,15 +242,10 @@ func handleInstallationWebhook(payload *cloud.WebhookPayload) error {
 	}
 
 	if alert {
-		err := sendMattermostWebhook(mmWebhookAlert, mmPayload)
-		if err != nil {
-			return err
-		}
-		err = sendPagerDutyNotification(payload)
-		if err != nil {
-			return err
+		if err := sendMattermostWebhook(mmWebhookAlert, mmPayload); err != nil {
+			log.WithError(err).Error("Failed to send the installation alert to Mattermost")
 		}
-		return nil
+		return sendPagerDutyNotification(payload)
 	}
 
 	if payload.NewState == cloud.InstallationStateCreationRequested {
@@ -261,28 +297,111 @@ type mmSlashResponse struct {
 }
 
 func (attachment *mmAttachment) AddField(field mmField) *mmAttachment {
+	field.Value = webhook.Truncate(field.Value, webhook.MaxFieldLength())
 	attachment.Fields = append(attachment.Fields, &field)
 	return attachment
 }
 
+// sendMattermostWebhook posts the payload to the Mattermost webhook. Network
+// errors, 429 and 5xx responses are retried with exponential backoff; any
+// other non-2xx response fails right away.
 func sendMattermostWebhook(webhookURL string, payload mmSlashResponse) error {
+	payload = truncatedPayload(payload, webhook.MaxPayloadLength())
 	marshalContent, _ := json.Marshal(payload)
-	var jsonStr = marshalContent
-	req, err := http.NewRequest("POST", webhookURL, bytes.NewBuffer(jsonStr))
-	if err != nil {
-		return err
+
+	client := &http.Client{Timeout: webhookTimeout}
+	return retry.Do(webhookAttempts(), webhookRetryDelay, func() error {
+		req, err := http.NewRequest("POST", webhookURL, bytes.NewBuffer(marshalContent))
+		if err != nil {
+			return retry.Permanent(err)
+		}
+		req.Header.Set("X-Custom-Header", "provisioner-webhook-notifier")
+		req.Header.Set("Content-Type", "application/json")
+
+		start := time.Now()
+		resp, err := client.Do(req)
+		metrics.RecordNotificationLatency(metrics.TargetMattermost, start)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		err = errors.Errorf("Mattermost webhook returned status %d", resp.StatusCode)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return err
+		}
+		return retry.Permanent(err)
+	})
+}
+
+// pagerDutyStructuredKeys are the ExtraData keys surfaced as top-level
+// PagerDuty details so custom fields can key off them.
+var pagerDutyStructuredKeys = []string{"DNS", "Owner", "Group", "Version"}
+
+func pagerDutyDetails(payload *cloud.WebhookPayload, provisionerEnv string) map[string]string {
+	tm := time.Unix(0, payload.Timestamp)
+	details := map[string]string{
+		"Type":      payload.Type.String(),
+		"State":     payload.NewState,
+		"Old_State": payload.OldState,
+		"Timestamp": tm.String(),
+		"Env":       provisionerEnv,
 	}
-	req.Header.Set("X-Custom-Header", "provisioner-webhook-notifier")
-	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+	for _, key := range pagerDutyStructuredKeys {
+		if value := payload.ExtraData[key]; value != "" {
+			details[key] = value
+		}
 	}
-	defer resp.Body.Close()
 
-	return nil
+	if len(payload.ExtraData) > 0 {
+		var extraData []string
+		for key, value := range payload.ExtraData {
+			extraData = append(extraData, fmt.Sprintf("%s: %s", key, value))
+		}
+		sort.Strings(extraData)
+		details["Extra_Data"] = strings.Join(extraData, "\n")
+	}
+
+	return details
+}
+
+// pagerDutyClient sends the PagerDuty events.
+var pagerDutyClient = pagerduty.NewClient("")
+
+// defaultPagerDutySeverity is the severity of every provisioner alert unless
+// the payload overrides it.
+const defaultPagerDutySeverity = "critical"
+
+// pagerDutySeverityKey is the ExtraData key operators can set to override the
+// PagerDuty severity, e.g. to downgrade a known-flaky cluster.
+const pagerDutySeverityKey = "PagerDutySeverity"
+
+var validPagerDutySeverities = map[string]bool{
+	"critical": true,
+	"error":    true,
+	"warning":  true,
+	"info":     true,
+}
+
+// pagerDutySeverity returns the severity override from the payload ExtraData
+// when it is a valid PagerDuty severity, and the default severity otherwise.
+func pagerDutySeverity(payload *cloud.WebhookPayload) string {
+	override, ok := payload.ExtraData[pagerDutySeverityKey]
+	if !ok {
+		return defaultPagerDutySeverity
+	}
+
+	severity := strings.ToLower(strings.TrimSpace(override))
+	if !validPagerDutySeverities[severity] {
+		log.WithField("severity", override).Warn("Ignoring invalid PagerDuty severity override")
+		return defaultPagerDutySeverity
+	}
+
+	return severity
 }
 
 func sendPagerDutyNotification(payload *cloud.WebhookPayload) error {
@@ -297,28 +416,24 @@ func sendPagerDutyNotification(payload *cloud.WebhookPayload) error {
 		return errors.New("missing pagerduty integration key")
 	}
 
-	tm := time.Unix(0, payload.Timestamp)
 	alertReq := &pagerduty.V2Payload{
 		Summary:  fmt.Sprintf("%s - %s %s", payload.Type, payload.ID, payload.NewState),
-		Source:   "Alarm System",
-		Severity: "critical",
-		Details: map[string]string{
-			"Type":      payload.Type.String(),
-			"State":     payload.NewState,
-			"Old_State": payload.OldState,
-			"Timestamp": tm.String(),
-			"Env":       provisionerEnv,
-		},
+		Severity: pagerDutySeverity(payload),
+		Details:  pagerDutyDetails(payload, provisionerEnv),
 	}
+	cloudpagerduty.SetOrigin(alertReq, defaultPagerDutySource)
 
 	event := pagerduty.V2Event{
 		RoutingKey: integrationKey,
 		Action:     "trigger",
+		DedupKey:   pagerDutyDedupKey(payload),
 		Payload:    alertReq,
 	}
 
 	// Send the event to PagerDuty
-	_, err := pagerduty.ManageEventWithContext(context.Background(), event)
+	start := time.Now()
+	_, err := pagerDutyClient.ManageEventWithContext(context.Background(), &event)
+	metrics.RecordNotificationLatency(metrics.TargetPagerDuty, start)
 	if err != nil {
 		log.WithError(err).Error("Failed to send PagerDuty notification")
 		return errors.New("Failed to send PagerDuty notification")
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	cloud "github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPagerDutyDetails(t *testing.T) {
+	t.Run("structured extra data", func(t *testing.T) {
+		payload := &cloud.WebhookPayload{
+			Type:     cloud.TypeInstallation,
+			ID:       "installation-id",
+			NewState: cloud.InstallationStateCreationFailed,
+			ExtraData: map[string]string{
+				"Environment": "prod",
+				"DNS":         "test.cloud.mattermost.com",
+				"Owner":       "owner-id",
+				"Group":       "group-id",
+				"Version":     "9.11.0",
+			},
+		}
+
+		details := pagerDutyDetails(payload, "PROD")
+		assert.Equal(t, "test.cloud.mattermost.com", details["DNS"])
+		assert.Equal(t, "owner-id", details["Owner"])
+		assert.Equal(t, "group-id", details["Group"])
+		assert.Equal(t, "9.11.0", details["Version"])
+		assert.Equal(t, "PROD", details["Env"])
+		assert.Contains(t, details["Extra_Data"], "DNS: test.cloud.mattermost.com")
+		assert.Contains(t, details["Extra_Data"], "Environment: prod")
+	})
+
+	t.Run("missing structured keys", func(t *testing.T) {
+		payload := &cloud.WebhookPayload{
+			Type:      cloud.TypeCluster,
+			ExtraData: map[string]string{"Environment": "prod"},
+		}
+
+		details := pagerDutyDetails(payload, "PROD")
+		for _, key := range pagerDutyStructuredKeys {
+			assert.NotContains(t, details, key)
+		}
+		assert.Equal(t, "Environment: prod", details["Extra_Data"])
+	})
+}
+
+func TestPagerDutySeverity(t *testing.T) {
+	testCases := []struct {
+		name      string
+		extraData map[string]string
+		expected  string
+	}{
+		{"no override", map[string]string{"Environment": "prod"}, "critical"},
+		{"override", map[string]string{"PagerDutySeverity": "warning"}, "warning"},
+		{"override casing", map[string]string{"PagerDutySeverity": " Info "}, "info"},
+		{"invalid override", map[string]string{"PagerDutySeverity": "meh"}, "critical"},
+		{"empty override", map[string]string{"PagerDutySeverity": ""}, "critical"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, pagerDutySeverity(&cloud.WebhookPayload{ExtraData: tc.extraData}))
+		})
+	}
+}
+
+func TestSendPagerDutyNotificationSeverityOverride(t *testing.T) {
+	pagerDuty := testutil.NewPagerDutyServer(t)
+	previousClient := pagerDutyClient
+	pagerDutyClient = pagerDuty.Client()
+	defer func() { pagerDutyClient = previousClient }()
+	t.Setenv("PAGERDUTY_INTEGRATION_KEY", "routing-key")
+
+	payload := &cloud.WebhookPayload{
+		Type:      cloud.TypeCluster,
+		ID:        "cluster-id",
+		NewState:  cloud.ClusterStateCreationFailed,
+		ExtraData: map[string]string{"Environment": "prod", "PagerDutySeverity": "warning"},
+	}
+	require.NoError(t, sendPagerDutyNotification(payload))
+
+	payload.ExtraData["PagerDutySeverity"] = "sev0"
+	require.NoError(t, sendPagerDutyNotification(payload))
+
+	events := pagerDuty.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, "warning", events[0].Payload.Severity)
+	assert.Equal(t, "critical", events[1].Payload.Severity)
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const samplePayload = `{"type":"cluster","id":"cluster-id","new_state":"stable","old_state":"creation-in-progress","timestamp":1600000000000000000}`
+
+func TestHandlerBase64Payload(t *testing.T) {
+	response, err := handler(events.APIGatewayProxyRequest{
+		Body:            base64.StdEncoding.EncodeToString([]byte(samplePayload)),
+		IsBase64Encoded: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	response, err = handler(events.APIGatewayProxyRequest{Body: "not base64!", IsBase64Encoded: true})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	pagerduty "github.com/PagerDuty/go-pagerduty"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/metrics"
+	cloud "github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultResolveStates are the states that resolve an open incident for each
+// resource type when RESOLVE_ON_STATES is not set.
+var defaultResolveStates = map[cloud.ResourceType][]string{
+	cloud.TypeCluster:      {cloud.ClusterStateStable},
+	cloud.TypeInstallation: {cloud.InstallationStateStable},
+}
+
+// pagerDutyDedupKey identifies the PagerDuty incident of a resource, so the
+// incident triggered by a failure can be resolved once the resource recovers.
+func pagerDutyDedupKey(payload *cloud.WebhookPayload) string {
+	return fmt.Sprintf("%s-%s", payload.Type, payload.ID)
+}
+
+// resolveStates returns the states that resolve an incident for the resource
+// type, from the comma-separated RESOLVE_ON_STATES when it is set.
+func resolveStates(resourceType cloud.ResourceType) []string {
+	if states := parseList(os.Getenv("RESOLVE_ON_STATES")); len(states) > 0 {
+		return states
+	}
+
+	return defaultResolveStates[resourceType]
+}
+
+func shouldResolve(payload *cloud.WebhookPayload) bool {
+	for _, state := range resolveStates(payload.Type) {
+		if payload.NewState == state {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolvePagerDutyIncident resolves the open incident of the resource, if any.
+func resolvePagerDutyIncident(payload *cloud.WebhookPayload) error {
+	integrationKey := os.Getenv("PAGERDUTY_INTEGRATION_KEY")
+	if integrationKey == "" {
+		log.Debug("No PagerDuty Integration Key setup, not resolving incidents")
+		return nil
+	}
+
+	event := pagerduty.V2Event{
+		RoutingKey: integrationKey,
+		Action:     "resolve",
+		DedupKey:   pagerDutyDedupKey(payload),
+	}
+
+	start := time.Now()
+	_, err := pagerDutyClient.ManageEventWithContext(context.Background(), &event)
+	metrics.RecordNotificationLatency(metrics.TargetPagerDuty, start)
+	if err != nil {
+		return errors.Wrapf(err, "failed to resolve PagerDuty incident %s", event.DedupKey)
+	}
+
+	log.WithField("dedup_key", event.DedupKey).Info("PagerDuty incident resolved")
+	return nil
+}
+
+// parseList splits a comma-separated environment variable value.
+func parseList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	cloud "github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupPagerDuty points the PagerDuty client at a fake Events API.
+func setupPagerDuty(t *testing.T) *testutil.PagerDutyServer {
+	t.Helper()
+
+	pagerDuty := testutil.NewPagerDutyServer(t)
+	previousClient := pagerDutyClient
+	pagerDutyClient = pagerDuty.Client()
+	t.Cleanup(func() { pagerDutyClient = previousClient })
+	t.Setenv("PAGERDUTY_INTEGRATION_KEY", "routing-key")
+
+	return pagerDuty
+}
+
+func TestShouldResolve(t *testing.T) {
+	t.Setenv("RESOLVE_ON_STATES", "")
+
+	assert.True(t, shouldResolve(&cloud.WebhookPayload{Type: cloud.TypeCluster, NewState: cloud.ClusterStateStable}))
+	assert.True(t, shouldResolve(&cloud.WebhookPayload{Type: cloud.TypeInstallation, NewState: cloud.InstallationStateStable}))
+	assert.False(t, shouldResolve(&cloud.WebhookPayload{Type: cloud.TypeCluster, NewState: cloud.ClusterStateCreationFailed}))
+	assert.False(t, shouldResolve(&cloud.WebhookPayload{Type: cloud.TypeInstallation, NewState: cloud.InstallationStateHibernating}))
+
+	t.Setenv("RESOLVE_ON_STATES", "stable, hibernating")
+	assert.True(t, shouldResolve(&cloud.WebhookPayload{Type: cloud.TypeInstallation, NewState: cloud.InstallationStateHibernating}))
+}
+
+func TestProcessWebhookEventResolvesIncident(t *testing.T) {
+	pagerDuty := setupPagerDuty(t)
+	mattermost := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_WEBHOOK_TEST", mattermost.URL)
+	t.Setenv("MATTERMOST_WEBHOOK_ALERT_TEST", mattermost.URL)
+	t.Setenv("RESOLVE_ON_STATES", "")
+
+	payload := &cloud.WebhookPayload{
+		Type:      cloud.TypeCluster,
+		ID:        "cluster-id",
+		NewState:  cloud.ClusterStateCreationFailed,
+		ExtraData: map[string]string{"Environment": "test"},
+	}
+	processWebhookEvent(payload)
+
+	payload.OldState, payload.NewState = cloud.ClusterStateCreationFailed, cloud.ClusterStateStable
+	processWebhookEvent(payload)
+
+	events := pagerDuty.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, "trigger", events[0].Action)
+	assert.Equal(t, "resolve", events[1].Action)
+	assert.Equal(t, "cluster-cluster-id", events[0].DedupKey)
+	assert.Equal(t, events[0].DedupKey, events[1].DedupKey)
+}
+
+func TestProcessWebhookEventNoResolveForOtherStates(t *testing.T) {
+	pagerDuty := setupPagerDuty(t)
+	mattermost := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_WEBHOOK_TEST", mattermost.URL)
+	t.Setenv("MATTERMOST_WEBHOOK_ALERT_TEST", mattermost.URL)
+	t.Setenv("RESOLVE_ON_STATES", "")
+
+	processWebhookEvent(&cloud.WebhookPayload{
+		Type:      cloud.TypeCluster,
+		ID:        "cluster-id",
+		NewState:  cloud.ClusterStateUpgradeRequested,
+		ExtraData: map[string]string{"Environment": "test"},
+	})
+
+	assert.Empty(t, pagerDuty.Events())
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultWebhookAttempts = 3
+	// maxWebhookAttempts bounds MATTERMOST_WEBHOOK_ATTEMPTS. A cluster alert
+	// sends two webhooks, and with the request timeout and the backoff both
+	// must fit in API Gateway's 29 second integration timeout.
+	maxWebhookAttempts = 4
+
+	// webhookTimeout is the timeout of a single webhook request.
+	webhookTimeout = 2 * time.Second
+)
+
+// webhookRetryDelay is the delay before the first webhook retry. It doubles
+// on every following retry.
+var webhookRetryDelay = 250 * time.Millisecond
+
+// webhookAttempts is how many times a Mattermost webhook is sent before giving
+// up, configured with MATTERMOST_WEBHOOK_ATTEMPTS and capped at
+// maxWebhookAttempts.
+func webhookAttempts() int {
+	return min(envLimit("MATTERMOST_WEBHOOK_ATTEMPTS", defaultWebhookAttempts), maxWebhookAttempts)
+}
+
+// envLimit reads a positive limit from the environment, falling back to the
+// default when it is unset or invalid.
+func envLimit(name string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+
+	return value
+}
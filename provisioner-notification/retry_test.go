@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	cloud "github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withoutRetryDelay(t *testing.T) {
+	t.Helper()
+	previous := webhookRetryDelay
+	webhookRetryDelay = time.Millisecond
+	t.Cleanup(func() { webhookRetryDelay = previous })
+}
+
+// statusServer answers webhook requests with the given statuses in order,
+// repeating the last one, and counts the requests.
+func statusServer(t *testing.T, statuses ...int) (*httptest.Server, *int32) {
+	t.Helper()
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		i := int(atomic.AddInt32(&requests, 1)) - 1
+		if i >= len(statuses) {
+			i = len(statuses) - 1
+		}
+		w.WriteHeader(statuses[i])
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func TestSendMattermostWebhookRetries(t *testing.T) {
+	withoutRetryDelay(t)
+
+	testCases := map[string]struct {
+		statuses []int
+		requests int32
+		wantErr  bool
+	}{
+		"ok":                   {[]int{http.StatusOK}, 1, false},
+		"server error then ok": {[]int{http.StatusBadGateway, http.StatusOK}, 2, false},
+		"rate limited then ok": {[]int{http.StatusTooManyRequests, http.StatusTooManyRequests, http.StatusCreated}, 3, false},
+		"server errors":        {[]int{http.StatusInternalServerError}, 3, true},
+		"client error":         {[]int{http.StatusBadRequest}, 1, true},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			server, requests := statusServer(t, tc.statuses...)
+
+			err := sendMattermostWebhook(server.URL, mmSlashResponse{Text: "cluster failed"})
+			if tc.wantErr {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "Mattermost webhook returned status")
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.requests, atomic.LoadInt32(requests))
+		})
+	}
+}
+
+func TestSendMattermostWebhookAttempts(t *testing.T) {
+	withoutRetryDelay(t)
+
+	for value, expected := range map[string]int32{"2": 2, "4": 4, "50": maxWebhookAttempts, "invalid": defaultWebhookAttempts} {
+		t.Run(value, func(t *testing.T) {
+			t.Setenv("MATTERMOST_WEBHOOK_ATTEMPTS", value)
+			server, requests := statusServer(t, http.StatusServiceUnavailable)
+
+			err := sendMattermostWebhook(server.URL, mmSlashResponse{Text: "cluster failed"})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), fmt.Sprintf("failed after %d attempts", expected))
+			assert.Equal(t, expected, atomic.LoadInt32(requests))
+		})
+	}
+}
+
+func TestSendMattermostWebhookResendsPayload(t *testing.T) {
+	withoutRetryDelay(t)
+	mattermost := testutil.NewMattermostServer(t)
+	mattermost.SetStatusCode(http.StatusServiceUnavailable)
+
+	require.Error(t, sendMattermostWebhook(mattermost.URL, mmSlashResponse{Text: "cluster failed"}))
+
+	payloads := testutil.Payloads[mmSlashResponse](t, mattermost)
+	require.Len(t, payloads, defaultWebhookAttempts)
+	for _, payload := range payloads {
+		assert.Equal(t, "cluster failed", payload.Text)
+	}
+}
+
+func TestInstallationAlertPagesWhenMattermostFails(t *testing.T) {
+	withoutRetryDelay(t)
+	pagerDuty := setupPagerDuty(t)
+	mattermost := testutil.NewMattermostServer(t)
+	mattermost.SetStatusCode(http.StatusInternalServerError)
+	t.Setenv("MATTERMOST_WEBHOOK_TEST", mattermost.URL)
+	t.Setenv("MATTERMOST_WEBHOOK_ALERT_TEST", mattermost.URL)
+
+	err := handleInstallationWebhook(&cloud.WebhookPayload{
+		Type:      cloud.TypeInstallation,
+		ID:        "installation-id",
+		NewState:  cloud.InstallationStateCreationFailed,
+		ExtraData: map[string]string{"Environment": "test"},
+	})
+	require.NoError(t, err)
+
+	assert.Len(t, mattermost.Bodies(), defaultWebhookAttempts)
+	events := pagerDuty.Events()
+	require.Len(t, events, 1)
+	assert.Equal(t, "trigger", events[0].Action)
+}
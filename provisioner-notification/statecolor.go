@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/colors"
+	cloud "github.com/mattermost/mattermost-cloud/model"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultInProgressColor   = "#FFD700"
+	defaultInstallationColor = "#80B3FA"
+)
+
+type stateCategory int
+
+const (
+	stateSuccess stateCategory = iota
+	stateInProgress
+	stateFailure
+	stateInfo
+)
+
+// clusterStateCategories maps every known cluster state to the kind of
+// color its notification should use.
+var clusterStateCategories = map[string]stateCategory{
+	cloud.ClusterStateStable:                      stateSuccess,
+	cloud.ClusterStateDeleted:                     stateSuccess,
+	cloud.ClusterStateRefreshMetadata:             stateInProgress,
+	cloud.ClusterStateCreationRequested:           stateInProgress,
+	cloud.ClusterStateCreationInProgress:          stateInProgress,
+	cloud.ClusterStateWaitingForNodes:             stateInProgress,
+	cloud.ClusterStateProvisionInProgress:         stateInProgress,
+	cloud.ClusterStateProvisioningRequested:       stateInProgress,
+	cloud.ClusterStateUpgradeRequested:            stateInProgress,
+	cloud.ClusterStateResizeRequested:             stateInProgress,
+	cloud.ClusterStateNodegroupsCreationRequested: stateInProgress,
+	cloud.ClusterStateNodegroupsDeletionRequested: stateInProgress,
+	cloud.ClusterStateDeletionRequested:           stateInProgress,
+	cloud.ClusterStateCreationFailed:              stateFailure,
+	cloud.ClusterStateProvisioningFailed:          stateFailure,
+	cloud.ClusterStateUpgradeFailed:               stateFailure,
+	cloud.ClusterStateResizeFailed:                stateFailure,
+	cloud.ClusterStateNodegroupsCreationFailed:    stateFailure,
+	cloud.ClusterStateNodegroupsDeletionFailed:    stateFailure,
+	cloud.ClusterStateDeletionFailed:              stateFailure,
+}
+
+// installationStateCategories maps every known installation state to the
+// kind of color its notification should use.
+var installationStateCategories = map[string]stateCategory{
+	cloud.InstallationStateStable:                        stateInfo,
+	cloud.InstallationStateHibernating:                   stateInfo,
+	cloud.InstallationStateImportComplete:                stateInfo,
+	cloud.InstallationStateDeletionPending:               stateInfo,
+	cloud.InstallationStateDeleted:                       stateInfo,
+	cloud.InstallationStateDNSMigrationHibernating:       stateInfo,
+	cloud.InstallationStateCreationRequested:             stateInProgress,
+	cloud.InstallationStateCreationPreProvisioning:       stateInProgress,
+	cloud.InstallationStateCreationInProgress:            stateInProgress,
+	cloud.InstallationStateCreationDNS:                   stateInProgress,
+	cloud.InstallationStateCreationFinalTasks:            stateInProgress,
+	cloud.InstallationStateHibernationRequested:          stateInProgress,
+	cloud.InstallationStateHibernationInProgress:         stateInProgress,
+	cloud.InstallationStateWakeUpRequested:               stateInProgress,
+	cloud.InstallationStateUpdateRequested:               stateInProgress,
+	cloud.InstallationStateUpdateInProgress:              stateInProgress,
+	cloud.InstallationStateImportInProgress:              stateInProgress,
+	cloud.InstallationStateDeletionPendingRequested:      stateInProgress,
+	cloud.InstallationStateDeletionPendingInProgress:     stateInProgress,
+	cloud.InstallationStateDeletionCancellationRequested: stateInProgress,
+	cloud.InstallationStateDeletionRequested:             stateInProgress,
+	cloud.InstallationStateDeletionInProgress:            stateInProgress,
+	cloud.InstallationStateDeletionFinalCleanup:          stateInProgress,
+	cloud.InstallationStateDBRestorationInProgress:       stateInProgress,
+	cloud.InstallationStateDBMigrationInProgress:         stateInProgress,
+	cloud.InstallationStateDBMigrationRollbackInProgress: stateInProgress,
+	cloud.InstallationStateCreationFailed:                stateFailure,
+	cloud.InstallationStateCreationNoCompatibleClusters:  stateFailure,
+	cloud.InstallationStateUpdateFailed:                  stateFailure,
+	cloud.InstallationStateDeletionFailed:                stateFailure,
+	cloud.InstallationStateDBRestorationFailed:           stateFailure,
+	cloud.InstallationStateDBMigrationFailed:             stateFailure,
+}
+
+func inProgressColor() string {
+	return colors.FromEnv("COLOR_IN_PROGRESS", defaultInProgressColor)
+}
+
+func installationColor() string {
+	return colors.FromEnv("COLOR_INSTALLATION", defaultInstallationColor)
+}
+
+// stateColorOverrides reads STATE_COLORS, a JSON object keyed by resource
+// type and then state, e.g. {"cluster": {"resize-requested": "#FFFF00"}}.
+func stateColorOverrides() map[string]map[string]string {
+	raw := os.Getenv("STATE_COLORS")
+	if raw == "" {
+		return nil
+	}
+
+	var overrides map[string]map[string]string
+	if err := json.Unmarshal([]byte(raw), &overrides); err != nil {
+		log.WithError(err).Warn("Invalid STATE_COLORS, using the built-in state colors")
+		return nil
+	}
+
+	return overrides
+}
+
+// stateColor returns the attachment color for a resource entering the given
+// state. Overrides from STATE_COLORS win over the built-in mapping, and
+// unknown states fall back to the resource's default color.
+func stateColor(resourceType cloud.ResourceType, state string) string {
+	if color, ok := stateColorOverrides()[resourceType.String()][state]; ok {
+		if colors.Valid(color) {
+			return color
+		}
+		log.WithFields(log.Fields{"type": resourceType, "state": state, "color": color}).Warn("Invalid hex color in STATE_COLORS, using the built-in state color")
+	}
+
+	categories := clusterStateCategories
+	defaultCategory := stateSuccess
+	if resourceType == cloud.TypeInstallation {
+		categories = installationStateCategories
+		defaultCategory = stateInfo
+	}
+
+	category, ok := categories[state]
+	if !ok {
+		category = defaultCategory
+	}
+
+	switch category {
+	case stateInProgress:
+		return inProgressColor()
+	case stateFailure:
+		return colors.Failure()
+	case stateInfo:
+		return installationColor()
+	default:
+		return colors.Success()
+	}
+}
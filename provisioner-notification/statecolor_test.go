@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/colors"
+	cloud "github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateColor(t *testing.T) {
+	testCases := []struct {
+		resourceType cloud.ResourceType
+		state        string
+		expected     string
+	}{
+		{cloud.TypeCluster, cloud.ClusterStateStable, colors.DefaultSuccess},
+		{cloud.TypeCluster, cloud.ClusterStateDeleted, colors.DefaultSuccess},
+		{cloud.TypeCluster, cloud.ClusterStateRefreshMetadata, defaultInProgressColor},
+		{cloud.TypeCluster, cloud.ClusterStateCreationRequested, defaultInProgressColor},
+		{cloud.TypeCluster, cloud.ClusterStateCreationInProgress, defaultInProgressColor},
+		{cloud.TypeCluster, cloud.ClusterStateWaitingForNodes, defaultInProgressColor},
+		{cloud.TypeCluster, cloud.ClusterStateProvisionInProgress, defaultInProgressColor},
+		{cloud.TypeCluster, cloud.ClusterStateProvisioningRequested, defaultInProgressColor},
+		{cloud.TypeCluster, cloud.ClusterStateUpgradeRequested, defaultInProgressColor},
+		{cloud.TypeCluster, cloud.ClusterStateResizeRequested, defaultInProgressColor},
+		{cloud.TypeCluster, cloud.ClusterStateNodegroupsCreationRequested, defaultInProgressColor},
+		{cloud.TypeCluster, cloud.ClusterStateNodegroupsDeletionRequested, defaultInProgressColor},
+		{cloud.TypeCluster, cloud.ClusterStateDeletionRequested, defaultInProgressColor},
+		{cloud.TypeCluster, cloud.ClusterStateCreationFailed, colors.DefaultFailure},
+		{cloud.TypeCluster, cloud.ClusterStateProvisioningFailed, colors.DefaultFailure},
+		{cloud.TypeCluster, cloud.ClusterStateUpgradeFailed, colors.DefaultFailure},
+		{cloud.TypeCluster, cloud.ClusterStateResizeFailed, colors.DefaultFailure},
+		{cloud.TypeCluster, cloud.ClusterStateNodegroupsCreationFailed, colors.DefaultFailure},
+		{cloud.TypeCluster, cloud.ClusterStateNodegroupsDeletionFailed, colors.DefaultFailure},
+		{cloud.TypeCluster, cloud.ClusterStateDeletionFailed, colors.DefaultFailure},
+		{cloud.TypeCluster, "unknown-state", colors.DefaultSuccess},
+		{cloud.TypeInstallation, cloud.InstallationStateStable, defaultInstallationColor},
+		{cloud.TypeInstallation, cloud.InstallationStateHibernating, defaultInstallationColor},
+		{cloud.TypeInstallation, cloud.InstallationStateImportComplete, defaultInstallationColor},
+		{cloud.TypeInstallation, cloud.InstallationStateDeletionPending, defaultInstallationColor},
+		{cloud.TypeInstallation, cloud.InstallationStateDeleted, defaultInstallationColor},
+		{cloud.TypeInstallation, cloud.InstallationStateDNSMigrationHibernating, defaultInstallationColor},
+		{cloud.TypeInstallation, cloud.InstallationStateCreationRequested, defaultInProgressColor},
+		{cloud.TypeInstallation, cloud.InstallationStateCreationPreProvisioning, defaultInProgressColor},
+		{cloud.TypeInstallation, cloud.InstallationStateCreationInProgress, defaultInProgressColor},
+		{cloud.TypeInstallation, cloud.InstallationStateCreationDNS, defaultInProgressColor},
+		{cloud.TypeInstallation, cloud.InstallationStateCreationFinalTasks, defaultInProgressColor},
+		{cloud.TypeInstallation, cloud.InstallationStateHibernationRequested, defaultInProgressColor},
+		{cloud.TypeInstallation, cloud.InstallationStateHibernationInProgress, defaultInProgressColor},
+		{cloud.TypeInstallation, cloud.InstallationStateWakeUpRequested, defaultInProgressColor},
+		{cloud.TypeInstallation, cloud.InstallationStateUpdateRequested, defaultInProgressColor},
+		{cloud.TypeInstallation, cloud.InstallationStateUpdateInProgress, defaultInProgressColor},
+		{cloud.TypeInstallation, cloud.InstallationStateImportInProgress, defaultInProgressColor},
+		{cloud.TypeInstallation, cloud.InstallationStateDeletionPendingRequested, defaultInProgressColor},
+		{cloud.TypeInstallation, cloud.InstallationStateDeletionPendingInProgress, defaultInProgressColor},
+		{cloud.TypeInstallation, cloud.InstallationStateDeletionCancellationRequested, defaultInProgressColor},
+		{cloud.TypeInstallation, cloud.InstallationStateDeletionRequested, defaultInProgressColor},
+		{cloud.TypeInstallation, cloud.InstallationStateDeletionInProgress, defaultInProgressColor},
+		{cloud.TypeInstallation, cloud.InstallationStateDeletionFinalCleanup, defaultInProgressColor},
+		{cloud.TypeInstallation, cloud.InstallationStateDBRestorationInProgress, defaultInProgressColor},
+		{cloud.TypeInstallation, cloud.InstallationStateDBMigrationInProgress, defaultInProgressColor},
+		{cloud.TypeInstallation, cloud.InstallationStateDBMigrationRollbackInProgress, defaultInProgressColor},
+		{cloud.TypeInstallation, cloud.InstallationStateCreationFailed, colors.DefaultFailure},
+		{cloud.TypeInstallation, cloud.InstallationStateCreationNoCompatibleClusters, colors.DefaultFailure},
+		{cloud.TypeInstallation, cloud.InstallationStateUpdateFailed, colors.DefaultFailure},
+		{cloud.TypeInstallation, cloud.InstallationStateDeletionFailed, colors.DefaultFailure},
+		{cloud.TypeInstallation, cloud.InstallationStateDBRestorationFailed, colors.DefaultFailure},
+		{cloud.TypeInstallation, cloud.InstallationStateDBMigrationFailed, colors.DefaultFailure},
+		{cloud.TypeInstallation, "unknown-state", defaultInstallationColor},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.resourceType.String()+"/"+tc.state, func(t *testing.T) {
+			assert.Equal(t, tc.expected, stateColor(tc.resourceType, tc.state))
+		})
+	}
+}
+
+func TestStateColorOverrides(t *testing.T) {
+	t.Setenv("STATE_COLORS", `{"cluster": {"resize-requested": "#FFFF00", "stable": "not-a-color"}, "installation": {"stable": "#123456"}}`)
+
+	assert.Equal(t, "#FFFF00", stateColor(cloud.TypeCluster, cloud.ClusterStateResizeRequested))
+	assert.Equal(t, colors.DefaultSuccess, stateColor(cloud.TypeCluster, cloud.ClusterStateStable))
+	assert.Equal(t, "#123456", stateColor(cloud.TypeInstallation, cloud.InstallationStateStable))
+	assert.Equal(t, defaultInProgressColor, stateColor(cloud.TypeInstallation, cloud.InstallationStateUpdateRequested))
+
+	t.Setenv("STATE_COLORS", "{invalid")
+	assert.Equal(t, defaultInProgressColor, stateColor(cloud.TypeCluster, cloud.ClusterStateResizeRequested))
+}
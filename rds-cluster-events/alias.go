@@ -0,0 +1,7 @@
+package main
+
+import "github.com/mattermost/mattermost-cloud-lambdas/internal/accountalias"
+
+// accountAliases holds the alias of the account the lambda runs in, looked up
+// once per container when INCLUDE_ACCOUNT_ALIAS is true.
+var accountAliases = &accountalias.Cache{}
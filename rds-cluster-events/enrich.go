@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/awsconfig"
+	log "github.com/sirupsen/logrus"
+)
+
+// newRDSClient creates the RDS client used to enrich failover alerts. It is a
+// variable so tests can swap it for a fake.
+var newRDSClient = func() (rdsiface.RDSAPI, error) {
+	sess, err := awsconfig.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return rds.New(sess), nil
+}
+
+// enrichEnabled reports whether ENRICH_RDS is set, in which case failover
+// alerts include the cluster's engine and instances.
+func enrichEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("ENRICH_RDS"))
+	return enabled
+}
+
+// clusterFields returns the attachment fields describing the cluster, or
+// nothing if enrichment is disabled or the cluster cannot be described.
+func clusterFields(clusterID string) []MMField {
+	if !enrichEnabled() {
+		return nil
+	}
+
+	svc, err := newRDSClient()
+	if err != nil {
+		log.WithError(err).Warn("Failed to create the RDS client, sending the alert without cluster metadata")
+		return nil
+	}
+
+	fields, err := describeCluster(svc, clusterID)
+	if err != nil {
+		log.WithError(err).WithField("cluster", clusterID).Warn("Failed to describe the RDS cluster, sending the alert without cluster metadata")
+		return nil
+	}
+
+	return fields
+}
+
+// describeCluster looks up the engine, version and member instances of the
+// cluster.
+func describeCluster(svc rdsiface.RDSAPI, clusterID string) ([]MMField, error) {
+	clusters, err := svc.DescribeDBClusters(&rds.DescribeDBClustersInput{
+		DBClusterIdentifier: aws.String(clusterID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(clusters.DBClusters) == 0 {
+		return nil, fmt.Errorf("cluster %s not found", clusterID)
+	}
+	cluster := clusters.DBClusters[0]
+
+	instances, err := svc.DescribeDBInstances(&rds.DescribeDBInstancesInput{
+		Filters: []*rds.Filter{
+			{Name: aws.String("db-cluster-id"), Values: []*string{aws.String(clusterID)}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	writers := map[string]bool{}
+	for _, member := range cluster.DBClusterMembers {
+		writers[aws.StringValue(member.DBInstanceIdentifier)] = aws.BoolValue(member.IsClusterWriter)
+	}
+
+	var members []string
+	for _, instance := range instances.DBInstances {
+		id := aws.StringValue(instance.DBInstanceIdentifier)
+		role := "reader"
+		if writers[id] {
+			role = "writer"
+		}
+		members = append(members, fmt.Sprintf("%s (%s, %s, %s)", id, role, aws.StringValue(instance.DBInstanceClass), aws.StringValue(instance.AvailabilityZone)))
+	}
+
+	fields := []MMField{
+		{Title: "Engine", Value: strings.TrimSpace(aws.StringValue(cluster.Engine) + " " + aws.StringValue(cluster.EngineVersion)), Short: true},
+		{Title: "Status", Value: aws.StringValue(cluster.Status), Short: true},
+	}
+	if len(members) > 0 {
+		fields = append(fields, MMField{Title: "Instances", Value: strings.Join(members, "\n"), Short: false})
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRDS embeds the client interface, so any call it does not implement
+// panics and fails the test.
+type fakeRDS struct {
+	rdsiface.RDSAPI
+	err error
+}
+
+func (f *fakeRDS) DescribeDBClusters(input *rds.DescribeDBClustersInput) (*rds.DescribeDBClustersOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &rds.DescribeDBClustersOutput{DBClusters: []*rds.DBCluster{{
+		DBClusterIdentifier: input.DBClusterIdentifier,
+		Engine:              aws.String("aurora-postgresql"),
+		EngineVersion:       aws.String("14.9"),
+		Status:              aws.String("available"),
+		DBClusterMembers: []*rds.DBClusterMember{
+			{DBInstanceIdentifier: aws.String("db-1"), IsClusterWriter: aws.Bool(true)},
+			{DBInstanceIdentifier: aws.String("db-2"), IsClusterWriter: aws.Bool(false)},
+		},
+	}}}, nil
+}
+
+func (f *fakeRDS) DescribeDBInstances(*rds.DescribeDBInstancesInput) (*rds.DescribeDBInstancesOutput, error) {
+	return &rds.DescribeDBInstancesOutput{DBInstances: []*rds.DBInstance{
+		{DBInstanceIdentifier: aws.String("db-1"), DBInstanceClass: aws.String("db.r6g.large"), AvailabilityZone: aws.String("us-east-1a")},
+		{DBInstanceIdentifier: aws.String("db-2"), DBInstanceClass: aws.String("db.r6g.large"), AvailabilityZone: aws.String("us-east-1b")},
+	}}, nil
+}
+
+func useFakeRDS(t *testing.T, fake *fakeRDS) {
+	previous := newRDSClient
+	newRDSClient = func() (rdsiface.RDSAPI, error) { return fake, nil }
+	t.Cleanup(func() { newRDSClient = previous })
+}
+
+func failoverEvent(t *testing.T) string {
+	message, err := json.Marshal(SNSMessageNotification{SourceID: "rds-cluster-1", EventMessage: "Started cross AZ failover to DB instance: db-2"})
+	require.NoError(t, err)
+	return string(message)
+}
+
+func TestHandlerEnrichRDS(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_HOOK", mattermost.URL)
+	t.Setenv("ENVIRONMENT", "test")
+	t.Setenv("ENRICH_RDS", "true")
+	useFakeRDS(t, &fakeRDS{})
+
+	handler(context.Background(), testutil.SNSEvent(failoverEvent(t)))
+
+	payloads := testutil.Payloads[MMSlashResponse](t, mattermost)
+	require.Len(t, payloads, 1)
+	fields := payloads[0].Attachments[0].Fields
+	require.Len(t, fields, 6)
+	assert.Equal(t, MMField{Title: "Engine", Value: "aurora-postgresql 14.9", Short: true}, *fields[3])
+	assert.Equal(t, MMField{Title: "Status", Value: "available", Short: true}, *fields[4])
+	assert.Equal(t, "db-1 (writer, db.r6g.large, us-east-1a)\ndb-2 (reader, db.r6g.large, us-east-1b)", fields[5].Value)
+}
+
+func TestHandlerEnrichRDSError(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_HOOK", mattermost.URL)
+	t.Setenv("ENVIRONMENT", "test")
+	t.Setenv("ENRICH_RDS", "true")
+	useFakeRDS(t, &fakeRDS{err: errors.New("AccessDenied")})
+
+	handler(context.Background(), testutil.SNSEvent(failoverEvent(t)))
+
+	payloads := testutil.Payloads[MMSlashResponse](t, mattermost)
+	require.Len(t, payloads, 1)
+	assert.Len(t, payloads[0].Attachments[0].Fields, 3)
+}
+
+func TestHandlerWithoutEnrichRDS(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_HOOK", mattermost.URL)
+	t.Setenv("ENVIRONMENT", "test")
+	t.Setenv("ENRICH_RDS", "")
+
+	handler(context.Background(), testutil.SNSEvent(failoverEvent(t)))
+
+	payloads := testutil.Payloads[MMSlashResponse](t, mattermost)
+	require.Len(t, payloads, 1)
+	assert.Len(t, payloads[0].Attachments[0].Fields, 3)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/colors"
+)
+
+// pagerDutyAction is what an RDS event does to the PagerDuty incidents.
+type pagerDutyAction int
+
+const (
+	pagerDutyNone pagerDutyAction = iota
+	// pagerDutyTrigger opens an incident, for critical events.
+	pagerDutyTrigger
+	// pagerDutyResolve resolves the incidents opened for the event message.
+	pagerDutyResolve
+)
+
+// eventCategory describes how the RDS events of a category are notified.
+type eventCategory struct {
+	// prefix is the start of the event messages of the category.
+	prefix string
+	// title heads the Mattermost attachment.
+	title string
+	color func() string
+	// mention notifies the cluster owner.
+	mention   bool
+	pagerDuty pagerDutyAction
+}
+
+// eventCategories are the notified RDS events, the first matching prefix
+// wins. Other events are ignored.
+var eventCategories = []eventCategory{
+	{prefix: "Started cross AZ failover", title: "RDS DB Cluster Failover", color: colors.Failure, mention: true, pagerDuty: pagerDutyTrigger},
+	{prefix: "Completed failover", title: "RDS DB Cluster Failover", color: colors.Success, pagerDuty: pagerDutyResolve},
+	{prefix: "The free storage capacity", title: "RDS Low Storage", color: colors.Failure, mention: true, pagerDuty: pagerDutyTrigger},
+	{prefix: "Storage", title: "RDS Storage", color: colors.Warning},
+	{prefix: "CPU", title: "RDS High CPU", color: colors.Warning},
+	{prefix: "DB instance restarted", title: "RDS Restart", color: colors.Warning},
+	{prefix: "DB cluster restarted", title: "RDS Restart", color: colors.Warning},
+}
+
+// categorizeEvent returns the category of an RDS event message.
+func categorizeEvent(message string) (eventCategory, bool) {
+	for _, category := range eventCategories {
+		if strings.HasPrefix(message, category.prefix) {
+			return category, true
+		}
+	}
+	return eventCategory{}, false
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/colors"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategorizeEvent(t *testing.T) {
+	testCases := []struct {
+		message   string
+		title     string
+		color     string
+		mention   bool
+		pagerDuty pagerDutyAction
+	}{
+		{"Started cross AZ failover to DB instance: db-2", "RDS DB Cluster Failover", colors.DefaultFailure, true, pagerDutyTrigger},
+		{"Completed failover to DB instance: db-2", "RDS DB Cluster Failover", colors.DefaultSuccess, false, pagerDutyResolve},
+		{"The free storage capacity for DB instance db-1 is low at 5% of the provisioned storage", "RDS Low Storage", colors.DefaultFailure, true, pagerDutyTrigger},
+		{"Storage size 100 GiB is approaching the maximum", "RDS Storage", colors.DefaultWarning, false, pagerDutyNone},
+		{"CPU utilization is above 90%", "RDS High CPU", colors.DefaultWarning, false, pagerDutyNone},
+		{"DB instance restarted", "RDS Restart", colors.DefaultWarning, false, pagerDutyNone},
+		{"DB cluster restarted", "RDS Restart", colors.DefaultWarning, false, pagerDutyNone},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.message, func(t *testing.T) {
+			category, ok := categorizeEvent(tc.message)
+			require.True(t, ok)
+			assert.Equal(t, tc.title, category.title)
+			assert.Equal(t, tc.color, category.color())
+			assert.Equal(t, tc.mention, category.mention)
+			assert.Equal(t, tc.pagerDuty, category.pagerDuty)
+		})
+	}
+
+	for _, message := range []string{"Finished DB Instance backup", "", "storage lowercase"} {
+		_, ok := categorizeEvent(message)
+		assert.False(t, ok, message)
+	}
+}
+
+func rdsEventMessage(t *testing.T, message string) string {
+	t.Helper()
+	raw, err := json.Marshal(SNSMessageNotification{SourceID: "rds-cluster-1", EventMessage: message})
+	require.NoError(t, err)
+	return string(raw)
+}
+
+func TestHandlerEventCategories(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	pagerDuty := testutil.NewPagerDutyServer(t)
+	previousClient := pagerDutyClient
+	pagerDutyClient = pagerDuty.Client()
+	defer func() { pagerDutyClient = previousClient }()
+	t.Setenv("MATTERMOST_HOOK", mattermost.URL)
+	t.Setenv("ENVIRONMENT", "prod")
+	t.Setenv("PAGERDUTY_INTEGRATION_KEY", "routing-key")
+
+	handler(context.Background(), testutil.SNSEvent(
+		rdsEventMessage(t, "The free storage capacity for DB instance db-1 is low at 5% of the provisioned storage"),
+		rdsEventMessage(t, "Finished DB Instance backup"),
+		rdsEventMessage(t, "CPU utilization is above 90%"),
+	))
+
+	payloads := testutil.Payloads[MMSlashResponse](t, mattermost)
+	require.Len(t, payloads, 2)
+	assert.Equal(t, "RDS Low Storage", payloads[0].Attachments[0].Fields[0].Title)
+	assert.Equal(t, colors.DefaultFailure, payloads[0].Attachments[0].Color)
+	assert.Equal(t, "RDS High CPU", payloads[1].Attachments[0].Fields[0].Title)
+	assert.Equal(t, colors.DefaultWarning, payloads[1].Attachments[0].Color)
+
+	events := pagerDuty.Events()
+	require.Len(t, events, 1, "only the critical low storage event is sent to PagerDuty")
+	assert.Equal(t, "The free storage capacity for DB instance db-1 is low at 5% of the provisioned storage", events[0].Payload.Summary)
+}
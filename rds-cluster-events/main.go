@@ -1,7 +1,7 @@
 // Package main defines a Lambda function that processes AWS SNS events, specifically related to AWS alarm notifications.
-// The function listens for SNS messages that contain alarm state information and handles two types of events:
-// 'Started cross AZ failover' and 'Completed failover'. Depending on the type of event, it sends notifications
-// with appropriate color coding to a Mattermost channel. In non-test environments, it also interacts with PagerDuty,
+// The function listens for SNS messages that contain RDS event notifications and handles the event categories
+// listed in eventCategories, such as failovers and low storage. Depending on the category of the event, it sends
+// notifications with appropriate color coding to a Mattermost channel. In non-test environments, it also interacts with PagerDuty,
 // creating or closing alerts corresponding to the received SNS events. The PagerDuty and Mattermost integrations
 // require specific environment variables to be set for API keys and webhook URLs. This package is designed to
 // streamline incident management workflows by automating alert notifications and updates through common operational
@@ -13,12 +13,15 @@ import (
 	"encoding/json"
 	"os"
 	"strings"
+	"time"
 
 	pagerduty "github.com/PagerDuty/go-pagerduty"
+	cloudpagerduty "github.com/mattermost/mattermost-cloud-lambdas/internal/pagerduty"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/metrics"
 )
 
 // SNSMessageNotification represents the details of an SNS message related to AWS alarms.
@@ -40,32 +43,47 @@ func handler(_ context.Context, snsEvent events.SNSEvent) {
 			return
 		}
 
-		if strings.HasPrefix(messageNotification.EventMessage, "Started cross AZ failover") {
-			sendMattermostNotification(record.EventSource, "#FF0000", messageNotification)
+		category, ok := categorizeEvent(messageNotification.EventMessage)
+		if !ok {
+			log.WithField("message", messageNotification.EventMessage).Debug("Ignoring RDS event")
+			continue
+		}
 
-			// Trigger PagerDuty
-			if os.Getenv("ENVIRONMENT") != "" && os.Getenv("ENVIRONMENT") != "test" {
-				sendPagerDutyNotification(messageNotification)
-			}
-		} else if strings.HasPrefix(messageNotification.EventMessage, "Completed failover") {
-			sendMattermostNotification(record.EventSource, "#006400", messageNotification)
+		sendMattermostNotification(record.EventSource, category, messageNotification)
 
-			// Trigger PagerDuty
-			if os.Getenv("ENVIRONMENT") != "" && os.Getenv("ENVIRONMENT") != "test" {
+		// Trigger PagerDuty
+		if os.Getenv("ENVIRONMENT") != "" && os.Getenv("ENVIRONMENT") != "test" {
+			switch category.pagerDuty {
+			case pagerDutyTrigger:
+				sendPagerDutyNotification(messageNotification)
+			case pagerDutyResolve:
 				closePagerDutyIncidents(messageNotification)
 			}
 		}
 	}
 }
 
-func sendMattermostNotification(source, color string, messageNotification SNSMessageNotification) {
+// sendMattermostNotification posts the RDS event to Mattermost. When the
+// category mentions and the cluster owner is an @mention, the owner is
+// notified.
+func sendMattermostNotification(source string, category eventCategory, messageNotification SNSMessageNotification) {
 	attachment := []MMAttachment{}
 	attach := MMAttachment{
-		Color: color,
+		Color: category.color(),
 	}
-	attach = *attach.AddField(MMField{Title: "RDS DB Cluster Failover", Short: false})
+	attach = *attach.AddField(MMField{Title: category.title, Short: false})
 	attach = *attach.AddField(MMField{Title: "Cluster", Value: messageNotification.SourceID, Short: true})
 	attach = *attach.AddField(MMField{Title: "Message", Value: messageNotification.EventMessage, Short: true})
+	owner := clusterOwner(messageNotification.SourceID)
+	if owner != "" {
+		attach = *attach.AddField(MMField{Title: "Owner", Value: owner, Short: true})
+	}
+	if alias := accountAliases.Alias(); alias != "" {
+		attach = *attach.AddField(MMField{Title: "Account Alias", Value: alias, Short: true})
+	}
+	for _, field := range clusterFields(messageNotification.SourceID) {
+		attach = *attach.AddField(field)
+	}
 
 	attachment = append(attachment, attach)
 
@@ -74,11 +92,30 @@ func sendMattermostNotification(source, color string, messageNotification SNSMes
 		IconURL:     "https://cdn2.iconfinder.com/data/icons/amazon-aws-stencils/100/Non-Service_Specific_copy__AWS_Cloud-128.png",
 		Attachments: attachment,
 	}
+	if category.mention && strings.HasPrefix(owner, "@") {
+		payload.Text = owner
+	}
 	if os.Getenv("MATTERMOST_HOOK") != "" {
 		send(os.Getenv("MATTERMOST_HOOK"), payload)
 	}
 }
 
+// pagerDutyClient sends the PagerDuty events.
+var pagerDutyClient = pagerduty.NewClient("")
+
+// pagerDutyDetails returns the details of the PagerDuty event of an RDS
+// event.
+func pagerDutyDetails(messageNotification SNSMessageNotification) map[string]string {
+	details := map[string]string{
+		"Cluster": messageNotification.SourceID,
+	}
+	if alias := accountAliases.Alias(); alias != "" {
+		details["Account Alias"] = alias
+	}
+
+	return details
+}
+
 func sendPagerDutyNotification(messageNotification SNSMessageNotification) {
 	integrationKey := os.Getenv("PAGERDUTY_INTEGRATION_KEY")
 	if integrationKey == "" {
@@ -91,16 +128,16 @@ func sendPagerDutyNotification(messageNotification SNSMessageNotification) {
 		Action:     "trigger",
 		Payload: &pagerduty.V2Payload{
 			Summary:  messageNotification.EventMessage,
-			Source:   "Alarm System",
 			Severity: "critical",
-			Details: map[string]string{
-				"Cluster": messageNotification.SourceID,
-			},
+			Details:  pagerDutyDetails(messageNotification),
 		},
 	}
+	cloudpagerduty.SetOrigin(event.Payload, defaultPagerDutySource)
 
 	// Send the event to PagerDuty
-	_, err := pagerduty.ManageEventWithContext(context.Background(), event)
+	start := time.Now()
+	_, err := pagerDutyClient.ManageEventWithContext(context.Background(), &event)
+	metrics.RecordNotificationLatency(metrics.TargetPagerDuty, start)
 	if err != nil {
 		log.WithError(err).Error("Failed to send PagerDuty notification")
 		return
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// parseClusterOwners parses the CLUSTER_OWNER_MAP JSON object which maps an
+// RDS cluster identifier prefix to the team owning the cluster, e.g. a
+// Mattermost @mention.
+func parseClusterOwners(value string) (map[string]string, error) {
+	owners := map[string]string{}
+	if value == "" {
+		return owners, nil
+	}
+
+	if err := json.Unmarshal([]byte(value), &owners); err != nil {
+		return nil, err
+	}
+
+	return owners, nil
+}
+
+// clusterOwner returns the owner for the longest cluster identifier prefix
+// configured in CLUSTER_OWNER_MAP. An empty prefix matches every cluster and
+// serves as the fallback owner.
+func clusterOwner(clusterID string) string {
+	owners, err := parseClusterOwners(os.Getenv("CLUSTER_OWNER_MAP"))
+	if err != nil {
+		log.WithError(err).Error("Failed to parse CLUSTER_OWNER_MAP")
+	}
+
+	var matched, owner string
+	found := false
+	for prefix, value := range owners {
+		if strings.HasPrefix(clusterID, prefix) && (!found || len(prefix) > len(matched)) {
+			matched, owner, found = prefix, value, true
+		}
+	}
+
+	return owner
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterOwner(t *testing.T) {
+	t.Setenv("CLUSTER_OWNER_MAP", `{"rds-cluster-": "@cloud-team", "rds-cluster-multitenant-": "@dba-oncall"}`)
+
+	testCases := []struct {
+		name      string
+		clusterID string
+		expected  string
+	}{
+		{"matching", "rds-cluster-abc", "@cloud-team"},
+		{"longest prefix", "rds-cluster-multitenant-1234", "@dba-oncall"},
+		{"non-matching", "other-cluster", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, clusterOwner(tc.clusterID))
+		})
+	}
+}
+
+func TestClusterOwnerFallback(t *testing.T) {
+	t.Setenv("CLUSTER_OWNER_MAP", `{"": "Platform", "rds-cluster-": "@cloud-team"}`)
+
+	assert.Equal(t, "@cloud-team", clusterOwner("rds-cluster-abc"))
+	assert.Equal(t, "Platform", clusterOwner("other-cluster"))
+
+	t.Setenv("CLUSTER_OWNER_MAP", "not json")
+	assert.Empty(t, clusterOwner("rds-cluster-abc"))
+}
+
+func TestHandlerClusterOwner(t *testing.T) {
+	mattermost := testutil.NewMattermostServer(t)
+	t.Setenv("MATTERMOST_HOOK", mattermost.URL)
+	t.Setenv("ENVIRONMENT", "test")
+	t.Setenv("CLUSTER_OWNER_MAP", `{"rds-cluster-": "@cloud-team"}`)
+
+	completed, err := json.Marshal(SNSMessageNotification{SourceID: "rds-cluster-1", EventMessage: "Completed failover to DB instance: db-2"})
+	require.NoError(t, err)
+
+	handler(context.Background(), testutil.SNSEvent(failoverEvent(t), string(completed)))
+
+	payloads := testutil.Payloads[MMSlashResponse](t, mattermost)
+	require.Len(t, payloads, 2)
+	assert.Equal(t, "@cloud-team", payloads[0].Text)
+	assert.Equal(t, MMField{Title: "Owner", Value: "@cloud-team", Short: true}, *payloads[0].Attachments[0].Fields[3])
+	assert.Empty(t, payloads[1].Text)
+	assert.Equal(t, "Owner", payloads[1].Attachments[0].Fields[3].Title)
+}
@@ -0,0 +1,5 @@
+package main
+
+// defaultPagerDutySource is the source of the PagerDuty events when
+// PAGERDUTY_SOURCE is unset.
+const defaultPagerDutySource = "rds-cluster-events"
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http"
+	"time"
+
+	"github.com/mattermost/mattermost-cloud-lambdas/internal/metrics"
 )
 
 // MMField represents a single field in a Mattermost message attachment.
@@ -59,7 +62,9 @@ func send(webhookURL string, payload MMSlashResponse) {
 	req.Header.Set("Content-Type", "application/json")
 
 	client := &http.Client{}
+	start := time.Now()
 	resp, err := client.Do(req)
+	metrics.RecordNotificationLatency(metrics.TargetMattermost, start)
 	if err != nil {
 		panic(err)
 	}